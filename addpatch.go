@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// addPatchChoice is the answer to the prompt shown before each repository
+// in add --patch.
+type addPatchChoice int
+
+const (
+	patchStage addPatchChoice = iota
+	patchSkip
+	patchQuit
+)
+
+// parsePatchChoice interprets an answer to the per-repository prompt. An
+// empty answer stages, so pressing enter walks through every repository.
+func parsePatchChoice(answer string) (addPatchChoice, bool) {
+	switch strings.TrimSpace(strings.ToLower(answer)) {
+	case "", "y", "yes":
+		return patchStage, true
+	case "s", "skip", "n", "no":
+		return patchSkip, true
+	case "q", "quit":
+		return patchQuit, true
+	}
+	return 0, false
+}
+
+// askPatchChoice asks question about the next repository until it gets a
+// valid answer; the end of stdin quits.
+func askPatchChoice(question string) addPatchChoice {
+	for {
+		fmt.Printf("%s [Y]es, [s]kip, [q]uit: ", question)
+		answer, ok := readStdinLine()
+		if !ok {
+			return patchQuit
+		}
+		if c, ok := parsePatchChoice(answer); ok {
+			return c
+		}
+	}
+}
+
+// readStdinLine reads one line from stdin a byte at a time, so nothing
+// meant for the git add --patch or difftool session that follows is
+// buffered away.
+func readStdinLine() (string, bool) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				return string(line), true
+			}
+			line = append(line, b[0])
+		}
+		if err != nil {
+			return string(line), len(line) > 0
+		}
+	}
+}
+
+// addPatch runs git add --patch in each repository in turn, asking before
+// each one so the remaining repositories can be skipped or abandoned. Only
+// repositories with unstaged changes under the pathspecs are visited. There
+// is no timeout since the user drives each session.
+func addPatch(repos []string, addArgs map[string][]string) {
+	ctx := context.Background()
+	for i, r := range repos {
+		args := addArgs[r]
+		specs := args[slices.Index(args, "--"):]
+		changed, err := gitOutput(ctx, r, append([]string{"diff", "--name-only"}, specs...)...)
+		if err != nil {
+			repoFailed(r, err)
+			continue
+		}
+		if changed == "" {
+			continue
+		}
+
+		fmt.Printf("\n==== [%d/%d] %s ====\n", i+1, len(repos), r)
+		switch askPatchChoice(fmt.Sprintf("Stage hunks in %s?", r)) {
+		case patchSkip:
+			fmt.Println("skipped")
+			continue
+		case patchQuit:
+			fmt.Println("stopped, remaining repositories left untouched")
+			return
+		}
+		patch := append([]string{"add", "--patch"}, args[1:]...)
+		if err := runGit(ctx, r, patch...); err != nil {
+			repoFailed(r, err)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestParsePatchChoice(t *testing.T) {
+	cases := []struct {
+		in   string
+		want addPatchChoice
+		ok   bool
+	}{
+		{"", patchStage, true},
+		{"Y", patchStage, true},
+		{" s ", patchSkip, true},
+		{"no", patchSkip, true},
+		{"q", patchQuit, true},
+		{"maybe", 0, false},
+	}
+	for _, c := range cases {
+		got, ok := parsePatchChoice(c.in)
+		if got != c.want || ok != c.ok {
+			t.Errorf("parsePatchChoice(%q) = %v, %v; want %v, %v", c.in, got, ok, c.want, c.ok)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Aliases are the [alias] section of the config file: named pipelines of
+// git commands run in every matching repository.
+//
+//	[alias]
+//	release = ["fetch", "switch main", "pull --ff-only", "push --tags"]
+//
+// Each step is a git command line without the leading "git"; arguments
+// with spaces are quoted as in a shell. gitbatch release <pattern>... runs
+// the steps in order in each repository and stops in a repository at its
+// first failing step. The [[policy]] rules apply to each step as well as to
+// the alias.
+type Aliases map[string][]string
+
+// splitWords splits a step into arguments. Single and double quotes group
+// words and a backslash escapes the next character outside single quotes.
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", s)
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// parseSteps splits the steps of alias name into git arguments.
+func parseSteps(name string, steps []string) ([][]string, error) {
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("alias %q has no steps", name)
+	}
+	parsed := make([][]string, len(steps))
+	for i, s := range steps {
+		args, err := splitWords(s)
+		if err != nil {
+			return nil, fmt.Errorf("alias %q: %v", name, err)
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("alias %q: step %d is empty", name, i+1)
+		}
+		if args[0] == "git" {
+			args = args[1:]
+		}
+		parsed[i] = args
+	}
+	return parsed, nil
+}
+
+// runPipeline runs steps in repo and returns the error of the first step
+// that fails; the remaining steps are skipped.
+func runPipeline(ctx context.Context, repo string, steps [][]string) error {
+	for i, step := range steps {
+		logf(levelNormal, "[%d/%d] git %s", i+1, len(steps), shellJoin(step))
+		if err := runGit(ctx, repo, step...); err != nil {
+			return fmt.Errorf("step %d (git %s): %v", i+1, shellJoin(step), err)
+		}
+	}
+	return nil
+}
+
+// aliasCommand is the command running the pipeline of alias name.
+func aliasCommand(name string, steps []string) *cobra.Command {
+	lines := make([]string, len(steps))
+	for i, s := range steps {
+		lines[i] = "git " + strings.TrimPrefix(strings.TrimSpace(s), "git ")
+	}
+	return &cobra.Command{
+		Use:   name + " <pattern>...",
+		Short: "Alias: " + strings.Join(lines, "; "),
+		Long: fmt.Sprintf(`%s is an alias from the config file. It runs these git commands in
+order in each matching repository and stops in a repository at its first
+failing step:
+
+  %s`, name, strings.Join(lines, "\n  ")),
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parsed, err := parseSteps(name, steps)
+			if err != nil {
+				return err
+			}
+			repos, err := resolveRepos(args)
+			if err != nil {
+				return err
+			}
+			if err := checkStepPolicies(parsed, repos); err != nil {
+				return err
+			}
+			if err := startRun(repos); err != nil {
+				return err
+			}
+			ctx, cancel := runContext()
+			defer cancel()
+			for _, r := range repos {
+				repoHeader(r)
+				if err := runPipeline(ctx, r, parsed); err != nil {
+					repoFailed(r, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// configFlag returns the value of --config in args, before cobra parses
+// them: aliases must be registered before the command line is resolved.
+func configFlag(args []string) string {
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		if v, ok := strings.CutPrefix(a, "--config="); ok {
+			return v
+		}
+		if a == "--config" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// registerAliases adds a command for every alias in the config file.
+// Aliases cannot replace built-in commands. A config file that does not
+// load is left for the command to report.
+func registerAliases(args []string) {
+	configPath = configFlag(args)
+	cfg, err := loadConfig()
+	if err != nil {
+		loadedConfig = nil
+		return
+	}
+	rootCmd.InitDefaultHelpCmd()
+	rootCmd.InitDefaultCompletionCmd()
+	names := make([]string, 0, len(cfg.Aliases))
+	for name := range cfg.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if c, _, err := rootCmd.Find([]string{name}); err == nil && c != rootCmd {
+			fmt.Fprintf(os.Stderr, "alias %q ignored: %s is a built-in command\n", name, name)
+			continue
+		}
+		rootCmd.AddCommand(aliasCommand(name, cfg.Aliases[name]))
+	}
+}
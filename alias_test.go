@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	cases := map[string][]string{
+		"fetch --all":                 {"fetch", "--all"},
+		"  switch   main ":            {"switch", "main"},
+		`log -1 --format='%h %s'`:     {"log", "-1", "--format=%h %s"},
+		`commit -m "it's done"`:       {"commit", "-m", "it's done"},
+		`tag -m release\ notes v1`:    {"tag", "-m", "release notes", "v1"},
+		`config user.name ""`:         {"config", "user.name", ""},
+		`grep -e "a \"quoted\" word"`: {"grep", "-e", `a "quoted" word`},
+		`grep -e 'back\slash'`:        {"grep", "-e", `back\slash`},
+	}
+	for in, want := range cases {
+		got, err := splitWords(in)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("splitWords(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{`commit -m "open`, `log 'x`, `trailing\`} {
+		if _, err := splitWords(bad); err == nil {
+			t.Errorf("splitWords(%q) accepted", bad)
+		}
+	}
+}
+
+func TestParseSteps(t *testing.T) {
+	steps, err := parseSteps("release", []string{"fetch", "git switch main"})
+	if err != nil || !reflect.DeepEqual(steps, [][]string{{"fetch"}, {"switch", "main"}}) {
+		t.Errorf("parseSteps = %q, %v", steps, err)
+	}
+	if _, err := parseSteps("empty", nil); err == nil {
+		t.Error("alias without steps accepted")
+	}
+	if _, err := parseSteps("blank", []string{"fetch", " "}); err == nil {
+		t.Error("blank step accepted")
+	}
+}
+
+func TestRunPipelineStopsAtFirstFailure(t *testing.T) {
+	m := useMockGit(t)
+	m.On("pull --ff-only", "", errors.New("exit status 128"))
+	err := runPipeline(context.Background(), "/repo", [][]string{{"fetch"}, {"pull", "--ff-only"}, {"push"}})
+	if err == nil || !strings.Contains(err.Error(), "step 2 (git pull --ff-only)") {
+		t.Errorf("err = %v", err)
+	}
+	if got := m.Commands(); !reflect.DeepEqual(got, []string{"git fetch", "git pull --ff-only"}) {
+		t.Errorf("ran %q", got)
+	}
+}
+
+func TestRegisterAliases(t *testing.T) {
+	useTestConfig(t, `
+[alias]
+release = ["fetch", "push --tags"]
+status = ["status -s"]
+`)
+	path := configPath
+	t.Cleanup(func() {
+		if c, _, err := rootCmd.Find([]string{"release"}); err == nil && c != rootCmd {
+			rootCmd.RemoveCommand(c)
+		}
+	})
+	stderr := captureStderr(t, func() { registerAliases([]string{"--config", path, "release", "x"}) })
+	if !strings.Contains(stderr, `alias "status" ignored`) {
+		t.Errorf("shadowing alias not reported: %q", stderr)
+	}
+	c, _, err := rootCmd.Find([]string{"release", "x"})
+	if err != nil || c.Name() != "release" {
+		t.Fatalf("release not registered: %v", err)
+	}
+	if !strings.Contains(c.Short, "git fetch; git push --tags") {
+		t.Errorf("Short = %q", c.Short)
+	}
+	if c, _, _ := rootCmd.Find([]string{"status"}); c != statusCmd {
+		t.Error("alias replaced the built-in status")
+	}
+}
+
+func TestConfigFlag(t *testing.T) {
+	for want, args := range map[string][]string{
+		"a.toml": {"--config", "a.toml", "sync"},
+		"b.toml": {"-q", "--config=b.toml", "sync"},
+		"":       {"sync", "--", "--config", "c.toml"},
+	} {
+		if got := configFlag(args); got != want {
+			t.Errorf("configFlag(%q) = %q, want %q", args, got, want)
+		}
+	}
+}
+
+func TestAliasPolicyBeforeRun(t *testing.T) {
+	useTestConfig(t, "[[policy]]\ncommand = \"push --force\"\ndeny = true\n")
+	repo := initTestRepo(t)
+	oldRepos := runSummary.repos
+	runSummary.repos = nil
+	t.Cleanup(func() { runSummary.repos = oldRepos })
+
+	cmd := aliasCommand("release", []string{"fetch", "push --force"})
+	if err := cmd.RunE(cmd, []string{repo}); exitCode(err) != exitPolicy {
+		t.Fatalf("denied step: exit %d (%v), want %d", exitCode(err), err, exitPolicy)
+	}
+	if len(runSummary.repos) != 0 {
+		t.Errorf("run started on %v before the policy check", runSummary.repos)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// archiveFormats are the --format values, as git archive names them.
+var archiveFormats = map[string]bool{"tar": true, "tar.gz": true, "tgz": true, "zip": true}
+
+// archiveName is the file name of repo's archive at commit short.
+func archiveName(repo, short, format string) string {
+	return filepath.Base(repo) + "-" + short + "." + format
+}
+
+// archive command
+var archiveOut string
+var archiveFormat string
+var archiveRef string
+var archiveCmd = &cobra.Command{
+	Use:   "archive --out <dir> [--format tar.gz] [--ref HEAD] <pattern>...",
+	Short: "Write a source archive of each repository with git archive",
+	Long: `archive runs git archive for every matching repository and writes
+<dir>/<repo>-<shortsha>.<format>, a snapshot of the tracked files at HEAD or
+--ref without the .git directory. Files inside the archive are under a
+<repo>/ directory. --format is tar, tar.gz, tgz or zip.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !archiveFormats[archiveFormat] {
+			return usageErrorf("invalid --format %q (expected tar, tar.gz, tgz or zip)", archiveFormat)
+		}
+		out, err := filepath.Abs(archiveOut)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(out, 0o755); err != nil {
+			return err
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		written := map[string]string{}
+		for _, r := range repos {
+			repoHeader(r)
+			short, err := gitOutput(ctx, r, "rev-parse", "--short", "--verify", archiveRef+"^{commit}")
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			file := filepath.Join(out, archiveName(r, short, archiveFormat))
+			// repositories with the same name at the same commit would
+			// overwrite each other
+			if other, ok := written[file]; ok {
+				repoFailed(r, fmt.Errorf("%s already written for %s", file, other))
+				continue
+			}
+			if err := runGit(ctx, r, "archive", "--format="+archiveFormat, "--prefix="+filepath.Base(r)+"/", "-o", file, archiveRef); err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			written[file] = r
+			fmt.Printf("wrote %s\n", file)
+		}
+		fmt.Printf("\n%d archives written to %s\n", len(written), archiveOut)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(archiveCmd)
+
+	archiveCmd.Flags().StringVar(&archiveOut, "out", "", "directory to write the archives to (required)")
+	archiveCmd.MarkFlagRequired("out")
+	archiveCmd.Flags().StringVar(&archiveFormat, "format", "tar.gz", "archive format: tar, tar.gz, tgz or zip")
+	archiveCmd.Flags().StringVar(&archiveRef, "ref", "HEAD", "branch, tag or commit to archive")
+}
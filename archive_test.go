@@ -0,0 +1,57 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestArchiveCommand(t *testing.T) {
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "src/main.go", "package main\n", "first")
+	out := t.TempDir()
+	t.Chdir(filepath.Dir(repo))
+	oldOut, oldFormat, oldRef := archiveOut, archiveFormat, archiveRef
+	t.Cleanup(func() { archiveOut, archiveFormat, archiveRef = oldOut, oldFormat, oldRef })
+	archiveOut, archiveFormat, archiveRef = out, "tar.gz", "HEAD"
+
+	if err := archiveCmd.RunE(archiveCmd, []string{filepath.Base(repo)}); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(out, "*"))
+	name := regexp.QuoteMeta(filepath.Base(repo))
+	if len(files) != 1 || !regexp.MustCompile(`^`+name+`-[0-9a-f]{7,}\.tar\.gz$`).MatchString(filepath.Base(files[0])) {
+		t.Fatalf("archives = %v", files)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err != nil {
+			break
+		}
+		if h.Name == filepath.Base(repo)+"/src/main.go" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("src/main.go missing from the archive")
+	}
+
+	archiveFormat = "rar"
+	if err := archiveCmd.RunE(archiveCmd, []string{filepath.Base(repo)}); err == nil {
+		t.Error("invalid format accepted")
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+// AuditConfig is the [audit] section of the config file. Mutating runs are
+// appended to the audit log unless it is disabled:
+//
+//	[audit]
+//	path = "/var/log/gitbatch/audit.jsonl"
+//	disabled = false
+type AuditConfig struct {
+	Path     string `toml:"path"`
+	Disabled bool   `toml:"disabled"`
+}
+
+// auditRecord is one line of the audit log.
+type auditRecord struct {
+	Time     time.Time   `json:"time"`
+	User     string      `json:"user"`
+	Host     string      `json:"host"`
+	Command  string      `json:"command"`
+	Args     []string    `json:"args"`
+	Duration float64     `json:"duration_seconds"`
+	Error    string      `json:"error,omitempty"`
+	Repos    []auditRepo `json:"repos"`
+}
+
+// auditRepo is the outcome of a run in one repository, with HEAD before
+// and after it ("" for repositories without commits).
+type auditRepo struct {
+	Path   string `json:"path"`
+	OK     bool   `json:"ok"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// auditState holds what the current run needs for its audit record.
+var auditState = struct {
+	sync.Mutex
+	enabled bool
+	before  map[string]string
+}{before: map[string]string{}}
+
+// auditLogPath returns the audit log from the config file, or audit.jsonl
+// next to the default config file.
+func auditLogPath(cfg *Config) string {
+	if cfg.Audit.Path != "" {
+		return expandHome(cfg.Audit.Path)
+	}
+	if p := defaultConfigPath(); p != "" {
+		return filepath.Join(filepath.Dir(p), "audit.jsonl")
+	}
+	return ""
+}
+
+// mutating reports whether cmd changes repositories, as opposed to the
+// commands allowed in read-only mode. Runs that only write --emit-script
+// change nothing.
+func mutating(cmd *cobra.Command) bool {
+	return cmd != nil && cmd.Runnable() && !cmd.Flags().Changed("help") && emitScript == "" && !allowedReadOnly(cmd)
+}
+
+// setupAudit turns on HEAD tracking for mutating commands when the audit
+// log is enabled.
+func setupAudit(cmd *cobra.Command) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	auditState.Lock()
+	defer auditState.Unlock()
+	auditState.enabled = !cfg.Audit.Disabled && auditLogPath(cfg) != "" && mutating(cmd)
+	return nil
+}
+
+// headSHA returns HEAD of repo, or "" without commits. The git binary is
+// used directly so these lookups are not logged or retried.
+func headSHA(repo string) string {
+	sha, err := gitBinary.Output(context.Background(), runner.Git(repo, "rev-parse", "--verify", "--quiet", "HEAD"))
+	if err != nil {
+		return ""
+	}
+	return sha
+}
+
+// auditBefore records HEAD of repos before a mutating command touches them.
+func auditBefore(repos []string) {
+	auditState.Lock()
+	defer auditState.Unlock()
+	if !auditState.enabled {
+		return
+	}
+	for _, r := range repos {
+		auditState.before[r] = headSHA(r)
+	}
+}
+
+// buildAuditRecord assembles the record of a finished run.
+func buildAuditRecord(command string, args, repos []string, failed map[string]bool, before map[string]string, elapsed time.Duration, runErr error) auditRecord {
+	rec := auditRecord{
+		Time:     time.Now().UTC(),
+		User:     currentUser(),
+		Command:  command,
+		Args:     redactArgs(args),
+		Duration: elapsed.Seconds(),
+		Repos:    []auditRepo{},
+	}
+	rec.Host, _ = os.Hostname()
+	if runErr != nil {
+		rec.Error = runErr.Error()
+	}
+	for _, r := range repos {
+		rec.Repos = append(rec.Repos, auditRepo{Path: r, OK: !failed[r], Before: before[r], After: headSHA(r)})
+	}
+	return rec
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// appendAudit appends rec to the audit log as one JSON line.
+func appendAudit(path string, rec auditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	// a single write keeps concurrent runs from interleaving lines
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// auditRun appends the finished run to the audit log when cmd mutates
+// repositories, including runs refused by a policy or read-only mode.
+func auditRun(cmd *cobra.Command, runErr error) {
+	if !mutating(cmd) {
+		return
+	}
+	cfg, err := loadConfig()
+	if err != nil || cfg.Audit.Disabled {
+		return
+	}
+	path := auditLogPath(cfg)
+	if path == "" {
+		return
+	}
+	runSummary.Lock()
+	repos, failed := runSummary.repos, runSummary.failed
+	runSummary.Unlock()
+	auditState.Lock()
+	before := auditState.before
+	auditState.Unlock()
+
+	rec := buildAuditRecord(commandName(cmd), os.Args[1:], repos, failed, before, time.Since(runSummary.start), runErr)
+	if err := appendAudit(path, rec); err != nil {
+		fmt.Fprintf(os.Stderr, "audit log: %v\n", err)
+	}
+}
+
+// headSHAs returns HEAD of each of repos.
+func headSHAs(repos []string) map[string]string {
+	before := map[string]string{}
+	for _, r := range repos {
+		before[r] = headSHA(r)
+	}
+	return before
+}
+
+// auditServeRun appends a run that serve started to the audit log. It is
+// the record auditRun writes for a command line, with args standing in for
+// the command line.
+func auditServeRun(command string, args, repos []string, failed map[string]bool, before map[string]string, elapsed time.Duration) {
+	cfg, err := loadConfig()
+	if err != nil || cfg.Audit.Disabled {
+		return
+	}
+	path := auditLogPath(cfg)
+	if path == "" {
+		return
+	}
+	if err := appendAudit(path, buildAuditRecord(command, args, repos, failed, before, elapsed, nil)); err != nil {
+		fmt.Fprintf(os.Stderr, "audit log: %v\n", err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuildAuditRecord(t *testing.T) {
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+	empty := initTestRepo(t)
+
+	rec := buildAuditRecord("push", []string{"push", "--auth-token", "s3cret", "api"}, []string{repo, empty},
+		map[string]bool{empty: true}, map[string]string{repo: "abc"}, 1500*time.Millisecond, errors.New("policy refused"))
+	if rec.Command != "push" || rec.Duration != 1.5 || rec.Error != "policy refused" || rec.User == "" {
+		t.Errorf("record = %+v", rec)
+	}
+	if !reflect.DeepEqual(rec.Args, []string{"push", "--auth-token", redacted, "api"}) {
+		t.Errorf("args = %q", rec.Args)
+	}
+	if len(rec.Repos) != 2 {
+		t.Fatalf("repos = %+v", rec.Repos)
+	}
+	if r := rec.Repos[0]; r.Path != repo || !r.OK || r.Before != "abc" || len(r.After) != 40 {
+		t.Errorf("repo with commits = %+v", r)
+	}
+	if r := rec.Repos[1]; r.Path != empty || r.OK || r.Before != "" || r.After != "" {
+		t.Errorf("repo without commits = %+v", r)
+	}
+}
+
+func TestAppendAuditReadAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.jsonl")
+	if records, err := readAudit(path); err != nil || records != nil {
+		t.Fatalf("readAudit of a missing log = %v, %v", records, err)
+	}
+	want := []auditRecord{
+		{Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), User: "ana", Host: "box", Command: "pull", Args: []string{"pull", "api"}, Duration: 2, Repos: []auditRepo{{Path: "/src/api", OK: true, Before: "a1", After: "b2"}}},
+		{Time: time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC), User: "bo", Command: "push", Args: []string{"push", "web"}, Error: "exit status 1", Repos: []auditRepo{{Path: "/src/web"}}},
+	}
+	for _, rec := range want {
+		if err := appendAudit(path, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := readAudit(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readAudit = %+v, want %+v", got, want)
+	}
+}
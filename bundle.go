@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// bundleExt is the extension of the bundle files written per repository.
+const bundleExt = ".bundle"
+
+// bundlePath is the bundle file of repo below dir. Bundles mirror the layout
+// of the repositories below the current directory, so "gitbatch bundle apply"
+// run from the same place on the other side finds them again.
+func bundlePath(dir, repo string) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(repo)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(cwd, abs)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%s is not below the current directory", repo)
+	}
+	// git runs in the repository, so the bundle path must be absolute
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, rel+bundleExt), nil
+}
+
+// emptyBundle reports whether git bundle create failed because no commit
+// was selected, going by its output.
+func emptyBundle(out string, err error) bool {
+	return err != nil && strings.Contains(out, "Refusing to create empty bundle")
+}
+
+// bundleFetchRefspecs bring a bundle's branches in as remote-tracking
+// branches of bundleRemote and its tags as tags. Local branches are left
+// alone, to be merged or reset as each repository needs.
+func bundleFetchRefspecs(remote string) []string {
+	return []string{"+refs/heads/*:refs/remotes/" + remote + "/*", "refs/tags/*:refs/tags/*"}
+}
+
+// bundle command
+var bundleOut string
+var bundleSince string
+var bundleRemote string
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Carry repositories across an air gap with git bundle",
+	Long: `bundle writes the branches and tags of every matching repository to one
+bundle file each, and applies such bundles on the other side. Bundle files
+mirror the repositories' paths below the current directory, so run both
+commands from the workspace root:
+
+  gitbatch bundle create --out /media/usb/ --since 2024-05-01 "**"
+  gitbatch bundle apply /media/usb/ "**"`,
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create --out <dir> [--since <date>] <pattern>...",
+	Short: "Write one bundle per repository to a directory",
+	Long: `create runs git bundle create for every matching repository, writing
+<dir>/<path>.bundle with all branches and tags. With --since, only commits
+newer than the date are included; the other side must already have the rest
+of the history. Repositories without new commits are skipped.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		created := 0
+		for _, r := range repos {
+			repoHeader(r)
+			file, err := bundlePath(bundleOut, r)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			gitArgs := []string{"bundle", "create", file}
+			if bundleSince != "" {
+				gitArgs = append(gitArgs, "--since="+bundleSince)
+			}
+			gitArgs = append(gitArgs, "--branches", "--tags")
+			if out, err := runGitCapture(ctx, r, gitArgs...); err != nil {
+				if emptyBundle(out, err) {
+					fmt.Println("no new commits, skipped")
+					continue
+				}
+				repoFailed(r, fmt.Errorf("%v\n%s", err, strings.TrimRight(out, "\n")))
+				continue
+			}
+			fmt.Printf("wrote %s\n", file)
+			created++
+		}
+		fmt.Printf("\n%d bundles written to %s\n", created, bundleOut)
+		return nil
+	},
+}
+
+var bundleApplyCmd = &cobra.Command{
+	Use:   "apply <dir> <pattern>...",
+	Short: "Fetch the bundles in a directory into the matching repositories",
+	Long: `apply fetches each repository's bundle from <dir>, as written by
+"gitbatch bundle create", into the repository. Branches arrive as
+remote-tracking branches under --remote (bundle/main, ...) and tags as tags;
+local branches are not changed. Repositories without a bundle are skipped.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := args[0]
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+		repos, err := collectRepos(args[1:])
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		for _, r := range repos {
+			repoHeader(r)
+			file, err := bundlePath(dir, r)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				fmt.Println("no bundle, skipped")
+				continue
+			}
+			if err := runGit(ctx, r, append([]string{"fetch", file}, bundleFetchRefspecs(bundleRemote)...)...); err != nil {
+				repoFailed(r, err)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bundleCmd)
+	bundleCmd.AddCommand(bundleCreateCmd)
+	bundleCmd.AddCommand(bundleApplyCmd)
+
+	bundleCreateCmd.Flags().StringVar(&bundleOut, "out", "", "directory to write the bundles to (required)")
+	bundleCreateCmd.MarkFlagRequired("out")
+	bundleCreateCmd.Flags().StringVar(&bundleSince, "since", "", "only include commits newer than this date (e.g. 2024-05-01 or 2.weeks.ago)")
+	bundleApplyCmd.Flags().StringVar(&bundleRemote, "remote", "bundle", "name under which the bundled branches appear as remote-tracking branches")
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestBundlePath(t *testing.T) {
+	root := t.TempDir()
+	t.Chdir(root)
+	got, err := bundlePath("out", filepath.Join(root, "services", "api"))
+	if err != nil || got != filepath.Join(root, "out", "services", "api.bundle") {
+		t.Errorf("bundlePath = %q, %v", got, err)
+	}
+	if _, err := bundlePath("out", filepath.Dir(root)); err == nil {
+		t.Error("repository outside the current directory accepted")
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src, dst := initTestRepo(t), initTestRepo(t)
+	commitTestFile(t, src, "a.txt", "a", "first")
+	file := filepath.Join(t.TempDir(), "src.bundle")
+	if _, err := runGitCapture(ctx, src, "bundle", "create", file, "--branches", "--tags"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGit(ctx, dst, append([]string{"fetch", file}, bundleFetchRefspecs("bundle")...)...); err != nil {
+		t.Fatal(err)
+	}
+	branch, _ := currentBranch(ctx, src)
+	c := exec.Command("git", "rev-parse", "--verify", "bundle/"+branch)
+	c.Dir = dst
+	if out, err := c.CombinedOutput(); err != nil {
+		t.Errorf("bundle/%s missing after fetch: %v %s", branch, err, out)
+	}
+	if out, err := runGitCapture(ctx, src, "bundle", "create", file, "--since=2090-01-01", "--branches", "--tags"); !emptyBundle(out, err) {
+		t.Errorf("empty bundle not detected: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// mergeCheck is the predicted outcome of merging a ref into HEAD.
+type mergeCheck struct {
+	ahead, behind int
+	conflicts     []string // files that would conflict
+}
+
+func (c mergeCheck) String() string {
+	switch {
+	case c.behind == 0:
+		return "up to date"
+	case c.ahead == 0:
+		return "fast-forward"
+	case len(c.conflicts) > 0:
+		return "conflicts: " + strings.Join(c.conflicts, ", ")
+	}
+	return "clean"
+}
+
+// refRemote returns the remote a remote-tracking ref such as origin/main
+// belongs to, "" for other refs.
+func refRemote(ctx context.Context, repo, ref string) string {
+	remotes, err := remoteNames(ctx, repo)
+	if err != nil {
+		return ""
+	}
+	for _, r := range remotes {
+		if strings.HasPrefix(ref, r+"/") {
+			return r
+		}
+	}
+	return ""
+}
+
+// predictMerge runs git merge-tree on HEAD and ref in repo, which merges in
+// memory and leaves the index and working tree alone.
+func predictMerge(ctx context.Context, repo, ref string) (mergeCheck, error) {
+	var c mergeCheck
+	var err error
+	if c.ahead, c.behind, err = aheadBehind(ctx, repo, "HEAD", ref); err != nil {
+		return c, err
+	}
+	if c.ahead == 0 || c.behind == 0 {
+		return c, nil
+	}
+	// the tree id, then one conflicted file per line; exit status 1 means
+	// the merge has conflicts
+	out, err := runGitCapture(ctx, repo, "merge-tree", "--write-tree", "--name-only", "--no-messages", "HEAD", ref)
+	var exit *exec.ExitError
+	if err != nil && !(errors.As(err, &exit) && exit.ExitCode() == 1) {
+		return c, fmt.Errorf("git merge-tree: %v: %s", err, firstLine(strings.TrimSpace(out)))
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	for _, l := range lines[1:] {
+		if l != "" {
+			c.conflicts = append(c.conflicts, l)
+		}
+	}
+	if err != nil && len(c.conflicts) == 0 {
+		c.conflicts = []string{"(unknown)"}
+	}
+	return c, nil
+}
+
+// check-merge command
+var checkMergeRef string
+var checkMergeNoFetch bool
+var checkMergeCmd = &cobra.Command{
+	Use:   "check-merge [--ref origin/main] [--no-fetch] <pattern>...",
+	Short: "Predict which repositories would conflict when merging a ref",
+	Long: `check-merge predicts, for every matching repository, whether merging --ref
+into the current branch would conflict, without touching the index or the
+working tree. It first fetches the remote of --ref (origin for
+origin/main), then merges in memory with git merge-tree (git 2.38 or newer)
+and prints each repository's result: up to date, fast-forward, clean, or
+the files that would conflict.
+
+Repositories that would conflict count as failed, so the exit status tells
+scripts whether a batch merge or rebase can go ahead. A rebase replays the
+local commits one by one, so it can stop at conflicts that cancel out in a
+merge, but the files listed are a good guide to which repositories to
+clean up first. --no-fetch checks against the ref as last fetched.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		var conflicting []string
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPO\tBRANCH\tAHEAD\tBEHIND\tRESULT")
+		for _, r := range repos {
+			branch, err := currentBranch(ctx, r)
+			if err != nil {
+				branch = "-"
+			} else if branch == "" {
+				branch = "(detached)"
+			}
+			if remote := refRemote(ctx, r, checkMergeRef); remote != "" && !checkMergeNoFetch {
+				if _, err := runGitCapture(ctx, r, "fetch", "--quiet", remote); err != nil {
+					fmt.Fprintf(w, "%s\t%s\t-\t-\terror: fetching %s: %v\n", r, branch, remote, err)
+					markRepoFailed(r)
+					continue
+				}
+			}
+			if _, err := gitOutput(ctx, r, "rev-parse", "--verify", "--quiet", checkMergeRef+"^{commit}"); err != nil {
+				fmt.Fprintf(w, "%s\t%s\t-\t-\tno %s\n", r, branch, checkMergeRef)
+				continue
+			}
+			c, err := predictMerge(ctx, r, checkMergeRef)
+			if err != nil {
+				fmt.Fprintf(w, "%s\t%s\t-\t-\terror: %v\n", r, branch, err)
+				markRepoFailed(r)
+				continue
+			}
+			if len(c.conflicts) > 0 {
+				conflicting = append(conflicting, r)
+				markRepoFailed(r)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", r, branch, c.ahead, c.behind, c)
+		}
+		w.Flush()
+
+		if len(conflicting) > 0 {
+			fmt.Printf("\n%d repositories would conflict merging %s:\n", len(conflicting), checkMergeRef)
+			for _, r := range conflicting {
+				fmt.Printf("  %s\n", r)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkMergeCmd)
+
+	checkMergeCmd.Flags().StringVar(&checkMergeRef, "ref", "origin/main", "ref to check merging into the current branch")
+	checkMergeCmd.Flags().BoolVar(&checkMergeNoFetch, "no-fetch", false, "do not fetch the ref's remote first")
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPredictMerge(t *testing.T) {
+	ctx := context.Background()
+	upstream := initTestRepo(t)
+	commitTestFile(t, upstream, "a.txt", "a\n", "first")
+	clone := func() string {
+		dir := filepath.Join(t.TempDir(), "clone")
+		if out, err := exec.Command("git", "clone", "-q", upstream, dir).CombinedOutput(); err != nil {
+			t.Fatalf("git clone: %v %s", err, out)
+		}
+		initTestRepoAt(t, dir) // sets the committer identity
+		return dir
+	}
+	current, clean, conflicting := clone(), clone(), clone()
+	commitTestFile(t, clean, "b.txt", "b\n", "unrelated")
+	commitTestFile(t, conflicting, "a.txt", "mine\n", "conflicting")
+	commitTestFile(t, upstream, "a.txt", "theirs\n", "upstream")
+	for _, r := range []string{current, clean, conflicting} {
+		if out, err := exec.Command("git", "-C", r, "fetch", "-q").CombinedOutput(); err != nil {
+			t.Fatalf("git fetch: %v %s", err, out)
+		}
+	}
+	ref := "@{upstream}"
+
+	for repo, want := range map[string]string{current: "fast-forward", clean: "clean", conflicting: "conflicts: a.txt"} {
+		c, err := predictMerge(ctx, repo, ref)
+		if err != nil || c.String() != want {
+			t.Errorf("%s: %v, %v, want %q", filepath.Base(repo), c, err, want)
+		}
+	}
+	if out, err := exec.Command("git", "-C", conflicting, "status", "--porcelain").CombinedOutput(); err != nil || len(out) != 0 {
+		t.Errorf("working tree touched: %q %v", out, err)
+	}
+	if c, err := predictMerge(ctx, upstream, "HEAD~1"); err != nil || c.String() != "up to date" {
+		t.Errorf("merging an ancestor: %v, %v", c, err)
+	}
+	if c, _ := predictMerge(ctx, conflicting, ref); !reflect.DeepEqual(c.conflicts, []string{"a.txt"}) || c.ahead != 1 || c.behind != 1 {
+		t.Errorf("conflicting: %+v", c)
+	}
+
+	if r := refRemote(ctx, clean, "origin/main"); r != "origin" {
+		t.Errorf("refRemote(origin/main) = %q", r)
+	}
+	if r := refRemote(ctx, clean, "main"); r != "" {
+		t.Errorf("refRemote(main) = %q", r)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import "os"
+
+const (
+	ansiReset  = "\033[0m"
+	ansiBold   = "\033[1m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+)
+
+// colorMode is --color: auto colors terminals unless NO_COLOR is set.
+var colorMode = "auto"
+
+func checkColorMode() error {
+	switch colorMode {
+	case "auto", "always", "never":
+		return nil
+	}
+	return usageErrorf("invalid --color %q (expected auto, always or never)", colorMode)
+}
+
+// colorEnabled reports whether output written to f should be colored.
+func colorEnabled(f *os.File) bool {
+	switch colorMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+// paint wraps s in the ANSI code when output to f is colored.
+func paint(f *os.File, code, s string) string {
+	if !colorEnabled(f) {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", "auto", "color output: auto, always or never (auto honours NO_COLOR)")
+}
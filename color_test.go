@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestColorEnabled(t *testing.T) {
+	defer func() { colorMode = "auto" }()
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	colorMode = "always"
+	if got := paint(f, ansiRed, "x"); got != ansiRed+"x"+ansiReset {
+		t.Errorf("always: got %q", got)
+	}
+	colorMode = "never"
+	if got := paint(f, ansiRed, "x"); got != "x" {
+		t.Errorf("never: got %q", got)
+	}
+	// auto never colors files that are not terminals
+	colorMode = "auto"
+	if colorEnabled(f) {
+		t.Error("auto colored a regular file")
+	}
+	colorMode = "sometimes"
+	if err := checkColorMode(); err == nil {
+		t.Error("expected error for invalid --color")
+	}
+}
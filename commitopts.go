@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+var commitAmend bool
+var commitSignoff bool
+var commitAllowEmpty bool
+var commitAuthor string
+var commitCoAuthors []string
+
+var personPattern = regexp.MustCompile(`^[^<>]+ <[^<>\s]+@[^<>\s]+>$`)
+
+// validatePerson checks that s has the "Name <email>" form git expects for
+// authors and co-author trailers.
+func validatePerson(flag, s string) error {
+	if !personPattern.MatchString(strings.TrimSpace(s)) {
+		return usageErrorf("invalid %s %q: expected \"Name <email>\"", flag, s)
+	}
+	return nil
+}
+
+// withCoAuthors appends a Co-authored-by trailer per co-author to msg.
+func withCoAuthors(msg string, coAuthors []string) (string, error) {
+	if len(coAuthors) == 0 {
+		return msg, nil
+	}
+	var trailers []string
+	for _, c := range coAuthors {
+		if err := validatePerson("--co-author", c); err != nil {
+			return "", err
+		}
+		trailers = append(trailers, "Co-authored-by: "+strings.TrimSpace(c))
+	}
+	return strings.TrimRight(msg, "\n") + "\n\n" + strings.Join(trailers, "\n"), nil
+}
+
+// headIsPushed reports whether HEAD is reachable from any remote-tracking
+// branch, in which case amending it would rewrite published history.
+func headIsPushed(ctx context.Context, repo string) (bool, error) {
+	out, err := gitOutput(ctx, repo, "for-each-ref", "--count=1", "--contains=HEAD", "--format=%(refname)", "refs/remotes")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// commitOptionArgs returns the git commit options selected by the flags.
+func commitOptionArgs() []string {
+	var args []string
+	if commitAmend {
+		args = append(args, "--amend")
+	}
+	if commitSignoff {
+		args = append(args, "--signoff")
+	}
+	if commitAllowEmpty {
+		args = append(args, "--allow-empty")
+	}
+	if commitAuthor != "" {
+		args = append(args, "--author="+commitAuthor)
+	}
+	return args
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithCoAuthors(t *testing.T) {
+	got, err := withCoAuthors("fix: typo\n", []string{"Ada Lovelace <ada@example.com>", " Alan Turing <alan@example.com> "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "fix: typo\n\nCo-authored-by: Ada Lovelace <ada@example.com>\nCo-authored-by: Alan Turing <alan@example.com>"
+	if got != want {
+		t.Errorf("withCoAuthors = %q, want %q", got, want)
+	}
+	if _, err := withCoAuthors("x", []string{"ada@example.com"}); err == nil {
+		t.Errorf("expected co-author without name to be rejected")
+	}
+}
+
+func TestHeadIsPushed(t *testing.T) {
+	ctx := context.Background()
+	bare := filepath.Join(t.TempDir(), "origin.git")
+	if out, err := exec.Command("git", "init", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare failed: %v, out=%s", err, out)
+	}
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+
+	if pushed, err := headIsPushed(ctx, repo); err != nil || pushed {
+		t.Fatalf("expected unpushed HEAD, got %v, %v", pushed, err)
+	}
+	for _, args := range [][]string{{"remote", "add", "origin", bare}, {"push", "origin", "HEAD:refs/heads/main"}, {"fetch", "origin"}} {
+		if out, err := runGitCapture(ctx, repo, args...); err != nil {
+			t.Fatalf("git %v failed: %v, out=%s", args, err, out)
+		}
+	}
+	if pushed, err := headIsPushed(ctx, repo); err != nil || !pushed {
+		t.Errorf("expected pushed HEAD, got %v, %v", pushed, err)
+	}
+}
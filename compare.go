@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// refComparison lists the commits reachable from one ref but not another.
+type refComparison struct {
+	count int
+	log   []string // one line per commit, newest first, at most the log limit
+}
+
+// compareRefs counts the commits in from..to in repo and reads the short
+// log of at most limit of them; limit 0 reads them all.
+func compareRefs(ctx context.Context, repo, from, to string, limit int) (refComparison, error) {
+	var c refComparison
+	rng := from + ".." + to
+	out, err := gitOutput(ctx, repo, "rev-list", "--count", rng)
+	if err != nil {
+		return c, err
+	}
+	if c.count, err = strconv.Atoi(out); err != nil {
+		return c, fmt.Errorf("unexpected rev-list output %q", out)
+	}
+	if c.count == 0 {
+		return c, nil
+	}
+	args := []string{"log", "--format=%h %s"}
+	if limit > 0 {
+		args = append(args, "--max-count="+strconv.Itoa(limit))
+	}
+	if out, err = gitOutput(ctx, repo, append(args, rng)...); err != nil {
+		return c, err
+	}
+	c.log = strings.Split(out, "\n")
+	return c, nil
+}
+
+// compare command
+var compareFrom string
+var compareTo string
+var compareLimit int
+var compareCmd = &cobra.Command{
+	Use:   "compare --from <ref> [--to HEAD] [-n 10] <pattern>...",
+	Short: "Show the commits between two refs in matching repositories",
+	Long: `compare prints, for every matching repository, the number of commits
+between --from and --to (the commits reachable from --to but not from
+--from, like git log from..to) and a short log of the newest -n of them.
+Repositories that lack either ref are skipped, so comparing a release tag
+across services only lists those that have it. The refs are resolved
+locally, so fetch first to compare against remote branches or new tags.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if compareFrom == "" {
+			return usageErrorf("--from is required")
+		}
+		if compareLimit < 0 {
+			return usageErrorf("-n must not be negative")
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		var compared, total int
+		for _, r := range repos {
+			if missing := missingRef(ctx, r, compareFrom, compareTo); missing != "" {
+				fmt.Printf("%s: no %s, skipped\n", r, missing)
+				markRepoSkipped(r, "no "+missing)
+				continue
+			}
+			c, err := compareRefs(ctx, r, compareFrom, compareTo, compareLimit)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			compared++
+			total += c.count
+			repoHeader(r)
+			fmt.Printf("%d commits\n", c.count)
+			for _, l := range c.log {
+				fmt.Printf("  %s\n", l)
+			}
+			if more := c.count - len(c.log); more > 0 {
+				fmt.Printf("  ... and %d more\n", more)
+			}
+		}
+		fmt.Printf("\n%d commits between %s and %s in %d of %d repositories\n", total, compareFrom, compareTo, compared, len(repos))
+		return nil
+	},
+}
+
+// missingRef returns the first of refs that does not name a commit in
+// repo, "" when all do.
+func missingRef(ctx context.Context, repo string, refs ...string) string {
+	for _, ref := range refs {
+		if !refExists(ctx, repo, ref+"^{commit}") {
+			return ref
+		}
+	}
+	return ""
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringVar(&compareFrom, "from", "", "ref to compare from, e.g. the last release tag")
+	compareCmd.Flags().StringVar(&compareTo, "to", "HEAD", "ref to compare to")
+	compareCmd.Flags().IntVarP(&compareLimit, "max-count", "n", 10, "commits to list per repository (0 = all)")
+}
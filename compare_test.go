@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCompareRefs(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+	if out, err := exec.Command("git", "-C", repo, "tag", "v1.0").CombinedOutput(); err != nil {
+		t.Fatalf("git tag: %v %s", err, out)
+	}
+
+	c, err := compareRefs(ctx, repo, "v1.0", "HEAD", 10)
+	if err != nil || c.count != 0 || c.log != nil {
+		t.Errorf("no commits since v1.0: %+v, %v", c, err)
+	}
+
+	for _, msg := range []string{"second", "third", "fourth"} {
+		commitTestFile(t, repo, msg+".txt", msg, msg)
+	}
+	c, err = compareRefs(ctx, repo, "v1.0", "HEAD", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.count != 3 || len(c.log) != 2 || !strings.HasSuffix(c.log[0], " fourth") || !strings.HasSuffix(c.log[1], " third") {
+		t.Errorf("compareRefs with a limit of 2 = %+v", c)
+	}
+	if c, err = compareRefs(ctx, repo, "v1.0", "HEAD", 0); err != nil || len(c.log) != 3 {
+		t.Errorf("compareRefs without a limit = %+v, %v", c, err)
+	}
+
+	if got := missingRef(ctx, repo, "v1.0", "HEAD"); got != "" {
+		t.Errorf("missingRef with both refs = %q", got)
+	}
+	if got := missingRef(ctx, repo, "v2.0", "HEAD"); got != "v2.0" {
+		t.Errorf("missingRef = %q, want v2.0", got)
+	}
+	if got := missingRef(ctx, repo, "HEAD", "release"); got != "release" {
+		t.Errorf("missingRef = %q, want release", got)
+	}
+}
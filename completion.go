@@ -0,0 +1,172 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// maxRecentPatterns caps the pattern suggestions taken from the audit log.
+const maxRecentPatterns = 20
+
+// leadingArgs returns the number of positional arguments before the first
+// <pattern> in a command's usage line, e.g. 1 for "apply <dir> <pattern>...".
+// Placeholders inside [...] or right after a flag are flag values, not
+// positional arguments. It returns -1 when the command takes no patterns.
+func leadingArgs(use string) int {
+	words := strings.Fields(use)
+	n, depth := 0, 0
+	for i := 1; i < len(words); i++ {
+		w := words[i]
+		if strings.HasPrefix(w, "<pattern") {
+			return n
+		}
+		if depth == 0 && strings.HasPrefix(w, "<") && !strings.HasPrefix(words[i-1], "-") {
+			n++
+		}
+		depth += strings.Count(w, "[") - strings.Count(w, "]")
+	}
+	return -1
+}
+
+// lookupFlag finds a flag of cmd, its own or inherited, by name or, for a
+// single letter, by shorthand.
+func lookupFlag(cmd *cobra.Command, name string) *pflag.Flag {
+	for _, fs := range []*pflag.FlagSet{cmd.Flags(), cmd.InheritedFlags()} {
+		if f := fs.Lookup(name); f != nil {
+			return f
+		}
+		if len(name) == 1 {
+			if f := fs.ShorthandLookup(name); f != nil {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// positionalArgs drops the flags and their values from args, the command
+// line after cmd's name.
+func positionalArgs(cmd *cobra.Command, args []string) []string {
+	var pos []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			return append(pos, args[i+1:]...)
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			name := strings.TrimLeft(a, "-")
+			if strings.Contains(name, "=") {
+				continue
+			}
+			if !strings.HasPrefix(a, "--") {
+				// -abc: only the last short flag can take the next argument
+				name = name[len(name)-1:]
+			}
+			if f := lookupFlag(cmd, name); f != nil && f.NoOptDefVal == "" {
+				i++
+			}
+		default:
+			pos = append(pos, a)
+		}
+	}
+	return pos
+}
+
+// recentPatterns returns the patterns of the runs in the audit log, most
+// recent first and without duplicates.
+func recentPatterns() []string {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil
+	}
+	path := auditLogPath(cfg)
+	if path == "" {
+		return nil
+	}
+	records, err := readAudit(path)
+	if err != nil {
+		return nil
+	}
+	var patterns []string
+	seen := map[string]bool{}
+	for i := len(records) - 1; i >= 0 && len(patterns) < maxRecentPatterns; i-- {
+		cmd, rest, err := rootCmd.Find(records[i].Args)
+		if err != nil || cmd == rootCmd {
+			continue
+		}
+		skip := leadingArgs(cmd.Use)
+		if skip < 0 {
+			continue
+		}
+		pos := positionalArgs(cmd, rest)
+		if skip >= len(pos) {
+			continue
+		}
+		for _, p := range pos[skip:] {
+			if !seen[p] {
+				seen[p] = true
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	return patterns
+}
+
+// completePatterns suggests recently used patterns for pattern arguments,
+// and directories when none of them fits.
+func completePatterns(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) < leadingArgs(cmd.Use) {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	var matches []string
+	for _, p := range recentPatterns() {
+		if strings.HasPrefix(p, toComplete) && !slices.Contains(args, p) {
+			matches = append(matches, p)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	}
+	return matches, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTags suggests the tags of the [[repo]] entries in the config file.
+func completeTags(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	seen := map[string]bool{}
+	var tags []string
+	for _, o := range cfg.Repos {
+		for _, t := range o.Tags {
+			if !seen[t] && strings.HasPrefix(t, toComplete) {
+				seen[t] = true
+				tags = append(tags, t)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags, cobra.ShellCompDirectiveNoFileComp
+}
+
+// setupCompletions attaches completePatterns to every command below cmd
+// that takes patterns, aliases included, and completes --tag and --tag-not
+// from the config file. cobra's completion command generates the scripts.
+func setupCompletions(cmd *cobra.Command) {
+	if cmd == rootCmd {
+		for _, name := range []string{"tag", "tag-not"} {
+			cmd.RegisterFlagCompletionFunc(name, completeTags)
+		}
+	}
+	if cmd.ValidArgsFunction == nil && leadingArgs(cmd.Use) >= 0 {
+		cmd.ValidArgsFunction = completePatterns
+	}
+	for _, c := range cmd.Commands() {
+		setupCompletions(c)
+	}
+}
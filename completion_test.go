@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestLeadingArgs(t *testing.T) {
+	tests := map[string]int{
+		"pull <pattern>...":        0,
+		"apply <dir> <pattern>...": 1,
+		"archive --out <dir> [--format tar.gz] [--ref HEAD] <pattern>...": 0,
+		"import --from mrconfig|gita [<file>|auto|-]":                     -1,
+		"add <name> <url> <pattern>...":                                   2,
+		"serve [--listen 127.0.0.1:7070] [<patterns...>]":                 -1,
+	}
+	for use, want := range tests {
+		if got := leadingArgs(use); got != want {
+			t.Errorf("leadingArgs(%q) = %d, want %d", use, got, want)
+		}
+	}
+}
+
+func TestRecentPatterns(t *testing.T) {
+	audit := filepath.Join(t.TempDir(), "audit.jsonl")
+	useTestConfig(t, "[audit]\npath = \""+audit+"\"\n\n[[repo]]\npath = \"api\"\ntags = [\"team:payments\", \"lang:go\"]\n\n[[repo]]\npath = \"web\"\ntags = [\"lang:js\", \"team:payments\"]\n")
+	records := `{"args":["pull","--retries","2","src/**","-v","api"]}
+{"args":["bundle","apply","/media/usb","x/*"]}
+{"args":["status"]}
+{"args":["push","--jobs=4","--","-odd","api"]}
+`
+	if err := os.WriteFile(audit, []byte(records), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"-odd", "api", "x/*", "src/**"}
+	if got := recentPatterns(); !reflect.DeepEqual(got, want) {
+		t.Errorf("recentPatterns() = %q, want %q", got, want)
+	}
+	got, directive := completePatterns(pullCmd, []string{"api"}, "")
+	if !reflect.DeepEqual(got, []string{"-odd", "x/*", "src/**"}) || directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("completePatterns = %q, %v", got, directive)
+	}
+	if got, directive := completePatterns(pullCmd, nil, "nope"); got != nil || directive != cobra.ShellCompDirectiveFilterDirs {
+		t.Errorf("completePatterns without match = %q, %v", got, directive)
+	}
+
+	tags, _ := completeTags(rootCmd, nil, "")
+	if !reflect.DeepEqual(tags, []string{"lang:go", "lang:js", "team:payments"}) {
+		t.Errorf("completeTags = %q", tags)
+	}
+	if tags, _ := completeTags(rootCmd, nil, "team"); !reflect.DeepEqual(tags, []string{"team:payments"}) {
+		t.Errorf("completeTags(team) = %q", tags)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config is the gitbatch configuration file. It is read from --config or,
+// by default, from gitbatch/config.toml in the user's config directory
+// (e.g. ~/.config/gitbatch/config.toml).
+type Config struct {
+	ReadOnly     bool                `toml:"read_only"`
+	Identities   map[string]Identity `toml:"identity"`
+	Secrets      SecretsConfig       `toml:"secrets"`
+	LargeFiles   LargeFilesConfig    `toml:"large_files"`
+	Notify       NotifyConfig        `toml:"notify"`
+	Git          GitConfig           `toml:"git"`
+	Repos        []RepoOverride      `toml:"repo"`
+	Policy       []PolicyRule        `toml:"policy"`
+	Audit        AuditConfig         `toml:"audit"`
+	Hooks        []RunHook           `toml:"hook"`
+	Aliases      Aliases             `toml:"alias"`
+	Dependencies Dependencies        `toml:"dependencies"`
+	Mirrors      Mirrors             `toml:"mirror"`
+	Pull         PullConfig          `toml:"pull"`
+	Defaults     Defaults            `toml:"defaults"`
+}
+
+var configPath string
+var loadedConfig *Config
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gitbatch", "config.toml")
+}
+
+// loadConfig reads the configuration file on first use. A missing file at
+// the default location is the same as an empty configuration, but a file
+// named with --config or $GITBATCH_CONFIG must exist. Unknown keys are
+// rejected so typos do not silently disable a setting.
+func loadConfig() (*Config, error) {
+	if loadedConfig != nil {
+		return loadedConfig, nil
+	}
+	path, explicit := configPath, configPath != ""
+	if !explicit {
+		path = os.Getenv("GITBATCH_CONFIG")
+		explicit = path != ""
+	}
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	cfg := &Config{}
+	if path != "" {
+		md, err := toml.DecodeFile(path, cfg)
+		switch {
+		case err != nil && !explicit && errors.Is(err, fs.ErrNotExist):
+			// no config file, use defaults
+		case err != nil:
+			return nil, fmt.Errorf("config %s: %v", path, err)
+		default:
+			if undecoded := md.Undecoded(); len(undecoded) > 0 {
+				return nil, fmt.Errorf("config %s: unknown key %q", path, undecoded[0].String())
+			}
+		}
+	}
+	loadedConfig = cfg
+	return cfg, nil
+}
+
+// expandHome replaces a leading "~/" in path with the user's home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "config file (default $GITBATCH_CONFIG, or gitbatch/config.toml in the user config directory)")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// conventionalTypes are the commit types accepted by --type and
+// --verify-conventional (the set used by @commitlint/config-conventional).
+var conventionalTypes = []string{"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test"}
+
+var conventionalSubject = regexp.MustCompile(`^(` + strings.Join(conventionalTypes, "|") + `)(\([\w./-]+\))?!?: \S`)
+
+var commitType string
+var commitScope string
+var commitBreaking bool
+var commitVerifyConventional bool
+
+// composeConventional prefixes msg with a Conventional Commits header built
+// from typ, scope and breaking, e.g. "feat(api)!: msg".
+func composeConventional(typ, scope string, breaking bool, msg string) (string, error) {
+	if typ == "" {
+		if scope != "" || breaking {
+			return "", usageErrorf("--scope and --breaking require --type")
+		}
+		return msg, nil
+	}
+	if !isConventionalType(typ) {
+		return "", usageErrorf("unknown commit type %q (expected one of %s)", typ, strings.Join(conventionalTypes, ", "))
+	}
+	header := typ
+	if scope != "" {
+		header += "(" + scope + ")"
+	}
+	if breaking {
+		header += "!"
+	}
+	return header + ": " + msg, nil
+}
+
+// verifyConventional checks that the first line of msg follows the
+// Conventional Commits format.
+func verifyConventional(msg string) error {
+	if !conventionalSubject.MatchString(firstLine(msg)) {
+		return fmt.Errorf("commit message %q is not a conventional commit: expected \"<type>[(scope)][!]: <description>\" with type one of %s",
+			firstLine(msg), strings.Join(conventionalTypes, ", "))
+	}
+	return nil
+}
+
+func isConventionalType(typ string) bool {
+	for _, t := range conventionalTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
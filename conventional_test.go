@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestComposeConventional(t *testing.T) {
+	cases := []struct {
+		typ, scope string
+		breaking   bool
+		want       string
+	}{
+		{"", "", false, "add endpoint"},
+		{"feat", "", false, "feat: add endpoint"},
+		{"feat", "api", false, "feat(api): add endpoint"},
+		{"feat", "api", true, "feat(api)!: add endpoint"},
+		{"fix", "", true, "fix!: add endpoint"},
+	}
+	for _, c := range cases {
+		got, err := composeConventional(c.typ, c.scope, c.breaking, "add endpoint")
+		if err != nil || got != c.want {
+			t.Errorf("composeConventional(%q, %q, %v) = %q, %v; want %q", c.typ, c.scope, c.breaking, got, err, c.want)
+		}
+	}
+	if _, err := composeConventional("", "api", false, "x"); err == nil {
+		t.Errorf("expected --scope without --type to fail")
+	}
+	if _, err := composeConventional("feature", "", false, "x"); err == nil {
+		t.Errorf("expected unknown type to fail")
+	}
+}
+
+func TestVerifyConventional(t *testing.T) {
+	for _, ok := range []string{"feat: add x", "fix(parser): handle y", "chore(deps)!: bump z\n\nbody", "docs(api/v2): clarify"} {
+		if err := verifyConventional(ok); err != nil {
+			t.Errorf("expected %q to be accepted: %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"Fix typo", "feat:missing space", "feature: x", "feat(): x", "feat: "} {
+		if err := verifyConventional(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
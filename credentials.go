@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// noCredentialCheck is --no-credential-check: leave credentials to git in
+// every repository.
+var noCredentialCheck bool
+
+// askpassEnv carries the credentials entered before the run to gitbatch
+// running as git's GIT_ASKPASS helper.
+const askpassEnv = "GITBATCH_ASKPASS"
+
+// askpassPrompt matches the prompts git passes to GIT_ASKPASS for HTTPS
+// credentials, e.g. "Password for 'https://me@github.com': ".
+var askpassPrompt = regexp.MustCompile(`^(Username|Password) for '([^']+)': $`)
+
+// credential is a username and password for one HTTPS host.
+type credential struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// credentialTarget is a remote host and the repositories whose default
+// remote points at it.
+type credentialTarget struct {
+	url   string // scheme://[user@]host[:port] for HTTPS, the host for SSH
+	key   string // scheme://host[:port], as askpassAnswer looks it up
+	ssh   bool
+	repos []string
+}
+
+// credentialTargets groups repos by the host of their default remote, in
+// the order the hosts are first seen. Local remotes and URLs that carry a
+// password need no credentials and are left out.
+func credentialTargets(ctx context.Context, repos []string) []*credentialTarget {
+	byKey := map[string]*credentialTarget{}
+	var targets []*credentialTarget
+	for _, r := range repos {
+		remote, err := gitExec.Output(ctx, runner.Git(r, "remote", "get-url", defaultRemote(ctx, gitExec, r)))
+		host := networkHost(remote)
+		if err != nil || host == "" {
+			continue
+		}
+		t := credentialTarget{url: host, key: "ssh://" + host, ssh: true}
+		if isHTTPURL(remote) {
+			u, err := url.Parse(remote)
+			if err != nil {
+				continue
+			}
+			if _, ok := u.User.Password(); ok {
+				continue
+			}
+			t = credentialTarget{url: u.Scheme + "://" + u.Host, key: u.Scheme + "://" + u.Host}
+			if u.User != nil {
+				t.url = u.Scheme + "://" + u.User.Username() + "@" + u.Host
+			}
+		}
+		if byKey[t.key] == nil {
+			byKey[t.key] = &t
+			targets = append(targets, &t)
+		}
+		byKey[t.key].repos = append(byKey[t.key].repos, r)
+	}
+	return targets
+}
+
+// fillCredential asks git's credential helpers for the credentials of
+// target, from dir so repository settings apply. With prompt, git asks on
+// the terminal when no helper has them; without, it fails instead. It runs
+// git right above gitBinary, below the wrappers that log, record or retry
+// commands, since the output holds the password.
+func fillCredential(ctx context.Context, dir, target string, prompt bool) (credential, error) {
+	cmd := runner.Git(dir, "credential", "fill")
+	if !prompt {
+		// an empty GIT_ASKPASS also keeps git from trying SSH_ASKPASS and
+		// core.askPass
+		cmd.Env = []string{"GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS="}
+	}
+	cmd.Stdin = strings.NewReader("url=" + target + "\n\n")
+	out, err := secretEnvExecutor{next: &gitBinary}.Capture(ctx, cmd)
+	if err != nil {
+		if msg := strings.TrimSpace(out); msg != "" {
+			return credential{}, errors.New(firstLine(msg))
+		}
+		return credential{}, err
+	}
+	var c credential
+	for _, line := range strings.Split(out, "\n") {
+		if v, ok := strings.CutPrefix(line, "username="); ok {
+			c.Username = v
+		} else if v, ok := strings.CutPrefix(line, "password="); ok {
+			c.Password = v
+		}
+	}
+	if c.Password == "" {
+		return credential{}, errors.New("no password")
+	}
+	return c, nil
+}
+
+// checkCredentials makes sure, before a network command, that git will not
+// stop for credentials in the middle of the run. For every HTTPS host it
+// asks the credential helpers; when none has credentials, it prompts once
+// if it can and hands the answer to every git command through GIT_ASKPASS,
+// or otherwise warns and disables git's prompts so those repositories fail
+// instead of hanging. For SSH hosts it loads keys into an empty ssh-agent.
+func checkCredentials(ctx context.Context, repos []string, prompt bool) {
+	if noCredentialCheck || emitScript != "" {
+		return
+	}
+	creds := map[string]credential{}
+	var sshHosts []string
+	noPrompt := false
+	for _, t := range credentialTargets(ctx, repos) {
+		if t.ssh {
+			sshHosts = append(sshHosts, t.url)
+			continue
+		}
+		if _, err := fillCredential(ctx, t.repos[0], t.url, false); err == nil {
+			continue
+		}
+		if prompt {
+			fmt.Fprintf(os.Stderr, "credentials for %s (%d repositories):\n", t.url, len(t.repos))
+			c, err := fillCredential(ctx, t.repos[0], t.url, true)
+			if err == nil {
+				creds[t.key] = c
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "%s %s: %v\n", paint(os.Stderr, ansiYellow, "credentials:"), t.url, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s %s: no stored credentials, %d repositories will fail instead of prompting\n", paint(os.Stderr, ansiYellow, "credentials:"), t.url, len(t.repos))
+		}
+		noPrompt = true
+	}
+	if noPrompt {
+		// set once the prompts above are done, and only for git, not for
+		// hooks, plugins or the pager
+		gitBinary.Env = append(gitBinary.Env, "GIT_TERMINAL_PROMPT=0")
+	}
+	if len(sshHosts) > 0 {
+		checkSSHKeys(ctx, sshHosts, prompt)
+	}
+	if len(creds) > 0 {
+		if err := useAskpass(creds); err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v\n", paint(os.Stderr, ansiYellow, "credentials:"), err)
+		}
+	}
+}
+
+// checkSSHKeys runs ssh-add once when the ssh-agent holds no keys, so a
+// passphrase is asked for up front instead of in every repository. Without
+// an agent, ssh keys without a passphrase may well be all that is needed,
+// so that is only logged.
+func checkSSHKeys(ctx context.Context, hosts []string, prompt bool) {
+	n, err := sshAgentKeys(ctx)
+	switch {
+	case err != nil:
+		logf(levelVerbose, "ssh-agent not usable (%v): ssh may ask for a passphrase in every repository on %s", err, strings.Join(hosts, ", "))
+	case n > 0:
+	case !prompt:
+		logf(levelVerbose, "ssh-agent holds no keys: ssh may ask for a passphrase in every repository on %s", strings.Join(hosts, ", "))
+	default:
+		fmt.Fprintf(os.Stderr, "adding ssh keys to the agent for %s:\n", strings.Join(hosts, ", "))
+		cmd := exec.CommandContext(ctx, "ssh-add")
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stderr, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s ssh-add: %v\n", paint(os.Stderr, ansiYellow, "credentials:"), err)
+		}
+	}
+}
+
+// useAskpass makes gitbatch the GIT_ASKPASS helper of every git command
+// that follows, answering with creds. They travel in secretEnv, the
+// environment of git only, never in a file.
+func useAskpass(creds map[string]credential) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot act as GIT_ASKPASS: %v", err)
+	}
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	secretEnv = append(secretEnv, "GIT_ASKPASS="+exe, askpassEnv+"="+string(data))
+	return nil
+}
+
+// askpassAnswer returns the answer to a git credential prompt from creds.
+func askpassAnswer(prompt string, creds map[string]credential) (string, bool) {
+	m := askpassPrompt.FindStringSubmatch(prompt)
+	if m == nil {
+		return "", false
+	}
+	u, err := url.Parse(m[2])
+	if err != nil {
+		return "", false
+	}
+	c, ok := creds[u.Scheme+"://"+u.Host]
+	if !ok {
+		return "", false
+	}
+	if m[1] == "Username" {
+		return c.Username, true
+	}
+	return c.Password, true
+}
+
+// runAskpass answers git's prompt when gitbatch runs as its GIT_ASKPASS
+// helper, and reports whether it did. A prompt it has no answer for fails,
+// so git stops instead of asking on the terminal.
+func runAskpass(args []string) (code int, ok bool) {
+	data := os.Getenv(askpassEnv)
+	if data == "" || len(args) != 1 || !askpassPrompt.MatchString(args[0]) {
+		return 0, false
+	}
+	var creds map[string]credential
+	if err := json.Unmarshal([]byte(data), &creds); err != nil {
+		fmt.Fprintf(os.Stderr, "gitbatch askpass: %v\n", err)
+		return 1, true
+	}
+	answer, found := askpassAnswer(args[0], creds)
+	if !found {
+		return 1, true
+	}
+	fmt.Println(answer)
+	return 0, true
+}
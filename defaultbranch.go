@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// renameResult describes what renameDefaultBranch did in one repository.
+type renameResult struct {
+	renamed        bool   // local branch was renamed
+	upstream       string // new upstream of the renamed branch, if any
+	remoteDefault  string // branch origin/HEAD points to after the update
+	remoteStillOld bool   // origin still has or defaults to the old name
+}
+
+// renameDefaultBranch renames the local branch oldName to newName, refreshes
+// origin's HEAD and, if origin already has newName, makes it the upstream.
+// Repositories without origin are only renamed locally.
+func renameDefaultBranch(ctx context.Context, dir, oldName, newName string) (renameResult, error) {
+	var res renameResult
+	hasOld := refExists(ctx, dir, "refs/heads/"+oldName)
+	hasNew := refExists(ctx, dir, "refs/heads/"+newName)
+	switch {
+	case hasOld && hasNew:
+		return res, fmt.Errorf("both %s and %s exist locally, not renaming", oldName, newName)
+	case hasOld:
+		if err := gitChange(ctx, dir, "branch", "-m", oldName, newName); err != nil {
+			return res, err
+		}
+		res.renamed = true
+	case !hasNew:
+		return res, fmt.Errorf("neither %s nor %s exists locally", oldName, newName)
+	}
+
+	if !hasRemote(ctx, dir, "origin") {
+		return res, nil
+	}
+	if err := gitChange(ctx, dir, "fetch", "--prune", "--quiet", "origin"); err != nil {
+		return res, fmt.Errorf("fetch origin: %v", err)
+	}
+	if err := gitChange(ctx, dir, "remote", "set-head", "origin", "--auto"); err != nil {
+		return res, fmt.Errorf("update origin/HEAD: %v", err)
+	}
+	if ref, err := gitOutput(ctx, dir, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		res.remoteDefault = ref
+	}
+	if refExists(ctx, dir, "refs/remotes/origin/"+newName) {
+		if err := gitChange(ctx, dir, "branch", "--set-upstream-to=origin/"+newName, newName); err != nil {
+			return res, err
+		}
+		res.upstream = "origin/" + newName
+	}
+	res.remoteStillOld = res.remoteDefault == "origin/"+oldName || refExists(ctx, dir, "refs/remotes/origin/"+oldName)
+	return res, nil
+}
+
+func refExists(ctx context.Context, dir, ref string) bool {
+	_, err := gitOutput(ctx, dir, "rev-parse", "--verify", "--quiet", ref)
+	return err == nil
+}
+
+func hasRemote(ctx context.Context, dir, name string) bool {
+	remotes, err := remoteNames(ctx, dir)
+	if err != nil {
+		return false
+	}
+	for _, r := range remotes {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// default-branch command
+var defaultBranchRename bool
+var defaultBranchCmd = &cobra.Command{
+	Use:   "default-branch [--rename <old> <new>] <pattern>...",
+	Short: "Show each repository's default branch or migrate it to a new name",
+	Long: `Without flags, default-branch prints the default branch detected for each
+repository (from origin/HEAD, falling back to main or master).
+
+With --rename, the first two arguments are the old and new branch names:
+the local branch is renamed, origin/HEAD is refreshed with
+"git remote set-head origin --auto", the renamed branch is set to track
+origin/<new> when it exists, and repositories whose remote still uses the
+old name are reported at the end.
+
+  gitbatch default-branch --rename master main repos/*`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if defaultBranchRename {
+			if len(args) < 3 {
+				return usageErrorf("--rename needs <old> <new> followed by at least one pattern")
+			}
+			return runDefaultBranchRename(args[0], args[1], args[2:])
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPO\tDEFAULT\tSOURCE")
+		for _, r := range repos {
+			name, err := defaultBranch(ctx, r)
+			if err != nil {
+				fmt.Fprintf(w, "%s\t-\terror: %v\n", r, err)
+				continue
+			}
+			source := "origin/HEAD"
+			if !refExists(ctx, r, "refs/remotes/origin/HEAD") {
+				source = "guessed"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r, name, source)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+func runDefaultBranchRename(oldName, newName string, patterns []string) error {
+	repos, err := collectRepos(patterns)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := runContext()
+	defer cancel()
+
+	var stillOld []string
+	for _, r := range repos {
+		repoHeader(r)
+		res, err := renameDefaultBranch(ctx, r, oldName, newName)
+		if err != nil {
+			repoFailed(r, err)
+			continue
+		}
+		if res.renamed {
+			fmt.Printf("renamed %s -> %s\n", oldName, newName)
+		} else {
+			fmt.Printf("already on %s\n", newName)
+		}
+		if res.upstream != "" {
+			fmt.Printf("tracking %s\n", res.upstream)
+		}
+		if res.remoteDefault != "" {
+			fmt.Printf("origin/HEAD -> %s\n", res.remoteDefault)
+		}
+		if res.remoteStillOld {
+			stillOld = append(stillOld, r)
+		}
+	}
+
+	if len(stillOld) > 0 {
+		fmt.Printf("\n%d repositories whose origin still uses %s:\n", len(stillOld), oldName)
+		for _, r := range stillOld {
+			fmt.Printf("  %s\n", r)
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(defaultBranchCmd)
+
+	defaultBranchCmd.Flags().BoolVar(&defaultBranchRename, "rename", false, "rename the default branch: takes <old> <new> as the first two arguments")
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenameDefaultBranch(t *testing.T) {
+	ctx := context.Background()
+	bare := filepath.Join(t.TempDir(), "origin.git")
+	if out, err := exec.Command("git", "init", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare failed: %v, out=%s", err, out)
+	}
+
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+	for _, args := range [][]string{
+		{"branch", "-M", "master"},
+		{"remote", "add", "origin", bare},
+		{"push", "-u", "origin", "master"},
+		{"push", "origin", "master:main"},
+	} {
+		if out, err := runGitCapture(ctx, repo, args...); err != nil {
+			t.Fatalf("git %v failed: %v, out=%s", args, err, out)
+		}
+	}
+	// simulate the branch having been renamed on the server
+	if out, err := exec.Command("git", "-C", bare, "symbolic-ref", "HEAD", "refs/heads/main").CombinedOutput(); err != nil {
+		t.Fatalf("setting bare HEAD failed: %v, out=%s", err, out)
+	}
+	if out, err := runGitCapture(ctx, repo, "push", "origin", "--delete", "master"); err != nil {
+		t.Fatalf("git push --delete failed: %v, out=%s", err, out)
+	}
+
+	res, err := renameDefaultBranch(ctx, repo, "master", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.renamed || res.upstream != "origin/main" || res.remoteDefault != "origin/main" || res.remoteStillOld {
+		t.Errorf("unexpected rename result: %+v", res)
+	}
+	if b, _ := currentBranch(ctx, repo); b != "main" {
+		t.Errorf("expected current branch main, got %q", b)
+	}
+
+	// running again is a no-op that still reports the remote state
+	res, err = renameDefaultBranch(ctx, repo, "master", "main")
+	if err != nil || res.renamed {
+		t.Errorf("expected second run to leave the branch alone, got %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Defaults is the [defaults] section of the config file: flags added to a
+// command, quoted as in a shell, unless the command line sets them:
+//
+//	[defaults]
+//	pull = "--rebase --jobs 8"
+//	status = "--summary"
+type Defaults map[string]string
+
+// conflictingFlags are groups of flags of which only one may be set. A
+// flag from the command line also replaces the defaults for the others in
+// its group, so pull --merge overrides a default --rebase.
+var conflictingFlags = [][]string{
+	{"ff-only", "merge", "rebase"},
+	{"tags", "no-tags"},
+	{"iglob", "regex"},
+	{"quiet", "verbose"},
+}
+
+// defaultFlag is a flag set by [defaults], with the value to set it to.
+type defaultFlag struct {
+	name, value string
+}
+
+// recordValue stands in for a flag's value while [defaults] is parsed, and
+// records what the defaults set instead of setting it.
+type recordValue struct {
+	name string
+	typ  string
+	set  *[]defaultFlag
+}
+
+func (v recordValue) String() string { return "" }
+func (v recordValue) Type() string   { return v.typ }
+
+func (v recordValue) Set(s string) error {
+	*v.set = append(*v.set, defaultFlag{v.name, s})
+	return nil
+}
+
+// parseDefaults parses the default flags line for cmd into the flags it
+// sets, in order, without touching cmd's flags.
+func parseDefaults(cmd *cobra.Command, line string) ([]defaultFlag, error) {
+	words, err := splitWords(line)
+	if err != nil {
+		return nil, err
+	}
+	var set []defaultFlag
+	probe := pflag.NewFlagSet(commandName(cmd), pflag.ContinueOnError)
+	probe.SetOutput(io.Discard)
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		probe.AddFlag(&pflag.Flag{Name: f.Name, Shorthand: f.Shorthand, NoOptDefVal: f.NoOptDefVal, Value: recordValue{f.Name, f.Value.Type(), &set}})
+	})
+	if err := probe.Parse(words); err != nil {
+		return nil, err
+	}
+	if probe.NArg() > 0 {
+		return nil, fmt.Errorf("only flags can have defaults, not %q", probe.Arg(0))
+	}
+	return set, nil
+}
+
+// applyDefaults sets the flags that [defaults] lists for cmd, except those
+// the command line sets itself or overrides with a conflicting flag.
+func applyDefaults(cmd *cobra.Command) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	name := commandName(cmd)
+	line, ok := cfg.Defaults[name]
+	if !ok {
+		return nil
+	}
+	set, err := parseDefaults(cmd, line)
+	if err != nil {
+		return fmt.Errorf("[defaults] %s: %v", name, err)
+	}
+	fromCommandLine := map[string]bool{}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		fromCommandLine[f.Name] = true
+		for _, group := range conflictingFlags {
+			if slices.Contains(group, f.Name) {
+				for _, other := range group {
+					fromCommandLine[other] = true
+				}
+			}
+		}
+	})
+	for _, d := range set {
+		if fromCommandLine[d.name] {
+			continue
+		}
+		if err := cmd.Flags().Set(d.name, d.value); err != nil {
+			return fmt.Errorf("[defaults] %s: --%s: %v", name, d.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	var merge, rebase, tags bool
+	var jobs, verbose int
+	var envs []string
+	newCmd := func(args ...string) *cobra.Command {
+		merge, rebase, tags, jobs, verbose, envs = false, false, false, 1, 0, nil
+		root := &cobra.Command{Use: "gitbatch"}
+		root.PersistentFlags().IntVarP(&jobs, "jobs", "j", 1, "")
+		root.PersistentFlags().CountVarP(&verbose, "verbose", "v", "")
+		root.PersistentFlags().StringArrayVar(&envs, "env", nil, "")
+		cmd := &cobra.Command{Use: "pull", Run: func(*cobra.Command, []string) {}}
+		cmd.Flags().BoolVar(&merge, "merge", false, "")
+		cmd.Flags().BoolVar(&rebase, "rebase", false, "")
+		cmd.Flags().BoolVar(&tags, "tags", false, "")
+		root.AddCommand(cmd)
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatal(err)
+		}
+		return cmd
+	}
+	useTestConfig(t, "[defaults]\npull = \"--rebase -j 8 -v --env 'A=b c' --tags\"\n")
+
+	if err := applyDefaults(newCmd()); err != nil {
+		t.Fatal(err)
+	}
+	if !rebase || jobs != 8 || verbose != 1 || len(envs) != 1 || envs[0] != "A=b c" || !tags {
+		t.Errorf("defaults not applied: rebase=%v jobs=%d verbose=%d env=%q tags=%v", rebase, jobs, verbose, envs, tags)
+	}
+
+	// the command line wins, also over conflicting defaults
+	cmd := newCmd("--merge", "--jobs=2", "--env", "X=y")
+	if err := applyDefaults(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if rebase || !merge || jobs != 2 || len(envs) != 1 || envs[0] != "X=y" || !cmd.Flags().Changed("tags") {
+		t.Errorf("command line not kept: merge=%v rebase=%v jobs=%d env=%q", merge, rebase, jobs, envs)
+	}
+
+	for config, want := range map[string]string{
+		"[defaults]\npull = \"--no-such-flag\"\n": "unknown flag",
+		"[defaults]\npull = \"--rebase src\"\n":   "only flags",
+		"[defaults]\npull = \"--jobs many\"\n":    "invalid argument",
+	} {
+		useTestConfig(t, config)
+		if err := applyDefaults(newCmd()); err == nil || !strings.Contains(err.Error(), want) || !strings.HasPrefix(err.Error(), "[defaults] pull: ") {
+			t.Errorf("%q: got %v, want %q", config, err, want)
+		}
+	}
+}
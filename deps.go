@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Dependencies is the [dependencies] section of the config file. Each key
+// is a repository that depends on the repositories in its list:
+//
+//	[dependencies]
+//	api = ["common", "proto"]
+//	web = ["api"]
+//
+// A name matches a repository by its directory name, by trailing
+// directories ("services/api") or by its full path. Commands visit the
+// matching repositories so that dependencies come first; with --jobs, a
+// repository starts once its dependencies have finished.
+type Dependencies map[string][]string
+
+// matchRepos returns the repositories in repos that name refers to.
+func matchRepos(name string, repos []string) []string {
+	name = filepath.Clean(expandHome(name))
+	var matched []string
+	for _, r := range repos {
+		if r == name || strings.HasSuffix(r, string(os.PathSeparator)+name) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// graph resolves the dependencies between repos: each repository maps to
+// the repositories it waits for. Names that match none of repos are
+// ignored, since a run usually covers only some of them.
+func (d Dependencies) graph(repos []string) map[string][]string {
+	g := map[string][]string{}
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, r := range matchRepos(name, repos) {
+			for _, dep := range d[name] {
+				for _, dr := range matchRepos(dep, repos) {
+					if dr != r && !slices.Contains(g[r], dr) {
+						g[r] = append(g[r], dr)
+					}
+				}
+			}
+		}
+	}
+	return g
+}
+
+// orderRepos sorts repos so that every repository comes after the ones it
+// waits for in g. Otherwise the order of repos is kept. A cycle is an
+// error naming the repositories on it.
+func orderRepos(repos []string, g map[string][]string) ([]string, error) {
+	ordered := make([]string, 0, len(repos))
+	placed := map[string]bool{}
+	for len(ordered) < len(repos) {
+		progress := false
+		for _, r := range repos {
+			if placed[r] || !allPlaced(g[r], placed) {
+				continue
+			}
+			ordered = append(ordered, r)
+			placed[r] = true
+			progress = true
+			break
+		}
+		if !progress {
+			return nil, fmt.Errorf("dependency cycle: %s", strings.Join(findCycle(repos, g, placed), " -> "))
+		}
+	}
+	return ordered, nil
+}
+
+func allPlaced(deps []string, placed map[string]bool) bool {
+	for _, d := range deps {
+		if !placed[d] {
+			return false
+		}
+	}
+	return true
+}
+
+// findCycle follows unplaced dependencies from the first unplaced
+// repository until one repeats.
+func findCycle(repos []string, g map[string][]string, placed map[string]bool) []string {
+	var r string
+	for _, r = range repos {
+		if !placed[r] {
+			break
+		}
+	}
+	seen := map[string]int{}
+	var path []string
+	for {
+		if i, ok := seen[r]; ok {
+			return append(path[i:], r)
+		}
+		seen[r] = len(path)
+		path = append(path, r)
+		for _, d := range g[r] {
+			if !placed[d] {
+				r = d
+				break
+			}
+		}
+	}
+}
+
+// repoDeps is the dependency graph of the current run, used by runCollect
+// to let independent repositories run in parallel.
+var repoDeps map[string][]string
+
+// orderByDependencies puts repos in dependency order when the config file
+// declares dependencies.
+func orderByDependencies(repos []string) ([]string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Dependencies) == 0 {
+		return repos, nil
+	}
+	g := cfg.Dependencies.graph(repos)
+	ordered, err := orderRepos(repos, g)
+	if err != nil {
+		return nil, err
+	}
+	repoDeps = g
+	return ordered, nil
+}
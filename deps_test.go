@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOrderRepos(t *testing.T) {
+	repos := []string{"/src/web", "/src/tools", "/src/services/api", "/src/common", "/src/proto"}
+	d := Dependencies{
+		"web":          {"services/api"},
+		"services/api": {"common", "proto", "missing"},
+		"/src/proto":   {"common"},
+	}
+	g := d.graph(repos)
+	want := map[string][]string{
+		"/src/web":          {"/src/services/api"},
+		"/src/services/api": {"/src/common", "/src/proto"},
+		"/src/proto":        {"/src/common"},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("graph = %v, want %v", g, want)
+	}
+	ordered, err := orderRepos(repos, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// tools has no dependencies and keeps its place before the others
+	// that had to wait
+	if got := strings.Join(ordered, " "); got != "/src/tools /src/common /src/proto /src/services/api /src/web" {
+		t.Errorf("order = %s", got)
+	}
+}
+
+func TestOrderReposCycle(t *testing.T) {
+	repos := []string{"/src/a", "/src/b", "/src/c", "/src/d"}
+	d := Dependencies{"a": {"b"}, "b": {"c"}, "c": {"a"}, "d": {"a"}}
+	_, err := orderRepos(repos, d.graph(repos))
+	if err == nil || !strings.Contains(err.Error(), "/src/a -> /src/b -> /src/c -> /src/a") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestOrderByDependencies(t *testing.T) {
+	useTestConfig(t, "[dependencies]\napi = [\"common\"]\n")
+	t.Cleanup(func() { repoDeps = nil })
+	ordered, err := orderByDependencies([]string{"/src/api", "/src/common"})
+	if err != nil || !reflect.DeepEqual(ordered, []string{"/src/common", "/src/api"}) {
+		t.Errorf("orderByDependencies = %v, %v", ordered, err)
+	}
+	if !reflect.DeepEqual(repoDeps["/src/api"], []string{"/src/common"}) {
+		t.Errorf("repoDeps = %v", repoDeps)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/patrickkdev/gitbatch/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+// repoVersion is the git describe output of one repository. Fields are
+// exported for --format templates.
+type repoVersion struct {
+	Repo    string `json:"repo"`
+	Version string `json:"version"`
+	Dirty   bool   `json:"dirty"`
+}
+
+// describeRepo runs git describe --tags --dirty in dir. Repositories without
+// a reachable tag fall back to the abbreviated commit.
+func describeRepo(ctx context.Context, dir string) (repoVersion, error) {
+	out, err := gitOutput(ctx, dir, "describe", "--tags", "--dirty", "--always")
+	if err != nil {
+		return repoVersion{}, err
+	}
+	return repoVersion{Repo: dir, Version: out, Dirty: strings.HasSuffix(out, "-dirty")}, nil
+}
+
+// describeTable lays out versions for csv and markdown output.
+func describeTable(versions []repoVersion) report.Table {
+	t := report.Table{Header: []string{"repo", "version"}}
+	for _, v := range versions {
+		t.Rows = append(t.Rows, []string{v.Repo, v.Version})
+	}
+	return t
+}
+
+// describe command
+var describeFormat string
+var describeOutput string
+var describeCmd = &cobra.Command{
+	Use:   "describe <pattern>...",
+	Short: "Show git describe --tags --dirty for each repository",
+	Long: `describe prints the version of each repository as git describe --tags
+--dirty reports it: the nearest tag, the number of commits since and the
+abbreviated commit, with "-dirty" when the working tree has changes.
+Repositories without tags show the abbreviated commit only. --format takes a
+Go template with the fields .Repo, .Version and .Dirty. --output prints the
+list as json, csv or markdown, ready to paste into a ticket.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkFormatOutput(describeFormat, describeOutput); err != nil {
+			return err
+		}
+		var tmpl *template.Template
+		if describeFormat != "" {
+			var err error
+			if tmpl, err = parseFormat(describeFormat); err != nil {
+				return err
+			}
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		var versions []repoVersion
+		for _, r := range repos {
+			v, err := describeRepo(ctx, r)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			versions = append(versions, v)
+		}
+		if tmpl != nil {
+			return writeFormatted(os.Stdout, tmpl, versions)
+		}
+		if describeOutput != "" {
+			return writeOutput(os.Stdout, describeOutput, versions, describeTable(versions))
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tREPO")
+		for _, v := range versions {
+			fmt.Fprintf(w, "%s\t%s\n", v.Version, v.Repo)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+
+	addFormatFlag(describeCmd, &describeFormat, `{{.Repo}}\t{{.Version}}`)
+	addOutputFlag(describeCmd, &describeOutput)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestDescribeRepo(t *testing.T) {
+	ctx := context.Background()
+	tagged, untagged := initTestRepo(t), initTestRepo(t)
+	commitTestFile(t, tagged, "a.txt", "a", "first")
+	c := exec.Command("git", "tag", "v1.2.0")
+	c.Dir = tagged
+	if out, err := c.CombinedOutput(); err != nil {
+		t.Fatalf("tag: %v %s", err, out)
+	}
+	commitTestFile(t, tagged, "a.txt", "b", "second")
+	commitTestFile(t, untagged, "a.txt", "a", "first")
+
+	v, err := describeRepo(ctx, tagged)
+	if err != nil || !regexp.MustCompile(`^v1\.2\.0-1-g[0-9a-f]+$`).MatchString(v.Version) || v.Dirty {
+		t.Errorf("tagged = %+v, %v", v, err)
+	}
+	if err := os.WriteFile(filepath.Join(tagged, "a.txt"), []byte("c"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := describeRepo(ctx, tagged); err != nil || !v.Dirty {
+		t.Errorf("dirty = %+v, %v", v, err)
+	}
+	if v, err := describeRepo(ctx, untagged); err != nil || !regexp.MustCompile(`^[0-9a-f]{7,}$`).MatchString(v.Version) {
+		t.Errorf("untagged = %+v, %v", v, err)
+	}
+}
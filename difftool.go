@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// diffWordDiff is diff --word-diff: the git --word-diff mode, or "" for
+// line diffs.
+var diffWordDiff string
+
+// wordDiffArgs returns the git diff options for --word-diff.
+func wordDiffArgs() ([]string, error) {
+	switch diffWordDiff {
+	case "":
+		return nil, nil
+	case "plain", "color", "porcelain", "none":
+		return []string{"--word-diff=" + diffWordDiff}, nil
+	}
+	return nil, usageErrorf("invalid --word-diff %q (expected plain, color, porcelain or none)", diffWordDiff)
+}
+
+var difftoolTool string
+var difftoolStaged bool
+var difftoolDirDiff bool
+var difftoolPathSpecs []string
+
+// difftoolArgs are the git difftool arguments for the flags. git's own
+// prompt before each file is turned off, since gitbatch asks once per
+// repository.
+func difftoolArgs() []string {
+	args := []string{"difftool", "--no-prompt"}
+	if difftoolTool != "" {
+		args = append(args, "--tool="+difftoolTool)
+	}
+	if difftoolStaged {
+		args = append(args, "--cached")
+	}
+	if difftoolDirDiff {
+		args = append(args, "--dir-diff")
+	}
+	return withPathSpecs(args, difftoolPathSpecs)
+}
+
+var difftoolCmd = &cobra.Command{
+	Use:   "difftool [--tool <name>] [--staged] [--dir-diff] [--pathspec <path>]... <pattern>...",
+	Short: "Open git difftool in each repository with changes, one at a time",
+	Long: `difftool launches git difftool in every matching repository with
+changes, one repository at a time, to review a change across repositories
+before committing it. Before each repository it asks whether to open the
+tool, skip the repository or quit.
+
+The tool is diff.tool from git's config unless --tool names one. Without
+--staged, unstaged changes are shown; with it, the staged ones. --dir-diff
+opens the whole repository in one session of tools that compare
+directories, instead of one file after another.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		difftool(repos)
+		return nil
+	},
+}
+
+// difftool runs git difftool in each repository in turn, asking before
+// each one. Repositories without changes are passed over. There is no
+// timeout since the user drives each session.
+func difftool(repos []string) {
+	ctx := context.Background()
+	names := []string{"diff", "--name-only"}
+	if difftoolStaged {
+		names = append(names, "--cached")
+	}
+	for i, r := range repos {
+		changed, err := gitOutput(ctx, r, withPathSpecs(names, difftoolPathSpecs)...)
+		if err != nil {
+			repoFailed(r, err)
+			continue
+		}
+		if changed == "" {
+			continue
+		}
+
+		fmt.Printf("\n==== [%d/%d] %s ====\n", i+1, len(repos), r)
+		fmt.Println(changed)
+		switch askPatchChoice(fmt.Sprintf("Open difftool for %d changed file(s)?", len(strings.Split(changed, "\n")))) {
+		case patchSkip:
+			fmt.Println("skipped")
+			continue
+		case patchQuit:
+			fmt.Println("stopped, remaining repositories not shown")
+			return
+		}
+		if err := runGit(ctx, r, difftoolArgs()...); err != nil {
+			repoFailed(r, err)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(difftoolCmd)
+
+	difftoolCmd.Flags().StringVarP(&difftoolTool, "tool", "t", "", "diff tool to launch (default: diff.tool from git's config)")
+	difftoolCmd.Flags().BoolVar(&difftoolStaged, "staged", false, "review staged changes instead of unstaged ones")
+	difftoolCmd.Flags().BoolVarP(&difftoolDirDiff, "dir-diff", "d", false, "open each repository in one directory diff instead of file by file")
+	difftoolCmd.Flags().StringArrayVarP(&difftoolPathSpecs, "pathspec", "p", nil, "only review changes under this pathspec (repeatable)")
+}
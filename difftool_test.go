@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWordDiffArgs(t *testing.T) {
+	defer func() { diffWordDiff = "" }()
+	for mode, want := range map[string][]string{"": nil, "plain": {"--word-diff=plain"}, "color": {"--word-diff=color"}} {
+		diffWordDiff = mode
+		if got, err := wordDiffArgs(); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("--word-diff=%s: %q, %v", mode, got, err)
+		}
+	}
+	diffWordDiff = "words"
+	if _, err := wordDiffArgs(); exitCode(err) != exitUsage {
+		t.Errorf("invalid mode accepted: %v", err)
+	}
+}
+
+func TestDifftool(t *testing.T) {
+	defer func() { difftoolTool, difftoolStaged, difftoolDirDiff = "", false, false }()
+	dir := t.TempDir()
+	global := filepath.Join(dir, "gitconfig")
+	if err := os.WriteFile(global, []byte("[difftool \"fake\"]\n\tcmd = echo reviewed $MERGED\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", global)
+
+	var repos []string
+	for _, name := range []string{"a", "clean", "b", "c"} {
+		r := initTestRepo(t)
+		commitTestFile(t, r, "f.txt", "one", "first")
+		if name != "clean" {
+			if err := os.WriteFile(filepath.Join(r, "f.txt"), []byte("two"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		repos = append(repos, r)
+	}
+
+	difftoolTool, difftoolStaged, difftoolDirDiff = "fake", true, true
+	if got := difftoolArgs(); !reflect.DeepEqual(got, []string{"difftool", "--no-prompt", "--tool=fake", "--cached", "--dir-diff"}) {
+		t.Errorf("difftoolArgs = %q", got)
+	}
+	difftoolStaged, difftoolDirDiff = false, false
+
+	// open a, skip b, quit before c
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString("\ns\nq\n")
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+	out := captureStdout(t, func() { difftool(repos) })
+
+	if strings.Count(out, "reviewed f.txt") != 1 || strings.Contains(out, repos[1]) || !strings.Contains(out, "skipped") || !strings.Contains(out, "stopped") {
+		t.Errorf("unexpected session:\n%s", out)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import "github.com/patrickkdev/gitbatch/pkg/discover"
+
+// followSymlinks is --follow-symlinks: let ** walk into symlinked
+// directories.
+var followSymlinks bool
+
+// iglob and regexPatterns are --iglob and --regex, which change how
+// patterns match.
+var iglob bool
+var regexPatterns bool
+
+// discoverOptions are the discovery settings from the command line.
+func discoverOptions() (discover.Options, error) {
+	if iglob && regexPatterns {
+		return discover.Options{}, usageErrorf("--iglob and --regex cannot be combined (start the regex with (?i) instead)")
+	}
+	return discover.Options{
+		IsRepo:          isGitRepo,
+		FollowSymlinks:  followSymlinks,
+		CaseInsensitive: iglob,
+		Regex:           regexPatterns,
+	}, nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&followSymlinks, "follow-symlinks", false, "let ** patterns walk into symlinked directories (symlink loops are skipped)")
+	rootCmd.PersistentFlags().BoolVar(&iglob, "iglob", false, "match glob patterns case-insensitively")
+	rootCmd.PersistentFlags().BoolVar(&regexPatterns, "regex", false, "treat patterns as regular expressions matching whole directory paths, e.g. 'services/.*-api'")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// divergence command
+var divergenceBase string
+var divergenceCmd = &cobra.Command{
+	Use:   "divergence [--base origin/main] <pattern>...",
+	Short: "Show how far each repository's current branch has diverged from a base ref",
+	Long: `divergence compares HEAD of every matching repository with --base and
+prints the commits ahead and behind and the date of their merge base.
+Repositories that are behind the base are flagged as needing a rebase.
+The base ref is resolved locally, so fetch first for up-to-date results.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		var needRebase []string
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPO\tBRANCH\tAHEAD\tBEHIND\tMERGE-BASE\tSTATE")
+		for _, r := range repos {
+			branch, err := currentBranch(ctx, r)
+			if err != nil {
+				fmt.Fprintf(w, "%s\t-\t-\t-\t-\terror: %v\n", r, err)
+				continue
+			}
+			if branch == "" {
+				branch = "(detached)"
+			}
+			if _, err := gitOutput(ctx, r, "rev-parse", "--verify", "--quiet", divergenceBase+"^{commit}"); err != nil {
+				fmt.Fprintf(w, "%s\t%s\t-\t-\t-\tno %s\n", r, branch, divergenceBase)
+				continue
+			}
+			ahead, behind, err := aheadBehind(ctx, r, "HEAD", divergenceBase)
+			if err != nil {
+				fmt.Fprintf(w, "%s\t%s\t-\t-\t-\terror: %v\n", r, branch, err)
+				continue
+			}
+			mergeBase := "-"
+			if mb, err := gitOutput(ctx, r, "merge-base", "HEAD", divergenceBase); err == nil {
+				if date, err := gitOutput(ctx, r, "show", "-s", "--format=%cs", mb); err == nil {
+					mergeBase = date
+				}
+			}
+			state := divergenceState(ahead, behind)
+			if behind > 0 {
+				needRebase = append(needRebase, r)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n", r, branch, ahead, behind, mergeBase, state)
+		}
+		w.Flush()
+
+		if len(needRebase) > 0 {
+			fmt.Printf("\n%d repositories need rebasing onto %s:\n", len(needRebase), divergenceBase)
+			for _, r := range needRebase {
+				fmt.Printf("  %s\n", r)
+			}
+		}
+		return nil
+	},
+}
+
+// divergenceState describes the relationship between a branch and its base.
+func divergenceState(ahead, behind int) string {
+	switch {
+	case ahead == 0 && behind == 0:
+		return "up to date"
+	case behind == 0:
+		return "ahead"
+	case ahead == 0:
+		return "behind, needs rebase"
+	default:
+		return "diverged, needs rebase"
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(divergenceCmd)
+
+	divergenceCmd.Flags().StringVar(&divergenceBase, "base", "origin/main", "base ref to compare the current branch against")
+}
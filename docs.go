@@ -0,0 +1,380 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Help topics are commands without Run: cobra lists them under "Additional
+// help topics" and "gitbatch help <topic>" prints them. docs writes them as
+// section 7 man pages.
+
+var patternsTopic = &cobra.Command{
+	Use:   "patterns",
+	Short: "How path patterns select repositories",
+	Long: `Most commands take one or more path patterns and run in every git
+repository they match. A directory is a repository when it contains .git.
+
+Patterns are globs relative to the current directory unless they are
+absolute or start with ~/:
+
+  *        any name within one directory: repos/* matches repos/api
+  **       any number of directories: "src/**" matches every repository
+           below src, however deep
+  ?        any single character
+  [abc]    one of the listed characters
+  {a,b}    one of the alternatives: "{api,web}-*"
+
+Quote patterns so gitbatch expands them instead of the shell; the shell
+does not know **. Repositories matched by several patterns run once.
+
+Selectors narrow the matched repositories further: --tag and --tag-not
+(tags of [[repo]] entries in the config file), --remote-match, --has-file
+and --has-path. With a selector and no pattern, every repository below the
+current directory is considered, as if "**" had been given.`,
+}
+
+var configTopic = &cobra.Command{
+	Use:   "config-file",
+	Short: "Keys of the configuration file",
+	// Long is built from Config in init, so it lists every key
+}
+
+var policyTopic = &cobra.Command{
+	Use:   "policies",
+	Short: "Forbidding or restricting commands with [[policy]] rules",
+	Long: `[[policy]] entries of the config file forbid an operation outright or only
+allow it in repositories under some paths:
+
+  [[policy]]
+  command = "push --force"
+  deny = true
+  reason = "force pushes need a review"
+
+  [[policy]]
+  command = "commit --amend"
+  paths = ["~/src/sandbox"]
+
+command is a gitbatch command, such as "push" or "remote set-url",
+optionally followed by flags that must be set for the rule to apply;
+"--flag=value" also compares the flag's value. A deny rule stops a matching
+command before it starts. A rule with paths stops it, before any repository
+is touched, when one of the matched repositories is not below one of the
+paths. reason is added to the error. Runs refused by a policy are recorded
+in the audit log.`,
+}
+
+var exitCodesTopic = &cobra.Command{
+	Use:   "exit-codes",
+	Short: "Exit status of gitbatch",
+	Long: `gitbatch exits with one of these statuses, so scripts and CI can tell
+what went wrong:
+
+  0   every repository succeeded
+  1   some repositories failed, or the command itself did
+  2   no repositories matched the patterns and filters, or none were left
+      to work on
+  3   invalid usage: an unknown command or flag, or a bad flag value or
+      argument
+  4   aborted: a confirmation prompt was declined
+  5   refused by a [[policy]] rule or read-only mode
+
+When repositories fail and a prompt is also declined, the status is 1.
+Plugins (gitbatch-<name> executables) exit with their own status.`,
+}
+
+// configSchema lists the keys of t, a config struct, in TOML layout. table
+// is the name of the table holding t, "" at the top level.
+func configSchema(t reflect.Type, table string) []string {
+	var lines, sections []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		name := key
+		if table != "" {
+			name = table + "." + key
+		}
+		ft := f.Type
+		switch {
+		case ft.Kind() == reflect.Struct:
+			sections = append(sections, "", "["+name+"]")
+			sections = append(sections, configSchema(ft, name)...)
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct:
+			sections = append(sections, "", "[["+name+"]]")
+			sections = append(sections, configSchema(ft.Elem(), name)...)
+		case ft.Kind() == reflect.Map && ft.Elem().Kind() == reflect.Struct:
+			sections = append(sections, "", "["+name+".<name>]")
+			sections = append(sections, configSchema(ft.Elem(), name+".<name>")...)
+		case ft.Kind() == reflect.Map && table == "":
+			// a top-level map is a table of its own
+			sections = append(sections, "", "["+name+"]", "<name> = "+schemaType(ft.Elem()))
+		default:
+			lines = append(lines, key+" = "+schemaType(ft))
+		}
+	}
+	return append(lines, sections...)
+}
+
+// schemaType describes the TOML value of a config field of type t.
+func schemaType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int64:
+		return "integer"
+	case reflect.Slice:
+		return "[" + schemaType(t.Elem()) + ", ...]"
+	case reflect.Map:
+		return "{ <name> = " + schemaType(t.Elem()) + ", ... }"
+	}
+	return t.Kind().String()
+}
+
+// indentLines joins lines, indenting those that are not empty.
+func indentLines(lines []string, indent string) string {
+	var b strings.Builder
+	for i, l := range lines {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if l != "" {
+			b.WriteString(indent + l)
+		}
+	}
+	return b.String()
+}
+
+// roffEscape escapes text for a man page: backslashes, hyphens, and dots
+// and quotes that would start a request at the beginning of a line.
+func roffEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// roffText turns a help text into man page paragraphs. Indented lines,
+// such as examples, are kept as they are.
+func roffText(text string) string {
+	var b strings.Builder
+	verbatim, blank := false, false
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if strings.TrimSpace(line) == "" {
+			blank = true
+			continue
+		}
+		indented := strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "\t")
+		switch {
+		case indented && !verbatim:
+			b.WriteString(".PP\n.RS 4\n.nf\n")
+			verbatim = true
+		case indented && blank:
+			b.WriteString("\n")
+		case !indented && verbatim:
+			b.WriteString(".fi\n.RE\n.PP\n")
+			verbatim = false
+		case blank:
+			b.WriteString(".PP\n")
+		}
+		blank = false
+		if verbatim {
+			line = strings.TrimPrefix(strings.TrimPrefix(line, "  "), "\t")
+		}
+		b.WriteString(roffEscape(line) + "\n")
+	}
+	if verbatim {
+		b.WriteString(".fi\n.RE\n")
+	}
+	return b.String()
+}
+
+// roffFlags lists flags as man page options.
+func roffFlags(flags *pflag.FlagSet) string {
+	var b strings.Builder
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		b.WriteString(".TP\n")
+		if f.Shorthand != "" {
+			fmt.Fprintf(&b, `\fB\-%s\fR, `, f.Shorthand)
+		}
+		fmt.Fprintf(&b, `\fB\-\-%s\fR`, roffEscape(f.Name))
+		if varname, _ := pflag.UnquoteUsage(f); varname != "" {
+			fmt.Fprintf(&b, ` \fI%s\fR`, roffEscape(varname))
+		}
+		b.WriteString("\n")
+		_, usage := pflag.UnquoteUsage(f)
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "[]" && f.DefValue != "0" && f.DefValue != "0s" {
+			usage += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+		b.WriteString(roffEscape(usage) + "\n")
+	})
+	return b.String()
+}
+
+// manName is the man page name of cmd: gitbatch, gitbatch-pull,
+// gitbatch-remote-add.
+func manName(cmd *cobra.Command) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+}
+
+// manSection is 1 for commands and 7 for help topics.
+func manSection(cmd *cobra.Command) string {
+	if cmd.Runnable() || cmd.HasAvailableSubCommands() {
+		return "1"
+	}
+	return "7"
+}
+
+// manPage renders cmd as a man page dated date.
+func manPage(cmd *cobra.Command, date time.Time) string {
+	var b strings.Builder
+	name, section := manName(cmd), manSection(cmd)
+	fmt.Fprintf(&b, ".TH %q %q %q \"gitbatch\" \"gitbatch manual\"\n", strings.ToUpper(name), section, date.Format("2006-01-02"))
+	fmt.Fprintf(&b, ".SH NAME\n%s \\- %s\n", roffEscape(name), roffEscape(cmd.Short))
+	if section == "1" {
+		fmt.Fprintf(&b, ".SH SYNOPSIS\n\\fB%s\\fR\n", roffEscape(cmd.UseLine()))
+	}
+	long := cmd.Long
+	if long == "" {
+		long = cmd.Short
+	}
+	b.WriteString(".SH DESCRIPTION\n" + roffText(long))
+	if cmd.Example != "" {
+		b.WriteString(".SH EXAMPLES\n" + roffText(cmd.Example))
+	}
+	// help topics have no options
+	if local := cmd.NonInheritedFlags(); section == "1" && local.HasAvailableFlags() {
+		b.WriteString(".SH OPTIONS\n" + roffFlags(local))
+	}
+	if inherited := cmd.InheritedFlags(); section == "1" && inherited.HasAvailableFlags() {
+		b.WriteString(".SH GLOBAL OPTIONS\n" + roffFlags(inherited))
+	}
+	var related []string
+	if cmd.HasParent() {
+		related = append(related, manName(cmd.Parent())+"("+manSection(cmd.Parent())+")")
+	}
+	for _, c := range manCommands(cmd) {
+		related = append(related, manName(c)+"("+manSection(c)+")")
+	}
+	if len(related) > 0 {
+		b.WriteString(".SH SEE ALSO\n" + roffEscape(strings.Join(related, ", ")) + "\n")
+	}
+	return b.String()
+}
+
+// manCommands are the children of cmd that get a man page: not hidden,
+// and not cobra's help and completion machinery.
+func manCommands(cmd *cobra.Command) []*cobra.Command {
+	var cmds []*cobra.Command
+	for _, c := range cmd.Commands() {
+		if c.Hidden || c.Name() == "help" || c.Name() == "completion" || !(c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand()) {
+			continue
+		}
+		cmds = append(cmds, c)
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name() < cmds[j].Name() })
+	return cmds
+}
+
+// writeManPages writes a man page for cmd and every command below it to
+// the man1 or man7 directory below dir, and returns the files written.
+func writeManPages(cmd *cobra.Command, dir string, date time.Time) ([]string, error) {
+	section := manSection(cmd)
+	file := filepath.Join(dir, "man"+section, manName(cmd)+"."+section)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(file, []byte(manPage(cmd, date)), 0o644); err != nil {
+		return nil, err
+	}
+	files := []string{file}
+	for _, c := range manCommands(cmd) {
+		sub, err := writeManPages(c, dir, date)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sub...)
+	}
+	return files, nil
+}
+
+// docsDate is the date in the man pages: SOURCE_DATE_EPOCH for
+// reproducible builds, else today.
+func docsDate() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Now(), nil
+	}
+	sec, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q", epoch)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// docs command
+var docsOut string
+var docsCmd = &cobra.Command{
+	Use:   "docs --out <dir>",
+	Short: "Generate man pages for every command and help topic",
+	Long: `docs writes a man page for gitbatch and each of its commands to
+<dir>/man1 (gitbatch.1, gitbatch-pull.1, gitbatch-remote-add.1, ...), and one
+for each help topic to <dir>/man7 (gitbatch-patterns.7, ...). Aliases from
+the config file are included. Install them with, for example:
+
+  gitbatch docs --out ~/.local/share/man
+  man gitbatch-pull
+
+The date in the pages is taken from SOURCE_DATE_EPOCH when it is set. The
+help topics are also shown by "gitbatch help <topic>".`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if docsOut == "" {
+			return usageErrorf("--out is required")
+		}
+		date, err := docsDate()
+		if err != nil {
+			return err
+		}
+		files, err := writeManPages(rootCmd, docsOut, date)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("wrote %d man pages to %s\n", len(files), docsOut)
+		return nil
+	},
+}
+
+func init() {
+	configTopic.Long = `The configuration file is TOML, read from --config or by default from
+gitbatch/config.toml in the user config directory
+(~/.config/gitbatch/config.toml on Linux). A missing default file is an
+empty configuration; unknown keys are rejected. <name> stands for a name
+you choose. These are all the keys:
+
+` + indentLines(configSchema(reflect.TypeOf(Config{}), ""), "  ") + `
+
+The README describes each section with examples.`
+
+	rootCmd.AddCommand(patternsTopic, configTopic, policyTopic, exitCodesTopic)
+	rootCmd.AddCommand(docsCmd)
+
+	docsCmd.Flags().StringVar(&docsOut, "out", "", "man directory to write the pages to, in man1 and man7 (required)")
+}
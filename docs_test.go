@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigSchema(t *testing.T) {
+	schema := configSchema(reflect.TypeOf(Config{}), "")
+	for _, want := range []string{
+		"read_only = boolean",
+		"[identity.<name>]",
+		"[[secrets.rules]]",
+		"[[repo]]",
+		"env = { <name> = string, ... }",
+		"tags = [string, ...]",
+		"[alias]",
+		"<name> = [string, ...]",
+	} {
+		if !slices.Contains(schema, want) {
+			t.Errorf("schema lacks %q:\n%s", want, strings.Join(schema, "\n"))
+		}
+	}
+	if schema[0] != "read_only = boolean" {
+		t.Errorf("top-level keys must come before the tables, got %q first", schema[0])
+	}
+}
+
+func TestRoffText(t *testing.T) {
+	got := roffText("First -v line.\n.dot\n\n  gitbatch pull\n\n  gitbatch push\nAfter.")
+	want := "First \\-v line.\n\\&.dot\n.PP\n.RS 4\n.nf\ngitbatch pull\n\ngitbatch push\n.fi\n.RE\n.PP\nAfter.\n"
+	if got != want {
+		t.Errorf("roffText =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteManPages(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	date, err := docsDate()
+	if err != nil || !date.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("docsDate() = %v, %v", date, err)
+	}
+	dir := t.TempDir()
+	files, err := writeManPages(rootCmd, dir, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"man1/gitbatch.1", "man1/gitbatch-pull.1", "man1/gitbatch-bundle-create.1", "man7/gitbatch-patterns.7", "man7/gitbatch-exit-codes.7"} {
+		if !slices.Contains(files, filepath.Join(dir, name)) {
+			t.Errorf("%s not written", name)
+		}
+	}
+	for _, name := range []string{"man1/gitbatch-help.1", "man1/gitbatch-completion.1"} {
+		if slices.Contains(files, filepath.Join(dir, name)) {
+			t.Errorf("%s written", name)
+		}
+	}
+	page, err := os.ReadFile(filepath.Join(dir, "man1", "gitbatch-pull.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`.TH "GITBATCH-PULL" "1" "2023-11-14"`, ".SH SYNOPSIS", ".SH OPTIONS", ".SH GLOBAL OPTIONS", `\fB\-\-config\fR`} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("gitbatch-pull.1 lacks %q", want)
+		}
+	}
+}
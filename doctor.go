@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+// minGitVersion is the oldest git release gitbatch is expected to work with.
+var minGitVersion = [3]int{2, 25, 0}
+
+// doctor command
+var doctorRemoteTimeout time.Duration
+var doctorCmd = &cobra.Command{
+	Use:   "doctor <pattern>...",
+	Short: "Check git, credentials and remote reachability in matching repositories",
+	Long: `doctor verifies the local git installation, credential helpers and
+ssh-agent, then contacts every remote of every matching repository with
+git ls-remote. Shallow and partial clones are reported per repository.
+Repositories that would likely fail a pull or push are listed at the end.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		checkGitVersion(ctx)
+		helpers := checkCredentialHelpers(ctx)
+		checkSSHAgent(ctx)
+
+		failing := map[string][]string{}
+		var order, shallow []string
+		for _, r := range repos {
+			repoHeader(r)
+			problems := doctorRepo(ctx, r, helpers)
+			if len(problems) > 0 {
+				failing[r] = problems
+				order = append(order, r)
+			}
+			// a shallow clone is not a problem, but log, blame and merge-base
+			// see only part of the history
+			if s, err := inspectClone(ctx, r); err == nil && s.String() != "" {
+				fmt.Printf("  %-10s %s\n", "clone", s)
+				if s.shallow {
+					shallow = append(shallow, r)
+				}
+			}
+		}
+
+		fmt.Println()
+		if len(shallow) > 0 {
+			fmt.Printf("%d shallow repositories (gitbatch unshallow fetches the full history): %s\n", len(shallow), strings.Join(shallow, ", "))
+		}
+		if len(order) == 0 {
+			fmt.Println(paint(os.Stdout, ansiGreen, fmt.Sprintf("all %d repositories look healthy", len(repos))))
+			return nil
+		}
+		fmt.Println(paint(os.Stdout, ansiRed, fmt.Sprintf("%d of %d repositories would likely fail a pull/push:", len(order), len(repos))))
+		for _, r := range order {
+			fmt.Printf("  %s: %s\n", r, strings.Join(failing[r], "; "))
+		}
+		return nil
+	},
+}
+
+// checkGitVersion prints the installed git version and warns when it is
+// older than minGitVersion.
+func checkGitVersion(ctx context.Context) {
+	out, err := gitOutput(ctx, "", "version")
+	if err != nil {
+		fmt.Printf("git:               not usable: %v\n", err)
+		return
+	}
+	v, ok := parseGitVersion(out)
+	if !ok {
+		fmt.Printf("git:               %s (could not parse version)\n", out)
+		return
+	}
+	if versionLess(v, minGitVersion) {
+		fmt.Printf("git:               %s (older than recommended %d.%d.%d)\n", out, minGitVersion[0], minGitVersion[1], minGitVersion[2])
+		return
+	}
+	fmt.Printf("git:               %s ok\n", out)
+}
+
+// checkCredentialHelpers prints the global/system credential helpers and
+// returns them so per-repo checks can tell whether one is configured at all.
+func checkCredentialHelpers(ctx context.Context) []string {
+	var helpers []string
+	for _, scope := range []string{"--system", "--global"} {
+		out, err := gitOutput(ctx, "", "config", scope, "--get-all", "credential.helper")
+		if err != nil {
+			continue
+		}
+		helpers = append(helpers, strings.Fields(out)...)
+	}
+	if len(helpers) == 0 {
+		fmt.Println("credential.helper: none configured (https remotes may prompt for credentials)")
+	} else {
+		fmt.Printf("credential.helper: %s\n", strings.Join(helpers, ", "))
+	}
+	return helpers
+}
+
+// checkSSHAgent reports whether an ssh-agent is reachable and how many keys
+// it holds.
+func checkSSHAgent(ctx context.Context) {
+	n, err := sshAgentKeys(ctx)
+	switch {
+	case err == errNoSSHAgent:
+		fmt.Println("ssh-agent:         not running (SSH_AUTH_SOCK is not set)")
+	case err != nil:
+		fmt.Printf("ssh-agent:         not reachable: %v\n", err)
+	case n == 0:
+		fmt.Println("ssh-agent:         running, no keys loaded")
+	default:
+		fmt.Printf("ssh-agent:         running, %d key(s) loaded\n", n)
+	}
+}
+
+// errNoSSHAgent is returned by sshAgentKeys when SSH_AUTH_SOCK is not set.
+var errNoSSHAgent = errors.New("SSH_AUTH_SOCK is not set")
+
+// sshAgentKeys returns the number of keys the ssh-agent holds.
+func sshAgentKeys(ctx context.Context) (int, error) {
+	if os.Getenv("SSH_AUTH_SOCK") == "" {
+		return 0, errNoSSHAgent
+	}
+	ctx, cancel := commandContext(ctx)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "ssh-add", "-l").Output()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return len(strings.Split(strings.TrimSpace(string(out)), "\n")), nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return 0, nil // ssh-add -l fails when the agent holds no keys
+	}
+	return 0, err
+}
+
+// doctorRepo checks every remote of the repository at dir and returns a
+// description of each problem found.
+func doctorRepo(ctx context.Context, dir string, globalHelpers []string) []string {
+	remotes, err := remoteNames(ctx, dir)
+	if err != nil {
+		fmt.Printf("  cannot list remotes: %v\n", err)
+		return []string{"cannot list remotes"}
+	}
+	if len(remotes) == 0 {
+		fmt.Println("  no remotes configured")
+		return []string{"no remotes configured"}
+	}
+
+	var problems []string
+	for _, name := range remotes {
+		url, err := gitOutput(ctx, dir, "remote", "get-url", name)
+		if err != nil {
+			fmt.Printf("  %-10s cannot read url: %v\n", name, err)
+			problems = append(problems, name+": cannot read url")
+			continue
+		}
+		if isHTTPURL(url) && len(globalHelpers) == 0 {
+			if local, _ := gitOutput(ctx, dir, "config", "--get-all", "credential.helper"); local == "" {
+				fmt.Printf("  %-10s %s: no credential helper, git may prompt\n", name, url)
+			}
+		}
+		start := time.Now()
+		if err := lsRemote(ctx, dir, name, doctorRemoteTimeout); err != nil {
+			fmt.Printf("  %-10s %s: %s: %v\n", name, url, paint(os.Stdout, ansiRed, "unreachable"), err)
+			problems = append(problems, name+" unreachable")
+			continue
+		}
+		fmt.Printf("  %-10s %s: %s (%s)\n", name, url, paint(os.Stdout, ansiGreen, "ok"), time.Since(start).Round(time.Millisecond))
+	}
+	return problems
+}
+
+// lsRemote contacts remote with git ls-remote, bounded by timeout. Terminal
+// prompts are disabled so a missing credential fails instead of blocking
+// the run.
+func lsRemote(ctx context.Context, dir, remote string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	out, err := gitExec.Capture(ctx, runner.Cmd{Dir: dir, Args: []string{"ls-remote", "--heads", remote}, Env: []string{"GIT_TERMINAL_PROMPT=0"}})
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(out); msg != "" {
+			return errors.New(firstLine(msg))
+		}
+		return err
+	}
+	return nil
+}
+
+// parseGitVersion extracts major, minor and patch numbers from the output of
+// `git version`, e.g. "git version 2.39.5" or "git version 2.42.0.windows.2".
+func parseGitVersion(s string) ([3]int, bool) {
+	var v [3]int
+	fields := strings.Fields(s)
+	if len(fields) < 3 {
+		return v, false
+	}
+	parts := strings.Split(fields[2], ".")
+	if len(parts) < 2 {
+		return v, false
+	}
+	for i := 0; i < 3 && i < len(parts); i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return v, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
+
+// versionLess reports whether a is an older version than b.
+func versionLess(a, b [3]int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+func isHTTPURL(url string) bool {
+	return strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://")
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().DurationVar(&doctorRemoteTimeout, "remote-timeout", 10*time.Second, "timeout for contacting each remote")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseGitVersion(t *testing.T) {
+	cases := map[string][3]int{
+		"git version 2.39.5":                 {2, 39, 5},
+		"git version 2.42.0.windows.2":       {2, 42, 0},
+		"git version 2.30.1 (Apple Git-130)": {2, 30, 1},
+	}
+	for in, want := range cases {
+		got, ok := parseGitVersion(in)
+		if !ok || got != want {
+			t.Errorf("parseGitVersion(%q) = %v, %v; want %v", in, got, ok, want)
+		}
+	}
+	if _, ok := parseGitVersion("not git"); ok {
+		t.Errorf("expected parse failure for garbage input")
+	}
+	if !versionLess([3]int{2, 24, 9}, minGitVersion) {
+		t.Errorf("expected 2.24.9 to be older than %v", minGitVersion)
+	}
+}
+
+func TestDoctorRepo(t *testing.T) {
+	repo := initTestRepo(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	doctorRemoteTimeout = 5 * time.Second
+
+	if problems := doctorRepo(ctx, repo, nil); len(problems) != 1 {
+		t.Fatalf("expected a problem for a repo without remotes, got %v", problems)
+	}
+
+	bare := filepath.Join(t.TempDir(), "origin.git")
+	if out, err := exec.Command("git", "init", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare failed: %v, out=%s", err, out)
+	}
+	if _, err := runGitCapture(ctx, repo, "remote", "add", "origin", bare); err != nil {
+		t.Fatal(err)
+	}
+	if problems := doctorRepo(ctx, repo, nil); len(problems) != 0 {
+		t.Errorf("expected reachable remote to pass, got %v", problems)
+	}
+
+	if _, err := runGitCapture(ctx, repo, "remote", "add", "gone", filepath.Join(t.TempDir(), "missing.git")); err != nil {
+		t.Fatal(err)
+	}
+	if problems := doctorRepo(ctx, repo, nil); len(problems) != 1 {
+		t.Errorf("expected missing remote to be reported, got %v", problems)
+	}
+}
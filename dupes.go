@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// normalizeRemote reduces a remote URL to host/path so that the https, ssh
+// and scp-like forms of the same repository compare equal. Local paths are
+// resolved against repo.
+func normalizeRemote(repo, remote string) string {
+	if !strings.Contains(remote, "://") && !strings.Contains(remote, ":") || strings.HasPrefix(remote, "/") {
+		if !filepath.IsAbs(remote) {
+			remote = filepath.Join(repo, remote)
+		}
+		return strings.TrimSuffix(filepath.Clean(remote), ".git")
+	}
+	var host, path string
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" && (u.Host != "" || u.Scheme == "file") {
+		host, path = u.Hostname(), u.Path
+	} else {
+		host, path, _ = strings.Cut(remote, ":")
+		if _, h, ok := strings.Cut(host, "@"); ok {
+			host = h
+		}
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" {
+		return "/" + path
+	}
+	return strings.ToLower(host) + "/" + path
+}
+
+// clone is a repository in a group of clones of the same remote.
+type clone struct {
+	repo     string
+	activity time.Time // last commit or fetch, zero without either
+	local    bool      // uncommitted changes, unpushed commits or stashes
+}
+
+// lastActivity returns the later of repo's last commit on a local branch
+// and its last fetch.
+func lastActivity(ctx context.Context, repo string) (time.Time, error) {
+	t, _, err := lastCommitTime(ctx, repo)
+	if err != nil {
+		return t, err
+	}
+	if fetched, ok := lastFetchTime(ctx, repo); ok && fetched.After(t) {
+		t = fetched
+	}
+	return t, nil
+}
+
+// hasLocalWork reports whether deleting repo would lose anything: changes
+// in the working tree, commits on no remote or stash entries.
+func hasLocalWork(ctx context.Context, repo string) (bool, error) {
+	s, err := readRepoState(ctx, repo)
+	if err != nil {
+		return false, err
+	}
+	w, err := unpushedWork(ctx, repo)
+	if err != nil {
+		return false, err
+	}
+	return !s.clean() || !w.empty(), nil
+}
+
+// groupClones groups repos by their normalized origin URL and keeps the
+// groups with more than one clone, sorted by remote. remotes maps each
+// repository to its origin URL; repositories missing from it are ignored.
+func groupClones(remotes map[string]string) (keys []string, groups map[string][]string) {
+	groups = map[string][]string{}
+	for repo, remote := range remotes {
+		key := normalizeRemote(repo, remote)
+		groups[key] = append(groups[key], repo)
+	}
+	for key, repos := range groups {
+		if len(repos) < 2 {
+			delete(groups, key)
+			continue
+		}
+		sort.Strings(repos)
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, groups
+}
+
+// dupeSuggestion is the --suggest column for c, the clone at index i of a
+// group sorted by activity, most recent first.
+func dupeSuggestion(i int, c clone) string {
+	switch {
+	case i == 0:
+		return "keep (most recent)"
+	case c.local:
+		return "has local work"
+	}
+	return "can delete"
+}
+
+// dupes command
+var dupesSuggest bool
+var dupesCmd = &cobra.Command{
+	Use:   "dupes [--suggest] <pattern>...",
+	Short: "Find directories that are clones of the same remote",
+	Long: `dupes groups the matching repositories by their origin URL and lists every
+remote that is cloned more than once, with the date of each clone's last
+commit or fetch. The https, ssh and scp-like forms of a URL count as the
+same remote. Repositories without an origin remote are ignored.
+
+With --suggest the most recently active clone of each group is marked to
+keep, and the others as safe to delete unless they have uncommitted
+changes, unpushed commits or stashes. Nothing is ever deleted.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		remotes := map[string]string{}
+		for _, r := range repos {
+			if u, err := gitOutput(ctx, r, "remote", "get-url", "origin"); err == nil && u != "" {
+				remotes[r] = u
+			}
+		}
+		keys, groups := groupClones(remotes)
+		if len(keys) == 0 {
+			fmt.Printf("no duplicate clones among %d repositories\n", len(repos))
+			return nil
+		}
+
+		now := time.Now()
+		extra := 0
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for i, key := range keys {
+			var clones []clone
+			for _, r := range groups[key] {
+				c := clone{repo: r}
+				if c.activity, err = lastActivity(ctx, r); err != nil {
+					repoFailed(r, err)
+					continue
+				}
+				if dupesSuggest {
+					if c.local, err = hasLocalWork(ctx, r); err != nil {
+						repoFailed(r, err)
+						continue
+					}
+				}
+				clones = append(clones, c)
+			}
+			sort.SliceStable(clones, func(i, j int) bool { return clones[i].activity.After(clones[j].activity) })
+			extra += len(groups[key]) - 1
+
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%s (%d clones)\n", key, len(groups[key]))
+			for j, c := range clones {
+				activity := "never"
+				if !c.activity.IsZero() {
+					activity = fmt.Sprintf("%s (%s ago)", c.activity.Format("2006-01-02"), formatAge(now.Sub(c.activity)))
+				}
+				if dupesSuggest {
+					fmt.Fprintf(w, "  %s\t%s\t%s\n", c.repo, activity, dupeSuggestion(j, c))
+				} else {
+					fmt.Fprintf(w, "  %s\t%s\n", c.repo, activity)
+				}
+			}
+		}
+		w.Flush()
+		fmt.Printf("\n%d remotes are cloned more than once, %d extra clones\n", len(keys), extra)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dupesCmd)
+
+	dupesCmd.Flags().BoolVar(&dupesSuggest, "suggest", false, "suggest which clones to keep and which can be deleted")
+}
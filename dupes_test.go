@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeRemote(t *testing.T) {
+	for remote, want := range map[string]string{
+		"https://github.com/org/api.git":       "github.com/org/api",
+		"https://user@GitHub.com/org/api/":     "github.com/org/api",
+		"ssh://git@github.com:22/org/api.git":  "github.com/org/api",
+		"git@github.com:org/api.git":           "github.com/org/api",
+		"github.com:org/api":                   "github.com/org/api",
+		"/srv/git/api.git":                     "/srv/git/api",
+		"file:///srv/git/api.git":              "/srv/git/api",
+		"../api.git":                           "/work/api",
+		"https://gitlab.example.com/a/b/c.git": "gitlab.example.com/a/b/c",
+	} {
+		if got := normalizeRemote("/work/clone", remote); got != want {
+			t.Errorf("normalizeRemote(%q) = %q, want %q", remote, got, want)
+		}
+	}
+}
+
+func TestGroupClones(t *testing.T) {
+	keys, groups := groupClones(map[string]string{
+		"/w/api":      "git@github.com:org/api.git",
+		"/old/api":    "https://github.com/org/api",
+		"/w/web":      "git@github.com:org/web.git",
+		"/tmp/api-v2": "ssh://git@github.com/org/api",
+	})
+	if !reflect.DeepEqual(keys, []string{"github.com/org/api"}) {
+		t.Fatalf("keys = %q", keys)
+	}
+	if want := []string{"/old/api", "/tmp/api-v2", "/w/api"}; !reflect.DeepEqual(groups[keys[0]], want) {
+		t.Errorf("group = %q, want %q", groups[keys[0]], want)
+	}
+}
+
+func TestHasLocalWork(t *testing.T) {
+	ctx := context.Background()
+	upstream := initTestRepo(t)
+	commitTestFile(t, upstream, "a.txt", "a", "first")
+	repo := filepath.Join(t.TempDir(), "clone")
+	if out, err := exec.Command("git", "clone", "-q", upstream, repo).CombinedOutput(); err != nil {
+		t.Fatalf("git clone: %v %s", err, out)
+	}
+	initTestRepoAt(t, repo) // sets the committer identity
+
+	if local, err := hasLocalWork(ctx, repo); err != nil || local {
+		t.Errorf("fresh clone: %v, %v", local, err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "a.txt"), []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if local, err := hasLocalWork(ctx, repo); err != nil || !local {
+		t.Errorf("changed file: %v, %v", local, err)
+	}
+	if out, err := exec.Command("git", "-C", repo, "checkout", "--", "a.txt").CombinedOutput(); err != nil {
+		t.Fatalf("git checkout: %v %s", err, out)
+	}
+	commitTestFile(t, repo, "b.txt", "b", "local")
+	if local, err := hasLocalWork(ctx, repo); err != nil || !local {
+		t.Errorf("unpushed commit: %v, %v", local, err)
+	}
+
+	if got := dupeSuggestion(0, clone{local: true}); got != "keep (most recent)" {
+		t.Errorf("most recent clone: %q", got)
+	}
+	if got := dupeSuggestion(1, clone{local: true}); got != "has local work" {
+		t.Errorf("clone with local work: %q", got)
+	}
+	if got := dupeSuggestion(2, clone{}); got != "can delete" {
+		t.Errorf("clean clone: %q", got)
+	}
+}
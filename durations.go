@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// slowest is --slowest: how many of the slowest repositories to list after
+// the run.
+var slowest int
+
+// timingExecutor wraps the git executor to add the wall time of every git
+// command to its repository's total in the run summary.
+type timingExecutor struct {
+	next runner.GitExecutor
+}
+
+// addRepoTime adds the time since start to the total of repo.
+func addRepoTime(repo string, start time.Time) {
+	runSummary.Lock()
+	defer runSummary.Unlock()
+	runSummary.durations[repo] += time.Since(start)
+}
+
+func (e timingExecutor) Stream(ctx context.Context, c runner.Cmd) error {
+	defer addRepoTime(c.Dir, time.Now())
+	return e.next.Stream(ctx, c)
+}
+
+func (e timingExecutor) Capture(ctx context.Context, c runner.Cmd) (string, error) {
+	defer addRepoTime(c.Dir, time.Now())
+	return e.next.Capture(ctx, c)
+}
+
+func (e timingExecutor) Output(ctx context.Context, c runner.Cmd) (string, error) {
+	defer addRepoTime(c.Dir, time.Now())
+	return e.next.Output(ctx, c)
+}
+
+func (e timingExecutor) Lines(ctx context.Context, c runner.Cmd, fn func(string)) error {
+	defer addRepoTime(c.Dir, time.Now())
+	return e.next.Lines(ctx, c, fn)
+}
+
+// setupTimings checks --slowest and times every git command, outside the
+// other executors so retries and waits for a host slot count too.
+func setupTimings() error {
+	if slowest < 0 {
+		return usageErrorf("invalid --slowest %d (expected a positive number)", slowest)
+	}
+	gitExec = timingExecutor{next: gitExec}
+	return nil
+}
+
+// repoDurations returns the time git took in each repository of the run.
+// The caller holds runSummary.
+func repoDurations() map[string]time.Duration {
+	d := make(map[string]time.Duration, len(runSummary.repos))
+	for _, r := range runSummary.repos {
+		d[r] = runSummary.durations[r]
+	}
+	return d
+}
+
+// slowestRepos returns the n repositories of durations that took longest,
+// slowest first.
+func slowestRepos(durations map[string]time.Duration, n int) []string {
+	repos := make([]string, 0, len(durations))
+	for r := range durations {
+		repos = append(repos, r)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		if durations[repos[i]] != durations[repos[j]] {
+			return durations[repos[i]] > durations[repos[j]]
+		}
+		return repos[i] < repos[j]
+	})
+	return repos[:min(n, len(repos))]
+}
+
+// printSlowest ends the run with the --slowest repositories and the time
+// git took in each.
+func printSlowest() {
+	if slowest == 0 {
+		return
+	}
+	runSummary.Lock()
+	defer runSummary.Unlock()
+	durations := repoDurations()
+	if len(durations) == 0 {
+		return
+	}
+	fmt.Printf("\n%s\n", paint(os.Stdout, ansiBold, fmt.Sprintf("slowest %d of %d repositories:", min(slowest, len(durations)), len(durations))))
+	for _, r := range slowestRepos(durations, slowest) {
+		fmt.Printf("  %s  %s\n", paint(os.Stdout, ansiYellow, fmt.Sprintf("%10s", durations[r].Round(time.Millisecond))), r)
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().IntVar(&slowest, "slowest", 0, "after the run, list the N repositories where git took longest")
+}
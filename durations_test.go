@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+func TestSlowestRepos(t *testing.T) {
+	d := map[string]time.Duration{"/r/a": time.Second, "/r/b": 3 * time.Second, "/r/c": time.Second, "/r/d": 2 * time.Second}
+	if got := slowestRepos(d, 3); !reflect.DeepEqual(got, []string{"/r/b", "/r/d", "/r/a"}) {
+		t.Errorf("slowestRepos = %q", got)
+	}
+	if got := slowestRepos(d, 10); len(got) != 4 {
+		t.Errorf("slowestRepos with n beyond the run = %q", got)
+	}
+}
+
+func TestTimingExecutor(t *testing.T) {
+	prevRepos, prevSlowest := runSummary.repos, slowest
+	t.Cleanup(func() {
+		runSummary.Lock()
+		runSummary.repos = prevRepos
+		delete(runSummary.durations, "/r/a")
+		delete(runSummary.durations, "/r/b")
+		runSummary.Unlock()
+		slowest = prevSlowest
+	})
+	exe := timingExecutor{next: &runner.Mock{}}
+	exe.Stream(context.Background(), runner.Git("/r/a", "pull"))
+	exe.Output(context.Background(), runner.Git("/r/a", "rev-parse", "HEAD"))
+	runSummary.Lock()
+	runSummary.repos = []string{"/r/a", "/r/b"}
+	runSummary.durations["/r/b"] = time.Hour
+	d := repoDurations()
+	runSummary.Unlock()
+	if _, ok := d["/r/a"]; !ok || d["/r/b"] != time.Hour {
+		t.Errorf("repoDurations = %v", d)
+	}
+
+	slowest = 1
+	out := captureStdout(t, printSlowest)
+	if !strings.Contains(out, "slowest 1 of 2 repositories:") || !strings.Contains(out, "1h0m0s  /r/b") || strings.Contains(out, "/r/a") {
+		t.Errorf("printSlowest printed %q", out)
+	}
+}
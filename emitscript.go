@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+// emitScript is --emit-script: the file that receives the git commands of
+// the run instead of running them.
+var emitScript string
+
+// noScriptCommands do more than run git, ask before each repository, or
+// never finish, so a script would not reproduce them.
+var noScriptCommands = map[string]bool{
+	"difftool":      true,
+	"hooks install": true,
+	"hooks remove":  true,
+	"pr create":     true,
+	"serve":         true,
+	"watch":         true,
+}
+
+// scriptExecutor writes the commands that act on repositories, run through
+// Stream and Capture, to a shell script instead of running them. Output and
+// Lines, which gitbatch uses to inspect repositories, still run, so the
+// script holds the commands a real run would have made.
+type scriptExecutor struct {
+	next runner.GitExecutor
+	git  string
+
+	mu       sync.Mutex
+	w        *bufio.Writer
+	lastDir  string
+	commands int
+}
+
+// line renders c as a shell command line. Per-command environment is set
+// with assignments in front of git.
+func (e *scriptExecutor) line(c runner.Cmd) string {
+	var b strings.Builder
+	for _, kv := range c.Env {
+		k, v, _ := strings.Cut(kv, "=")
+		b.WriteString(k + "=" + shellQuote(v) + " ")
+	}
+	b.WriteString(shellQuote(e.git))
+	if c.Dir != "" {
+		b.WriteString(" -C " + shellQuote(c.Dir))
+	}
+	if len(c.Args) > 0 {
+		b.WriteString(" " + shellJoin(c.Args))
+	}
+	return b.String()
+}
+
+func (e *scriptExecutor) write(c runner.Cmd) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if c.Dir != e.lastDir {
+		fmt.Fprintf(e.w, "\n# %s\n", c.Dir)
+		e.lastDir = c.Dir
+	}
+	fmt.Fprintf(e.w, "%s || status=1\n", e.line(c))
+	e.commands++
+}
+
+func (e *scriptExecutor) Stream(ctx context.Context, c runner.Cmd) error {
+	e.write(c)
+	return nil
+}
+
+func (e *scriptExecutor) Capture(ctx context.Context, c runner.Cmd) (string, error) {
+	e.write(c)
+	return "", nil
+}
+
+func (e *scriptExecutor) Output(ctx context.Context, c runner.Cmd) (string, error) {
+	return e.next.Output(ctx, c)
+}
+
+func (e *scriptExecutor) Lines(ctx context.Context, c runner.Cmd, fn func(string)) error {
+	return e.next.Lines(ctx, c, fn)
+}
+
+// scriptHeader starts the script: it names the gitbatch invocation and sets
+// up the environment every git command gets.
+func scriptHeader(args []string, env []string, now time.Time) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "# gitbatch %s\n", shellJoin(redactArgs(args)))
+	fmt.Fprintf(&b, "# generated %s; every command runs even if an earlier one\n# fails, and the exit status reports whether any failed\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&b, "unset %s\n", strings.Join(runner.RepoEnvVars(), " "))
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		fmt.Fprintf(&b, "export %s=%s\n", k, shellQuote(v))
+	}
+	b.WriteString("status=0\n")
+	return b.String()
+}
+
+// script is the scriptExecutor of the run and the file it writes to.
+var script struct {
+	exe  *scriptExecutor
+	file *os.File
+}
+
+// checkEmitScript refuses --emit-script for commands a script cannot
+// reproduce.
+func checkEmitScript(cmd *cobra.Command) error {
+	if emitScript == "" {
+		return nil
+	}
+	if name := commandName(cmd); noScriptCommands[name] {
+		return fmt.Errorf("--emit-script: %s does more than run git and cannot be written to a script", name)
+	}
+	return nil
+}
+
+// setupEmitScript routes git through a scriptExecutor writing to
+// --emit-script. Repositories are processed one at a time so the script
+// lists them in order.
+func setupEmitScript() error {
+	if emitScript == "" {
+		return nil
+	}
+	f, err := os.OpenFile(emitScript, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o755)
+	if err != nil {
+		return fmt.Errorf("--emit-script: %v", err)
+	}
+	git := gitBinary.Path
+	if git == "" {
+		git = "git"
+	}
+	exe := &scriptExecutor{next: gitExec, git: git, w: bufio.NewWriter(f)}
+	exe.w.WriteString(scriptHeader(os.Args[1:], gitBinary.Env, time.Now()))
+	script.exe, script.file = exe, f
+	gitExec = exe
+	jobs = 1
+	return nil
+}
+
+// finishScript completes the script after the command has run. A failed
+// run leaves no script behind, since it may be missing repositories.
+func finishScript(runErr error) {
+	if script.file == nil {
+		return
+	}
+	exe, f := script.exe, script.file
+	script.exe, script.file = nil, nil
+	if runErr != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return
+	}
+	exe.w.WriteString("\nexit $status\n")
+	err := exe.w.Flush()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "--emit-script: %v\n", err)
+		return
+	}
+	logf(levelNormal, "wrote %d git commands to %s", exe.commands, f.Name())
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&emitScript, "emit-script", "", "write the git commands to this shell script instead of running them")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+func TestScriptExecutor(t *testing.T) {
+	ctx := context.Background()
+	m := &runner.Mock{}
+	m.On("rev-parse --abbrev-ref HEAD", "main\n", nil)
+	var b strings.Builder
+	exe := &scriptExecutor{next: m, git: "/opt/my git/bin/git", w: bufio.NewWriter(&b)}
+
+	if out, err := exe.Output(ctx, runner.Git("/src/a", "rev-parse", "--abbrev-ref", "HEAD")); err != nil || out != "main" {
+		t.Errorf("Output = %q, %v; queries should still run", out, err)
+	}
+	exe.Stream(ctx, runner.Git("/src/a", "commit", "-m", "it's done"))
+	exe.Capture(ctx, runner.Cmd{Dir: "/src/a", Args: []string{"push"}, Env: []string{"GIT_TERMINAL_PROMPT=0"}})
+	exe.Stream(ctx, runner.Git("/src/my repo", "push", "origin", "HEAD:refs/heads/x"))
+	exe.w.Flush()
+
+	want := `
+# /src/a
+'/opt/my git/bin/git' -C /src/a commit -m 'it'\''s done' || status=1
+GIT_TERMINAL_PROMPT=0 '/opt/my git/bin/git' -C /src/a push || status=1
+
+# /src/my repo
+'/opt/my git/bin/git' -C '/src/my repo' push origin HEAD:refs/heads/x || status=1
+`
+	if b.String() != want {
+		t.Errorf("script:\n%s\nwant:\n%s", b.String(), want)
+	}
+	if exe.commands != 3 {
+		t.Errorf("counted %d commands, want 3", exe.commands)
+	}
+	if got := m.Commands(); len(got) != 1 {
+		t.Errorf("ran %v, want only the rev-parse query", got)
+	}
+}
+
+func TestScriptHeader(t *testing.T) {
+	h := scriptHeader([]string{"push", "--force", "*"}, []string{"GIT_SSH_COMMAND=ssh -i ~/.ssh/work"}, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	for _, want := range []string{"#!/bin/sh\n", "# gitbatch push --force '*'\n", "2024-05-01T12:00:00Z", "unset GIT_ALTERNATE_OBJECT_DIRECTORIES GIT_COMMON_DIR GIT_CONFIG ", "GIT_DIR GIT_GRAFT_FILE", "export GIT_SSH_COMMAND='ssh -i ~/.ssh/work'\n", "status=0\n"} {
+		if !strings.Contains(h, want) {
+			t.Errorf("header lacks %q:\n%s", want, h)
+		}
+	}
+}
+
+func TestCheckEmitScript(t *testing.T) {
+	emitScript = "out.sh"
+	t.Cleanup(func() { emitScript = "" })
+
+	run := func(*cobra.Command, []string) {}
+	root := &cobra.Command{Use: "gitbatch"}
+	push := &cobra.Command{Use: "push", Run: run}
+	hooks := &cobra.Command{Use: "hooks"}
+	install := &cobra.Command{Use: "install", Run: run}
+	hooks.AddCommand(install)
+	root.AddCommand(push, hooks)
+
+	if err := checkEmitScript(push); err != nil {
+		t.Errorf("push refused: %v", err)
+	}
+	if err := checkEmitScript(install); err == nil {
+		t.Error("hooks install accepted")
+	}
+	if mutating(push) {
+		t.Error("push --emit-script counts as mutating")
+	}
+	if err := checkEmitScript(prCreateCmd); err == nil {
+		t.Error("pr create accepted")
+	}
+}
+
+func TestEmitScriptRecordsConfigSet(t *testing.T) {
+	useTestConfig(t, "")
+	repo := initTestRepo(t)
+	path := filepath.Join(t.TempDir(), "out.sh")
+	oldExec, oldJobs := gitExec, jobs
+	emitScript = path
+	t.Cleanup(func() { emitScript, gitExec, jobs = "", oldExec, oldJobs })
+
+	if err := setupEmitScript(); err != nil {
+		t.Fatal(err)
+	}
+	captureStdout(t, func() {
+		if err := configSetCmd.RunE(configSetCmd, []string{"user.email", "new@example.com", repo}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	finishScript(nil)
+
+	if got, _ := gitOutput(context.Background(), repo, "config", "--local", "--get", "user.email"); got == "new@example.com" {
+		t.Error("config set ran under --emit-script")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "config --local user.email new@example.com || status=1") {
+		t.Errorf("script lacks the config command:\n%s", data)
+	}
+}
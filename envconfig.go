@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// envFlags are the GITBATCH_* environment variables that set flags, so
+// containers and CI jobs can configure a run without files or long command
+// lines. A flag on the command line wins over its variable, and the
+// variable over [defaults] in the config file. GITBATCH_CONFIG, read by
+// loadConfig, names the config file.
+var envFlags = []struct {
+	env  string
+	flag string
+}{
+	{"GITBATCH_JOBS", "jobs"},
+	{"GITBATCH_TIMEOUT", "timeout"},
+	{"GITBATCH_OUTPUT", "output"},
+	{"GITBATCH_READ_ONLY", "read-only"},
+}
+
+// applyEnv sets the flags of cmd that a GITBATCH_* variable configures and
+// the command line leaves unset. A variable for a flag cmd does not have,
+// such as GITBATCH_OUTPUT for push, is ignored.
+func applyEnv(cmd *cobra.Command) error {
+	for _, e := range envFlags {
+		v := os.Getenv(e.env)
+		f := cmd.Flags().Lookup(e.flag)
+		if v == "" || f == nil || f.Changed {
+			continue
+		}
+		if err := cmd.Flags().Set(e.flag, v); err != nil {
+			return usageErrorf("invalid %s=%q: %v", e.env, v, err)
+		}
+	}
+	return nil
+}
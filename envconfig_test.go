@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestApplyEnv(t *testing.T) {
+	var jobs int
+	var timeout time.Duration
+	newCmd := func(args ...string) *cobra.Command {
+		root := &cobra.Command{Use: "gitbatch"}
+		root.PersistentFlags().IntVarP(&jobs, "jobs", "j", 1, "")
+		root.PersistentFlags().DurationVar(&timeout, "timeout", time.Minute, "")
+		cmd := &cobra.Command{Use: "push", Run: func(*cobra.Command, []string) {}}
+		root.AddCommand(cmd)
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatal(err)
+		}
+		return cmd
+	}
+	t.Setenv("GITBATCH_JOBS", "8")
+	t.Setenv("GITBATCH_TIMEOUT", "30s")
+	t.Setenv("GITBATCH_OUTPUT", "json") // push has no --output
+	useTestConfig(t, "[defaults]\npush = \"--jobs 4\"\n")
+
+	cmd := newCmd()
+	if err := applyEnv(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyDefaults(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if jobs != 8 || timeout != 30*time.Second {
+		t.Errorf("from the environment: jobs=%d timeout=%s", jobs, timeout)
+	}
+
+	if err := applyEnv(newCmd("-j", "2")); err != nil || jobs != 2 {
+		t.Errorf("command line: jobs=%d, %v", jobs, err)
+	}
+
+	t.Setenv("GITBATCH_JOBS", "many")
+	if err := applyEnv(newCmd()); exitCode(err) != exitUsage {
+		t.Errorf("invalid GITBATCH_JOBS accepted: %v", err)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ci.toml")
+	if err := os.WriteFile(path, []byte("read_only = true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	oldPath, oldCfg := configPath, loadedConfig
+	defer func() { configPath, loadedConfig = oldPath, oldCfg }()
+	configPath, loadedConfig = "", nil
+	t.Setenv("GITBATCH_CONFIG", path)
+	if cfg, err := loadConfig(); err != nil || !cfg.ReadOnly {
+		t.Errorf("GITBATCH_CONFIG not read: %+v, %v", cfg, err)
+	}
+
+	loadedConfig = nil
+	t.Setenv("GITBATCH_CONFIG", filepath.Join(t.TempDir(), "missing.toml"))
+	if _, err := loadConfig(); err == nil {
+		t.Error("missing GITBATCH_CONFIG file accepted")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/patrickkdev/gitbatch/pkg/discover"
+	"github.com/spf13/cobra"
+)
+
+// Exit statuses, documented in "gitbatch help exit-codes".
+const (
+	exitOK      = 0 // every repository succeeded
+	exitFailed  = 1 // some repositories failed, or the command did
+	exitNoRepos = 2 // no repositories matched or were left to work on
+	exitUsage   = 3 // invalid command, flag or argument
+	exitAborted = 4 // the user declined a confirmation
+	exitPolicy  = 5 // refused by a [[policy]] rule or read-only mode
+)
+
+// exitError gives err an exit status other than exitFailed.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// exitWith returns err with the exit status code.
+func exitWith(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// usageErrorf is an error about invalid flags or arguments.
+func usageErrorf(format string, args ...any) error {
+	return exitWith(exitUsage, fmt.Errorf(format, args...))
+}
+
+// noReposErrorf is an error for a run left without repositories.
+func noReposErrorf(format string, args ...any) error {
+	return exitWith(exitNoRepos, fmt.Errorf(format, args...))
+}
+
+// markAborted records that the user declined to go ahead.
+func markAborted() {
+	runSummary.Lock()
+	defer runSummary.Unlock()
+	runSummary.aborted = true
+}
+
+// exitCode is the status gitbatch exits with after a run that returned
+// err.
+func exitCode(err error) int {
+	var ee *exitError
+	switch {
+	case errors.As(err, &ee):
+		return ee.code
+	case errors.Is(err, discover.ErrNoRepos):
+		return exitNoRepos
+	case err != nil:
+		return exitFailed
+	}
+	runSummary.Lock()
+	defer runSummary.Unlock()
+	switch {
+	case len(runSummary.failed) > 0:
+		return exitFailed
+	case runSummary.aborted:
+		return exitAborted
+	}
+	return exitOK
+}
+
+// printError reports err from running cmd on w. Only usage errors are
+// followed by the usage of cmd, so a run refused by a policy prints its
+// reason alone. rootCmd silences cobra's own report of both.
+func printError(w io.Writer, cmd *cobra.Command, err error) {
+	fmt.Fprintln(w, err)
+	if cmd != nil && exitCode(err) == exitUsage {
+		fmt.Fprint(w, cmd.UsageString())
+	}
+}
+
+// markUsageErrors gives flag and argument errors of cmd and the commands
+// below it the usage exit status.
+func markUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return exitWith(exitUsage, err)
+	})
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			if err := validate(cmd, args); err != nil {
+				return exitWith(exitUsage, err)
+			}
+			return nil
+		}
+	}
+	for _, c := range cmd.Commands() {
+		markUsageErrors(c)
+	}
+}
+
+// unknownCommand returns the error for a command line naming a command that
+// does not exist. cobra only prints the help for it, since the root command
+// does not run anything itself.
+func unknownCommand(args []string) error {
+	if len(args) > 0 && strings.HasPrefix(args[0], cobra.ShellCompRequestCmd) {
+		return nil // added by cobra when it runs
+	}
+	cmd, rest, err := rootCmd.Find(args)
+	if err != nil || cmd != rootCmd {
+		return nil
+	}
+	if pos := positionalArgs(rootCmd, rest); len(pos) > 0 {
+		return usageErrorf("unknown command %q for %q", pos[0], rootCmd.Name())
+	}
+	return nil
+}
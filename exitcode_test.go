@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/patrickkdev/gitbatch/pkg/discover"
+	"github.com/spf13/cobra"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{errors.New("boom"), exitFailed},
+		{fmt.Errorf("finding repos: %w", discover.ErrNoRepos), exitNoRepos},
+		{noReposErrorf("no matching repositories pass %s", "--tag x"), exitNoRepos},
+		{usageErrorf("invalid --color %q", "pink"), exitUsage},
+		{PolicyRule{Command: "push"}.violation("is not allowed"), exitPolicy},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+	if err := usageErrorf("bad %s", "flag"); err.Error() != "bad flag" {
+		t.Errorf("usage error message %q", err)
+	}
+}
+
+func TestExitCodeFromRunSummary(t *testing.T) {
+	t.Cleanup(func() {
+		runSummary.Lock()
+		defer runSummary.Unlock()
+		runSummary.aborted = false
+		delete(runSummary.failed, "repo")
+	})
+
+	markAborted()
+	if got := exitCode(nil); got != exitAborted {
+		t.Errorf("aborted run exits %d, want %d", got, exitAborted)
+	}
+	markRepoFailed("repo")
+	if got := exitCode(nil); got != exitFailed {
+		t.Errorf("run with failed repos exits %d, want %d", got, exitFailed)
+	}
+}
+
+func TestMarkUsageErrors(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	var ran bool
+	sub := &cobra.Command{
+		Use:  "sub",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { ran = true; return nil },
+	}
+	sub.Flags().Int("n", 0, "")
+	root.AddCommand(sub)
+	root.SetOut(io.Discard)
+	root.SetErr(io.Discard)
+	markUsageErrors(root)
+
+	for _, args := range [][]string{{"sub", "extra"}, {"sub", "--nosuch"}, {"sub", "--n", "x"}} {
+		root.SetArgs(args)
+		if err := root.Execute(); exitCode(err) != exitUsage {
+			t.Errorf("%v: exit %d (%v), want %d", args, exitCode(err), err, exitUsage)
+		}
+	}
+	root.SetArgs([]string{"sub", "--n", "2"})
+	if err := root.Execute(); err != nil || !ran {
+		t.Errorf("valid command line: ran %v, err %v", ran, err)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	if err := unknownCommand([]string{"nosuch", "repos/*"}); exitCode(err) != exitUsage {
+		t.Errorf("unknown command: %v", err)
+	}
+	for _, args := range [][]string{nil, {"--help"}, {"status", "repos/*"}, {cobra.ShellCompRequestCmd, "st"}} {
+		if err := unknownCommand(args); err != nil {
+			t.Errorf("%v: %v", args, err)
+		}
+	}
+}
+
+func TestPrintError(t *testing.T) {
+	cmd := &cobra.Command{Use: "sub <pattern>...", RunE: func(*cobra.Command, []string) error { return nil }}
+	var b strings.Builder
+	printError(&b, cmd, usageErrorf("commit message required"))
+	if out := b.String(); !strings.HasPrefix(out, "commit message required\nUsage:\n  sub <pattern>...") {
+		t.Errorf("usage error printed %q", out)
+	}
+	b.Reset()
+	printError(&b, cmd, PolicyRule{Command: "push"}.violation("is not allowed"))
+	if out := b.String(); out != "policy: \"push\" is not allowed\n" {
+		t.Errorf("policy refusal printed %q", out)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	commitMsg = ""
+	if err := commitCmd.RunE(commitCmd, []string{"repos/*"}); exitCode(err) != exitUsage {
+		t.Errorf("commit without a message: exit %d (%v)", exitCode(err), err)
+	}
+	t.Chdir(t.TempDir())
+	if _, err := discoverRepos([]string{"repos/["}); exitCode(err) != exitUsage {
+		t.Errorf("invalid pattern: exit %d (%v)", exitCode(err), err)
+	}
+}
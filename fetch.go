@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// tag options shared by fetch and pull
+var fetchTags bool
+var fetchNoTags bool
+var fetchPruneTags bool
+
+var fetchPrune bool
+
+// tagArgs returns the git options for --tags and --no-tags.
+func tagArgs() ([]string, error) {
+	switch {
+	case fetchTags && fetchNoTags:
+		return nil, usageErrorf("--tags and --no-tags cannot be used together")
+	case fetchTags:
+		return []string{"--tags"}, nil
+	case fetchNoTags:
+		return []string{"--no-tags"}, nil
+	}
+	return nil, nil
+}
+
+// countPruned counts the refs git fetch reports as deleted.
+func countPruned(out string) int {
+	n := 0
+	for _, l := range strings.Split(out, "\n") {
+		if strings.Contains(l, "[deleted]") {
+			n++
+		}
+	}
+	return n
+}
+
+// reportPruned prints how many refs a fetch pruned in repo.
+func reportPruned(repo, out string) {
+	if logLevel > levelQuiet && !onlyFailures {
+		fmt.Printf("%s: pruned %d refs\n", repo, countPruned(out))
+	}
+}
+
+// fetchPruning runs git fetch args in repos, prints git's output and the
+// number of pruned refs per repository, and returns the repositories where
+// the fetch succeeded. The output is captured to count the pruned refs.
+func fetchPruning(repos []string, args []string) []string {
+	var ok []string
+	if jobs > 1 {
+		results := runBuffered(repos, args...)
+		for _, res := range results {
+			if res.OK() {
+				reportPruned(res.Repo, res.Output)
+				ok = append(ok, res.Repo)
+			}
+		}
+		return ok
+	}
+	ctx, cancel := runContext()
+	defer cancel()
+	for _, r := range repos {
+		repoHeader(r)
+		out, err := runGitCapture(ctx, r, args...)
+		repoOutput(r, out, err != nil)
+		if err != nil {
+			repoFailed(r, err)
+			continue
+		}
+		reportPruned(r, out)
+		ok = append(ok, r)
+	}
+	return ok
+}
+
+// pruneTagsFirst runs git fetch --prune --prune-tags in repos before a pull,
+// which has no --prune-tags of its own, and returns the repositories where
+// it succeeded. With --output the counts are left out of the report.
+func pruneTagsFirst(repos []string) []string {
+	args := []string{"fetch", "--prune", "--prune-tags"}
+	if pullOutput == "" {
+		return fetchPruning(repos, args)
+	}
+	var ok []string
+	for _, res := range runCollect(repos, args...) {
+		if !res.OK() {
+			repoFailed(res.Repo, fmt.Errorf("git fetch --prune-tags: %v", res.Err))
+			continue
+		}
+		ok = append(ok, res.Repo)
+	}
+	return ok
+}
+
+var fetchCmd = &cobra.Command{
+	Use:   "fetch [--prune] [--tags|--no-tags] [--prune-tags] <pattern>...",
+	Short: "Run git fetch in matching repositories",
+	Long: `fetch runs git fetch in every matching repository. With --prune or
+--prune-tags, the number of refs removed is reported per repository.
+
+Remote-tracking branches that moved to a tip whose history lacks the old
+one were force-pushed. They are reported per repository, and the
+repositories are listed at the end and in the run summary, since pulling
+them would bring back or conflict with the commits that were dropped.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fetchArgs, err := tagArgs()
+		if err != nil {
+			return err
+		}
+		fetchArgs = append([]string{"fetch"}, fetchArgs...)
+		if fetchPrune || fetchPruneTags {
+			fetchArgs = append(fetchArgs, "--prune")
+		}
+		if fetchPruneTags {
+			fetchArgs = append(fetchArgs, "--prune-tags")
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		if repos, err = preflight(repos); err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+		before := snapshotRemoteRefs(ctx, repos)
+		switch {
+		case fetchPrune || fetchPruneTags:
+			fetchPruning(repos, fetchArgs)
+		case jobs > 1:
+			runBuffered(repos, fetchArgs...)
+		default:
+			for _, r := range repos {
+				repoHeader(r)
+				if err := runGit(ctx, r, fetchArgs...); err != nil {
+					repoFailed(r, err)
+				}
+			}
+		}
+		if forced := reportForcePushes(ctx, repos, before); len(forced) > 0 && logLevel > levelQuiet {
+			fmt.Printf("\n%s\n", paint(os.Stdout, ansiRed, fmt.Sprintf("%d repositories have force-pushed branches; check them before pulling:", len(forced))))
+			for _, r := range forced {
+				fmt.Printf("  %s\n", r)
+			}
+		}
+		return nil
+	},
+}
+
+// addTagFlags registers --tags, --no-tags and --prune-tags on fetch or pull.
+func addTagFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&fetchTags, "tags", false, "fetch all tags from the remote, not only those pointing into fetched history")
+	cmd.Flags().BoolVar(&fetchNoTags, "no-tags", false, "do not fetch any tags")
+	cmd.Flags().BoolVar(&fetchPruneTags, "prune-tags", false, "delete local tags that no longer exist on the remote (implies --prune)")
+}
+
+func init() {
+	rootCmd.AddCommand(fetchCmd)
+	fetchCmd.Flags().BoolVarP(&fetchPrune, "prune", "p", false, "remove remote-tracking branches that no longer exist on the remote")
+	addTagFlags(fetchCmd)
+	addRetryFlags(fetchCmd)
+	addPreflightFlags(fetchCmd)
+}
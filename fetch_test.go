@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTagArgs(t *testing.T) {
+	t.Cleanup(func() { fetchTags, fetchNoTags = false, false })
+	for _, c := range []struct {
+		tags, noTags bool
+		want         []string
+	}{
+		{false, false, nil},
+		{true, false, []string{"--tags"}},
+		{false, true, []string{"--no-tags"}},
+	} {
+		fetchTags, fetchNoTags = c.tags, c.noTags
+		if got, err := tagArgs(); err != nil || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("tagArgs(%v, %v) = %q, %v", c.tags, c.noTags, got, err)
+		}
+	}
+	fetchTags, fetchNoTags = true, true
+	if _, err := tagArgs(); exitCode(err) != exitUsage {
+		t.Error("--tags with --no-tags accepted")
+	}
+}
+
+func TestCountPruned(t *testing.T) {
+	out := `From github.com:org/api
+ - [deleted]         (none)     -> origin/feature/old
+ - [deleted]         (none)     -> v0.9.0
+   3f1c2aa..9b0de41  main       -> origin/main
+`
+	if n := countPruned(out); n != 2 {
+		t.Errorf("countPruned = %d, want 2", n)
+	}
+	if n := countPruned(""); n != 0 {
+		t.Errorf("countPruned(\"\") = %d", n)
+	}
+}
+
+func TestPullPrunesTagsFirst(t *testing.T) {
+	m := useMockGit(t)
+	m.On("fetch --prune --prune-tags", " - [deleted]         (none)     -> v1\n", nil)
+	if ok := pruneTagsFirst([]string{"/r/a"}); !reflect.DeepEqual(ok, []string{"/r/a"}) {
+		t.Errorf("pruneTagsFirst = %v", ok)
+	}
+	if got := m.Commands(); !reflect.DeepEqual(got, []string{"git fetch --prune --prune-tags"}) {
+		t.Errorf("ran %q", got)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+// tag selectors: --tag keeps repositories that have every listed tag,
+// --tag-not drops repositories that have any of them.
+var selectTags []string
+var excludeTags []string
+
+// remoteMatch is --remote-match, a regular expression the URL of the
+// default remote must match.
+var remoteMatch string
+
+// content selectors: --has-file needs a regular file and --has-path any
+// file or directory at the path, relative to the repository root. Each
+// may be repeated and accepts glob patterns; all must match.
+var hasFiles []string
+var hasPaths []string
+
+// selecting reports whether repository selectors are set, which lets
+// commands run without patterns.
+func selecting() bool {
+	return len(selectors()) > 0
+}
+
+// selectors names the selector flags that are set.
+func selectors() []string {
+	var set []string
+	if len(selectTags) > 0 {
+		set = append(set, "--tag")
+	}
+	if len(excludeTags) > 0 {
+		set = append(set, "--tag-not")
+	}
+	if remoteMatch != "" {
+		set = append(set, "--remote-match")
+	}
+	if len(hasFiles) > 0 {
+		set = append(set, "--has-file")
+	}
+	if len(hasPaths) > 0 {
+		set = append(set, "--has-path")
+	}
+	return set
+}
+
+// tagsOf returns the tags [[repo]] entries give repo.
+func tagsOf(ctx context.Context, repo string) []string {
+	if repoSettings == nil {
+		return nil
+	}
+	return repoSettings.lookup(ctx, repo).tags
+}
+
+// matchesTags reports whether a repository with tags passes --tag and
+// --tag-not.
+func matchesTags(tags []string) bool {
+	for _, t := range selectTags {
+		if !slices.Contains(tags, t) {
+			return false
+		}
+	}
+	for _, t := range excludeTags {
+		if slices.Contains(tags, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasContent reports whether repo has a match for pattern in its working
+// tree; with fileOnly the match must be a regular file.
+func hasContent(repo, pattern string, fileOnly bool) bool {
+	matches, _ := filepath.Glob(filepath.Join(repo, filepath.FromSlash(pattern)))
+	for _, m := range matches {
+		fi, err := os.Stat(m)
+		if err == nil && (!fileOnly || fi.Mode().IsRegular()) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesContent reports whether repo passes --has-file and --has-path.
+func matchesContent(repo string) bool {
+	for _, p := range hasFiles {
+		if !hasContent(repo, p, true) {
+			return false
+		}
+	}
+	for _, p := range hasPaths {
+		if !hasContent(repo, p, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// remoteURL returns the URL of the default remote of repo, or "" without
+// remotes.
+func remoteURL(ctx context.Context, repo string) string {
+	u, err := gitExec.Output(ctx, runner.Git(repo, "remote", "get-url", defaultRemote(ctx, gitExec, repo)))
+	if err != nil {
+		return ""
+	}
+	return u
+}
+
+// filterRepos keeps the repositories that pass the selectors.
+func filterRepos(repos []string) ([]string, error) {
+	if !selecting() {
+		return repos, nil
+	}
+	var remoteRe *regexp.Regexp
+	if remoteMatch != "" {
+		re, err := regexp.Compile(remoteMatch)
+		if err != nil {
+			return nil, usageErrorf("--remote-match: %v", err)
+		}
+		remoteRe = re
+	}
+	ctx, cancel := runContext()
+	defer cancel()
+	var kept []string
+	for _, r := range repos {
+		if !matchesContent(r) || !matchesTags(tagsOf(ctx, r)) {
+			continue
+		}
+		if remoteRe != nil {
+			if u := remoteURL(ctx, r); u == "" || !remoteRe.MatchString(u) {
+				continue
+			}
+		}
+		kept = append(kept, r)
+	}
+	if len(kept) == 0 {
+		return nil, noReposErrorf("no matching repositories pass %s", strings.Join(selectors(), " and "))
+	}
+	return kept, nil
+}
+
+// allowSelectorsWithoutPatterns lets every command run without patterns
+// when selectors are set: the repositories are then searched for below the
+// current directory. Validators still check the other arguments, so
+// "remote add <name> <url>" keeps its checks.
+func allowSelectorsWithoutPatterns(cmd *cobra.Command) {
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			err := validate(cmd, args)
+			if err != nil && selecting() {
+				err = validate(cmd, append(slices.Clip(args), defaultPattern))
+			}
+			return err
+		}
+	}
+	for _, c := range cmd.Commands() {
+		allowSelectorsWithoutPatterns(c)
+	}
+}
+
+// defaultPattern is searched when selectors are given without patterns.
+const defaultPattern = "**"
+
+func init() {
+	rootCmd.PersistentFlags().StringArrayVar(&selectTags, "tag", nil, "only repositories with this tag from [[repo]] in the config file (repeatable, all must match)")
+	rootCmd.PersistentFlags().StringArrayVar(&excludeTags, "tag-not", nil, "skip repositories with this tag (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVar(&hasFiles, "has-file", nil, "only repositories with this file, relative to the repository root (repeatable, globs allowed, e.g. go.mod)")
+	rootCmd.PersistentFlags().StringArrayVar(&hasPaths, "has-path", nil, "only repositories where this file or directory exists (repeatable, e.g. .github/workflows)")
+	rootCmd.PersistentFlags().StringVar(&remoteMatch, "remote-match", "", "only repositories whose default remote URL matches this regular expression (e.g. github.com[:/]myorg/)")
+}
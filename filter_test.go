@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+func useSelectors(t *testing.T, tags, notTags []string) {
+	t.Helper()
+	oldTags, oldNot := selectTags, excludeTags
+	selectTags, excludeTags = tags, notTags
+	t.Cleanup(func() { selectTags, excludeTags = oldTags, oldNot })
+}
+
+func TestFilterReposByTags(t *testing.T) {
+	old := repoSettings
+	t.Cleanup(func() { repoSettings = old })
+	repoSettings = &repoOverrides{
+		rules: []RepoOverride{
+			{Path: "payments/*", Tags: []string{"team:payments"}},
+			{Path: "**/*-go", Tags: []string{"lang:go"}},
+		},
+		exe:   useMockGit(t),
+		cache: map[string]overrideSet{},
+	}
+	repos := []string{"/src/payments/ledger-go", "/src/payments/web", "/src/search/index-go"}
+
+	useSelectors(t, []string{"team:payments"}, nil)
+	if got, err := filterRepos(repos); err != nil || !reflect.DeepEqual(got, repos[:2]) {
+		t.Errorf("--tag team:payments = %v, %v", got, err)
+	}
+	useSelectors(t, []string{"team:payments", "lang:go"}, nil)
+	if got, err := filterRepos(repos); err != nil || !reflect.DeepEqual(got, repos[:1]) {
+		t.Errorf("--tag team:payments --tag lang:go = %v, %v", got, err)
+	}
+	useSelectors(t, nil, []string{"lang:go"})
+	if got, err := filterRepos(repos); err != nil || !reflect.DeepEqual(got, repos[1:2]) {
+		t.Errorf("--tag-not lang:go = %v, %v", got, err)
+	}
+	useSelectors(t, []string{"team:search"}, nil)
+	if _, err := filterRepos(repos); err == nil {
+		t.Error("no repository left but no error")
+	}
+}
+
+func TestAllowSelectorsWithoutPatterns(t *testing.T) {
+	useSelectors(t, nil, nil)
+	root := &cobra.Command{Use: "gitbatch"}
+	pull := &cobra.Command{Use: "pull", Args: cobra.MinimumNArgs(1)}
+	history := &cobra.Command{Use: "history", Args: cobra.NoArgs}
+	root.AddCommand(pull, history)
+	allowSelectorsWithoutPatterns(root)
+
+	if err := pull.Args(pull, nil); err == nil {
+		t.Error("pull without patterns or selectors accepted")
+	}
+	useSelectors(t, []string{"team:payments"}, nil)
+	if err := pull.Args(pull, nil); err != nil {
+		t.Errorf("pull --tag without patterns refused: %v", err)
+	}
+	if err := history.Args(history, nil); err != nil {
+		t.Errorf("history --tag refused: %v", err)
+	}
+}
+
+func TestFilterReposByRemote(t *testing.T) {
+	ctx := context.Background()
+	mine, theirs, none := initTestRepo(t), initTestRepo(t), initTestRepo(t)
+	for repo, url := range map[string]string{mine: "git@github.com:myorg/api.git", theirs: "https://github.com/other/api.git"} {
+		if _, err := runner.Output(ctx, repo, "remote", "add", "origin", url); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := remoteMatch
+	t.Cleanup(func() { remoteMatch = old })
+
+	remoteMatch = `github\.com[:/]myorg/`
+	if got, err := filterRepos([]string{mine, theirs, none}); err != nil || !reflect.DeepEqual(got, []string{mine}) {
+		t.Errorf("--remote-match = %v, %v", got, err)
+	}
+	remoteMatch = `gitlab\.com`
+	if _, err := filterRepos([]string{mine, theirs}); err == nil || !strings.Contains(err.Error(), "--remote-match") {
+		t.Errorf("err = %v", err)
+	}
+	remoteMatch = `(`
+	if _, err := filterRepos([]string{mine}); exitCode(err) != exitUsage {
+		t.Error("invalid regular expression accepted")
+	}
+}
+
+func TestFilterReposByContent(t *testing.T) {
+	goRepo, jsRepo := t.TempDir(), t.TempDir()
+	for path, dir := range map[string]bool{
+		goRepo + "/go.mod":                       false,
+		goRepo + "/.github/workflows/ci.yml":     false,
+		jsRepo + "/package.json":                 false,
+		jsRepo + "/go.mod":                       true, // a directory, not a file
+		jsRepo + "/src/components/Button.tsx":    false,
+		goRepo + "/internal/handlers/handler.go": false,
+	} {
+		path = filepath.FromSlash(path)
+		if dir {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldFiles, oldPaths := hasFiles, hasPaths
+	t.Cleanup(func() { hasFiles, hasPaths = oldFiles, oldPaths })
+	repos := []string{goRepo, jsRepo}
+
+	for _, c := range []struct {
+		files, paths []string
+		want         []string
+	}{
+		{[]string{"go.mod"}, nil, []string{goRepo}},
+		{nil, []string{"go.mod"}, repos},
+		{nil, []string{".github/workflows"}, []string{goRepo}},
+		{[]string{"*.json"}, nil, []string{jsRepo}},
+		{[]string{"go.mod"}, []string{"src/*/Button.tsx"}, nil},
+	} {
+		hasFiles, hasPaths = c.files, c.paths
+		got, err := filterRepos(repos)
+		if c.want == nil {
+			if err == nil {
+				t.Errorf("--has-file %v --has-path %v kept %v", c.files, c.paths, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("--has-file %v --has-path %v = %v, %v", c.files, c.paths, got, err)
+		}
+	}
+}
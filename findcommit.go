@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// foundCommit is one commit matched by find-commit.
+type foundCommit struct {
+	hash, author, date, subject string
+}
+
+// findCommits runs git log with the given search options in repo.
+func findCommits(ctx context.Context, repo string, search []string) ([]foundCommit, error) {
+	if _, err := gitOutput(ctx, repo, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return nil, nil // no commits yet
+	}
+	args := append([]string{"log", "--format=%h%x00%an%x00%ad%x00%s", "--date=short"}, search...)
+	out, err := gitOutput(ctx, repo, args...)
+	if err != nil {
+		return nil, err
+	}
+	var commits []foundCommit
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.SplitN(line, "\x00", 4)
+		if len(f) != 4 {
+			continue
+		}
+		commits = append(commits, foundCommit{hash: f[0], author: f[1], date: f[2], subject: f[3]})
+	}
+	return commits, nil
+}
+
+// find-commit command
+var findGrep string
+var findPickaxe string
+var findPickaxeRegex string
+var findIgnoreCase bool
+var findAllRefs bool
+var findCommitCmd = &cobra.Command{
+	Use:   "find-commit [--grep <text>] [-S <string>] [-G <regex>] <pattern>...",
+	Short: "Search commit messages and patches across matching repositories",
+	Long: `find-commit searches the history of every repository and prints the
+matching commits grouped by repository. --grep matches commit messages, -S
+finds commits that change the number of occurrences of a string and -G
+commits whose diff matches a regular expression. -S and -G cannot be
+combined, but either can be given with --grep, and a commit must then
+match both.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if findGrep == "" && findPickaxe == "" && findPickaxeRegex == "" {
+			return usageErrorf("nothing to search for: use --grep, -S or -G")
+		}
+		if findPickaxe != "" && findPickaxeRegex != "" {
+			// git log refuses the combination
+			return usageErrorf("-S and -G cannot be used together")
+		}
+		search := findCommitArgs()
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		total, matched := 0, 0
+		for _, r := range repos {
+			commits, err := findCommits(ctx, r, search)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			if len(commits) == 0 {
+				continue
+			}
+			matched++
+			total += len(commits)
+			repoHeader(r)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, c := range commits {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.hash, c.date, c.author, c.subject)
+			}
+			w.Flush()
+		}
+		fmt.Printf("\n%d commits in %d of %d repositories\n", total, matched, len(repos))
+		return nil
+	},
+}
+
+// findCommitArgs turns the find-commit flags into git log options.
+func findCommitArgs() []string {
+	var args []string
+	if findGrep != "" {
+		args = append(args, "--grep="+findGrep)
+	}
+	if findPickaxe != "" {
+		args = append(args, "-S"+findPickaxe)
+	}
+	if findPickaxeRegex != "" {
+		args = append(args, "-G"+findPickaxeRegex)
+	}
+	if findIgnoreCase {
+		args = append(args, "--regexp-ignore-case")
+	}
+	if findAllRefs {
+		args = append(args, "--all")
+	}
+	return args
+}
+
+func init() {
+	rootCmd.AddCommand(findCommitCmd)
+
+	findCommitCmd.Flags().StringVar(&findGrep, "grep", "", "match commit messages against this regular expression")
+	findCommitCmd.Flags().StringVarP(&findPickaxe, "pickaxe", "S", "", "find commits that add or remove this string")
+	findCommitCmd.Flags().StringVarP(&findPickaxeRegex, "pickaxe-regex", "G", "", "find commits whose diff matches this regular expression")
+	findCommitCmd.Flags().BoolVarP(&findIgnoreCase, "ignore-case", "i", false, "match case-insensitively")
+	findCommitCmd.Flags().BoolVar(&findAllRefs, "all", false, "search all branches and tags, not just HEAD")
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFindCommits(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "hello\n", "JIRA-1234: add greeting")
+	commitTestFile(t, repo, "b.txt", "retry = 3\n", "tune retries")
+	commitTestFile(t, repo, "a.txt", "hello world\n", "jira-1234 follow-up")
+
+	found, err := findCommits(ctx, repo, []string{"--grep=JIRA-1234", "--regexp-ignore-case"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].subject != "jira-1234 follow-up" || found[1].subject != "JIRA-1234: add greeting" {
+		t.Fatalf("unexpected --grep matches: %+v", found)
+	}
+	if found[0].hash == "" || found[0].date == "" || found[0].author == "" {
+		t.Errorf("expected hash, date and author to be filled: %+v", found[0])
+	}
+
+	found, err = findCommits(ctx, repo, []string{"-Sretry"})
+	if err != nil || len(found) != 1 || found[0].subject != "tune retries" {
+		t.Errorf("unexpected -S matches: %+v, %v", found, err)
+	}
+
+	empty := initTestRepo(t)
+	if found, err := findCommits(ctx, empty, []string{"--grep=x"}); err != nil || found != nil {
+		t.Errorf("expected no matches in an empty repo, got %+v, %v", found, err)
+	}
+}
+
+func TestFindCommitPickaxeCombined(t *testing.T) {
+	defer func() { findPickaxe, findPickaxeRegex = "", "" }()
+	findPickaxe, findPickaxeRegex = "retry", "retr.*"
+	if err := findCommitCmd.RunE(findCommitCmd, []string{t.TempDir()}); exitCode(err) != exitUsage {
+		t.Errorf("-S with -G: %v", err)
+	}
+}
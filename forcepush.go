@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// isAncestor reports whether commit a is part of the history of b.
+func isAncestor(ctx context.Context, repo, a, b string) bool {
+	_, err := gitOutput(ctx, repo, "merge-base", "--is-ancestor", a, b)
+	return err == nil
+}
+
+// forcedBranches returns the remote-tracking branches that moved between
+// two snapshots to a tip whose history lacks the old one, i.e. that were
+// force-pushed, as "origin/main (1a2b3c4...5d6e7f8)" in name order.
+func forcedBranches(ctx context.Context, repo string, before, after map[string]string) []string {
+	var forced []string
+	for name, hash := range after {
+		old, ok := before[name]
+		if ok && old != hash && !isAncestor(ctx, repo, old, hash) {
+			forced = append(forced, fmt.Sprintf("%s (%s...%s)", name, old[:7], hash[:7]))
+		}
+	}
+	sort.Strings(forced)
+	return forced
+}
+
+// snapshotRemoteRefs records the remote-tracking branches of repos before a
+// fetch. Repositories whose refs cannot be read are left out and not
+// checked afterwards.
+func snapshotRemoteRefs(ctx context.Context, repos []string) map[string]map[string]string {
+	refs := map[string]map[string]string{}
+	for _, r := range repos {
+		if before, err := remoteRefs(ctx, r); err == nil {
+			refs[r] = before
+		}
+	}
+	return refs
+}
+
+// reportForcePushes compares the remote-tracking branches of repos with the
+// snapshot taken before the fetch. Repositories with force-pushed branches
+// get a warning on stderr and are listed in the run summary, since pulling
+// them would bring back or conflict with the commits that were dropped. It
+// returns those repositories.
+func reportForcePushes(ctx context.Context, repos []string, before map[string]map[string]string) []string {
+	var flagged []string
+	for _, r := range repos {
+		old, ok := before[r]
+		if !ok {
+			continue
+		}
+		after, err := remoteRefs(ctx, r)
+		if err != nil {
+			continue
+		}
+		forced := forcedBranches(ctx, r, old, after)
+		if len(forced) == 0 {
+			continue
+		}
+		for _, b := range forced {
+			fmt.Fprintf(os.Stderr, "%s %s: %s\n", paint(os.Stderr, ansiRed, "force-pushed in"), r, b)
+		}
+		markRepoForced(r, forced)
+		flagged = append(flagged, r)
+	}
+	return flagged
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestReportForcePushes(t *testing.T) {
+	ctx := context.Background()
+	upstream := initTestRepo(t)
+	commitTestFile(t, upstream, "a.txt", "a", "first")
+	commitTestFile(t, upstream, "b.txt", "b", "second")
+	forced, moved := cloneTestRepo(t, upstream), cloneTestRepo(t, upstream)
+	repos := []string{forced, moved}
+
+	before := snapshotRemoteRefs(ctx, repos)
+	commitTestFile(t, upstream, "c.txt", "c", "third")
+	if out, err := exec.Command("git", "-C", moved, "fetch", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git fetch: %v %s", err, out)
+	}
+	if out, err := exec.Command("git", "-C", upstream, "reset", "-q", "--hard", "HEAD~2").CombinedOutput(); err != nil {
+		t.Fatalf("git reset: %v %s", err, out)
+	}
+	commitTestFile(t, upstream, "d.txt", "d", "rewritten")
+	if out, err := exec.Command("git", "-C", forced, "fetch", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git fetch: %v %s", err, out)
+	}
+
+	var flagged []string
+	stderr := captureStderr(t, func() {
+		flagged = reportForcePushes(ctx, repos, before)
+	})
+	if len(flagged) != 1 || flagged[0] != forced {
+		t.Errorf("flagged = %v, want only %s", flagged, forced)
+	}
+	if !strings.Contains(stderr, forced+": origin/") || strings.Contains(stderr, moved) {
+		t.Errorf("stderr:\n%s", stderr)
+	}
+	runSummary.Lock()
+	branches := runSummary.forced[forced]
+	delete(runSummary.forced, forced)
+	runSummary.Unlock()
+	if len(branches) != 1 || !strings.HasPrefix(branches[0], "origin/") {
+		t.Errorf("summary lists %v", branches)
+	}
+}
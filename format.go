@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/patrickkdev/gitbatch/pkg/report"
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+// formatFuncs are available in --format templates on top of the built-ins.
+var formatFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// parseFormat compiles a --format template. The escapes \t and \n are
+// interpreted so they can be typed inside single quotes on the command line,
+// and each row ends with a newline unless the template already does.
+func parseFormat(s string) (*template.Template, error) {
+	s = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(s)
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	t, err := template.New("format").Funcs(formatFuncs).Parse(s)
+	if err != nil {
+		return nil, usageErrorf("invalid --format: %v", err)
+	}
+	return t, nil
+}
+
+// writeFormatted executes t once per row.
+func writeFormatted[T any](w io.Writer, t *template.Template, rows []T) error {
+	for _, row := range rows {
+		if err := t.Execute(w, row); err != nil {
+			return fmt.Errorf("--format: %v", err)
+		}
+	}
+	return nil
+}
+
+// addFormatFlag registers --format on cmd; fields lists the template fields
+// for the help text.
+func addFormatFlag(cmd *cobra.Command, dst *string, fields string) {
+	cmd.Flags().StringVar(dst, "format", "", "print one line per row with a Go template, e.g. '"+fields+"'")
+}
+
+// --output formats for structured results. junit is only offered by
+// commands that report per-repository command results.
+const (
+	outputJSON     = "json"
+	outputCSV      = "csv"
+	outputMarkdown = "markdown"
+	outputJUnit    = "junit"
+)
+
+// outputChoices lists the accepted --output values for help and errors.
+func outputChoices(extra []string) string {
+	choices := append([]string{outputJSON, outputCSV, outputMarkdown}, extra...)
+	return strings.Join(choices[:len(choices)-1], ", ") + " or " + choices[len(choices)-1]
+}
+
+// checkOutput validates --output against json, csv, markdown and extra.
+func checkOutput(output string, extra ...string) error {
+	switch output {
+	case "", outputJSON, outputCSV, outputMarkdown:
+		return nil
+	}
+	for _, e := range extra {
+		if output == e {
+			return nil
+		}
+	}
+	return usageErrorf("invalid --output %q (expected %s)", output, outputChoices(extra))
+}
+
+// checkFormatOutput validates --output and rejects combining it with
+// --format.
+func checkFormatOutput(format, output string, extra ...string) error {
+	if format != "" && output != "" {
+		return usageErrorf("--format and --output cannot be used together")
+	}
+	return checkOutput(output, extra...)
+}
+
+// writeOutput writes v as indented JSON or t as CSV or Markdown, depending
+// on format. v and t must describe the same rows.
+func writeOutput(w io.Writer, format string, v any, t report.Table) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputCSV:
+		return t.WriteCSV(w)
+	case outputMarkdown:
+		return t.WriteMarkdown(w)
+	}
+	return checkOutput(format)
+}
+
+// writeResults writes the results of running a git command in each
+// repository in format; suite names the run in JUnit reports.
+func writeResults(w io.Writer, format, suite string, results []runner.Result) error {
+	if format == outputJUnit {
+		return report.WriteJUnit(w, suite, results)
+	}
+	return writeOutput(w, format, report.ToJSON(results), report.ResultsTable(results))
+}
+
+// addOutputFlag registers --output on cmd, accepting json, csv, markdown and
+// extra.
+func addOutputFlag(cmd *cobra.Command, dst *string, extra ...string) {
+	cmd.Flags().StringVarP(dst, "output", "o", "", "print results as "+outputChoices(extra))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteFormatted(t *testing.T) {
+	tmpl, err := parseFormat(`{{.Repo}}\t{{.Branch}}\t{{.Ahead}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []repoState{
+		{Repo: "/r/a", Branch: "main", Ahead: 2},
+		{Repo: "/r/b", Branch: "dev"},
+	}
+	var b strings.Builder
+	if err := writeFormatted(&b, tmpl, rows); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/r/a\tmain\t2\n/r/b\tdev\t0\n"; b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+
+	tmpl, err = parseFormat(`{{upper .Branch}} {{json .Repo}}\n`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	if err := writeFormatted(&b, tmpl, rows[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if want := "MAIN \"/r/a\"\n"; b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+
+	if _, err := parseFormat("{{.Repo"); err == nil {
+		t.Error("expected parse error")
+	}
+	tmpl, _ = parseFormat("{{.Missing}}")
+	if err := writeFormatted(&b, tmpl, rows); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	states := []repoState{{Repo: "/r/a", Branch: "main", Upstream: "origin/main", Ahead: 1, Untracked: 3}}
+	var b strings.Builder
+	if err := writeOutput(&b, outputCSV, states, stateTable(states)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "repo,branch,upstream,ahead,behind,staged,unstaged,untracked,ignored,conflicts,stashes,oldest_stash\n/r/a,main,origin/main,1,0,0,0,3,0,0,0,\n"; b.String() != want {
+		t.Errorf("csv = %q, want %q", b.String(), want)
+	}
+
+	b.Reset()
+	if err := writeOutput(&b, outputJSON, states, stateTable(states)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"upstream": "origin/main"`) {
+		t.Errorf("unexpected json %s", b.String())
+	}
+
+	if err := checkFormatOutput("", "xml"); err == nil {
+		t.Error("expected error for unknown --output")
+	}
+	if err := checkFormatOutput("{{.Repo}}", outputCSV); exitCode(err) != exitUsage {
+		t.Error("expected error for --format with --output")
+	}
+}
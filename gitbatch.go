@@ -17,23 +17,55 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"text/template"
 	"time"
 
-	"github.com/bmatcuk/doublestar/v4"
+	"github.com/patrickkdev/gitbatch/pkg/discover"
+	"github.com/patrickkdev/gitbatch/pkg/report"
+	"github.com/patrickkdev/gitbatch/pkg/runner"
 	"github.com/spf13/cobra"
 )
 
-const defaultTimeout = 2 * time.Minute
+// defaultTimeout is --timeout: how long one git command may run before it
+// is killed; zero means no limit.
+var defaultTimeout = 2 * time.Minute
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	if code, ok := runAskpass(os.Args[1:]); ok {
+		os.Exit(code)
 	}
+	if code, ok := runCredentialHelper(os.Args[1:]); ok {
+		os.Exit(code)
+	}
+	registerAliases(os.Args[1:])
+	allowSelectorsWithoutPatterns(rootCmd)
+	setupCompletions(rootCmd)
+	markUsageErrors(rootCmd)
+	if path, ok := findPlugin(os.Args[1:]); ok {
+		os.Exit(runPlugin(path, os.Args[1:]))
+	}
+	if err := unknownCommand(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\nRun '%s --help' for usage.\n", err, rootCmd.Name())
+		os.Exit(exitCode(err))
+	}
+	cmd, err := rootCmd.ExecuteC()
+	finishScript(err)
+	printRunSummary()
+	printSlowest()
+	notifyRun(cmd, err)
+	auditRun(cmd, err)
+	unlockWorkspace()
+	runPostHooks(err)
+	closePager()
+	if err != nil {
+		printError(os.Stderr, cmd, err)
+	}
+	os.Exit(exitCode(err))
 }
 
 var rootCmd = &cobra.Command{
@@ -41,120 +73,359 @@ var rootCmd = &cobra.Command{
 	Short: "Run common git commands across many repos (supports globs)",
 	Long: `gitbatch finds directories that contain git repositories and runs
 specified git commands inside each repo. Patterns support shell globs and
-recursive ** patterns (doublestar).`,
+recursive ** patterns (doublestar). The exit status tells success, failed
+repositories, no matches, invalid usage, an aborted prompt and policy
+refusals apart; see "gitbatch help exit-codes".`,
 	Args: cobra.MinimumNArgs(1),
+	// main prints errors, and the usage only for usage errors
+	SilenceErrors: true,
+	SilenceUsage:  true,
 }
 
-func collectRepos(patterns []string) ([]string, error) {
-	seen := map[string]struct{}{}
-	var repos []string
-	for _, pat := range patterns {
-		matches, err := doublestar.Glob(os.DirFS("."), pat)
-		if err != nil {
-			// try fallback to filepath.Glob (handles simple globs and cases where shell already expanded)
-			matches2, err2 := filepath.Glob(pat)
-			if err2 != nil {
-				return nil, fmt.Errorf("invalid pattern %q: %v", pat, err)
-			}
-			matches = matches2
+// prepareRun runs before every command: it applies the environment and
+// the config file, refuses what policies and read-only mode forbid, and
+// sets up the run and the git executor.
+func prepareRun(cmd *cobra.Command, args []string) error {
+	// cobra checks required and grouped flags only after this hook, and
+	// not as flag errors
+	if err := cmd.ValidateRequiredFlags(); err != nil {
+		return exitWith(exitUsage, err)
+	}
+	if err := cmd.ValidateFlagGroups(); err != nil {
+		return exitWith(exitUsage, err)
+	}
+	// the environment and defaults first, so every check sees them like
+	// flags typed out
+	if err := applyEnv(cmd); err != nil {
+		return err
+	}
+	if err := applyDefaults(cmd); err != nil {
+		return err
+	}
+	if err := checkColorMode(); err != nil {
+		return err
+	}
+	if err := checkPolicy(cmd); err != nil {
+		return err
+	}
+	if err := checkReadOnly(cmd); err != nil {
+		return err
+	}
+	if err := checkEmitScript(cmd); err != nil {
+		return err
+	}
+	if err := setupAudit(cmd); err != nil {
+		return err
+	}
+	if err := setupLock(cmd); err != nil {
+		return err
+	}
+	if err := setupRunHooks(cmd); err != nil {
+		return err
+	}
+	if err := setupRepoState(cmd); err != nil {
+		return err
+	}
+	if err := setupPager(cmd); err != nil {
+		return err
+	}
+	// each step wraps gitExec, so the order decides what sees what:
+	// log files record every retry attempt, each attempt waits for its
+	// own host slot, and scripts get the per-repository overrides
+	for _, setup := range []func() error{setupGitBinary, setupTimeout, setupEmitScript, setupRepoOverrides, setupRepoLogs, setupHostLimit, setupRetries, setupLogging, setupTimings} {
+		if err := setup(); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		// doublestar.Glob returns paths relative to FS root; convert to OS paths
-		for _, m := range matches {
-			// doublestar returns paths with unix separators when using DirFS; ensure correct OS path
-			mp := filepath.FromSlash(m)
-			abs, err := filepath.Abs(mp)
-			if err != nil {
-				abs = mp
-			}
-			fi, err := os.Stat(abs)
-			if err != nil {
-				continue
-			}
-			if !fi.IsDir() {
-				// if it's a file, consider its parent
-				abs = filepath.Dir(abs)
-			}
-			if _, ok := seen[abs]; ok {
-				continue
-			}
-			if isGitRepo(abs) {
-				seen[abs] = struct{}{}
-				repos = append(repos, abs)
-			}
+// collectRepos finds the repositories matching patterns with
+// resolveRepos and starts the run on them with startRun.
+func collectRepos(patterns []string) ([]string, error) {
+	repos, err := resolveRepos(patterns)
+	if err != nil {
+		return nil, err
+	}
+	if err := startRun(repos); err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// resolveRepos finds the repositories matching patterns and the selectors
+// for a command in dependency order and checks them against the path
+// policies. Nothing has run yet, so commands can check the repositories
+// further before calling startRun.
+func resolveRepos(patterns []string) ([]string, error) {
+	if len(patterns) == 0 && selecting() {
+		patterns = []string{defaultPattern}
+		if regexPatterns {
+			patterns = []string{".*"}
 		}
 	}
-	if len(repos) == 0 {
-		return nil, errors.New("no git repositories found for given pattern(s)")
+	repos, err := discoverRepos(patterns)
+	if err != nil {
+		return nil, err
+	}
+	if repos, err = filterRepos(repos); err != nil {
+		return nil, err
+	}
+	if repos, err = skipBusyRepos(repos); err != nil {
+		return nil, err
+	}
+	if repos, err = orderByDependencies(repos); err != nil {
+		return nil, err
+	}
+	if err := checkPolicyPaths(repos); err != nil {
+		return nil, err
 	}
 	return repos, nil
 }
 
-func isGitRepo(dir string) bool {
-	// Prefer calling git to detect repository (handles git worktrees and submodules)
-	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
-	cmd.Dir = dir
-	out, err := cmd.Output()
+// startRun records repos for the run summary, runs the pre hooks, locks
+// their workspace and notes their HEAD for the audit log.
+func startRun(repos []string) error {
+	recordRepos(repos)
+	if err := runPreHooks(repos); err != nil {
+		return err
+	}
+	if err := lockWorkspace(repos); err != nil {
+		return err
+	}
+	auditBefore(repos)
+	return nil
+}
+
+// discoverRepos returns the git repositories matching the glob patterns,
+// minus those excluded by the nearest .gitbatchignore.
+func discoverRepos(patterns []string) ([]string, error) {
+	if err := checkBackend(); err != nil {
+		return nil, err
+	}
+	opts, err := discoverOptions()
+	if err != nil {
+		return nil, err
+	}
+	ignore, err := discover.LoadIgnore(".")
+	if err != nil {
+		return nil, err
+	}
+	repos, err := discover.FindWith(patterns, opts)
+	if errors.Is(err, discover.ErrInvalidPattern) {
+		return nil, exitWith(exitUsage, err)
+	}
 	if err != nil {
-		return false
+		return nil, err
 	}
-	return strings.TrimSpace(string(out)) == "true"
+	var paths []string
+	for _, r := range discover.Paths(repos) {
+		if ignore.Ignored(r) {
+			logf(levelVerbose, "ignoring %s (%s)", r, ignore.Path)
+			continue
+		}
+		paths = append(paths, r)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%w (all matches are excluded by %s)", discover.ErrNoRepos, ignore.Path)
+	}
+	return paths, nil
 }
 
 func runGit(ctx context.Context, dir string, args ...string) error {
-	cmd := exec.CommandContext(ctx, "git", args...)
-	cmd.Dir = dir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	return cmd.Run()
+	return gitExec.Stream(ctx, runner.Git(dir, args...))
 }
 
 func runGitCapture(ctx context.Context, dir string, args ...string) (string, error) {
-	cmd := exec.CommandContext(ctx, "git", args...)
-	cmd.Dir = dir
-	b, err := cmd.CombinedOutput()
-	return string(b), err
+	return gitExec.Capture(ctx, runner.Git(dir, args...))
 }
 
 // status command
+var statusFormat string
+var statusOutput string
+var statusSummary bool
 var statusCmd = &cobra.Command{
-	Use:   "status <pattern>...",
+	Use:   "status [--pathspec <path>]... [--untracked no|normal|all] [--ignored] <pattern>...",
 	Short: "Run git status in matching repositories",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `status runs git status in every matching repository. --summary prints one
+line per repository instead, with its branch, ahead/behind counts, changed
+files, stash entries and the age of the oldest stash. With --format it
+prints one line per repository from a Go template, with the fields .Repo,
+.Branch, .Upstream, .Ahead, .Behind, .Staged, .Unstaged, .Untracked,
+.Ignored, .Conflicts, .Stashes and .OldestStash. --output prints the same fields as
+json, csv or markdown; --output junit reports each repository as a test
+case that fails when git status fails.
+
+--pathspec limits status to changes under the given paths, and only lists
+the repositories that have any there. --warn-stash-older-than warns on
+stderr about repositories whose oldest stash is older than the threshold,
+so forgotten work does not rot there.
+
+--untracked=no skips untracked files, which makes status fast and short
+in repositories with large untracked build directories; normal and all
+are git's modes. --ignored also lists ignored files, which git only looks
+for along with untracked files.`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		fileArgs, err := statusFileArgs()
+		if err != nil {
+			return err
+		}
+		if statusFormat != "" || statusOutput != "" || statusSummary {
+			return statusStructured(args, fileArgs)
+		}
 		repos, err := collectRepos(args)
 		if err != nil {
 			return err
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		ctx, cancel := runContext()
 		defer cancel()
+		repos = changedUnder(ctx, repos, statusPathSpecs, append([]string{"status", "--porcelain"}, fileArgs...)...)
+		statusArgs := withPathSpecs(append([]string{"status"}, fileArgs...), statusPathSpecs)
+		if jobs > 1 {
+			runBuffered(repos, statusArgs...)
+			warnOldStashes(ctx, repos)
+			return nil
+		}
 		for _, r := range repos {
-			fmt.Printf("\n---- %s ----\n", r)
-			if err := runGit(ctx, r, "status"); err != nil {
-				fmt.Fprintf(os.Stderr, "error in %s: %v\n", r, err)
+			repoHeader(r)
+			if err := runGit(ctx, r, statusArgs...); err != nil {
+				repoFailed(r, err)
 			}
 		}
+		warnOldStashes(ctx, repos)
 		return nil
 	},
 }
 
+// statusStructured prints the state of each repository as the --summary
+// table, through the --format template or as --output. fileArgs are the
+// git status options of --untracked and --ignored.
+func statusStructured(patterns, fileArgs []string) error {
+	if err := checkFormatOutput(statusFormat, statusOutput, outputJUnit); err != nil {
+		return err
+	}
+	if statusSummary && (statusFormat != "" || statusOutput != "") {
+		return usageErrorf("--summary cannot be combined with --format or --output")
+	}
+	var tmpl *template.Template
+	if statusFormat != "" {
+		var err error
+		if tmpl, err = parseFormat(statusFormat); err != nil {
+			return err
+		}
+	}
+	repos, err := collectRepos(patterns)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := runContext()
+	defer cancel()
+	repos = changedUnder(ctx, repos, statusPathSpecs, append([]string{"status", "--porcelain"}, fileArgs...)...)
+	if statusOutput == outputJUnit {
+		return writeResults(os.Stdout, statusOutput, "gitbatch status", runCollect(repos, withPathSpecs(append([]string{"status"}, fileArgs...), statusPathSpecs)...))
+	}
+	var states []repoState
+	now := time.Now()
+	for _, r := range repos {
+		s, err := readRepoStateUnder(ctx, r, statusPathSpecs, fileArgs...)
+		if err != nil {
+			repoFailed(r, err)
+			continue
+		}
+		stashes, err := readStashes(ctx, r)
+		if err != nil {
+			repoFailed(r, err)
+			continue
+		}
+		s.Stashes = stashes.count
+		if stashes.count > 0 {
+			s.OldestStash = formatAge(stashes.age(now))
+		}
+		warnOldStash(r, stashes, now)
+		states = append(states, s)
+	}
+	switch {
+	case statusSummary:
+		writeStatusSummary(os.Stdout, states)
+		return nil
+	case tmpl != nil:
+		return writeFormatted(os.Stdout, tmpl, states)
+	}
+	return writeOutput(os.Stdout, statusOutput, states, stateTable(states))
+}
+
+// writeStatusSummary prints one line per repository state.
+func writeStatusSummary(out io.Writer, states []repoState) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tBRANCH\tAHEAD\tBEHIND\tCHANGES\tSTASHES\tOLDEST STASH")
+	for _, s := range states {
+		branch := s.Branch
+		if branch == "" {
+			branch = "(detached)"
+		}
+		changes := "clean"
+		if !s.clean() {
+			changes = fmt.Sprintf("%d staged, %d unstaged, %d untracked", s.Staged, s.Unstaged, s.Untracked)
+			if s.Conflicts > 0 {
+				changes += fmt.Sprintf(", %d conflicts", s.Conflicts)
+			}
+		}
+		if s.Ignored > 0 {
+			changes += fmt.Sprintf(", %d ignored", s.Ignored)
+		}
+		oldest := s.OldestStash
+		if oldest == "" {
+			oldest = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%d\t%s\n", s.Repo, branch, s.Ahead, s.Behind, changes, s.Stashes, oldest)
+	}
+	w.Flush()
+}
+
+// stateTable lays out repository states for csv and markdown output.
+func stateTable(states []repoState) report.Table {
+	t := report.Table{Header: []string{"repo", "branch", "upstream", "ahead", "behind", "staged", "unstaged", "untracked", "ignored", "conflicts", "stashes", "oldest_stash"}}
+	for _, s := range states {
+		t.Rows = append(t.Rows, []string{s.Repo, s.Branch, s.Upstream,
+			strconv.Itoa(s.Ahead), strconv.Itoa(s.Behind), strconv.Itoa(s.Staged),
+			strconv.Itoa(s.Unstaged), strconv.Itoa(s.Untracked), strconv.Itoa(s.Ignored), strconv.Itoa(s.Conflicts),
+			strconv.Itoa(s.Stashes), s.OldestStash})
+	}
+	return t
+}
+
 // diff command
 var diffCmd = &cobra.Command{
-	Use:   "diff <pattern>...",
+	Use:   "diff [--pathspec <path>]... [--word-diff[=mode]] <pattern>...",
 	Short: "Run git --no-pager diff in matching repositories",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `diff runs git --no-pager diff in every matching repository. --pathspec
+limits the diff to the given paths, and only lists the repositories with
+unstaged changes there. --word-diff shows changed words instead of whole
+lines, as git diff --word-diff does; the mode is plain (the default),
+color, porcelain or none.`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		wordArgs, err := wordDiffArgs()
+		if err != nil {
+			return err
+		}
 		repos, err := collectRepos(args)
 		if err != nil {
 			return err
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		ctx, cancel := runContext()
 		defer cancel()
+		repos = changedUnder(ctx, repos, diffPathSpecs, "diff", "--name-only")
+		diffArgs := withPathSpecs(append([]string{"--no-pager", "diff"}, wordArgs...), diffPathSpecs)
+		if jobs > 1 {
+			runBuffered(repos, diffArgs...)
+			return nil
+		}
 		for _, r := range repos {
-			fmt.Printf("\n---- %s ----\n", r)
-			if err := runGit(ctx, r, "--no-pager", "diff"); err != nil {
-				fmt.Fprintf(os.Stderr, "error in %s: %v\n", r, err)
+			repoHeader(r)
+			if err := runGit(ctx, r, diffArgs...); err != nil {
+				repoFailed(r, err)
 			}
 		}
 		return nil
@@ -162,53 +433,249 @@ var diffCmd = &cobra.Command{
 }
 
 // pull command
+var pullLFS bool
+var pullOutput string
 var pullCmd = &cobra.Command{
 	Use:   "pull <pattern>...",
 	Short: "Run git pull in matching repositories",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `pull runs git pull in every matching repository. --output prints one
+result per repository as json, csv, markdown or junit instead of git's
+output; junit reports can be published by CI systems as a per-repository
+pass/fail matrix.
+
+pull only fast-forwards by default (git pull --ff-only), so a batch pull
+never creates merge commits; repositories whose branch has diverged from
+its upstream fail and are reported. --merge and --rebase pull them with
+that strategy instead, and --ff-only overrides a different mode from the
+config file. mode = "git" under [pull] in the config file leaves the choice
+to each repository's pull.rebase and pull.ff settings.
+
+--skip-current first checks which repositories are already current with
+their upstream and reports them as "current" instead of pulling them. By
+default it fetches and compares; --skip-current=local compares with the
+upstream as last fetched, without touching the network.`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutput(pullOutput, outputJUnit); err != nil {
+			return err
+		}
+		if err := checkSkipCurrent(); err != nil {
+			return err
+		}
+		if noUpstreamMode == noUpstreamSet {
+			return usageErrorf("--no-upstream=%s only applies to push", noUpstreamSet)
+		}
+		mode, err := pullMode()
+		if err != nil {
+			return err
+		}
+		fetchArgs, err := tagArgs()
+		if err != nil {
+			return err
+		}
+		pullArgs := append(append([]string{"pull"}, pullModeArgs(mode)...), fetchArgs...)
 		repos, err := collectRepos(args)
 		if err != nil {
 			return err
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		if repos, _, err = checkUpstreams(repos, noUpstreamMode); err != nil {
+			return err
+		}
+		if repos, err = preflight(repos); err != nil {
+			return err
+		}
+		if fetchPruneTags {
+			if repos = pruneTagsFirst(repos); len(repos) == 0 {
+				return nil
+			}
+		}
+		checked := repos
+		repos, current := skipCurrent(repos, fetchArgs)
+		if pullOutput != "" {
+			results := pullCollect(repos, pullArgs)
+			explainPullResults(mode, results)
+			return writeResults(os.Stdout, pullOutput, "gitbatch pull", withCurrent(checked, results, current))
+		}
+		if jobs > 1 {
+			pulled := runBuffered(repos, pullArgs...)
+			reportDiverged(mode, pulled)
+			if pullLFS {
+				ctx, cancel := runContext()
+				defer cancel()
+				var lfsRepos []string
+				for _, res := range pulled {
+					if res.OK() && usesLFS(ctx, res.Repo) {
+						lfsRepos = append(lfsRepos, res.Repo)
+					}
+				}
+				runBuffered(lfsRepos, "lfs", "pull")
+			}
+			return nil
+		}
+		ctx, cancel := runContext()
 		defer cancel()
 		for _, r := range repos {
-			fmt.Printf("\n---- %s ----\n", r)
-			if err := runGit(ctx, r, "pull"); err != nil {
-				fmt.Fprintf(os.Stderr, "error in %s: %v\n", r, err)
+			repoHeader(r)
+			if err := runGit(ctx, r, pullArgs...); err != nil {
+				repoFailed(r, explainPullFailure(ctx, mode, r, err))
+				continue
+			}
+			if pullLFS && usesLFS(ctx, r) {
+				if err := runGit(ctx, r, "lfs", "pull"); err != nil {
+					repoFailed(r, fmt.Errorf("git lfs pull: %v", err))
+				}
 			}
 		}
 		return nil
 	},
 }
 
+// pullCollect pulls repos, followed by git lfs pull with --lfs, and returns
+// one result per repository. An LFS failure fails the repository's result.
+func pullCollect(repos []string, pullArgs []string) []runner.Result {
+	results := runCollect(repos, pullArgs...)
+	if !pullLFS {
+		return results
+	}
+	ctx, cancel := runContext()
+	defer cancel()
+	var lfsRepos []string
+	index := map[string]int{}
+	for i, res := range results {
+		if res.OK() && usesLFS(ctx, res.Repo) {
+			lfsRepos = append(lfsRepos, res.Repo)
+			index[res.Repo] = i
+		}
+	}
+	for _, lfs := range runCollect(lfsRepos, "lfs", "pull") {
+		res := &results[index[lfs.Repo]]
+		res.Output += lfs.Output
+		res.Duration += lfs.Duration
+		if !lfs.OK() {
+			res.Err = fmt.Errorf("git lfs pull: %v", lfs.Err)
+		}
+	}
+	return results
+}
+
 // add command
-var addPathSpec string
+var addPathSpecs []string
+var addUpdate bool
+var addAll bool
+var addIntentToAdd bool
+var addPreview bool
+var addPatchMode bool
 var addCmd = &cobra.Command{
-	Use:   "add [--pathspec <path>] <pattern>...",
+	Use:   "add [--pathspec <path>]... <pattern>...",
 	Short: "Run git add (safe) in matching repositories",
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if addUpdate && addAll {
+			return usageErrorf("--update and --all cannot be used together")
+		}
+		if addPatchMode && (addPreview || addAll || addIntentToAdd) {
+			return usageErrorf("--patch cannot be combined with --preview, --all or --intent-to-add")
+		}
 		repos, err := collectRepos(args)
 		if err != nil {
 			return err
 		}
-		if addPathSpec == "" {
-			addPathSpec = "."
+		if len(addPathSpecs) == 0 {
+			addPathSpecs = []string{"."}
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		limit, abortOnLarge, err := largeFileGuard(cmd)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
 		defer cancel()
+
+		// work out the git add arguments per repository first so the preview
+		// shows exactly what will be staged
+		var targets []string
+		addArgs := map[string][]string{}
 		for _, r := range repos {
-			fmt.Printf("\n---- %s ----\n", r)
-			if err := runGit(ctx, r, "add", "--", addPathSpec); err != nil {
-				fmt.Fprintf(os.Stderr, "error in %s: %v\n", r, err)
+			a := append([]string{"add"}, addModeArgs()...)
+			a = append(append(a, "--"), addPathSpecs...)
+			if limit > 0 {
+				large, err := largeFilesToAdd(ctx, r, addPathSpecs, !addUpdate, limit)
+				if err != nil {
+					repoFailed(r, err)
+					continue
+				}
+				if len(large) > 0 && abortOnLarge {
+					reportLargeFiles(r, large, limit, "found, nothing added")
+					continue
+				}
+				if len(large) > 0 {
+					reportLargeFiles(r, large, limit, "skipped")
+					a = append(a, largeFilePathspecs(large, "exclude,literal")...)
+				}
+			}
+			targets = append(targets, r)
+			addArgs[r] = a
+		}
+
+		if addPatchMode {
+			addPatch(targets, addArgs)
+			return nil
+		}
+
+		if addPreview {
+			var pending []string
+			for _, r := range targets {
+				dry := append([]string{"add", "--dry-run"}, addArgs[r][1:]...)
+				out, err := runGitCapture(ctx, r, dry...)
+				if err != nil {
+					repoFailed(r, fmt.Errorf("%v\n%s", err, strings.TrimRight(out, "\n")))
+					continue
+				}
+				if strings.TrimSpace(out) == "" {
+					continue
+				}
+				repoHeader(r)
+				fmt.Print(out)
+				pending = append(pending, r)
+			}
+			if len(pending) == 0 {
+				fmt.Println("nothing to add")
+				return nil
+			}
+			fmt.Printf("\nStage these changes in %d repositories? (y/N): ", len(pending))
+			if !userConfirm() {
+				fmt.Println("aborted")
+				markAborted()
+				return nil
+			}
+			targets = pending
+		}
+
+		for _, r := range targets {
+			repoHeader(r)
+			if err := runGit(ctx, r, addArgs[r]...); err != nil {
+				repoFailed(r, err)
 			}
 		}
 		return nil
 	},
 }
 
+// addModeArgs returns the git add options selected by --update, --all and
+// --intent-to-add.
+func addModeArgs() []string {
+	var args []string
+	if addUpdate {
+		args = append(args, "--update")
+	}
+	if addAll {
+		args = append(args, "--all")
+	}
+	if addIntentToAdd {
+		args = append(args, "--intent-to-add")
+	}
+	return args
+}
+
 // commit command
 var commitMsg string
 var commitCmd = &cobra.Command{
@@ -217,25 +684,89 @@ var commitCmd = &cobra.Command{
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if strings.TrimSpace(commitMsg) == "" {
-			return errors.New("commit message required: use -m \"message\"")
+			return usageErrorf("commit message required: use -m \"message\"")
+		}
+		message, err := composeConventional(commitType, commitScope, commitBreaking, commitMsg)
+		if err != nil {
+			return err
+		}
+		if commitVerifyConventional {
+			if err := verifyConventional(message); err != nil {
+				return err
+			}
+		}
+		if message, err = withCoAuthors(message, commitCoAuthors); err != nil {
+			return err
+		}
+		if commitAuthor != "" {
+			if err := validatePerson("--author", commitAuthor); err != nil {
+				return err
+			}
+		}
+		sign, err := resolveSigning()
+		if err != nil {
+			return err
+		}
+		var commitArgs []string
+		if sign != nil {
+			commitArgs = append(commitArgs, sign.configArgs...)
+		}
+		commitArgs = append(commitArgs, "commit", "-m", message)
+		commitArgs = append(commitArgs, commitOptionArgs()...)
+		if sign != nil {
+			commitArgs = append(commitArgs, sign.commitArgs()...)
 		}
 		repos, err := collectRepos(args)
 		if err != nil {
 			return err
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		limit, abortOnLarge, err := largeFileGuard(cmd)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
 		defer cancel()
 		for _, r := range repos {
-			fmt.Printf("\n---- %s ----\n", r)
+			repoHeader(r)
+			if commitAmend {
+				pushed, err := headIsPushed(ctx, r)
+				if err != nil {
+					repoFailed(r, err)
+					continue
+				}
+				if pushed {
+					repoFailed(r, errors.New("HEAD is already on a remote, refusing to amend"))
+					continue
+				}
+			}
+			if limit > 0 {
+				large, err := largeStagedFiles(ctx, r, limit)
+				if err != nil {
+					repoFailed(r, err)
+					continue
+				}
+				if len(large) > 0 && abortOnLarge {
+					reportLargeFiles(r, large, limit, "staged, not committing")
+					continue
+				}
+				if len(large) > 0 {
+					reportLargeFiles(r, large, limit, "unstaged")
+					unstage := append([]string{"reset", "--quiet", "--"}, largeFilePathspecs(large, "literal")...)
+					if err := runGit(ctx, r, unstage...); err != nil {
+						repoFailed(r, err)
+						continue
+					}
+				}
+			}
 			// Use -m, but allow git to skip if there's nothing to commit
-			out, err := runGitCapture(ctx, r, "commit", "-m", commitMsg)
+			out, err := runGitCapture(ctx, r, commitArgs...)
 			fmt.Print(out)
 			if err != nil {
 				// if exit status is 1 and message indicates nothing to commit, ignore
 				if strings.Contains(out, "nothing to commit") || strings.Contains(out, "nothing added to commit") {
 					continue
 				}
-				fmt.Fprintf(os.Stderr, "error in %s: %v\n", r, err)
+				repoFailed(r, err)
 			}
 		}
 		return nil
@@ -245,40 +776,88 @@ var commitCmd = &cobra.Command{
 // push command
 var pushForce bool
 var pushYes bool
+var pushAllowSecrets bool
 var pushCmd = &cobra.Command{
 	Use:   "push <pattern>...",
 	Short: "Run git push in matching repositories (asks confirmation)",
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		mode := noUpstreamMode
+		if pushSetUpstream {
+			if cmd.Flags().Changed("no-upstream") && mode != noUpstreamSet {
+				return usageErrorf("--set-upstream conflicts with --no-upstream=%s", mode)
+			}
+			mode = noUpstreamSet
+		}
 		repos, err := collectRepos(args)
 		if err != nil {
 			return err
 		}
+		repos, setUpstream, err := checkUpstreams(repos, mode)
+		if err != nil {
+			return err
+		}
+		if repos, err = preflight(repos); err != nil {
+			return err
+		}
+		if !pushAllowSecrets {
+			if repos, err = filterSecretFindings(repos); err != nil {
+				return err
+			}
+			if len(repos) == 0 {
+				return errors.New("push blocked in every repository by the secret scan (use --allow-secrets to override)")
+			}
+		}
 		if !pushYes {
 			fmt.Printf("About to push to %d repositories. This will contact remotes and may change remote history. Continue? (y/N): ", len(repos))
 			s := userConfirm()
 			if !s {
 				fmt.Println("aborted")
+				markAborted()
 				return nil
 			}
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		pushArgs := []string{"push"}
+		if pushForce {
+			pushArgs = append(pushArgs, "--force")
+		}
+		if jobs > 1 {
+			var plain []string
+			for _, r := range repos {
+				if _, ok := setUpstream[r]; !ok {
+					plain = append(plain, r)
+				}
+			}
+			runBuffered(plain, pushArgs...)
+			remotes, groups := upstreamGroups(setUpstream)
+			for _, remote := range remotes {
+				runBuffered(groups[remote], setUpstreamArgs(pushArgs, remote)...)
+			}
+			return nil
+		}
+		ctx, cancel := runContext()
 		defer cancel()
 		for _, r := range repos {
-			fmt.Printf("\n---- %s ----\n", r)
-			args := []string{"push"}
-			if pushForce {
-				args = append(args, "--force")
+			repoHeader(r)
+			args := pushArgs
+			if remote, ok := setUpstream[r]; ok {
+				args = setUpstreamArgs(pushArgs, remote)
 			}
 			if err := runGit(ctx, r, args...); err != nil {
-				fmt.Fprintf(os.Stderr, "error in %s: %v\n", r, err)
+				repoFailed(r, err)
 			}
 		}
 		return nil
 	},
 }
 
+// userConfirm reads a yes/no answer from stdin. Runs that only write an
+// --emit-script do not ask: the script is reviewed before it runs instead.
 func userConfirm() bool {
+	if emitScript != "" {
+		fmt.Println("y (--emit-script)")
+		return true
+	}
 	s := bufio.NewScanner(os.Stdin)
 	if s.Scan() {
 		text := strings.TrimSpace(strings.ToLower(s.Text()))
@@ -288,6 +867,7 @@ func userConfirm() bool {
 }
 
 func init() {
+	rootCmd.PersistentPreRunE = prepareRun
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(diffCmd)
 	rootCmd.AddCommand(pullCmd)
@@ -295,11 +875,52 @@ func init() {
 	rootCmd.AddCommand(commitCmd)
 	rootCmd.AddCommand(pushCmd)
 
-	addCmd.Flags().StringVarP(&addPathSpec, "pathspec", "p", ".", "pathspec to add (defaults to '.')")
+	addFormatFlag(statusCmd, &statusFormat, `{{.Repo}}\t{{.Branch}}\t{{.Ahead}}`)
+	addOutputFlag(statusCmd, &statusOutput, outputJUnit)
+	statusCmd.Flags().BoolVar(&statusSummary, "summary", false, "print one line per repository with its changes and stashes")
+	addStashWarnFlag(statusCmd)
+	statusCmd.Flags().StringArrayVarP(&statusPathSpecs, "pathspec", "p", nil, "only show changes under this pathspec (repeatable)")
+	statusCmd.Flags().StringVar(&statusUntracked, "untracked", "", "how to show untracked files: no, normal or all (default: git's status.showUntrackedFiles)")
+	statusCmd.Flags().BoolVar(&statusIgnored, "ignored", false, "also show ignored files")
+	diffCmd.Flags().StringArrayVarP(&diffPathSpecs, "pathspec", "p", nil, "only show changes under this pathspec (repeatable)")
+	diffCmd.Flags().StringVar(&diffWordDiff, "word-diff", "", "show changed words: plain, color, porcelain or none")
+	diffCmd.Flags().Lookup("word-diff").NoOptDefVal = "plain"
+
+	pullCmd.Flags().BoolVar(&pullLFS, "lfs", false, "run git lfs pull afterwards in repositories that use Git LFS")
+	addOutputFlag(pullCmd, &pullOutput, outputJUnit)
+	addRetryFlags(pullCmd)
+	addPreflightFlags(pullCmd)
+	addNoUpstreamFlag(pullCmd, "skip with a warning or fail")
+	addTagFlags(pullCmd)
+
+	addCmd.Flags().StringArrayVarP(&addPathSpecs, "pathspec", "p", []string{"."}, "pathspec to add (repeatable, defaults to '.')")
+	addCmd.Flags().BoolVarP(&addUpdate, "update", "u", false, "only stage changes to tracked files")
+	addCmd.Flags().BoolVarP(&addAll, "all", "A", false, "stage new, modified and deleted files")
+	addCmd.Flags().BoolVarP(&addIntentToAdd, "intent-to-add", "N", false, "record only that the paths will be added later")
+	addCmd.Flags().BoolVar(&addPatchMode, "patch", false, "interactively pick hunks with git add --patch, one repository at a time")
+	addCmd.Flags().BoolVar(&addPreview, "preview", false, "show git add --dry-run output per repository and confirm before staging")
 
 	commitCmd.Flags().StringVarP(&commitMsg, "message", "m", "", "commit message (required)")
 	commitCmd.MarkFlagRequired("message")
+	commitCmd.Flags().StringVar(&commitType, "type", "", "conventional commit type prepended to the message (feat, fix, chore, ...)")
+	commitCmd.Flags().StringVar(&commitScope, "scope", "", "conventional commit scope, e.g. api in feat(api): ...")
+	commitCmd.Flags().BoolVar(&commitBreaking, "breaking", false, "mark the conventional commit as a breaking change (feat!: ...)")
+	commitCmd.Flags().BoolVar(&commitVerifyConventional, "verify-conventional", false, "reject messages that are not conventional commits")
+
+	addLargeFileFlags(addCmd)
+	commitCmd.Flags().BoolVar(&commitAmend, "amend", false, "amend HEAD instead of creating a commit (refused where HEAD is already pushed)")
+	commitCmd.Flags().BoolVarP(&commitSignoff, "signoff", "s", false, "add a Signed-off-by trailer")
+	commitCmd.Flags().BoolVar(&commitAllowEmpty, "allow-empty", false, "commit even when nothing is staged")
+	commitCmd.Flags().StringVar(&commitAuthor, "author", "", "override the commit author, as \"Name <email>\"")
+	commitCmd.Flags().StringArrayVar(&commitCoAuthors, "co-author", nil, "add a Co-authored-by trailer, as \"Name <email>\" (repeatable)")
+	addLargeFileFlags(commitCmd)
+	addSigningFlags(commitCmd)
 
 	pushCmd.Flags().BoolVarP(&pushForce, "force", "f", false, "force push (use with caution)")
 	pushCmd.Flags().BoolVarP(&pushYes, "yes", "y", false, "skip confirmation for push")
+	pushCmd.Flags().BoolVarP(&pushSetUpstream, "set-upstream", "u", false, "push branches without an upstream to the default remote and set it (same as --no-upstream=set-upstream)")
+	addNoUpstreamFlag(pushCmd, "skip with a warning, fail, or set-upstream to create it")
+	addRetryFlags(pushCmd)
+	addPreflightFlags(pushCmd)
+	pushCmd.Flags().BoolVar(&pushAllowSecrets, "allow-secrets", false, "push even if the secret scan finds credentials in outgoing commits")
 }
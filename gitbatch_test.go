@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -189,3 +190,46 @@ func TestCollectReposNoMatch(t *testing.T) {
 		t.Errorf("expected error when no repos found")
 	}
 }
+
+// commitTestFile writes name with content inside repo and commits it with msg.
+// Extra environment variables (e.g. GIT_COMMITTER_DATE) are passed to git commit.
+func commitTestFile(t *testing.T, repo, name, content, msg string, env ...string) {
+	t.Helper()
+	path := filepath.Join(repo, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"add", "--", name}, {"commit", "-m", msg}} {
+		c := exec.Command("git", args...)
+		c.Dir = repo
+		c.Env = append(os.Environ(), env...)
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed in %s: %v, out=%s", args, repo, err, string(out))
+		}
+	}
+}
+
+func TestPullCollectLFSFailure(t *testing.T) {
+	m := useMockGit(t)
+	m.On("pull", "Already up to date.\n", nil)
+	m.On("grep --quiet --fixed-strings filter=lfs -- :(glob)**/.gitattributes", "", nil)
+	m.On("lfs pull", "batch request: missing credentials\n", errors.New("exit status 2"))
+	pullLFS = true
+	defer func() { pullLFS = false }()
+
+	results := pullCollect([]string{"/r/a", "/r/b"}, []string{"pull"})
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	for _, res := range results {
+		if res.OK() || !strings.HasPrefix(res.Err.Error(), "git lfs pull:") {
+			t.Errorf("%s: expected lfs failure, got %v", res.Repo, res.Err)
+		}
+		if res.Output != "Already up to date.\nbatch request: missing credentials\n" {
+			t.Errorf("%s: unexpected output %q", res.Repo, res.Output)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// GitConfig is the [git] section of the config file; --git and
+// --ssh-command take precedence:
+//
+//	[git]
+//	path = "/opt/git-2.45/bin/git"
+//	ssh_command = "ssh -i ~/.ssh/work_ed25519 -J bastion.example.com"
+type GitConfig struct {
+	Path       string `toml:"path"`
+	SSHCommand string `toml:"ssh_command"`
+}
+
+var gitPath string
+var sshCommand string
+
+// extraEnv is --env: variables set for every git command, including ones
+// that gitbatch otherwise removes, such as GIT_DIR.
+var extraEnv []string
+
+// secretEnv holds the variables that carry credentials: the token of
+// --auth-token and the answers for GIT_ASKPASS. secretEnvExecutor adds
+// them to git commands only, so run hooks, plugins and the pager never see
+// them, and they are kept out of gitBinary.Env, which --emit-script writes
+// to the script.
+var secretEnv []string
+
+// secretEnvExecutor adds secretEnv to the environment of every command.
+// It sits right above gitBinary, so no wrapper logs or records it, and
+// holds a pointer to it, so variables added to gitBinary.Env after setup,
+// as checkCredentials does, still apply.
+type secretEnvExecutor struct {
+	next runner.GitExecutor
+}
+
+func (e secretEnvExecutor) apply(c runner.Cmd) runner.Cmd {
+	if len(secretEnv) > 0 {
+		c.Env = append(append([]string{}, secretEnv...), c.Env...)
+	}
+	return c
+}
+
+func (e secretEnvExecutor) Stream(ctx context.Context, c runner.Cmd) error {
+	return e.next.Stream(ctx, e.apply(c))
+}
+
+func (e secretEnvExecutor) Capture(ctx context.Context, c runner.Cmd) (string, error) {
+	return e.next.Capture(ctx, e.apply(c))
+}
+
+func (e secretEnvExecutor) Output(ctx context.Context, c runner.Cmd) (string, error) {
+	return e.next.Output(ctx, e.apply(c))
+}
+
+func (e secretEnvExecutor) Lines(ctx context.Context, c runner.Cmd, fn func(string)) error {
+	return e.next.Lines(ctx, e.apply(c), fn)
+}
+
+// gitBinary is the git installation gitbatch runs, before any logging or
+// retry wrappers. Discovery uses it directly so probing candidate
+// directories is neither logged nor retried.
+var gitBinary = runner.Exec{}
+
+// setupGitBinary applies --git, --ssh-command, --auth-token and --env,
+// falling back to the config file, before any other executor wrapper is
+// installed.
+func setupGitBinary() error {
+	for _, kv := range extraEnv {
+		if name, _, ok := strings.Cut(kv, "="); !ok || name == "" {
+			return usageErrorf("invalid --env %q (expected KEY=VALUE)", kv)
+		}
+	}
+	authEnv, err := authTokenEnv()
+	if err != nil {
+		return err
+	}
+	path, ssh := gitPath, sshCommand
+	if path == "" || ssh == "" {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			path = cfg.Git.Path
+		}
+		if ssh == "" {
+			ssh = cfg.Git.SSHCommand
+		}
+	}
+	// secretEnv is only filled in later, by preflight, so the executor is
+	// always installed
+	gitExec = secretEnvExecutor{next: &gitBinary}
+	if path == "" && ssh == "" && len(authEnv) == 0 && len(extraEnv) == 0 {
+		return nil
+	}
+	if path != "" {
+		resolved, err := exec.LookPath(path)
+		if err != nil {
+			return fmt.Errorf("git binary %s: %v", path, err)
+		}
+		gitBinary.Path = resolved
+	}
+	if ssh != "" {
+		// GIT_SSH_COMMAND takes precedence over core.sshCommand in every
+		// repository, so the whole run uses the same key or jump host
+		gitBinary.Env = []string{"GIT_SSH_COMMAND=" + ssh}
+	}
+	// authEnv comes last, its GIT_CONFIG_COUNT includes the entries of
+	// --env
+	gitBinary.Env = append(gitBinary.Env, extraEnv...)
+	gitBinary.Env = append(gitBinary.Env, authEnv...)
+	gitExec = secretEnvExecutor{next: &gitBinary}
+	return nil
+}
+
+// isGitRepo reports whether dir is inside a git work tree.
+func isGitRepo(dir string) bool {
+	if useGoGit() {
+		return gogitIsRepo(dir)
+	}
+	out, err := gitBinary.Output(context.Background(), runner.Git(dir, "rev-parse", "--is-inside-work-tree"))
+	return err == nil && out == "true"
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&gitPath, "git", "", "git binary to run (default: git from PATH)")
+	rootCmd.PersistentFlags().StringArrayVar(&extraEnv, "env", nil, "set an environment variable for every git command, as KEY=VALUE (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&sshCommand, "ssh-command", "", "ssh command for git to use, e.g. \"ssh -i ~/.ssh/work\" (sets GIT_SSH_COMMAND)")
+}
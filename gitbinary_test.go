@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetupGitBinary(t *testing.T) {
+	prevExec, prevBinary, prevConfig := gitExec, gitBinary, loadedConfig
+	defer func() {
+		gitExec, gitBinary, loadedConfig, gitPath, sshCommand = prevExec, prevBinary, prevConfig, "", ""
+	}()
+
+	// a wrapper script stands in for a specific git installation
+	dir := t.TempDir()
+	wrapper := filepath.Join(dir, "git-wrapper")
+	script := "#!/bin/sh\necho \"ssh=$GIT_SSH_COMMAND\"\n"
+	if err := os.WriteFile(wrapper, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	loadedConfig = &Config{Git: GitConfig{Path: "/nonexistent/git", SSHCommand: "ssh -i config-key"}}
+	gitPath, sshCommand = wrapper, ""
+	if err := setupGitBinary(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := gitOutput(context.Background(), dir, "version")
+	if err != nil || out != "ssh=ssh -i config-key" {
+		t.Errorf("expected the wrapper with the configured ssh command, got %q, %v", out, err)
+	}
+
+	gitPath = ""
+	if err := setupGitBinary(); err == nil || !strings.Contains(err.Error(), "/nonexistent/git") {
+		t.Errorf("expected an error for the missing configured binary, got %v", err)
+	}
+}
+
+func TestGitEnvironment(t *testing.T) {
+	prevExec, prevBinary, prevConfig := gitExec, gitBinary, loadedConfig
+	defer func() {
+		gitExec, gitBinary, loadedConfig, extraEnv = prevExec, prevBinary, prevConfig, nil
+	}()
+	loadedConfig = &Config{}
+
+	// as in a hook, GIT_DIR points at another repository
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+	other := initTestRepo(t)
+	t.Setenv("GIT_DIR", filepath.Join(other, ".git"))
+	t.Setenv("GIT_INDEX_FILE", filepath.Join(other, ".git", "index"))
+
+	if err := setupGitBinary(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := gitOutput(context.Background(), repo, "ls-files")
+	if err != nil || out != "a.txt" {
+		t.Errorf("git ls-files with an inherited GIT_DIR = %q, %v", out, err)
+	}
+
+	extraEnv = []string{"GIT_DIR=" + filepath.Join(other, ".git")}
+	if err := setupGitBinary(); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := gitOutput(context.Background(), repo, "ls-files"); err != nil || out != "" {
+		t.Errorf("git ls-files with --env GIT_DIR = %q, %v", out, err)
+	}
+
+	extraEnv = []string{"NOVALUE"}
+	if err := setupGitBinary(); exitCode(err) != exitUsage {
+		t.Errorf("--env without a value accepted: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// configValue is the effective value of a git config key in one repository.
+type configValue struct {
+	repo  string
+	value string
+	scope string // local, global, system, ...; empty when unset
+	set   bool
+}
+
+// readConfigValue returns the effective value of key in dir and the scope it
+// comes from.
+func readConfigValue(ctx context.Context, dir, key string) (configValue, error) {
+	cv := configValue{repo: dir}
+	out, err := gitOutput(ctx, dir, "config", "--show-scope", "--get", key)
+	if err != nil {
+		// exit status 1 means the key is not set anywhere
+		if _, verr := gitOutput(ctx, dir, "config", "--get", key); verr != nil {
+			return cv, nil
+		}
+		return cv, err
+	}
+	cv.scope, cv.value, _ = strings.Cut(out, "\t")
+	cv.set = true
+	return cv, nil
+}
+
+// expectedConfigValue picks the value every repository should have: expect
+// when given, otherwise the most common value (ties broken alphabetically).
+func expectedConfigValue(values []configValue, expect string) string {
+	if expect != "" {
+		return expect
+	}
+	counts := map[string]int{}
+	for _, v := range values {
+		if v.set {
+			counts[v.value]++
+		}
+	}
+	var best string
+	for val, n := range counts {
+		if n > counts[best] || (n == counts[best] && val < best) {
+			best = val
+		}
+	}
+	return best
+}
+
+// config command
+var configExpect string
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Audit or set git config values across repositories",
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key> <pattern>...",
+	Short: "Show the effective value of a config key and flag mismatches",
+	Long: `get prints the effective value of <key> in every repository together with
+the scope it comes from. Repositories whose value differs from --expect (or,
+without it, from the most common value) are flagged.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := args[0]
+		repos, err := collectRepos(args[1:])
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		var values []configValue
+		for _, r := range repos {
+			cv, err := readConfigValue(ctx, r, key)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			values = append(values, cv)
+		}
+		expected := expectedConfigValue(values, configExpect)
+
+		var mismatched []configValue
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "REPO\t%s\tSCOPE\t\n", key)
+		for _, v := range values {
+			val, scope, flag := v.value, v.scope, ""
+			if !v.set {
+				val, scope = "(unset)", "-"
+			}
+			if !v.set || v.value != expected {
+				flag = "MISMATCH"
+				mismatched = append(mismatched, v)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.repo, val, scope, flag)
+		}
+		w.Flush()
+
+		if len(mismatched) > 0 {
+			fmt.Printf("\n%d of %d repositories differ from %q\n", len(mismatched), len(values), expected)
+		}
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value> <pattern>...",
+	Short: "Set a config key in each repository's local config",
+	Args:  cobra.MinimumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, value := args[0], args[1]
+		repos, err := collectRepos(args[2:])
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		for _, r := range repos {
+			local, _ := gitOutput(ctx, r, "config", "--local", "--get", key)
+			if local == value {
+				fmt.Printf("%s: %s already %q\n", r, key, value)
+				continue
+			}
+			if err := gitChange(ctx, r, "config", "--local", key, value); err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			if local == "" {
+				fmt.Printf("%s: %s set to %q\n", r, key, value)
+			} else {
+				fmt.Printf("%s: %s %q -> %q\n", r, key, local, value)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+
+	configGetCmd.Flags().StringVar(&configExpect, "expect", "", "value every repository should have (default: the most common value)")
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReadConfigValue(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+
+	cv, err := readConfigValue(ctx, repo, "user.email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cv.set || cv.value != "test@example.com" || cv.scope != "local" {
+		t.Errorf("unexpected value for user.email: %+v", cv)
+	}
+
+	cv, err = readConfigValue(ctx, repo, "gitbatch.notset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cv.set {
+		t.Errorf("expected gitbatch.notset to be unset, got %+v", cv)
+	}
+}
+
+func TestExpectedConfigValue(t *testing.T) {
+	values := []configValue{
+		{repo: "a", value: "me@work.com", set: true},
+		{repo: "b", value: "me@home.com", set: true},
+		{repo: "c", value: "me@work.com", set: true},
+		{repo: "d"},
+	}
+	if got := expectedConfigValue(values, ""); got != "me@work.com" {
+		t.Errorf("expected majority value, got %q", got)
+	}
+	if got := expectedConfigValue(values, "me@home.com"); got != "me@home.com" {
+		t.Errorf("expected explicit value to win, got %q", got)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// gitExec runs every git command of the CLI. Tests can swap in a
+// runner.Mock to check the commands issued without spawning git.
+var gitExec runner.GitExecutor = runner.Exec{}
+
+// gitOutput runs git in dir and returns its trimmed stdout. Unlike
+// runGitCapture, stderr is kept out of the result so it can be parsed; on
+// failure the error carries git's stderr so callers can report it as-is.
+// It is for queries: --emit-script runs it for real, so commands that change
+// a repository go through gitChange, runGit or runGitCapture.
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	return gitExec.Output(ctx, runner.Git(dir, args...))
+}
+
+// remoteNames returns the names of the remotes configured in dir.
+func remoteNames(ctx context.Context, dir string) ([]string, error) {
+	out, err := gitOutput(ctx, dir, "remote")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+// gitChange runs a git command that changes dir. It goes through Capture,
+// like runGitCapture, so --emit-script records it instead of running it; on
+// failure the error carries the first line git printed.
+func gitChange(ctx context.Context, dir string, args ...string) error {
+	out, err := runGitCapture(ctx, dir, args...)
+	if err != nil {
+		if msg := strings.TrimSpace(out); msg != "" {
+			return fmt.Errorf("%v: %s", err, firstLine(msg))
+		}
+	}
+	return err
+}
+
+// firstLine returns s up to (not including) the first newline.
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// currentBranch returns the short name of the branch HEAD points to, or ""
+// when HEAD is detached.
+func currentBranch(ctx context.Context, dir string) (string, error) {
+	if useGoGit() {
+		return gogitCurrentBranch(dir)
+	}
+	out, err := gitOutput(ctx, dir, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		// symbolic-ref exits 1 without output for a detached HEAD
+		if _, verr := gitOutput(ctx, dir, "rev-parse", "--verify", "--quiet", "HEAD"); verr == nil {
+			return "", nil
+		}
+		return "", err
+	}
+	return out, nil
+}
+
+// aheadBehind counts the commits reachable from left but not right (ahead)
+// and from right but not left (behind).
+func aheadBehind(ctx context.Context, dir, left, right string) (ahead, behind int, err error) {
+	if useGoGit() {
+		return gogitAheadBehind(dir, left, right)
+	}
+	out, err := gitOutput(ctx, dir, "rev-list", "--left-right", "--count", left+"..."+right)
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := fmt.Sscanf(out, "%d\t%d", &ahead, &behind); err != nil {
+		return 0, 0, fmt.Errorf("unexpected rev-list output %q", out)
+	}
+	return ahead, behind, nil
+}
+
+// defaultBranch guesses the repository's default branch: the branch
+// origin/HEAD points to, else the first existing local branch among
+// init.defaultBranch, main and master.
+func defaultBranch(ctx context.Context, dir string) (string, error) {
+	if ref, err := gitOutput(ctx, dir, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil && ref != "" {
+		return strings.TrimPrefix(ref, "origin/"), nil
+	}
+	candidates := []string{"main", "master"}
+	if configured, _ := gitOutput(ctx, dir, "config", "--get", "init.defaultBranch"); configured != "" {
+		candidates = append([]string{configured}, candidates...)
+	}
+	for _, name := range candidates {
+		if _, err := gitOutput(ctx, dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+name); err == nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("cannot determine default branch (no origin/HEAD, main or master)")
+}
+
+// shellQuote quotes s for POSIX shells unless it only contains characters
+// that never need quoting.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:@%+=,") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// repoState is a machine-readable summary of a repository's working tree
+// and branch, as reported by git status --porcelain=v2 --branch.
+type repoState struct {
+	Repo      string `json:"repo"`
+	Branch    string `json:"branch"` // "" when HEAD is detached
+	Upstream  string `json:"upstream,omitempty"`
+	Ahead     int    `json:"ahead"`
+	Behind    int    `json:"behind"`
+	Staged    int    `json:"staged"`
+	Unstaged  int    `json:"unstaged"`
+	Untracked int    `json:"untracked"`
+	Ignored   int    `json:"ignored,omitempty"` // only counted with --ignored
+	Conflicts int    `json:"conflicts"`
+	// Stashes and OldestStash, the formatted age of the oldest stash, are
+	// only filled in by the status command.
+	Stashes     int    `json:"stashes,omitempty"`
+	OldestStash string `json:"oldest_stash,omitempty"`
+}
+
+func (s repoState) clean() bool {
+	return s.Staged+s.Unstaged+s.Untracked+s.Conflicts == 0
+}
+
+// readRepoState parses git status --porcelain=v2 --branch for repo.
+func readRepoState(ctx context.Context, repo string) (repoState, error) {
+	return readRepoStateUnder(ctx, repo, nil)
+}
+
+// readRepoStateUnder is readRepoState counting only the changes under
+// pathspecs; the branch and ahead/behind counts stay repository-wide. opts
+// are further git status options, such as --untracked-files=no.
+func readRepoStateUnder(ctx context.Context, repo string, pathspecs []string, opts ...string) (repoState, error) {
+	if useGoGit() && len(pathspecs) == 0 && len(opts) == 0 {
+		return gogitState(ctx, repo)
+	}
+	args := append([]string{"status", "--porcelain=v2", "--branch"}, opts...)
+	out, err := gitOutput(ctx, repo, withPathSpecs(args, pathspecs)...)
+	if err != nil {
+		return repoState{}, err
+	}
+	s := repoState{Repo: repo}
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		switch f[0] {
+		case "#":
+			switch {
+			case f[1] == "branch.head" && len(f) == 3 && f[2] != "(detached)":
+				s.Branch = f[2]
+			case f[1] == "branch.upstream" && len(f) == 3:
+				s.Upstream = f[2]
+			case f[1] == "branch.ab" && len(f) == 4:
+				fmt.Sscanf(f[2], "+%d", &s.Ahead)
+				fmt.Sscanf(f[3], "-%d", &s.Behind)
+			}
+		case "1", "2":
+			if f[1][0] != '.' {
+				s.Staged++
+			}
+			if f[1][1] != '.' {
+				s.Unstaged++
+			}
+		case "u":
+			s.Conflicts++
+		case "?":
+			s.Untracked++
+		case "!":
+			s.Ignored++
+		}
+	}
+	return s, nil
+}
+
+// shellJoin quotes and joins args into a shell command line.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
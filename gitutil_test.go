@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+func TestCurrentBranchAndAheadBehind(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+	if out, err := runGitCapture(ctx, repo, "branch", "-M", "main"); err != nil {
+		t.Fatalf("git branch failed: %v, out=%s", err, out)
+	}
+
+	if b, err := currentBranch(ctx, repo); err != nil || b != "main" {
+		t.Fatalf("currentBranch = %q, %v; want main", b, err)
+	}
+
+	if out, err := runGitCapture(ctx, repo, "switch", "-c", "topic"); err != nil {
+		t.Fatalf("git switch failed: %v, out=%s", err, out)
+	}
+	commitTestFile(t, repo, "b.txt", "b", "topic work")
+	commitTestFile(t, repo, "c.txt", "c", "more topic work")
+	if out, err := runGitCapture(ctx, repo, "switch", "main"); err != nil {
+		t.Fatalf("git switch failed: %v, out=%s", err, out)
+	}
+	commitTestFile(t, repo, "d.txt", "d", "main work")
+
+	ahead, behind, err := aheadBehind(ctx, repo, "topic", "main")
+	if err != nil || ahead != 2 || behind != 1 {
+		t.Errorf("aheadBehind(topic, main) = %d, %d, %v; want 2, 1", ahead, behind, err)
+	}
+
+	if out, err := runGitCapture(ctx, repo, "switch", "--detach", "HEAD"); err != nil {
+		t.Fatalf("git switch --detach failed: %v, out=%s", err, out)
+	}
+	if b, err := currentBranch(ctx, repo); err != nil || b != "" {
+		t.Errorf("currentBranch on detached HEAD = %q, %v; want empty", b, err)
+	}
+}
+
+func TestReadRepoState(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+	commitTestFile(t, repo, "b.txt", "b", "second")
+
+	s, err := readRepoState(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Branch == "" || !s.clean() || s.Upstream != "" {
+		t.Errorf("expected a clean branch without upstream, got %+v", s)
+	}
+
+	for name, content := range map[string]string{"a.txt": "changed", "b.txt": "staged", "new.txt": "new"} {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, err := runGitCapture(ctx, repo, "add", "b.txt"); err != nil {
+		t.Fatalf("git add failed: %v, out=%s", err, out)
+	}
+	if s, err = readRepoState(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+	if s.Staged != 1 || s.Unstaged != 1 || s.Untracked != 1 || s.clean() {
+		t.Errorf("unexpected counts: %+v", s)
+	}
+}
+
+// useMockGit routes the CLI's git commands to a mock for the rest of the
+// test.
+func useMockGit(t *testing.T) *runner.Mock {
+	t.Helper()
+	m := &runner.Mock{}
+	prev := gitExec
+	gitExec = m
+	t.Cleanup(func() { gitExec = prev })
+	return m
+}
+
+func TestDefaultBranchWithMock(t *testing.T) {
+	ctx := context.Background()
+	m := useMockGit(t)
+	m.On("symbolic-ref --quiet --short refs/remotes/origin/HEAD", "origin/trunk\n", nil)
+	if b, err := defaultBranch(ctx, "/repo"); err != nil || b != "trunk" {
+		t.Errorf("defaultBranch = %q, %v", b, err)
+	}
+
+	m = useMockGit(t)
+	m.On("symbolic-ref --quiet --short refs/remotes/origin/HEAD", "", errors.New("exit status 1"))
+	m.On("config --get init.defaultBranch", "", errors.New("exit status 1"))
+	m.On("rev-parse --verify --quiet refs/heads/main", "", errors.New("exit status 1"))
+	if b, err := defaultBranch(ctx, "/repo"); err != nil || b != "master" {
+		t.Errorf("defaultBranch fallback = %q, %v", b, err)
+	}
+	want := []string{
+		"git symbolic-ref --quiet --short refs/remotes/origin/HEAD",
+		"git config --get init.defaultBranch",
+		"git rev-parse --verify --quiet refs/heads/main",
+		"git rev-parse --verify --quiet refs/heads/master",
+	}
+	if got := m.Commands(); !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
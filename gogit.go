@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// The go-git backend answers read-only questions (repository detection,
+// branch, ahead/behind, status counts, last commit) in-process instead of
+// spawning git for each one. It is selected with --backend go-git.
+
+const (
+	backendGit   = "git"
+	backendGoGit = "go-git"
+)
+
+var backend = os.Getenv("GITBATCH_BACKEND")
+
+func useGoGit() bool {
+	return backend == backendGoGit
+}
+
+func checkBackend() error {
+	switch backend {
+	case "", backendGit, backendGoGit:
+		return nil
+	}
+	return usageErrorf("unknown backend %q (expected %s or %s)", backend, backendGit, backendGoGit)
+}
+
+func openGoGit(dir string) (*git.Repository, error) {
+	return git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+}
+
+func gogitIsRepo(dir string) bool {
+	r, err := openGoGit(dir)
+	if err != nil {
+		return false
+	}
+	_, err = r.Worktree()
+	return err == nil // bare repositories have no work tree
+}
+
+func gogitCurrentBranch(dir string) (string, error) {
+	r, err := openGoGit(dir)
+	if err != nil {
+		return "", err
+	}
+	head, err := r.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", err
+	}
+	if head.Type() != plumbing.SymbolicReference {
+		return "", nil // detached
+	}
+	return head.Target().Short(), nil
+}
+
+// gogitUpstream returns the remote-tracking branch the current branch
+// follows, e.g. "origin/main".
+func gogitUpstream(r *git.Repository) (string, error) {
+	head, err := r.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", err
+	}
+	if head.Type() != plumbing.SymbolicReference {
+		return "", errors.New("HEAD is detached")
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		return "", err
+	}
+	b, ok := cfg.Branches[head.Target().Short()]
+	if !ok || b.Remote == "" || b.Merge == "" {
+		return "", fmt.Errorf("no upstream configured for branch %s", head.Target().Short())
+	}
+	return b.Remote + "/" + b.Merge.Short(), nil
+}
+
+// gogitResolve resolves the revisions aheadBehind receives: HEAD, branch or
+// remote-tracking branch names, hashes and @{upstream}.
+func gogitResolve(r *git.Repository, rev string) (plumbing.Hash, error) {
+	if rev == "@{upstream}" || rev == "@{u}" {
+		up, err := gogitUpstream(r)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		rev = up
+	}
+	h, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("%s: %v", rev, err)
+	}
+	return *h, nil
+}
+
+// reachable returns the commits reachable from h.
+func reachable(r *git.Repository, h plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	seen := map[plumbing.Hash]bool{}
+	c, err := r.CommitObject(h)
+	if err != nil {
+		return nil, err
+	}
+	err = object.NewCommitPreorderIter(c, seen, nil).ForEach(func(c *object.Commit) error {
+		seen[c.Hash] = true
+		return nil
+	})
+	return seen, err
+}
+
+func gogitAheadBehind(dir, left, right string) (ahead, behind int, err error) {
+	r, err := openGoGit(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	l, err := gogitResolve(r, left)
+	if err != nil {
+		return 0, 0, err
+	}
+	rt, err := gogitResolve(r, right)
+	if err != nil {
+		return 0, 0, err
+	}
+	fromLeft, err := reachable(r, l)
+	if err != nil {
+		return 0, 0, err
+	}
+	fromRight, err := reachable(r, rt)
+	if err != nil {
+		return 0, 0, err
+	}
+	for h := range fromLeft {
+		if !fromRight[h] {
+			ahead++
+		}
+	}
+	for h := range fromRight {
+		if !fromLeft[h] {
+			behind++
+		}
+	}
+	return ahead, behind, nil
+}
+
+func gogitState(ctx context.Context, dir string) (repoState, error) {
+	r, err := openGoGit(dir)
+	if err != nil {
+		return repoState{}, err
+	}
+	s := repoState{Repo: dir}
+	if s.Branch, err = gogitCurrentBranch(dir); err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return repoState{}, err
+	}
+	if up, err := gogitUpstream(r); err == nil {
+		s.Upstream = up
+		if s.Ahead, s.Behind, err = gogitAheadBehind(dir, "HEAD", up); err != nil {
+			s.Ahead, s.Behind = 0, 0 // upstream not fetched yet
+		}
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return repoState{}, err
+	}
+	status, err := wt.StatusWithOptions(git.StatusOptions{Strategy: git.Preload})
+	if err != nil {
+		return repoState{}, err
+	}
+	for _, fs := range status {
+		switch {
+		case fs.Staging == git.UpdatedButUnmerged || fs.Worktree == git.UpdatedButUnmerged:
+			s.Conflicts++
+		case fs.Worktree == git.Untracked:
+			s.Untracked++
+		default:
+			if fs.Staging != git.Unmodified {
+				s.Staged++
+			}
+			if fs.Worktree != git.Unmodified {
+				s.Unstaged++
+			}
+		}
+	}
+	return s, ctx.Err()
+}
+
+func gogitLastCommitTime(dir string) (time.Time, bool, error) {
+	r, err := openGoGit(dir)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	refs, err := r.Branches()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	var latest time.Time
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		c, err := r.CommitObject(ref.Hash())
+		if err != nil {
+			return err
+		}
+		if c.Committer.When.After(latest) {
+			latest = c.Committer.When
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return latest, !latest.IsZero(), nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&backend, "backend", backend, "answer read-only queries with git (spawn git) or go-git (in-process); defaults to $GITBATCH_BACKEND")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestGoGitBackendMatchesGit runs the read-only queries through both
+// backends and expects the same answers.
+func TestGoGitBackendMatchesGit(t *testing.T) {
+	ctx := context.Background()
+	bare := filepath.Join(t.TempDir(), "origin.git")
+	if out, err := exec.Command("git", "init", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare failed: %v, out=%s", err, out)
+	}
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+	for _, args := range [][]string{{"branch", "-M", "main"}, {"remote", "add", "origin", bare}, {"push", "-u", "origin", "main"}} {
+		if out, err := runGitCapture(ctx, repo, args...); err != nil {
+			t.Fatalf("git %v failed: %v, out=%s", args, err, out)
+		}
+	}
+	commitTestFile(t, repo, "b.txt", "b", "second")
+	commitTestFile(t, repo, "c.txt", "c", "third")
+	for name, content := range map[string]string{"a.txt": "changed", "b.txt": "staged", "new.txt": "new"} {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, err := runGitCapture(ctx, repo, "add", "b.txt"); err != nil {
+		t.Fatalf("git add failed: %v, out=%s", err, out)
+	}
+
+	type answers struct {
+		isRepo        bool
+		branch        string
+		ahead, behind int
+		state         repoState
+		lastCommitOK  bool
+	}
+	query := func(b string) answers {
+		t.Helper()
+		prev := backend
+		backend = b
+		defer func() { backend = prev }()
+		var a answers
+		var err error
+		a.isRepo = isGitRepo(repo)
+		if a.branch, err = currentBranch(ctx, repo); err != nil {
+			t.Fatalf("%s: currentBranch: %v", b, err)
+		}
+		if a.ahead, a.behind, err = aheadBehind(ctx, repo, "HEAD", "@{upstream}"); err != nil {
+			t.Fatalf("%s: aheadBehind: %v", b, err)
+		}
+		if a.state, err = readRepoState(ctx, repo); err != nil {
+			t.Fatalf("%s: readRepoState: %v", b, err)
+		}
+		if _, a.lastCommitOK, err = lastCommitTime(ctx, repo); err != nil {
+			t.Fatalf("%s: lastCommitTime: %v", b, err)
+		}
+		return a
+	}
+
+	want := query(backendGit)
+	got := query(backendGoGit)
+	if got != want {
+		t.Errorf("go-git backend = %+v\ngit backend    = %+v", got, want)
+	}
+	if want.ahead != 2 || want.state.Staged != 1 || want.state.Unstaged != 1 || want.state.Untracked != 1 {
+		t.Errorf("unexpected answers from git: %+v", want)
+	}
+
+	backend = backendGoGit
+	defer func() { backend = "" }()
+	if isGitRepo(t.TempDir()) {
+		t.Error("go-git backend detected a repository in an empty directory")
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// readAudit returns the records of the audit log at path, oldest first.
+// Lines that cannot be parsed are skipped with a warning.
+func readAudit(path string) ([]auditRecord, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []auditRecord
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; s.Scan(); n++ {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+		var rec auditRecord
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			fmt.Fprintf(os.Stderr, "%s:%d: skipping unreadable record: %v\n", path, n, err)
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, s.Err()
+}
+
+// historyFilter selects audit records.
+type historyFilter struct {
+	since   time.Time // zero for no limit
+	command string
+	repo    string // absolute path, "" for all
+	failed  bool
+}
+
+// match reports whether rec passes f and returns the repositories of rec
+// to show: all of them, or only f.repo.
+func (f historyFilter) match(rec auditRecord) ([]auditRepo, bool) {
+	if !f.since.IsZero() && rec.Time.Before(f.since) {
+		return nil, false
+	}
+	if f.command != "" && rec.Command != f.command && !strings.HasPrefix(rec.Command, f.command+" ") {
+		return nil, false
+	}
+	repos := rec.Repos
+	if f.repo != "" {
+		repos = nil
+		for _, r := range rec.Repos {
+			if r.Path == f.repo {
+				repos = append(repos, r)
+			}
+		}
+		if len(repos) == 0 {
+			return nil, false
+		}
+	}
+	if f.failed {
+		failed := rec.Error != ""
+		for _, r := range repos {
+			failed = failed || !r.OK
+		}
+		if !failed {
+			return nil, false
+		}
+	}
+	return repos, true
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 10 {
+		return sha[:10]
+	}
+	if sha == "" {
+		return "-"
+	}
+	return sha
+}
+
+// writeHistory prints records as a table, one row per run, or per
+// repository when filtering by repository.
+func writeHistory(w io.Writer, records []auditRecord, f historyFilter) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if f.repo != "" {
+		fmt.Fprintln(tw, "TIME\tUSER\tRESULT\tBEFORE\tAFTER\tCOMMAND")
+	} else {
+		fmt.Fprintln(tw, "TIME\tUSER\tRESULT\tCOMMAND")
+	}
+	for _, rec := range records {
+		repos, _ := f.match(rec)
+		when := rec.Time.Local().Format("2006-01-02 15:04:05")
+		command := "gitbatch " + shellJoin(rec.Args)
+		if f.repo != "" {
+			for _, r := range repos {
+				result := "ok"
+				if !r.OK {
+					result = "failed"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", when, rec.User, result, shortSHA(r.Before), shortSHA(r.After), command)
+			}
+			continue
+		}
+		ok := 0
+		for _, r := range rec.Repos {
+			if r.OK {
+				ok++
+			}
+		}
+		result := fmt.Sprintf("%d/%d ok", ok, len(rec.Repos))
+		if rec.Error != "" {
+			result = "error: " + firstLine(rec.Error)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", when, rec.User, result, command)
+	}
+	tw.Flush()
+}
+
+// history command
+var historySince ageValue
+var historyCommand string
+var historyRepo string
+var historyFailed bool
+var historyLimit int
+var historyJSON bool
+var historyCmd = &cobra.Command{
+	Use:   "history [--since 7d] [--command push] [--repo <path>]",
+	Short: "Show mutating gitbatch runs from the audit log",
+	Long: `history lists the runs recorded in the audit log, newest last: when, by
+whom, the command line and how many repositories succeeded. With --repo it
+shows one row per run that touched that repository, with HEAD before and
+after. --json prints the matching records as stored.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		path := auditLogPath(cfg)
+		if path == "" {
+			return errors.New("no audit log location: set audit.path in the config file")
+		}
+		records, err := readAudit(path)
+		if err != nil {
+			return err
+		}
+
+		f := historyFilter{command: historyCommand, failed: historyFailed}
+		if historySince > 0 {
+			f.since = time.Now().Add(-time.Duration(historySince))
+		}
+		if historyRepo != "" {
+			if f.repo, err = filepath.Abs(expandHome(historyRepo)); err != nil {
+				return err
+			}
+		}
+		var matched []auditRecord
+		for _, rec := range records {
+			if _, ok := f.match(rec); ok {
+				matched = append(matched, rec)
+			}
+		}
+		if historyLimit > 0 && len(matched) > historyLimit {
+			matched = matched[len(matched)-historyLimit:]
+		}
+
+		if historyJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if matched == nil {
+				matched = []auditRecord{}
+			}
+			return enc.Encode(matched)
+		}
+		if len(matched) == 0 {
+			fmt.Printf("no matching runs in %s\n", path)
+			return nil
+		}
+		writeHistory(os.Stdout, matched, f)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().Var(&historySince, "since", "only show runs newer than this (e.g. 7d, 12h)")
+	historyCmd.Flags().StringVar(&historyCommand, "command", "", "only show runs of this command (e.g. push, remote)")
+	historyCmd.Flags().StringVar(&historyRepo, "repo", "", "only show runs that touched this repository")
+	historyCmd.Flags().BoolVar(&historyFailed, "failed", false, "only show runs with a failure")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "show at most the last N runs (0 for all)")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "print the records as JSON")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryFilterMatch(t *testing.T) {
+	now := time.Now()
+	ok := auditRecord{Time: now.Add(-time.Hour), Command: "remote set-url", Repos: []auditRepo{{Path: "/src/api", OK: true}, {Path: "/src/web", OK: true}}}
+	failed := auditRecord{Time: now.Add(-48 * time.Hour), Command: "push", Repos: []auditRepo{{Path: "/src/api", OK: true}, {Path: "/src/web"}}}
+	refused := auditRecord{Time: now, Command: "pull", Error: "read-only mode", Repos: []auditRepo{}}
+
+	tests := []struct {
+		name  string
+		f     historyFilter
+		rec   auditRecord
+		match bool
+		repos int
+	}{
+		{"no filter", historyFilter{}, ok, true, 2},
+		{"since keeps newer", historyFilter{since: now.Add(-2 * time.Hour)}, ok, true, 2},
+		{"since drops older", historyFilter{since: now.Add(-2 * time.Hour)}, failed, false, 0},
+		{"command", historyFilter{command: "push"}, failed, true, 2},
+		{"command prefix of a subcommand", historyFilter{command: "remote"}, ok, true, 2},
+		{"command prefix of a word", historyFilter{command: "pu"}, failed, false, 0},
+		{"repo", historyFilter{repo: "/src/web"}, ok, true, 1},
+		{"repo not touched", historyFilter{repo: "/src/docs"}, ok, false, 0},
+		{"failed repository", historyFilter{failed: true}, failed, true, 2},
+		{"failed run", historyFilter{failed: true}, refused, true, 0},
+		{"failed drops successes", historyFilter{failed: true}, ok, false, 0},
+		{"failed in another repo", historyFilter{failed: true, repo: "/src/api"}, failed, false, 0},
+	}
+	for _, tt := range tests {
+		repos, match := tt.f.match(tt.rec)
+		if match != tt.match || len(repos) != tt.repos {
+			t.Errorf("%s: match = %v with %d repos, want %v with %d", tt.name, match, len(repos), tt.match, tt.repos)
+		}
+	}
+}
+
+func TestWriteHistory(t *testing.T) {
+	when := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	records := []auditRecord{
+		{Time: when, User: "ana", Command: "pull", Args: []string{"pull", "services/*"}, Repos: []auditRepo{{Path: "/src/api", OK: true, Before: "0123456789abcdef", After: "fedcba9876543210"}, {Path: "/src/web"}}},
+		{Time: when, User: "bo", Command: "push", Args: []string{"push", "api"}, Error: "policy refused push\nmore", Repos: []auditRepo{}},
+	}
+
+	var b bytes.Buffer
+	writeHistory(&b, records, historyFilter{})
+	out := b.String()
+	for _, want := range []string{"TIME", "2026-01-02 03:04:05", "1/2 ok", "gitbatch pull 'services/*'", "error: policy refused push  gitbatch push api"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("history lacks %q:\n%s", want, out)
+		}
+	}
+
+	b.Reset()
+	writeHistory(&b, records[:1], historyFilter{repo: "/src/api"})
+	out = b.String()
+	if !strings.Contains(out, "BEFORE") || !strings.Contains(out, "0123456789  fedcba9876") || strings.Contains(out, "/src/web") {
+		t.Errorf("history for one repository:\n%s", out)
+	}
+}
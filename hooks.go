@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// gitHookNames are the hooks git runs; other files in --source are ignored.
+var gitHookNames = map[string]bool{
+	"applypatch-msg": true, "pre-applypatch": true, "post-applypatch": true,
+	"pre-commit": true, "pre-merge-commit": true, "prepare-commit-msg": true,
+	"commit-msg": true, "post-commit": true, "pre-rebase": true,
+	"post-checkout": true, "post-merge": true, "pre-push": true,
+	"pre-auto-gc": true, "post-rewrite": true, "sendemail-validate": true,
+	"fsmonitor-watchman": true, "post-index-change": true, "reference-transaction": true,
+}
+
+// hookBackupSuffix is appended to an existing hook that install replaces.
+const hookBackupSuffix = ".gitbatch-orig"
+
+// sourceHooks reads the hook scripts in dir, keyed by hook name.
+func sourceHooks(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	hooks := map[string][]byte{}
+	for _, e := range entries {
+		if e.IsDir() || !gitHookNames[e.Name()] {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		hooks[e.Name()] = b
+	}
+	if len(hooks) == 0 {
+		return nil, fmt.Errorf("no git hook scripts (pre-commit, commit-msg, ...) found in %s", dir)
+	}
+	return hooks, nil
+}
+
+func sortedHookNames(hooks map[string][]byte) []string {
+	names := make([]string, 0, len(hooks))
+	for name := range hooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hooksDir returns the directory git reads hooks from in repo, honoring
+// core.hooksPath.
+func hooksDir(ctx context.Context, repo string) (string, error) {
+	p, err := gitOutput(ctx, repo, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(repo, p)
+	}
+	return p, nil
+}
+
+// inRepository reports whether dir, a hooks directory of repo, belongs to
+// that repository alone: it is inside its work tree or its git directory.
+// core.hooksPath can point anywhere, including a directory other
+// repositories share.
+func inRepository(ctx context.Context, repo, dir string) bool {
+	gitDir, err := gitOutput(ctx, repo, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(repo, gitDir)
+	}
+	return underAny(dir, []string{repo, gitDir})
+}
+
+// hookState compares an installed hook with its source version.
+func hookState(path string, want []byte) string {
+	got, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return "missing"
+	case err != nil:
+		return "error: " + err.Error()
+	case bytes.Equal(got, want):
+		return "installed"
+	default:
+		return "different"
+	}
+}
+
+// installHook writes content as hook name into dir, keeping a backup of a
+// different hook that was already there. When there is a backup already,
+// the hook is only replaced if it is the backup or force is set, so a hook
+// edited after an earlier install is not lost.
+func installHook(dir, name string, content []byte, force bool) (backedUp bool, err error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return false, err
+	}
+	path := filepath.Join(dir, name)
+	if hookState(path, content) == "different" {
+		backup, err := os.ReadFile(path + hookBackupSuffix)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			if err := os.Rename(path, path+hookBackupSuffix); err != nil {
+				return false, err
+			}
+			backedUp = true
+		case err != nil:
+			return false, err
+		case !force && hookState(path, backup) != "installed":
+			return false, fmt.Errorf("modified locally and %s%s exists, kept (use --force)", name, hookBackupSuffix)
+		}
+	}
+	return backedUp, os.WriteFile(path, content, 0o755)
+}
+
+// removeHook deletes hook name from dir if it matches content (or force is
+// set) and restores the backup made by installHook.
+func removeHook(dir, name string, content []byte, force bool) (string, error) {
+	path := filepath.Join(dir, name)
+	switch hookState(path, content) {
+	case "missing":
+		return "not installed", nil
+	case "different":
+		if !force {
+			return "modified locally, kept (use --force)", nil
+		}
+	}
+	if err := os.Remove(path); err != nil {
+		return "", err
+	}
+	if err := os.Rename(path+hookBackupSuffix, path); err == nil {
+		return "removed, previous hook restored", nil
+	}
+	return "removed", nil
+}
+
+// hooks command
+var hooksSource string
+var hooksUsePath bool
+var hooksForce bool
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Install, inspect or remove git hooks across repositories",
+	Long: `hooks rolls out the hook scripts found in --source (files named after git
+hooks, e.g. pre-commit) to every matching repository, either by copying
+them into the repository's hooks directory or, with install --hooks-path,
+by pointing core.hooksPath at the source directory.`,
+}
+
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install --source <dir> <pattern>...",
+	Short: "Install the hooks from --source",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src, hooks, err := loadSourceHooks()
+		if err != nil {
+			return err
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		for _, r := range repos {
+			repoHeader(r)
+			if hooksUsePath {
+				if err := gitChange(ctx, r, "config", "--local", "core.hooksPath", src); err != nil {
+					repoFailed(r, err)
+					continue
+				}
+				fmt.Printf("core.hooksPath = %s\n", src)
+				continue
+			}
+			dir, err := hooksDir(ctx, r)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			if !hooksForce && !inRepository(ctx, r, dir) {
+				reason := fmt.Sprintf("hooks directory %s is outside the repository", dir)
+				markRepoSkipped(r, reason)
+				fmt.Fprintf(os.Stderr, "%s %s: %s, probably shared through core.hooksPath (use --force to install there)\n", paint(os.Stderr, ansiYellow, "skipping"), r, reason)
+				continue
+			}
+			for _, name := range sortedHookNames(hooks) {
+				backedUp, err := installHook(dir, name, hooks[name], hooksForce)
+				if err != nil {
+					repoFailed(r, fmt.Errorf("%s: %v", name, err))
+					continue
+				}
+				if backedUp {
+					fmt.Printf("%s installed (previous hook saved as %s%s)\n", name, name, hookBackupSuffix)
+				} else {
+					fmt.Printf("%s installed\n", name)
+				}
+			}
+		}
+		return nil
+	},
+}
+
+var hooksStatusCmd = &cobra.Command{
+	Use:   "status --source <dir> <pattern>...",
+	Short: "Show whether each repository has the hooks from --source",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src, hooks, err := loadSourceHooks()
+		if err != nil {
+			return err
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPO\tHOOK\tSTATE")
+		for _, r := range repos {
+			if hp, _ := gitOutput(ctx, r, "config", "--get", "core.hooksPath"); hp != "" && filepath.Clean(expandHome(hp)) == src {
+				fmt.Fprintf(w, "%s\t*\tvia core.hooksPath\n", r)
+				continue
+			}
+			dir, err := hooksDir(ctx, r)
+			if err != nil {
+				fmt.Fprintf(w, "%s\t-\terror: %v\n", r, err)
+				continue
+			}
+			for _, name := range sortedHookNames(hooks) {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", r, name, hookState(filepath.Join(dir, name), hooks[name]))
+			}
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var hooksRemoveCmd = &cobra.Command{
+	Use:   "remove --source <dir> <pattern>...",
+	Short: "Remove the hooks from --source (or unset core.hooksPath pointing at it)",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src, hooks, err := loadSourceHooks()
+		if err != nil {
+			return err
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		for _, r := range repos {
+			repoHeader(r)
+			if hp, _ := gitOutput(ctx, r, "config", "--local", "--get", "core.hooksPath"); hp != "" && filepath.Clean(expandHome(hp)) == src {
+				if err := gitChange(ctx, r, "config", "--local", "--unset", "core.hooksPath"); err != nil {
+					repoFailed(r, err)
+				} else {
+					fmt.Println("core.hooksPath unset")
+				}
+				continue
+			}
+			dir, err := hooksDir(ctx, r)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			for _, name := range sortedHookNames(hooks) {
+				msg, err := removeHook(dir, name, hooks[name], hooksForce)
+				if err != nil {
+					repoFailed(r, fmt.Errorf("%s: %v", name, err))
+					continue
+				}
+				fmt.Printf("%s %s\n", name, msg)
+			}
+		}
+		return nil
+	},
+}
+
+// loadSourceHooks resolves --source to an absolute path and reads its hooks.
+func loadSourceHooks() (string, map[string][]byte, error) {
+	if hooksSource == "" {
+		return "", nil, usageErrorf("--source <dir> is required")
+	}
+	src, err := filepath.Abs(expandHome(hooksSource))
+	if err != nil {
+		return "", nil, err
+	}
+	hooks, err := sourceHooks(src)
+	if err != nil {
+		return "", nil, err
+	}
+	return src, hooks, nil
+}
+
+func init() {
+	rootCmd.AddCommand(hooksCmd)
+	hooksCmd.AddCommand(hooksInstallCmd)
+	hooksCmd.AddCommand(hooksStatusCmd)
+	hooksCmd.AddCommand(hooksRemoveCmd)
+
+	hooksCmd.PersistentFlags().StringVar(&hooksSource, "source", "", "directory containing the hook scripts")
+	hooksInstallCmd.Flags().BoolVar(&hooksUsePath, "hooks-path", false, "set core.hooksPath to --source instead of copying scripts")
+	hooksInstallCmd.Flags().BoolVar(&hooksForce, "force", false, "replace hooks modified after an earlier install, and install into hooks directories outside the repository")
+	hooksRemoveCmd.Flags().BoolVar(&hooksForce, "force", false, "remove hooks even if they were modified after installation")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstallAndRemoveHook(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+
+	src := t.TempDir()
+	script := []byte("#!/bin/sh\nexit 0\n")
+	for name, content := range map[string][]byte{"pre-commit": script, "README.md": []byte("docs")} {
+		if err := os.WriteFile(filepath.Join(src, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hooks, err := sourceHooks(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 1 {
+		t.Fatalf("expected only pre-commit to be picked up, got %v", sortedHookNames(hooks))
+	}
+
+	dir, err := hooksDir(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := filepath.Join(dir, "pre-commit")
+	if err := os.WriteFile(existing, []byte("#!/bin/sh\necho mine\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	backedUp, err := installHook(dir, "pre-commit", script, false)
+	if err != nil || !backedUp {
+		t.Fatalf("installHook = %v, %v; want backup of the existing hook", backedUp, err)
+	}
+	if state := hookState(existing, script); state != "installed" {
+		t.Errorf("expected installed hook, got %q", state)
+	}
+
+	msg, err := removeHook(dir, "pre-commit", script, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "removed, previous hook restored" {
+		t.Errorf("unexpected remove message %q", msg)
+	}
+	if b, _ := os.ReadFile(existing); string(b) != "#!/bin/sh\necho mine\n" {
+		t.Errorf("expected original hook to be restored, got %q", b)
+	}
+}
+
+func TestInstallHookKeepsEditedHook(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pre-commit")
+	v1, v2, edited := []byte("#!/bin/sh\nexit 0\n"), []byte("#!/bin/sh\nexit 1\n"), []byte("#!/bin/sh\necho edited\n")
+	for _, f := range []struct {
+		path    string
+		content []byte
+	}{{path, edited}, {path + hookBackupSuffix, []byte("#!/bin/sh\necho original\n")}} {
+		if err := os.WriteFile(f.path, f.content, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := installHook(dir, "pre-commit", v1, false); err == nil {
+		t.Error("edited hook replaced without --force")
+	}
+	if b, _ := os.ReadFile(path); string(b) != string(edited) {
+		t.Errorf("hook = %q, want the edited one kept", b)
+	}
+	if _, err := installHook(dir, "pre-commit", v1, true); err != nil {
+		t.Fatal(err)
+	}
+	// a hook that is the backup again may be replaced
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho original\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := installHook(dir, "pre-commit", v2, false); err != nil {
+		t.Errorf("hook identical to its backup refused: %v", err)
+	}
+}
+
+func TestInRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	dir, err := hooksDir(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inRepository(ctx, repo, dir) {
+		t.Errorf("%s not in %s", dir, repo)
+	}
+	shared := t.TempDir()
+	if err := gitChange(ctx, repo, "config", "core.hooksPath", shared); err != nil {
+		t.Fatal(err)
+	}
+	if dir, _ = hooksDir(ctx, repo); inRepository(ctx, repo, dir) {
+		t.Errorf("shared hooks directory %s counted as part of %s", dir, repo)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// maxPerHost is --max-per-host: the number of network commands that may run
+// against one remote host at the same time. 0 means no limit.
+var maxPerHost int
+
+// hostLimiter hands out a fixed number of slots per host.
+type hostLimiter struct {
+	mu    sync.Mutex
+	limit int
+	slots map[string]chan struct{}
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, slots: map[string]chan struct{}{}}
+}
+
+// acquire blocks until a slot for host is free and returns the function
+// that gives it back.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (release func(), err error) {
+	l.mu.Lock()
+	slot, ok := l.slots[host]
+	if !ok {
+		slot = make(chan struct{}, l.limit)
+		l.slots[host] = slot
+	}
+	l.mu.Unlock()
+	select {
+	case slot <- struct{}{}:
+		return func() { <-slot }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// networkHost returns the host of a remote URL, or "" for local paths and
+// file URLs, which are never limited.
+func networkHost(remote string) string {
+	if remote == "" || strings.HasPrefix(remote, "/") || strings.HasPrefix(remote, ".") || strings.HasPrefix(remote, "file://") {
+		return ""
+	}
+	if !strings.Contains(remote, "://") && !strings.Contains(remote, ":") {
+		return "" // relative path
+	}
+	return strings.ToLower(remoteHost(remote))
+}
+
+// hostLimitExecutor wraps the git executor so that network commands wait
+// for a slot on the host of the remote they talk to.
+type hostLimitExecutor struct {
+	next    runner.GitExecutor
+	limiter *hostLimiter
+}
+
+// defaultRemote returns the remote that pull, push and fetch use in dir:
+// the current branch's remote, or origin.
+func defaultRemote(ctx context.Context, exe runner.GitExecutor, dir string) string {
+	if branch, err := exe.Output(ctx, runner.Git(dir, "symbolic-ref", "--quiet", "--short", "HEAD")); err == nil {
+		if r, err := exe.Output(ctx, runner.Git(dir, "config", "--get", "branch."+branch+".remote")); err == nil && r != "" && r != "." {
+			return r
+		}
+	}
+	return "origin"
+}
+
+// valueFlags are the pull, push, fetch and clone options that take their
+// value as the next argument, so that value is not mistaken for the remote.
+var valueFlags = map[string]bool{
+	"-o": true, "--push-option": true, "--server-option": true, "--origin": true,
+	"-b": true, "--branch": true, "-c": true, "--config": true,
+	"-j": true, "--jobs": true, "--depth": true, "--deepen": true,
+	"--shallow-since": true, "--shallow-exclude": true, "--reference": true,
+	"--separate-git-dir": true, "--upload-pack": true, "--receive-pack": true,
+	"--exec": true, "--negotiation-tip": true, "--refmap": true,
+	"-s": true, "--strategy": true, "-X": true, "--strategy-option": true,
+}
+
+// remoteArg returns the remote a pull, push, fetch or clone command names:
+// --repo, or the first argument that is not an option. It returns "" when
+// the command relies on the default remote.
+func remoteArg(args []string) string {
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+			return ""
+		case a == "--repo" && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(a, "--repo="):
+			return strings.TrimPrefix(a, "--repo=")
+		case valueFlags[a]:
+			i++
+		case strings.HasPrefix(a, "-"):
+		default:
+			return a
+		}
+	}
+	return ""
+}
+
+// host returns the host of the remote c talks to: the remote or URL named
+// in its arguments, or else the default remote of its directory.
+func (e hostLimitExecutor) host(ctx context.Context, c runner.Cmd) string {
+	remote := remoteArg(c.Args)
+	if remote == "" {
+		remote = defaultRemote(ctx, e.next, c.Dir)
+	}
+	if u, err := e.next.Output(ctx, runner.Git(c.Dir, "remote", "get-url", remote)); err == nil {
+		return networkHost(u)
+	}
+	// Not a configured remote: git takes it as a URL or path.
+	return networkHost(remote)
+}
+
+// wait acquires a host slot for network commands; release is a no-op for
+// everything else.
+func (e hostLimitExecutor) wait(ctx context.Context, c runner.Cmd) (release func(), err error) {
+	if len(c.Args) == 0 || !retriedCommands[c.Args[0]] || c.Dir == "" {
+		return func() {}, nil
+	}
+	host := e.host(ctx, c)
+	if host == "" {
+		return func() {}, nil
+	}
+	return e.limiter.acquire(ctx, host)
+}
+
+func (e hostLimitExecutor) Stream(ctx context.Context, c runner.Cmd) error {
+	release, err := e.wait(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return e.next.Stream(ctx, c)
+}
+
+func (e hostLimitExecutor) Capture(ctx context.Context, c runner.Cmd) (string, error) {
+	release, err := e.wait(ctx, c)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return e.next.Capture(ctx, c)
+}
+
+func (e hostLimitExecutor) Output(ctx context.Context, c runner.Cmd) (string, error) {
+	release, err := e.wait(ctx, c)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return e.next.Output(ctx, c)
+}
+
+func (e hostLimitExecutor) Lines(ctx context.Context, c runner.Cmd, fn func(string)) error {
+	release, err := e.wait(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return e.next.Lines(ctx, c, fn)
+}
+
+// setupHostLimit routes git through hostLimitExecutor when --max-per-host
+// is set.
+func setupHostLimit() error {
+	if maxPerHost < 0 {
+		return usageErrorf("--max-per-host must not be negative")
+	}
+	if maxPerHost > 0 {
+		gitExec = hostLimitExecutor{next: gitExec, limiter: newHostLimiter(maxPerHost)}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().IntVar(&maxPerHost, "max-per-host", 0, "with --jobs, run at most N pulls, pushes or fetches against the same remote host at once (0 = no limit)")
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+func TestNetworkHost(t *testing.T) {
+	cases := map[string]string{
+		"git@github.com:org/repo.git":        "github.com",
+		"https://GitLab.example.com/g/r.git": "gitlab.example.com",
+		"ssh://git@host:2222/r.git":          "host",
+		"/srv/git/r.git":                     "",
+		"../r.git":                           "",
+		"file:///srv/git/r.git":              "",
+	}
+	for in, want := range cases {
+		if got := networkHost(in); got != want {
+			t.Errorf("networkHost(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// slowExecutor counts concurrent pushes.
+type slowExecutor struct {
+	runner.Mock
+	running, peak atomic.Int32
+}
+
+func (s *slowExecutor) Capture(ctx context.Context, c runner.Cmd) (string, error) {
+	n := s.running.Add(1)
+	for {
+		p := s.peak.Load()
+		if n <= p || s.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	s.running.Add(-1)
+	return "", nil
+}
+
+func (s *slowExecutor) Output(ctx context.Context, c runner.Cmd) (string, error) {
+	if c.Args[0] == "remote" {
+		return "git@github.com:org/repo.git", nil
+	}
+	return s.Mock.Output(ctx, c)
+}
+
+func TestHostLimitExecutor(t *testing.T) {
+	slow := &slowExecutor{}
+	exe := hostLimitExecutor{next: slow, limiter: newHostLimiter(2)}
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			exe.Capture(context.Background(), runner.Git("/r", "push"))
+		}()
+	}
+	wg.Wait()
+	if peak := slow.peak.Load(); peak != 2 {
+		t.Errorf("expected at most 2 concurrent pushes to one host, saw %d", peak)
+	}
+}
+
+func TestRemoteArg(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"push"}, ""},
+		{[]string{"push", "--mirror", "backup"}, "backup"},
+		{[]string{"push", "-o", "ci.skip", "upstream", "main"}, "upstream"},
+		{[]string{"push", "--repo=backup"}, "backup"},
+		{[]string{"fetch", "--all", "--prune"}, ""},
+		{[]string{"fetch", "--depth", "1", "git@gitlab.com:g/r.git"}, "git@gitlab.com:g/r.git"},
+		{[]string{"pull", "--ff-only", "--", "origin"}, "origin"},
+	}
+	for _, c := range cases {
+		if got := remoteArg(c.args); got != c.want {
+			t.Errorf("remoteArg(%q) = %q, want %q", c.args, got, c.want)
+		}
+	}
+}
+
+// remotesExecutor knows the URLs of a fixed set of remotes.
+type remotesExecutor struct {
+	runner.Mock
+	urls map[string]string
+}
+
+func (r *remotesExecutor) Output(ctx context.Context, c runner.Cmd) (string, error) {
+	if c.Args[0] == "remote" {
+		if u, ok := r.urls[c.Args[2]]; ok {
+			return u, nil
+		}
+		return "", errors.New("no such remote")
+	}
+	return "", errors.New("not on a branch")
+}
+
+func TestHostLimitExecutorHost(t *testing.T) {
+	exe := hostLimitExecutor{next: &remotesExecutor{urls: map[string]string{
+		"origin": "git@github.com:org/repo.git",
+		"backup": "https://backup.example.com/org/repo.git",
+	}}}
+	cases := map[string][]string{
+		"github.com":         {"push"},
+		"backup.example.com": {"push", "--mirror", "backup"},
+		"gitlab.com":         {"fetch", "git@gitlab.com:g/r.git"},
+		"":                   {"push", "../local.git"},
+	}
+	for want, args := range cases {
+		if got := exe.host(context.Background(), runner.Git("/r", args...)); got != want {
+			t.Errorf("host(%q) = %q, want %q", args, got, want)
+		}
+	}
+}
+
+func TestSetupHostLimitNegative(t *testing.T) {
+	old := maxPerHost
+	t.Cleanup(func() { maxPerHost = old })
+	maxPerHost = -1
+	if err := setupHostLimit(); exitCode(err) != exitUsage {
+		t.Errorf("--max-per-host -1: exit %d (%v), want %d", exitCode(err), err, exitUsage)
+	}
+}
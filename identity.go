@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// Identity is a named set of author, SSH and signing settings, declared in
+// the config file as
+//
+//	[identity.work]
+//	name = "Jane Doe"
+//	email = "jane@corp.example"
+//	ssh_key = "~/.ssh/id_work"
+//	signing_key = "ABCD1234"
+//	signing_format = "openpgp" # or "ssh" with signing_key set to a key path
+type Identity struct {
+	Name          string `toml:"name"`
+	Email         string `toml:"email"`
+	SSHKey        string `toml:"ssh_key"`
+	SigningKey    string `toml:"signing_key"`
+	SigningFormat string `toml:"signing_format"`
+}
+
+// identitySetting is a git config key an identity controls.
+type identitySetting struct {
+	key, value string
+}
+
+// settings returns the git config values id sets, in a stable order. Empty
+// fields are left out so a profile can manage only some of them.
+func (id Identity) settings() []identitySetting {
+	var s []identitySetting
+	if id.Name != "" {
+		s = append(s, identitySetting{"user.name", id.Name})
+	}
+	if id.Email != "" {
+		s = append(s, identitySetting{"user.email", id.Email})
+	}
+	if id.SSHKey != "" {
+		s = append(s, identitySetting{"core.sshCommand", "ssh -i " + shellQuote(expandHome(id.SSHKey)) + " -o IdentitiesOnly=yes"})
+	}
+	if id.SigningKey != "" {
+		s = append(s, identitySetting{"user.signingkey", id.SigningKey})
+	}
+	if id.SigningFormat != "" {
+		s = append(s, identitySetting{"gpg.format", id.SigningFormat})
+	}
+	return s
+}
+
+// lookupIdentity returns the named profile from the config file.
+func lookupIdentity(name string) (Identity, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return Identity{}, err
+	}
+	id, ok := cfg.Identities[name]
+	if !ok {
+		return Identity{}, fmt.Errorf("unknown identity %q (known: %s)", name, strings.Join(identityNames(cfg), ", "))
+	}
+	return id, nil
+}
+
+func identityNames(cfg *Config) []string {
+	names := make([]string, 0, len(cfg.Identities))
+	for name := range cfg.Identities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// identityMismatches returns the settings of id whose effective value in dir
+// differs, formatted as "key=actual".
+func identityMismatches(ctx context.Context, dir string, id Identity) ([]string, error) {
+	var wrong []string
+	for _, s := range id.settings() {
+		cv, err := readConfigValue(ctx, dir, s.key)
+		if err != nil {
+			return nil, err
+		}
+		if cv.value != s.value {
+			actual := cv.value
+			if !cv.set {
+				actual = "(unset)"
+			}
+			wrong = append(wrong, s.key+"="+actual)
+		}
+	}
+	return wrong, nil
+}
+
+// identity command
+var identityAuditProfile string
+var identityCmd = &cobra.Command{
+	Use:   "identity",
+	Short: "Apply or audit identity profiles (name, email, SSH key, signing key)",
+	Long: `identity manages the author identity of repositories using the profiles
+declared under [identity.<name>] in the config file.`,
+}
+
+var identityListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the identity profiles from the config file",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if len(cfg.Identities) == 0 {
+			return errors.New("no identities configured: add [identity.<name>] sections to the config file")
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROFILE\tNAME\tEMAIL\tSSH KEY\tSIGNING KEY")
+		for _, name := range identityNames(cfg) {
+			id := cfg.Identities[name]
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, id.Name, id.Email, id.SSHKey, id.SigningKey)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var identityApplyCmd = &cobra.Command{
+	Use:   "apply <profile> <pattern>...",
+	Short: "Write an identity profile into each repository's local git config",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := lookupIdentity(args[0])
+		if err != nil {
+			return err
+		}
+		repos, err := collectRepos(args[1:])
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		for _, r := range repos {
+			repoHeader(r)
+			for _, s := range id.settings() {
+				if err := gitChange(ctx, r, "config", "--local", s.key, s.value); err != nil {
+					repoFailed(r, fmt.Errorf("setting %s: %v", s.key, err))
+					continue
+				}
+				fmt.Printf("%s = %s\n", s.key, s.value)
+			}
+		}
+		return nil
+	},
+}
+
+var identityAuditCmd = &cobra.Command{
+	Use:   "audit [--profile <name>] <pattern>...",
+	Short: "Show which identity each repository uses and flag the wrong ones",
+	Long: `audit matches each repository's effective user.email against the
+configured profiles. With --profile every repository is expected to use that
+profile, and each setting that differs is reported.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		var expected Identity
+		if identityAuditProfile != "" {
+			if expected, err = lookupIdentity(identityAuditProfile); err != nil {
+				return err
+			}
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		flagged := 0
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPO\tEMAIL\tPROFILE\tPROBLEM")
+		for _, r := range repos {
+			email, _ := readConfigValue(ctx, r, "user.email")
+			profile := "-"
+			for _, name := range identityNames(cfg) {
+				if email.set && cfg.Identities[name].Email == email.value {
+					profile = name
+					break
+				}
+			}
+			problem := ""
+			if identityAuditProfile != "" {
+				wrong, err := identityMismatches(ctx, r, expected)
+				if err != nil {
+					problem = err.Error()
+				} else if len(wrong) > 0 {
+					problem = "differs: " + strings.Join(wrong, ", ")
+				}
+			} else if profile == "-" {
+				problem = "no matching profile"
+			}
+			if problem != "" {
+				flagged++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r, email.value, profile, problem)
+		}
+		w.Flush()
+		if flagged > 0 {
+			fmt.Printf("\n%d of %d repositories use the wrong identity\n", flagged, len(repos))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(identityCmd)
+	identityCmd.AddCommand(identityListCmd)
+	identityCmd.AddCommand(identityApplyCmd)
+	identityCmd.AddCommand(identityAuditCmd)
+
+	identityAuditCmd.Flags().StringVar(&identityAuditProfile, "profile", "", "identity profile every repository should use")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useTestConfig points loadConfig at a config file with the given contents
+// for the duration of the test.
+func useTestConfig(t *testing.T, contents string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	oldPath, oldCfg := configPath, loadedConfig
+	configPath, loadedConfig = path, nil
+	t.Cleanup(func() { configPath, loadedConfig = oldPath, oldCfg })
+}
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	useTestConfig(t, "[identity.work]\nemial = \"typo@example.com\"\n")
+	if _, err := loadConfig(); err == nil {
+		t.Errorf("expected unknown key to be rejected")
+	}
+}
+
+func TestIdentityApplyAndAudit(t *testing.T) {
+	useTestConfig(t, `
+[identity.work]
+name = "Jane Work"
+email = "jane@corp.example"
+ssh_key = "/keys/id work"
+`)
+	id, err := lookupIdentity("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lookupIdentity("personal"); err == nil {
+		t.Errorf("expected error for unknown profile")
+	}
+
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	wrong, err := identityMismatches(ctx, repo, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wrong) != 3 {
+		t.Errorf("expected name, email and sshCommand to differ, got %v", wrong)
+	}
+
+	for _, s := range id.settings() {
+		if _, err := gitOutput(ctx, repo, "config", "--local", s.key, s.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if wrong, err := identityMismatches(ctx, repo, id); err != nil || len(wrong) != 0 {
+		t.Errorf("expected no mismatches after applying, got %v, %v", wrong, err)
+	}
+	if got, _ := gitOutput(ctx, repo, "config", "core.sshCommand"); got != "ssh -i '/keys/id work' -o IdentitiesOnly=yes" {
+		t.Errorf("unexpected core.sshCommand %q", got)
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+)
+
+// importedRepo is one repository read from another tool's configuration,
+// written out as a [[repo]] entry of the config file.
+type importedRepo struct {
+	Path string   `toml:"path"`
+	Tags []string `toml:"tags,omitempty"`
+}
+
+// importSource reads the repository list of one tool. parse gets the file
+// and the directory relative paths in it are resolved against.
+type importSource struct {
+	tag   string                 // added to every imported repository
+	auto  func() (string, error) // default file, for "auto"
+	parse func(io.Reader, string) ([]importedRepo, error)
+}
+
+var importSources = map[string]importSource{
+	"mrconfig":      {tag: "from:mr", auto: homeFile(".mrconfig"), parse: parseMrconfig},
+	"gita":          {tag: "from:gita", auto: gitaReposFile, parse: parseGita},
+	"ghq":           {tag: "from:ghq", parse: parseGhq},
+	"repo-manifest": {tag: "from:repo", auto: repoManifestFile, parse: parseRepoManifest},
+}
+
+func homeFile(name string) func() (string, error) {
+	return func() (string, error) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, name), nil
+	}
+}
+
+// parseMrconfig reads a myrepos ~/.mrconfig. Every section except DEFAULT
+// names a repository, relative to the directory of the file.
+func parseMrconfig(r io.Reader, base string) ([]importedRepo, error) {
+	var repos []importedRepo
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+			continue
+		}
+		name := strings.TrimSpace(line[1 : len(line)-1])
+		if name == "" || name == "DEFAULT" {
+			continue
+		}
+		repos = append(repos, importedRepo{Path: resolveImportPath(base, name)})
+	}
+	return repos, sc.Err()
+}
+
+// gitaReposFile is gita's repos.csv in its config directory.
+func gitaReposFile() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "gita", "repos.csv"), nil
+}
+
+// parseGita reads gita's repos.csv (path,name,...) and, from the same
+// directory, groups.csv (group:repo repo ... or group:path:repo repo ...).
+// Groups become group:<name> tags.
+func parseGita(r io.Reader, base string) ([]importedRepo, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	groups, err := readGitaGroups(filepath.Join(base, "groups.csv"))
+	if err != nil {
+		return nil, err
+	}
+	var repos []importedRepo
+	for _, rec := range records {
+		if len(rec) == 0 || rec[0] == "" {
+			continue
+		}
+		name := filepath.Base(rec[0])
+		if len(rec) > 1 && rec[1] != "" {
+			name = rec[1]
+		}
+		repos = append(repos, importedRepo{Path: resolveImportPath(base, rec[0]), Tags: groups[name]})
+	}
+	return repos, nil
+}
+
+// readGitaGroups maps repository names to group:<name> tags. A missing
+// file means no groups.
+func readGitaGroups(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	groups := map[string][]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) < 2 {
+			continue
+		}
+		group := strings.TrimSpace(fields[0])
+		for _, name := range strings.Fields(fields[len(fields)-1]) {
+			groups[name] = append(groups[name], "group:"+group)
+		}
+	}
+	return groups, nil
+}
+
+// parseGhq reads the output of ghq list --full-path, one repository per
+// line.
+func parseGhq(r io.Reader, base string) ([]importedRepo, error) {
+	var repos []importedRepo
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			repos = append(repos, importedRepo{Path: resolveImportPath(base, line)})
+		}
+	}
+	return repos, sc.Err()
+}
+
+// repoManifestFile is .repo/manifest.xml of the repo client in the current
+// directory.
+func repoManifestFile() (string, error) {
+	return filepath.Abs(filepath.Join(".repo", "manifest.xml"))
+}
+
+type repoManifest struct {
+	Includes []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"include"`
+	Projects []struct {
+		Name   string `xml:"name,attr"`
+		Path   string `xml:"path,attr"`
+		Groups string `xml:"groups,attr"`
+	} `xml:"project"`
+}
+
+// parseRepoManifest reads a manifest of Google's repo tool. Projects are
+// checked out relative to the client root, the parent of .repo; their
+// groups become group:<name> tags. Includes are looked up in
+// .repo/manifests.
+func parseRepoManifest(r io.Reader, base string) ([]importedRepo, error) {
+	root := base
+	if filepath.Base(base) == "manifests" {
+		root = filepath.Dir(base)
+	}
+	if filepath.Base(root) == ".repo" {
+		root = filepath.Dir(root)
+	}
+	return readRepoManifest(r, root, filepath.Join(root, ".repo", "manifests"), map[string]bool{})
+}
+
+func readRepoManifest(r io.Reader, root, manifests string, seen map[string]bool) ([]importedRepo, error) {
+	var m repoManifest
+	if err := xml.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	var repos []importedRepo
+	for _, inc := range m.Includes {
+		file := filepath.Join(manifests, inc.Name)
+		if seen[file] {
+			continue
+		}
+		seen[file] = true
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %v", inc.Name, err)
+		}
+		included, err := readRepoManifest(f, root, manifests, seen)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %v", inc.Name, err)
+		}
+		repos = append(repos, included...)
+	}
+	for _, p := range m.Projects {
+		path := p.Path
+		if path == "" {
+			path = p.Name
+		}
+		var tags []string
+		for _, g := range strings.FieldsFunc(p.Groups, func(r rune) bool { return r == ',' || r == ' ' }) {
+			tags = append(tags, "group:"+g)
+		}
+		repos = append(repos, importedRepo{Path: resolveImportPath(root, path), Tags: tags})
+	}
+	return repos, nil
+}
+
+// resolveImportPath makes path absolute against base, expanding ~/.
+func resolveImportPath(base, path string) string {
+	path = expandHome(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	return filepath.Clean(path)
+}
+
+// readImport reads the repository list of src from file, "auto" for the
+// tool's default location, or "-" for standard input. It returns the
+// repositories and a description of where they came from.
+func readImport(ctx context.Context, src importSource, file string) ([]importedRepo, string, error) {
+	base, err := os.Getwd()
+	if err != nil {
+		return nil, "", err
+	}
+	var r io.Reader
+	switch {
+	case file == "-":
+		r, file = os.Stdin, "standard input"
+	case file == "auto" && src.auto == nil:
+		// ghq keeps no list of its own
+		out, err := exec.CommandContext(ctx, "ghq", "list", "--full-path").Output()
+		if err != nil {
+			return nil, "", fmt.Errorf("ghq list: %v", err)
+		}
+		r, file = bytes.NewReader(out), "ghq list --full-path"
+	default:
+		if file == "auto" {
+			if file, err = src.auto(); err != nil {
+				return nil, "", err
+			}
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+		r = f
+		if abs, err := filepath.Abs(file); err == nil {
+			base = filepath.Dir(abs)
+		}
+	}
+	repos, err := src.parse(r, base)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %v", file, err)
+	}
+	for i := range repos {
+		repos[i].Tags = append(repos[i].Tags, src.tag)
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Path < repos[j].Path })
+	return repos, file, nil
+}
+
+// writeImport writes repos as [[repo]] entries of the config file.
+func writeImport(w io.Writer, from string, repos []importedRepo) error {
+	fmt.Fprintf(w, "# imported from %s\n", from)
+	enc := toml.NewEncoder(w)
+	enc.Indent = ""
+	return enc.Encode(struct {
+		Repos []importedRepo `toml:"repo"`
+	}{repos})
+}
+
+// import command
+var importFrom string
+var importCmd = &cobra.Command{
+	Use:   "import --from mrconfig|gita|ghq|repo-manifest [<file>|auto|-]",
+	Short: "Convert the repository list of another multi-repo tool to config entries",
+	Long: `import reads the repositories known to myrepos, gita, ghq or Google's repo
+tool and prints them as [[repo]] entries for the gitbatch config file. Each
+entry has the repository's absolute path and a from:<tool> tag; gita and
+repo groups become group:<name> tags. Append the output to the config file
+and select the repositories with --tag:
+
+  gitbatch import --from gita >> ~/.config/gitbatch/config.toml
+  gitbatch --tag from:gita status "**"
+
+The file defaults to "auto", the tool's usual location: ~/.mrconfig, gita's
+repos.csv, the output of ghq list --full-path, or .repo/manifest.xml in the
+current directory. "-" reads standard input. Repositories are not cloned;
+the listed paths are only labelled.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src, ok := importSources[importFrom]
+		if !ok {
+			return usageErrorf("invalid --from %q (expected mrconfig, gita, ghq or repo-manifest)", importFrom)
+		}
+		file := "auto"
+		if len(args) == 1 {
+			file = args[0]
+		}
+		ctx, cancel := commandContext(context.Background())
+		defer cancel()
+
+		repos, from, err := readImport(ctx, src, file)
+		if err != nil {
+			return err
+		}
+		if len(repos) == 0 {
+			return noReposErrorf("no repositories found in %s", from)
+		}
+		for _, r := range repos {
+			if _, err := os.Stat(r.Path); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %s does not exist\n", r.Path)
+			}
+		}
+		return writeImport(os.Stdout, from, repos)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVar(&importFrom, "from", "", "tool to import from: mrconfig, gita, ghq or repo-manifest (required)")
+	importCmd.MarkFlagRequired("from")
+}
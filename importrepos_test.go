@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestImportSources(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		from, file string
+		want       []importedRepo
+	}{
+		{"mrconfig", write(".mrconfig", "[DEFAULT]\ngit_gc = git gc\n\n[src/api]\ncheckout = git clone 'git@example.com:api.git' 'api'\n\n[/opt/tools]\ncheckout = git clone x tools\n"), []importedRepo{
+			{Path: "/opt/tools", Tags: []string{"from:mr"}},
+			{Path: filepath.Join(dir, "src/api"), Tags: []string{"from:mr"}},
+		}},
+		{"gita", write("gita/repos.csv", "/work/api,api,,\n/work/web,frontend,,\n/work/docs,,,\n"), []importedRepo{
+			{Path: "/work/api", Tags: []string{"group:backend", "from:gita"}},
+			{Path: "/work/docs", Tags: []string{"group:backend", "group:all", "from:gita"}},
+			{Path: "/work/web", Tags: []string{"group:all", "from:gita"}},
+		}},
+		{"ghq", write("ghq.txt", "/home/me/ghq/github.com/a/b\n\n/home/me/ghq/github.com/c/d\n"), []importedRepo{
+			{Path: "/home/me/ghq/github.com/a/b", Tags: []string{"from:ghq"}},
+			{Path: "/home/me/ghq/github.com/c/d", Tags: []string{"from:ghq"}},
+		}},
+		{"repo-manifest", write("client/.repo/manifest.xml", `<manifest><include name="default.xml"/></manifest>`), []importedRepo{
+			{Path: filepath.Join(dir, "client/build"), Tags: []string{"group:pdk", "group:tools", "from:repo"}},
+			{Path: filepath.Join(dir, "client/platform/core"), Tags: []string{"from:repo"}},
+		}},
+	}
+	write("gita/groups.csv", "backend:api docs\nall:/work:frontend docs\n")
+	write("client/.repo/manifests/default.xml", `<manifest>
+  <remote name="aosp" fetch=".."/>
+  <project name="platform/core"/>
+  <project name="platform/build" path="build" groups="pdk,tools"/>
+</manifest>`)
+
+	for _, tt := range tests {
+		repos, _, err := readImport(ctx, importSources[tt.from], tt.file)
+		if err != nil {
+			t.Errorf("%s: %v", tt.from, err)
+			continue
+		}
+		if !reflect.DeepEqual(repos, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.from, repos, tt.want)
+		}
+	}
+}
+
+func TestWriteImport(t *testing.T) {
+	var buf bytes.Buffer
+	repos := []importedRepo{{Path: `/work/my "api"`, Tags: []string{"from:gita"}}}
+	if err := writeImport(&buf, "repos.csv", repos); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "# imported from repos.csv\n") {
+		t.Errorf("missing header:\n%s", buf.String())
+	}
+	var cfg Config
+	if _, err := toml.Decode(buf.String(), &cfg); err != nil {
+		t.Fatalf("output is not a valid config: %v\n%s", err, buf.String())
+	}
+	if len(cfg.Repos) != 1 || cfg.Repos[0].Path != repos[0].Path || !reflect.DeepEqual(cfg.Repos[0].Tags, repos[0].Tags) {
+		t.Errorf("decoded %+v", cfg.Repos)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// pullRange is what pull would bring in: the upstream commits HEAD lacks.
+var pullRange = upstreamRange{from: "HEAD", to: "@{u}", verb: "pull from"}
+
+// fetchUpstream fetches the remote of repo's upstream and reports whether
+// the upstream was rewritten: its tip before the fetch is no longer part of
+// its history. Repositories whose upstream is missing or a local branch are
+// left alone.
+func fetchUpstream(ctx context.Context, repo string) (forced bool, err error) {
+	upstream, err := gitOutput(ctx, repo, "rev-parse", "--abbrev-ref", "@{u}")
+	if err != nil {
+		return false, nil
+	}
+	remote := refRemote(ctx, repo, upstream)
+	if remote == "" {
+		return false, nil
+	}
+	old, _ := gitOutput(ctx, repo, "rev-parse", "@{u}")
+	if _, err := runGitCapture(ctx, repo, "fetch", "--quiet", remote); err != nil {
+		return false, fmt.Errorf("fetching %s: %v", remote, err)
+	}
+	tip, err := gitOutput(ctx, repo, "rev-parse", "@{u}")
+	if err != nil || old == "" || tip == old {
+		return false, nil
+	}
+	return !isAncestor(ctx, repo, old, tip), nil
+}
+
+// incoming command
+var incomingLimit int
+var incomingNoFetch bool
+var incomingCmd = &cobra.Command{
+	Use:   "incoming [-n 10] [--no-fetch] <pattern>...",
+	Short: "Fetch and show the commits and diffstat that pull would bring into matching repositories",
+	Long: `incoming fetches the remote of each matching repository's upstream and
+prints the commits pull would bring in (git log HEAD..@{u}), newest -n
+first, and the diffstat of their changes. Repositories with nothing to pull
+are left out, and those without an upstream are skipped.
+
+An upstream whose previous tip is no longer in its history after the fetch
+was force-pushed; pulling it would bring back or conflict with the commits
+that were dropped. Such repositories are listed at the end and count as
+failed. --no-fetch compares against the upstream as last fetched, which
+cannot tell.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if incomingLimit < 0 {
+			return usageErrorf("-n must not be negative")
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		fetched := repos
+		var forced []string
+		if !incomingNoFetch {
+			fetched = nil
+			for _, r := range repos {
+				f, err := fetchUpstream(ctx, r)
+				if err != nil {
+					repoFailed(r, err)
+					continue
+				}
+				if f {
+					forced = append(forced, r)
+					markRepoFailed(r)
+				}
+				fetched = append(fetched, r)
+			}
+		}
+		total, with := showUpstreamRange(ctx, fetched, pullRange, incomingLimit)
+		fmt.Printf("\n%d commits to pull in %d of %d repositories\n", total, with, len(repos))
+		if len(forced) > 0 {
+			fmt.Printf("\n%s\n", paint(os.Stdout, ansiRed, fmt.Sprintf("%d upstreams were force-pushed since the last fetch:", len(forced))))
+			for _, r := range forced {
+				fmt.Printf("  %s\n", r)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(incomingCmd)
+
+	incomingCmd.Flags().IntVarP(&incomingLimit, "max-count", "n", 10, "commits to list per repository (0 = all)")
+	incomingCmd.Flags().BoolVar(&incomingNoFetch, "no-fetch", false, "compare against the upstream as last fetched")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestIncoming(t *testing.T) {
+	ctx := context.Background()
+	upstream := initTestRepo(t)
+	commitTestFile(t, upstream, "a.txt", "a", "first")
+	behind, even := cloneTestRepo(t, upstream), cloneTestRepo(t, upstream)
+	commitTestFile(t, upstream, "b.txt", "b\n", "add b")
+
+	if forced, err := fetchUpstream(ctx, behind); err != nil || forced {
+		t.Fatalf("fetchUpstream = %v, %v", forced, err)
+	}
+	out := captureStdout(t, func() {
+		total, with := showUpstreamRange(ctx, []string{behind, even}, pullRange, 10)
+		if total != 1 || with != 1 {
+			t.Errorf("total = %d, with = %d", total, with)
+		}
+	})
+	for _, want := range []string{"1 commits to pull from origin/", " add b\n", "b.txt"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, even+"\n") {
+		t.Errorf("repository with nothing to pull listed:\n%s", out)
+	}
+}
+
+func TestFetchUpstreamForced(t *testing.T) {
+	ctx := context.Background()
+	upstream := initTestRepo(t)
+	commitTestFile(t, upstream, "a.txt", "a", "first")
+	commitTestFile(t, upstream, "b.txt", "b", "second")
+	clone := cloneTestRepo(t, upstream)
+	if out, err := exec.Command("git", "-C", upstream, "reset", "-q", "--hard", "HEAD~1").CombinedOutput(); err != nil {
+		t.Fatalf("git reset: %v %s", err, out)
+	}
+	commitTestFile(t, upstream, "c.txt", "c", "rewritten")
+
+	forced, err := fetchUpstream(ctx, clone)
+	if err != nil || !forced {
+		t.Errorf("fetchUpstream = %v, %v, want a forced update", forced, err)
+	}
+	if forced, err := fetchUpstream(ctx, clone); err != nil || forced {
+		t.Errorf("second fetchUpstream = %v, %v", forced, err)
+	}
+}
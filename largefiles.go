@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const defaultMaxFileSize = 50 << 20
+
+// LargeFilesConfig configures the size guard of add and commit:
+//
+//	[large_files]
+//	max_size = "50MB"
+//	action = "skip" # or "abort" to leave the whole repository alone
+type LargeFilesConfig struct {
+	MaxSize string `toml:"max_size"`
+	Action  string `toml:"action"`
+}
+
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+	{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+}
+
+// parseSize parses sizes like "50MB", "512k" or "1GiB". Units are binary,
+// so 1MB is 1024KB, matching how git and hosting limits are usually quoted.
+func parseSize(s string) (int64, error) {
+	u := strings.ToUpper(strings.TrimSpace(s))
+	factor := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(u, unit.suffix) {
+			u, factor = strings.TrimSpace(strings.TrimSuffix(u, unit.suffix)), unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(u, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q: use e.g. 50MB, 512K or 1GiB", s)
+	}
+	return int64(n * float64(factor)), nil
+}
+
+// sizeValue is a flag value accepting the sizes parseSize understands.
+type sizeValue int64
+
+func (v *sizeValue) String() string { return formatBytes(int64(*v)) }
+func (v *sizeValue) Type() string   { return "size" }
+
+func (v *sizeValue) Set(s string) error {
+	n, err := parseSize(s)
+	if err != nil {
+		return err
+	}
+	*v = sizeValue(n)
+	return nil
+}
+
+var allowLargeFiles bool
+var maxFileSize = sizeValue(defaultMaxFileSize)
+
+// largeFileGuard resolves the size limit for cmd from --max-file-size, the
+// config file and the default, in that order. limit is 0 when the guard is
+// disabled with --allow-large-files.
+func largeFileGuard(cmd *cobra.Command) (limit int64, abort bool, err error) {
+	if allowLargeFiles {
+		return 0, false, nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return 0, false, err
+	}
+	switch cfg.LargeFiles.Action {
+	case "", "skip":
+	case "abort":
+		abort = true
+	default:
+		return 0, false, fmt.Errorf("large_files.action must be \"skip\" or \"abort\", got %q", cfg.LargeFiles.Action)
+	}
+	limit = int64(maxFileSize)
+	if !cmd.Flags().Changed("max-file-size") && cfg.LargeFiles.MaxSize != "" {
+		if limit, err = parseSize(cfg.LargeFiles.MaxSize); err != nil {
+			return 0, false, fmt.Errorf("large_files.max_size: %v", err)
+		}
+	}
+	return limit, abort, nil
+}
+
+type largeFile struct {
+	path string
+	size int64
+}
+
+// largeFilesToAdd returns the modified (and, with untracked, new) files
+// matching pathspecs in repo that are bigger than limit.
+func largeFilesToAdd(ctx context.Context, repo string, pathspecs []string, untracked bool, limit int64) ([]largeFile, error) {
+	args := []string{"ls-files", "-z", "--modified", "--exclude-standard"}
+	if untracked {
+		args = append(args, "--others")
+	}
+	out, err := gitOutput(ctx, repo, append(append(args, "--"), pathspecs...)...)
+	if err != nil {
+		return nil, err
+	}
+	var large []largeFile
+	for _, p := range strings.Split(out, "\x00") {
+		if p == "" {
+			continue
+		}
+		fi, err := os.Lstat(filepath.Join(repo, p))
+		if err != nil || !fi.Mode().IsRegular() {
+			continue // deleted files and symlinks are fine
+		}
+		if fi.Size() > limit {
+			large = append(large, largeFile{path: p, size: fi.Size()})
+		}
+	}
+	return large, nil
+}
+
+// largeStagedFiles returns the staged files in repo whose blobs are bigger
+// than limit.
+func largeStagedFiles(ctx context.Context, repo string, limit int64) ([]largeFile, error) {
+	out, err := gitOutput(ctx, repo, "diff", "--cached", "--name-only", "-z", "--diff-filter=ACMR")
+	if err != nil {
+		return nil, err
+	}
+	var large []largeFile
+	for _, p := range strings.Split(out, "\x00") {
+		if p == "" {
+			continue
+		}
+		s, err := gitOutput(ctx, repo, "cat-file", "-s", ":"+p)
+		if err != nil {
+			return nil, err
+		}
+		size, _ := strconv.ParseInt(s, 10, 64)
+		if size > limit {
+			large = append(large, largeFile{path: p, size: size})
+		}
+	}
+	return large, nil
+}
+
+// largeFilePathspecs turns files into pathspecs with the given magic, e.g.
+// "exclude,literal" to leave them out of a git add.
+func largeFilePathspecs(files []largeFile, magic string) []string {
+	specs := make([]string, 0, len(files))
+	for _, f := range files {
+		specs = append(specs, ":("+magic+")"+f.path)
+	}
+	return specs
+}
+
+func reportLargeFiles(repo string, files []largeFile, limit int64, what string) {
+	fmt.Fprintf(os.Stderr, "%s: %s (use --allow-large-files to include):\n", repo, paint(os.Stderr, ansiYellow, fmt.Sprintf("%d file(s) larger than %s %s", len(files), formatBytes(limit), what)))
+	for _, f := range files {
+		fmt.Fprintf(os.Stderr, "  %s (%s)\n", f.path, formatBytes(f.size))
+	}
+}
+
+func addLargeFileFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&allowLargeFiles, "allow-large-files", false, "do not check file sizes")
+	cmd.Flags().Var(&maxFileSize, "max-file-size", "largest file size allowed without --allow-large-files (e.g. 50MB)")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"50MB":  50 << 20,
+		"512k":  512 << 10,
+		"1GiB":  1 << 30,
+		"1.5M":  3 << 19,
+		"100":   100,
+		"10 KB": 10 << 10,
+	}
+	for in, want := range cases {
+		got, err := parseSize(in)
+		if err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "MB", "lots", "-1MB"} {
+		if _, err := parseSize(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLargeFilesToAddAndStaged(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "small.txt", "ok", "small file")
+	if err := os.WriteFile(filepath.Join(repo, "build.bin"), make([]byte, 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "notes.txt"), []byte("short"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	large, err := largeFilesToAdd(ctx, repo, []string{"."}, true, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(large) != 1 || large[0].path != "build.bin" || large[0].size != 100 {
+		t.Fatalf("expected build.bin to be reported, got %+v", large)
+	}
+
+	if tracked, err := largeFilesToAdd(ctx, repo, []string{"."}, false, 10); err != nil || len(tracked) != 0 {
+		t.Errorf("expected untracked file to be ignored for --update, got %+v, %v", tracked, err)
+	}
+
+	add := append([]string{"add", "--", "."}, largeFilePathspecs(large, "exclude,literal")...)
+	if out, err := runGitCapture(ctx, repo, add...); err != nil {
+		t.Fatalf("git add failed: %v, out=%s", err, out)
+	}
+	if staged, err := largeStagedFiles(ctx, repo, 10); err != nil || len(staged) != 0 {
+		t.Errorf("expected large file to stay unstaged, got %+v, %v", staged, err)
+	}
+
+	if out, err := runGitCapture(ctx, repo, "add", "build.bin"); err != nil {
+		t.Fatalf("git add failed: %v, out=%s", err, out)
+	}
+	if staged, err := largeStagedFiles(ctx, repo, 10); err != nil || len(staged) != 1 {
+		t.Errorf("expected staged large file to be reported, got %+v, %v", staged, err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// usesLFS reports whether any tracked .gitattributes file in repo routes
+// paths through the LFS filter.
+func usesLFS(ctx context.Context, repo string) bool {
+	_, err := gitOutput(ctx, repo, "grep", "--quiet", "--fixed-strings", "filter=lfs", "--", ":(glob)**/.gitattributes")
+	return err == nil
+}
+
+// requireLFS fails when the git-lfs extension is not installed.
+func requireLFS(ctx context.Context) error {
+	if _, err := gitOutput(ctx, "", "lfs", "version"); err != nil {
+		return errors.New("git-lfs is not installed (git lfs version failed)")
+	}
+	return nil
+}
+
+// lfs command
+var lfsCmd = &cobra.Command{
+	Use:   "lfs",
+	Short: "Run git lfs commands in the matching repositories that use LFS",
+	Long: `lfs runs a git lfs subcommand in every matching repository whose
+.gitattributes uses the LFS filter. Repositories without LFS are skipped.`,
+}
+
+func newLFSSubcommand(name, short string, gitArgs ...string) *cobra.Command {
+	return &cobra.Command{
+		Use:   name + " <pattern>...",
+		Short: short,
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repos, err := collectRepos(args)
+			if err != nil {
+				return err
+			}
+			ctx, cancel := runContext()
+			defer cancel()
+			if err := requireLFS(ctx); err != nil {
+				return err
+			}
+
+			skipped := 0
+			for _, r := range repos {
+				if !usesLFS(ctx, r) {
+					skipped++
+					continue
+				}
+				repoHeader(r)
+				if err := runGit(ctx, r, append([]string{"lfs"}, gitArgs...)...); err != nil {
+					repoFailed(r, err)
+				}
+			}
+			if skipped > 0 {
+				fmt.Printf("\nskipped %d repositories without LFS\n", skipped)
+			}
+			return nil
+		},
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(lfsCmd)
+	lfsCmd.AddCommand(newLFSSubcommand("pull", "Run git lfs pull", "pull"))
+	lfsCmd.AddCommand(newLFSSubcommand("fetch", "Run git lfs fetch", "fetch"))
+	lfsCmd.AddCommand(newLFSSubcommand("prune", "Run git lfs prune", "prune"))
+	lfsCmd.AddCommand(newLFSSubcommand("status", "Run git lfs status", "status"))
+}
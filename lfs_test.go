@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUsesLFS(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, ".gitattributes", "*.txt text\n", "attributes")
+	if usesLFS(ctx, repo) {
+		t.Errorf("expected repo without filter=lfs not to use LFS")
+	}
+
+	commitTestFile(t, repo, "assets/.gitattributes", "*.psd filter=lfs diff=lfs merge=lfs -text\n", "track psd with lfs")
+	if !usesLFS(ctx, repo) {
+		t.Errorf("expected nested .gitattributes with filter=lfs to be detected")
+	}
+}
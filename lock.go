@@ -0,0 +1,253 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var noLock bool
+var lockWait bool
+
+// lockPoll is how often --wait checks whether the workspace is free.
+var lockPoll = time.Second
+
+// lockInfo is the content of a lock file. The lock files of all
+// workspaces live in one directory so overlapping workspaces, such as
+// ~/src and ~/src/api, can see each other.
+type lockInfo struct {
+	Root    string    `json:"root"`
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+func (l lockInfo) String() string {
+	return fmt.Sprintf("gitbatch %s (pid %d on %s, since %s)", l.Command, l.PID, l.Host, l.Started.Local().Format("15:04:05"))
+}
+
+// overlaps reports whether the workspaces of l and o share repositories.
+func (l lockInfo) overlaps(o lockInfo) bool {
+	return within(l.Root, o.Root) || within(o.Root, l.Root)
+}
+
+// stale reports whether the process holding the lock is gone. Locks taken
+// on other hosts, e.g. over a shared home directory, are never stale.
+func (l lockInfo) stale() bool {
+	host, _ := os.Hostname()
+	if l.Host != host {
+		return false
+	}
+	p, err := os.FindProcess(l.PID)
+	if err != nil {
+		return true
+	}
+	return errors.Is(p.Signal(syscall.Signal(0)), os.ErrProcessDone)
+}
+
+// within reports whether dir is root or inside it.
+func within(dir, root string) bool {
+	return dir == root || strings.HasPrefix(dir, strings.TrimSuffix(root, string(os.PathSeparator))+string(os.PathSeparator))
+}
+
+// workspaceRoot is the deepest directory containing all of repos.
+func workspaceRoot(repos []string) string {
+	root := repos[0]
+	for _, r := range repos[1:] {
+		for !within(r, root) {
+			parent := filepath.Dir(root)
+			if parent == root {
+				break
+			}
+			root = parent
+		}
+	}
+	return root
+}
+
+func lockDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gitbatch", "locks"), nil
+}
+
+// lockFile is the lock file of the workspace at root.
+func lockFile(dir, root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".lock")
+}
+
+func readLock(path string) (lockInfo, error) {
+	var l lockInfo
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return l, err
+	}
+	return l, json.Unmarshal(b, &l)
+}
+
+// workspaceLock is a held lock; release removes its file.
+type workspaceLock struct {
+	path string
+}
+
+func (w *workspaceLock) release() {
+	os.Remove(w.path)
+}
+
+// tryLock takes the lock for info.Root. It returns the lock that is in the
+// way when the same or an overlapping workspace is already locked.
+func tryLock(dir string, info lockInfo) (*workspaceLock, *lockInfo, error) {
+	path := lockFile(dir, info.Root)
+	b, err := json.Marshal(info)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		held, rerr := readLock(path)
+		if rerr == nil && !held.stale() {
+			return nil, &held, nil
+		}
+		// left behind by a run that died, or half written: take it over
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, err
+		}
+		return tryLock(dir, info)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	_, werr := f.Write(b)
+	if err := f.Close(); werr == nil {
+		werr = err
+	}
+	lock := &workspaceLock{path: path}
+	if werr != nil {
+		lock.release()
+		return nil, nil, werr
+	}
+
+	// overlapping workspaces have different files. Of two runs that see
+	// each other, the later one backs off: its file did not exist yet when
+	// the earlier one looked.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		lock.release()
+		return nil, nil, err
+	}
+	for _, e := range entries {
+		other := filepath.Join(dir, e.Name())
+		if other == path || !strings.HasSuffix(e.Name(), ".lock") {
+			continue
+		}
+		held, err := readLock(other)
+		if err != nil || !held.overlaps(info) || held.Started.After(info.Started) {
+			continue
+		}
+		if held.stale() {
+			os.Remove(other)
+			continue
+		}
+		lock.release()
+		return nil, &held, nil
+	}
+	return lock, nil, nil
+}
+
+// acquireLock takes the lock for the workspace at root. Without wait it
+// fails when another run holds the same or an overlapping workspace.
+func acquireLock(dir, root, command string, wait bool) (*workspaceLock, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("lock: %v", err)
+	}
+	host, _ := os.Hostname()
+	waiting := false
+	for {
+		info := lockInfo{Root: root, PID: os.Getpid(), Host: host, Command: command, Started: time.Now()}
+		lock, held, err := tryLock(dir, info)
+		if err != nil {
+			return nil, fmt.Errorf("lock: %v", err)
+		}
+		if held == nil {
+			return lock, nil
+		}
+		if !wait {
+			return nil, fmt.Errorf("%s is in use by %s; use --wait to wait for it or --no-lock to run anyway", held.Root, held)
+		}
+		if !waiting {
+			logf(levelNormal, "waiting for %s to finish in %s", held, held.Root)
+			waiting = true
+		}
+		time.Sleep(lockPoll)
+	}
+}
+
+// runLock is the lock of the current run.
+var runLock = struct {
+	sync.Mutex
+	wanted  bool
+	command string
+	held    *workspaceLock
+}{}
+
+// setupLock decides whether this run locks its workspace: commands that
+// change repositories do, unless --no-lock is given.
+func setupLock(cmd *cobra.Command) error {
+	runLock.Lock()
+	defer runLock.Unlock()
+	runLock.wanted = !noLock && mutating(cmd)
+	if runLock.wanted {
+		runLock.command = commandName(cmd)
+	}
+	return nil
+}
+
+// lockWorkspace locks the workspace of repos for the rest of the run. It
+// does nothing when the run already holds a lock.
+func lockWorkspace(repos []string) error {
+	runLock.Lock()
+	defer runLock.Unlock()
+	if !runLock.wanted || runLock.held != nil || len(repos) == 0 {
+		return nil
+	}
+	dir, err := lockDir()
+	if err != nil {
+		return fmt.Errorf("lock: %v", err)
+	}
+	lock, err := acquireLock(dir, workspaceRoot(repos), runLock.command, lockWait)
+	if err != nil {
+		return err
+	}
+	runLock.held = lock
+	return nil
+}
+
+// unlockWorkspace releases the lock of the run, if any.
+func unlockWorkspace() {
+	runLock.Lock()
+	defer runLock.Unlock()
+	if runLock.held != nil {
+		runLock.held.release()
+		runLock.held = nil
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&noLock, "no-lock", false, "do not lock the workspace against other gitbatch runs")
+	rootCmd.PersistentFlags().BoolVar(&lockWait, "wait", false, "wait for other gitbatch runs on the same repositories instead of failing")
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkspaceRoot(t *testing.T) {
+	cases := []struct {
+		repos []string
+		want  string
+	}{
+		{[]string{"/src/api"}, "/src/api"},
+		{[]string{"/src/api", "/src/web"}, "/src"},
+		{[]string{"/src/corp/api", "/src/corp/web", "/src/tools"}, "/src"},
+		{[]string{"/src/api", "/src/api-v2"}, "/src"},
+		{[]string{"/src/api", "/home/me/x"}, "/"},
+	}
+	for _, c := range cases {
+		if got := workspaceRoot(c.repos); got != c.want {
+			t.Errorf("workspaceRoot(%v) = %q, want %q", c.repos, got, c.want)
+		}
+	}
+}
+
+func TestAcquireLock(t *testing.T) {
+	dir := t.TempDir()
+	held, err := acquireLock(dir, "/src/corp", "push", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, root := range []string{"/src/corp", "/src", "/src/corp/api"} {
+		if _, err := acquireLock(dir, root, "pull", false); err == nil || !strings.Contains(err.Error(), "gitbatch push") {
+			t.Errorf("locking %s: expected it to be in use by push, got %v", root, err)
+		}
+	}
+	other, err := acquireLock(dir, "/src/tools", "pull", false)
+	if err != nil {
+		t.Fatalf("a separate workspace was refused: %v", err)
+	}
+	other.release()
+
+	held.release()
+	again, err := acquireLock(dir, "/src", "pull", false)
+	if err != nil {
+		t.Fatalf("released lock still in the way: %v", err)
+	}
+	again.release()
+}
+
+func TestAcquireLockTakesOverStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	host, _ := os.Hostname()
+	// no process has this pid: Linux pids stay below 2^22
+	b, _ := json.Marshal(lockInfo{Root: "/src", PID: 1 << 30, Host: host, Command: "push", Started: time.Now().Add(-time.Hour)})
+	if err := os.WriteFile(lockFile(dir, "/src"), b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := acquireLock(dir, "/src/api", "pull", false)
+	if err != nil {
+		t.Fatalf("stale lock was not taken over: %v", err)
+	}
+	lock.release()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("lock files left behind: %v", entries)
+	}
+}
+
+func TestAcquireLockWaits(t *testing.T) {
+	dir := t.TempDir()
+	oldPoll := lockPoll
+	lockPoll = 10 * time.Millisecond
+	t.Cleanup(func() { lockPoll = oldPoll })
+
+	held, err := acquireLock(dir, "/src", "push", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		held.release()
+	}()
+	lock, err := acquireLock(dir, filepath.Join("/src", "api"), "pull", true)
+	if err != nil {
+		t.Fatalf("--wait did not get the lock: %v", err)
+	}
+	lock.release()
+}
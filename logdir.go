@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// logDir is --log-dir: every git command's output is also written to a log
+// file per repository in this directory.
+var logDir string
+
+// repoLogs maps repositories to their log files for the current run. Log
+// files are named after the repository directory; a numeric suffix keeps
+// repositories with the same name apart.
+type repoLogs struct {
+	sync.Mutex
+	dir   string
+	paths map[string]string // repo -> log file
+	taken map[string]bool   // file names in use
+}
+
+func newRepoLogs(dir string) (*repoLogs, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("--log-dir: %v", err)
+	}
+	return &repoLogs{dir: dir, paths: map[string]string{}, taken: map[string]bool{}}, nil
+}
+
+// path returns the log file of repo, truncating it the first time it is
+// used in this run.
+func (l *repoLogs) path(repo string) (string, error) {
+	l.Lock()
+	defer l.Unlock()
+	if p, ok := l.paths[repo]; ok {
+		return p, nil
+	}
+	base := shortRepoName(strings.TrimRight(repo, `/\`))
+	name := base
+	for i := 2; l.taken[name]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	p := filepath.Join(l.dir, name+".log")
+	if err := os.WriteFile(p, nil, 0o644); err != nil {
+		return "", err
+	}
+	l.taken[name] = true
+	l.paths[repo] = p
+	return p, nil
+}
+
+// lookup returns the log file of repo if anything was logged for it.
+func (l *repoLogs) lookup(repo string) (string, bool) {
+	l.Lock()
+	defer l.Unlock()
+	p, ok := l.paths[repo]
+	return p, ok
+}
+
+// open appends a header for c to the log of c.Dir and returns the file for
+// the command's output. It returns nil for commands outside a repository.
+func (l *repoLogs) open(c runner.Cmd) *os.File {
+	if c.Dir == "" {
+		return nil
+	}
+	p, err := l.path(c.Dir)
+	if err != nil {
+		logf(levelNormal, "cannot write log for %s: %v", c.Dir, err)
+		return nil
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		logf(levelNormal, "cannot write log for %s: %v", c.Dir, err)
+		return nil
+	}
+	fmt.Fprintf(f, "$ %s\t# %s\n", c, time.Now().Format(time.RFC3339))
+	return f
+}
+
+// closeEntry ends a log entry with the command's result.
+func closeEntry(f *os.File, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	}
+	fmt.Fprintf(f, "# %s (%s)\n\n", result, time.Since(start).Round(time.Millisecond))
+	f.Close()
+}
+
+// repoLogExecutor wraps the git executor to tee each command's output into
+// the log file of its repository.
+type repoLogExecutor struct {
+	next runner.GitExecutor
+	logs *repoLogs
+}
+
+func (e repoLogExecutor) Stream(ctx context.Context, c runner.Cmd) error {
+	f := e.logs.open(c)
+	if f == nil {
+		return e.next.Stream(ctx, c)
+	}
+	start := time.Now()
+	stdout, stderr := c.Stdout, c.Stderr
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	c.Stdout, c.Stderr = io.MultiWriter(stdout, f), io.MultiWriter(stderr, f)
+	err := e.next.Stream(ctx, c)
+	closeEntry(f, start, err)
+	return err
+}
+
+func (e repoLogExecutor) Capture(ctx context.Context, c runner.Cmd) (string, error) {
+	f := e.logs.open(c)
+	if f == nil {
+		return e.next.Capture(ctx, c)
+	}
+	start := time.Now()
+	out, err := e.next.Capture(ctx, c)
+	io.WriteString(f, out)
+	closeEntry(f, start, err)
+	return out, err
+}
+
+func (e repoLogExecutor) Output(ctx context.Context, c runner.Cmd) (string, error) {
+	f := e.logs.open(c)
+	if f == nil {
+		return e.next.Output(ctx, c)
+	}
+	start := time.Now()
+	out, err := e.next.Output(ctx, c)
+	if out != "" {
+		fmt.Fprintln(f, out)
+	}
+	closeEntry(f, start, err)
+	return out, err
+}
+
+func (e repoLogExecutor) Lines(ctx context.Context, c runner.Cmd, fn func(string)) error {
+	f := e.logs.open(c)
+	if f == nil {
+		return e.next.Lines(ctx, c, fn)
+	}
+	start := time.Now()
+	err := e.next.Lines(ctx, c, func(line string) {
+		fmt.Fprintln(f, line)
+		fn(line)
+	})
+	closeEntry(f, start, err)
+	return err
+}
+
+// runLogs holds the log files of this run when --log-dir is set.
+var runLogs *repoLogs
+
+// setupRepoLogs routes git through repoLogExecutor when --log-dir is set.
+func setupRepoLogs() error {
+	if logDir == "" {
+		return nil
+	}
+	logs, err := newRepoLogs(logDir)
+	if err != nil {
+		return err
+	}
+	runLogs = logs
+	gitExec = repoLogExecutor{next: gitExec, logs: logs}
+	return nil
+}
+
+// logHint points at the log file of repo, for error messages.
+func logHint(repo string) string {
+	if runLogs == nil {
+		return ""
+	}
+	if p, ok := runLogs.lookup(repo); ok {
+		return " (log: " + p + ")"
+	}
+	return ""
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&logDir, "log-dir", "", "also write each repository's full git output to <dir>/<repo-name>.log")
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+func TestRepoLogExecutor(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	logs, err := newRepoLogs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := (&runner.Mock{}).On("fetch", "fatal: could not read from remote\n", errors.New("exit status 128"))
+	m.On("rev-parse HEAD", "abc123", nil)
+	exe := repoLogExecutor{next: m, logs: logs}
+	ctx := context.Background()
+
+	exe.Capture(ctx, runner.Git("/src/api", "fetch"))
+	exe.Output(ctx, runner.Git("/src/api", "rev-parse", "HEAD"))
+	exe.Capture(ctx, runner.Git("/vendor/api", "fetch"))
+	exe.Output(ctx, runner.Git("", "rev-parse", "HEAD")) // not in a repository
+
+	b, err := os.ReadFile(filepath.Join(dir, "api.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := string(b)
+	for _, want := range []string{"$ git fetch\t", "fatal: could not read from remote\n# exit status 128 (", "$ git rev-parse HEAD\t", "abc123\n# ok ("} {
+		if !strings.Contains(log, want) {
+			t.Errorf("api.log lacks %q:\n%s", want, log)
+		}
+	}
+	if p, ok := logs.lookup("/vendor/api"); !ok || filepath.Base(p) != "api-2.log" {
+		t.Errorf("second api repository logged to %q", p)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("expected 2 log files, got %d", len(entries))
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// Output levels selected with --quiet and -v. Normal output is the per
+// repository header followed by git's own output.
+const (
+	levelQuiet   = -1 // only errors and the final summary
+	levelNormal  = 0
+	levelVerbose = 1 // plus per-repository timings and the summary
+	levelDebug   = 2 // plus every git command line
+)
+
+var verboseCount int
+var quiet bool
+var logLevel = levelNormal
+
+// onlyFailures (--only-failures) hides the header and output of every
+// repository that succeeds.
+var onlyFailures bool
+
+// heldHeader is the header of the repository being worked on, held back
+// by --only-failures until the repository fails.
+var heldHeader struct {
+	sync.Mutex
+	repo string
+}
+
+// logf writes a progress message to stderr when the level is enabled.
+func logf(level int, format string, args ...any) {
+	if logLevel >= level {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// repoHeader prints the "---- repo ----" line that starts each
+// repository's output, unless --quiet is set. With --only-failures it is
+// held back until the repository fails.
+func repoHeader(repo string) {
+	switch {
+	case logLevel == levelQuiet:
+	case onlyFailures:
+		heldHeader.Lock()
+		heldHeader.repo = repo
+		heldHeader.Unlock()
+	default:
+		printHeader(repo)
+	}
+}
+
+func printHeader(repo string) {
+	fmt.Printf("\n%s\n", paint(os.Stdout, ansiBold, "---- "+repo+" ----"))
+}
+
+// releaseHeader prints the header of repo held back by --only-failures,
+// once the repository has failed.
+func releaseHeader(repo string) {
+	heldHeader.Lock()
+	defer heldHeader.Unlock()
+	if heldHeader.repo == repo {
+		printHeader(repo)
+		heldHeader.repo = ""
+	}
+}
+
+// repoOutput prints the captured git output of repo, unless --quiet is set
+// or --only-failures is and the command succeeded.
+func repoOutput(repo, out string, failed bool) {
+	if logLevel == levelQuiet || onlyFailures && !failed {
+		return
+	}
+	releaseHeader(repo)
+	fmt.Print(out)
+}
+
+// logExecutor wraps the git executor to log commands and their timings
+// and, with --quiet or --only-failures, to swallow the output of streamed
+// commands that succeed.
+type logExecutor struct {
+	next runner.GitExecutor
+}
+
+func (l logExecutor) log(c runner.Cmd, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = firstLine(err.Error())
+	}
+	logf(levelDebug, "[%s] %s: %s (%s)", c.Dir, c, result, time.Since(start).Round(time.Millisecond))
+}
+
+func (l logExecutor) Stream(ctx context.Context, c runner.Cmd) error {
+	start := time.Now()
+	var err error
+	if logLevel == levelQuiet {
+		var out string
+		out, err = l.next.Capture(ctx, c)
+		// with --log-dir the output is in the repository's log instead
+		if err != nil && strings.TrimSpace(out) != "" && runLogs == nil {
+			err = fmt.Errorf("%v\n%s", err, strings.TrimRight(out, "\n"))
+		}
+	} else if onlyFailures {
+		var out string
+		out, err = l.next.Capture(ctx, c)
+		repoOutput(c.Dir, out, err != nil)
+	} else {
+		err = l.next.Stream(ctx, c)
+	}
+	l.log(c, start, err)
+	if logLevel == levelVerbose {
+		// streamed commands are the repository's main operation
+		logf(levelVerbose, "[%s] git %s took %s", c.Dir, c.Args[0], time.Since(start).Round(time.Millisecond))
+	}
+	return err
+}
+
+func (l logExecutor) Capture(ctx context.Context, c runner.Cmd) (string, error) {
+	start := time.Now()
+	out, err := l.next.Capture(ctx, c)
+	l.log(c, start, err)
+	return out, err
+}
+
+func (l logExecutor) Output(ctx context.Context, c runner.Cmd) (string, error) {
+	start := time.Now()
+	out, err := l.next.Output(ctx, c)
+	l.log(c, start, err)
+	return out, err
+}
+
+func (l logExecutor) Lines(ctx context.Context, c runner.Cmd, fn func(string)) error {
+	start := time.Now()
+	err := l.next.Lines(ctx, c, fn)
+	l.log(c, start, err)
+	return err
+}
+
+// setupLogging applies --quiet and -v before a command runs.
+func setupLogging() error {
+	if quiet && verboseCount > 0 {
+		return usageErrorf("--quiet and --verbose cannot be used together")
+	}
+	if quiet && onlyFailures {
+		return usageErrorf("--quiet and --only-failures cannot be used together")
+	}
+	if prefixOutput && (quiet || onlyFailures) {
+		return usageErrorf("--prefix cannot be combined with --quiet or --only-failures")
+	}
+	switch {
+	case quiet:
+		logLevel = levelQuiet
+	case verboseCount > 0:
+		logLevel = min(verboseCount, levelDebug)
+	}
+	if logLevel != levelNormal || onlyFailures {
+		gitExec = logExecutor{next: gitExec}
+	}
+	return nil
+}
+
+// printRunSummary ends quiet, verbose and --only-failures runs with the
+// number of repositories that succeeded, followed by those that failed,
+// were skipped or had branches force-pushed upstream.
+func printRunSummary() {
+	if logLevel == levelNormal && !onlyFailures {
+		return
+	}
+	runSummary.Lock()
+	defer runSummary.Unlock()
+	if len(runSummary.repos) == 0 {
+		return
+	}
+	summary := fmt.Sprintf("%d of %d repositories succeeded in %s", len(runSummary.repos)-len(runSummary.failed), len(runSummary.repos), time.Since(runSummary.start).Round(time.Millisecond))
+	code := ansiGreen
+	if len(runSummary.failed) > 0 {
+		code = ansiRed
+	}
+	fmt.Printf("\n%s\n", paint(os.Stdout, code, summary))
+	for _, r := range runSummary.repos {
+		if runSummary.failed[r] {
+			fmt.Printf("  %s %s%s\n", paint(os.Stdout, ansiRed, "failed:"), r, logHint(r))
+		}
+	}
+	skipped := make([]string, 0, len(runSummary.skipped))
+	for r := range runSummary.skipped {
+		skipped = append(skipped, r)
+	}
+	sort.Strings(skipped)
+	for _, r := range skipped {
+		fmt.Printf("  %s %s (%s)\n", paint(os.Stdout, ansiYellow, "skipped:"), r, runSummary.skipped[r])
+	}
+	for _, r := range runSummary.repos {
+		if forced := runSummary.forced[r]; len(forced) > 0 {
+			fmt.Printf("  %s %s: %s\n", paint(os.Stdout, ansiRed, "force-pushed:"), r, strings.Join(forced, ", "))
+		}
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().CountVarP(&verboseCount, "verbose", "v", "print timings and the run summary; repeat (-vv) to log every git command")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "print only errors and the final summary")
+	rootCmd.PersistentFlags().BoolVar(&onlyFailures, "only-failures", false, "print the output of failed repositories only, then the summary")
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// captureStderr returns what fn writes to stderr.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := os.Stderr
+	os.Stderr = w
+	fn()
+	os.Stderr = prev
+	w.Close()
+	b, _ := io.ReadAll(r)
+	return string(b)
+}
+
+// captureStdout returns what fn writes to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := os.Stdout
+	os.Stdout = w
+	fn()
+	os.Stdout = prev
+	w.Close()
+	b, _ := io.ReadAll(r)
+	return string(b)
+}
+
+func TestLogExecutor(t *testing.T) {
+	defer func() { logLevel = levelNormal }()
+	ctx := context.Background()
+	m := (&runner.Mock{}).On("pull", "CONFLICT in a.txt\n", errors.New("exit status 1"))
+	exe := logExecutor{next: m}
+
+	logLevel = levelDebug
+	out := captureStderr(t, func() {
+		exe.Output(ctx, runner.Git("/r", "rev-parse", "HEAD"))
+	})
+	if !strings.HasPrefix(out, "[/r] git rev-parse HEAD: ok (") {
+		t.Errorf("unexpected debug log %q", out)
+	}
+
+	logLevel = levelQuiet
+	var err error
+	out = captureStderr(t, func() {
+		err = exe.Stream(ctx, runner.Git("/r", "pull"))
+	})
+	if out != "" {
+		t.Errorf("quiet mode logged %q", out)
+	}
+	if err == nil || !strings.Contains(err.Error(), "CONFLICT in a.txt") {
+		t.Errorf("quiet mode should keep the output of a failed command in the error, got %v", err)
+	}
+	if got := m.Commands(); got[len(got)-1] != "git pull" {
+		t.Errorf("expected pull to be captured, got %q", got)
+	}
+}
+
+func TestSetupLoggingRejectsQuietAndVerbose(t *testing.T) {
+	defer func() { quiet, verboseCount, logLevel = false, 0, levelNormal }()
+	quiet, verboseCount = true, 1
+	if err := setupLogging(); err == nil {
+		t.Error("expected --quiet with -v to be rejected")
+	}
+}
+
+func TestOnlyFailures(t *testing.T) {
+	defer func() { onlyFailures = false }()
+	onlyFailures = true
+	ctx := context.Background()
+	m := (&runner.Mock{}).
+		On("pull", "Already up to date.\n", nil).
+		On("push", "rejected\n", errors.New("exit status 1"))
+	exe := logExecutor{next: m}
+
+	out := captureStdout(t, func() {
+		repoHeader("/ok")
+		exe.Stream(ctx, runner.Git("/ok", "pull"))
+		repoHeader("/bad")
+		exe.Stream(ctx, runner.Git("/bad", "push"))
+	})
+	if strings.Contains(out, "/ok") || strings.Contains(out, "up to date") {
+		t.Errorf("output of a successful repository was printed: %q", out)
+	}
+	if !strings.Contains(out, "---- /bad ----\nrejected\n") {
+		t.Errorf("failed repository's header and output missing: %q", out)
+	}
+
+	// a failure reported without git output still gets its header, once
+	out = captureStdout(t, func() {
+		repoHeader("/gone")
+		releaseHeader("/gone")
+		releaseHeader("/gone")
+	})
+	if strings.Count(out, "---- /gone ----") != 1 {
+		t.Errorf("header printed %q", out)
+	}
+}
+
+func TestSetupLoggingRejectsQuietAndOnlyFailures(t *testing.T) {
+	defer func() { quiet, onlyFailures, logLevel = false, false, levelNormal }()
+	quiet, onlyFailures = true, true
+	if err := setupLogging(); exitCode(err) != exitUsage {
+		t.Errorf("expected --quiet with --only-failures to be a usage error, got %v", err)
+	}
+}
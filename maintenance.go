@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// dirSize returns the total size of the regular files below dir.
+func dirSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// formatBytes renders n with a binary unit, e.g. "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit && n > -unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit || m <= -unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// gitDir returns the absolute path of the repository's .git directory.
+func gitDir(ctx context.Context, repo string) (string, error) {
+	return gitOutput(ctx, repo, "rev-parse", "--absolute-git-dir")
+}
+
+type maintenanceResult struct {
+	repo          string
+	before, after int64
+}
+
+// maintenance command
+var maintenanceFsck bool
+var maintenanceRegister bool
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance [--fsck] [--register] <pattern>...",
+	Short: "Run git gc and prune (and optionally fsck) and report reclaimed space",
+	Long: `maintenance runs "git gc" and "git prune" in every matching repository and
+prints how much disk space each one reclaimed. --fsck additionally checks
+object integrity, and --register enrolls the repositories in git's
+background maintenance ("git maintenance register").`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		var results []maintenanceResult
+		for _, r := range repos {
+			repoHeader(r)
+			dir, err := gitDir(ctx, r)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			res := maintenanceResult{repo: r}
+			res.before, _ = dirSize(dir)
+
+			steps := [][]string{{"gc", "--quiet"}, {"prune"}}
+			if maintenanceFsck {
+				steps = append(steps, []string{"fsck", "--no-progress"})
+			}
+			if maintenanceRegister {
+				steps = append(steps, []string{"maintenance", "register"})
+			}
+			for _, step := range steps {
+				if err := runGit(ctx, r, step...); err != nil {
+					repoFailed(r, fmt.Errorf("git %s: %v", step[0], err))
+				}
+			}
+
+			res.after, _ = dirSize(dir)
+			results = append(results, res)
+			fmt.Printf("%s -> %s\n", formatBytes(res.before), formatBytes(res.after))
+		}
+
+		var total int64
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "BEFORE\tAFTER\tRECLAIMED\t\tREPO")
+		for _, res := range results {
+			total += res.before - res.after
+			fmt.Fprintf(w, "%s\t%s\t%s\t\t%s\n", formatBytes(res.before), formatBytes(res.after), formatBytes(res.before-res.after), res.repo)
+		}
+		w.Flush()
+		fmt.Printf("\nreclaimed %s across %d repositories\n", formatBytes(total), len(results))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(maintenanceCmd)
+
+	maintenanceCmd.Flags().BoolVar(&maintenanceFsck, "fsck", false, "also run git fsck")
+	maintenanceCmd.Flags().BoolVar(&maintenanceRegister, "register", false, "enroll repositories in git's background maintenance")
+}
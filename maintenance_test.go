@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		0:                "0 B",
+		1023:             "1023 B",
+		1536:             "1.5 KiB",
+		5 * 1024 * 1024:  "5.0 MiB",
+		-2 * 1024 * 1024: "-2.0 MiB",
+		3 << 30:          "3.0 GiB",
+	}
+	for in, want := range cases {
+		if got := formatBytes(in); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"a": 10, "sub/b": 32} {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := dirSize(dir); err != nil || got != 42 {
+		t.Errorf("dirSize = %d, %v; want 42", got, err)
+	}
+}
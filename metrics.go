@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// opResults remembers whether the last operation run by serve or watch
+// succeeded in each repository.
+type opResults struct {
+	mu sync.Mutex
+	ok map[string]bool
+}
+
+func newOpResults() *opResults {
+	return &opResults{ok: map[string]bool{}}
+}
+
+func (o *opResults) record(repo string, ok bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.ok[repo] = ok
+}
+
+func (o *opResults) get(repo string) (ok, known bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	ok, known = o.ok[repo]
+	return ok, known
+}
+
+// oldestUnpushedAge returns how long ago the oldest commit not on the
+// upstream was committed, or 0 when everything is pushed or there is no
+// upstream.
+func oldestUnpushedAge(ctx context.Context, repo string, now time.Time) time.Duration {
+	out, err := gitOutput(ctx, repo, "log", "--reverse", "--format=%ct", "@{upstream}..HEAD")
+	if err != nil || out == "" {
+		return 0
+	}
+	sec, err := strconv.ParseInt(firstLine(out), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return now.Sub(time.Unix(sec, 0))
+}
+
+// metric is one gauge of the /metrics output with a value per repository.
+type metric struct {
+	name, help string
+	values     map[string]float64
+}
+
+// repoMetrics collects the per-repository gauges.
+func repoMetrics(ctx context.Context, repos []string, results *opResults, now time.Time) []*metric {
+	gauge := func(name, help string) *metric {
+		return &metric{name: "gitbatch_repo_" + name, help: help, values: map[string]float64{}}
+	}
+	dirty := gauge("dirty_files", "Changed, staged, untracked and conflicted files in the working tree.")
+	ahead := gauge("ahead_commits", "Commits on HEAD not on the upstream branch.")
+	behind := gauge("behind_commits", "Commits on the upstream branch not on HEAD.")
+	unpushed := gauge("oldest_unpushed_commit_age_seconds", "Age of the oldest commit not pushed to the upstream, 0 when all is pushed.")
+	fetchAge := gauge("last_fetch_age_seconds", "Time since the last fetch, absent for repositories never fetched.")
+	lastOK := gauge("last_operation_success", "1 if the last operation run by gitbatch succeeded, 0 if it failed.")
+	up := gauge("up", "1 if the repository could be inspected.")
+
+	for _, r := range repos {
+		st, err := readRepoState(ctx, r)
+		if err != nil {
+			up.values[r] = 0
+			continue
+		}
+		up.values[r] = 1
+		dirty.values[r] = float64(st.Staged + st.Unstaged + st.Untracked + st.Conflicts)
+		ahead.values[r] = float64(st.Ahead)
+		behind.values[r] = float64(st.Behind)
+		unpushed.values[r] = oldestUnpushedAge(ctx, r, now).Seconds()
+		if t, ok := lastFetchTime(ctx, r); ok {
+			fetchAge.values[r] = now.Sub(t).Seconds()
+		}
+		if ok, known := results.get(r); known {
+			lastOK.values[r] = 0
+			if ok {
+				lastOK.values[r] = 1
+			}
+		}
+	}
+	return []*metric{up, dirty, ahead, behind, unpushed, fetchAge, lastOK}
+}
+
+// writeMetrics writes metrics in the Prometheus text exposition format.
+func writeMetrics(w io.Writer, metrics []*metric) {
+	for _, m := range metrics {
+		if len(m.values) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		repos := make([]string, 0, len(m.values))
+		for r := range m.values {
+			repos = append(repos, r)
+		}
+		sort.Strings(repos)
+		for _, r := range repos {
+			fmt.Fprintf(w, "%s{repo=\"%s\"} %s\n", m.name, escapeLabel(r), strconv.FormatFloat(m.values[r], 'f', -1, 64))
+		}
+	}
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// metricsHandler serves /metrics for the repositories returned by repos.
+func metricsHandler(repos func(r *http.Request) ([]string, error), results *opResults) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := repos(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		metrics := repoMetrics(r.Context(), list, results, time.Now())
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, metrics)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRepoMetrics(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+	if err := os.WriteFile(filepath.Join(repo, "new.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	results := newOpResults()
+	results.record(repo, false)
+
+	var b bytes.Buffer
+	writeMetrics(&b, repoMetrics(ctx, []string{repo, filepath.Join(repo, "missing")}, results, time.Now()))
+	out := b.String()
+	label := `{repo="` + repo + `"}`
+	for _, want := range []string{
+		"# TYPE gitbatch_repo_dirty_files gauge\n",
+		"gitbatch_repo_up" + label + " 1\n",
+		"gitbatch_repo_dirty_files" + label + " 1\n",
+		"gitbatch_repo_ahead_commits" + label + " 0\n",
+		"gitbatch_repo_last_operation_success" + label + " 0\n",
+		`gitbatch_repo_up{repo="` + filepath.Join(repo, "missing") + `"} 0` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "gitbatch_repo_last_fetch_age_seconds") {
+		t.Errorf("never fetched repository should have no fetch age:\n%s", out)
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel(`C:\repo "x"`); got != `C:\\repo \"x\"` {
+		t.Errorf("escapeLabel = %s", got)
+	}
+}
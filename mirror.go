@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// Mirrors is the [mirror] section of the config file: URL templates for
+// backup remotes by remote name. {{repo}} is replaced by the repository's
+// directory name:
+//
+//	[mirror]
+//	backup = "ssh://backup.example.com/git/{{repo}}.git"
+type Mirrors map[string]string
+
+// mirrorURL returns the URL template for remote from --url or the config
+// file.
+func mirrorURL(remote, flagURL string) (string, error) {
+	if flagURL != "" {
+		return flagURL, nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	return cfg.Mirrors[remote], nil
+}
+
+// ensureMirrorRemote adds remote to dir with the URL from urlTmpl unless it
+// exists. The default remote is refused: push --mirror would delete every
+// branch there that is not local.
+func ensureMirrorRemote(ctx context.Context, dir, remote, urlTmpl string) error {
+	if defaultRemote(ctx, gitExec, dir) == remote {
+		return fmt.Errorf("%s is the repository's default remote, not a backup", remote)
+	}
+	if hasRemote(ctx, dir, remote) {
+		return nil
+	}
+	if urlTmpl == "" {
+		return fmt.Errorf("no remote %s and no URL for it (set --url or [mirror] %s in the config file)", remote, remote)
+	}
+	url := expandRepoURL(urlTmpl, dir)
+	if err := runGit(ctx, dir, "remote", "add", remote, url); err != nil {
+		return err
+	}
+	fmt.Printf("added %s %s\n", remote, url)
+	return nil
+}
+
+// mirror command
+var mirrorRemote string
+var mirrorURLFlag string
+var mirrorCmd = &cobra.Command{
+	Use:   "mirror [--remote backup] [--url <url>] <pattern>...",
+	Short: "Push every ref to a backup remote with git push --mirror",
+	Long: `mirror backs up each matching repository with git push --mirror to a
+separate remote, --remote (backup by default). Repositories without that
+remote get it first, with the URL from --url or from the [mirror] section of
+the config file; {{repo}} in the URL is replaced by the repository's
+directory name:
+
+  [mirror]
+  backup = "ssh://backup.example.com/git/{{repo}}.git"
+
+push --mirror makes the remote an exact copy, deleting refs that do not
+exist locally, so the repository's default remote is never used.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		urlTmpl, err := mirrorURL(mirrorRemote, mirrorURLFlag)
+		if err != nil {
+			return err
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		var ready []string
+		for _, r := range repos {
+			if err := ensureMirrorRemote(ctx, r, mirrorRemote, urlTmpl); err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			ready = append(ready, r)
+		}
+		pushArgs := []string{"push", "--mirror", mirrorRemote}
+		if jobs > 1 {
+			runBuffered(ready, pushArgs...)
+			return nil
+		}
+		for _, r := range ready {
+			repoHeader(r)
+			if err := runGit(ctx, r, pushArgs...); err != nil {
+				repoFailed(r, err)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mirrorCmd)
+
+	mirrorCmd.Flags().StringVar(&mirrorRemote, "remote", "backup", "name of the backup remote")
+	mirrorCmd.Flags().StringVar(&mirrorURLFlag, "url", "", "URL for repositories without the remote; {{repo}} is replaced by the directory name")
+	addRetryFlags(mirrorCmd)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMirrorURL(t *testing.T) {
+	useTestConfig(t, "[mirror]\nbackup = \"ssh://backup/{{repo}}.git\"\n")
+	if u, err := mirrorURL("backup", ""); err != nil || u != "ssh://backup/{{repo}}.git" {
+		t.Errorf("from config = %q, %v", u, err)
+	}
+	if u, _ := mirrorURL("backup", "/srv/{{repo}}.git"); u != "/srv/{{repo}}.git" {
+		t.Errorf("--url = %q", u)
+	}
+	if u, _ := mirrorURL("offsite", ""); u != "" {
+		t.Errorf("unknown remote = %q", u)
+	}
+}
+
+func TestMirrorPush(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+	backups := t.TempDir()
+	bare := filepath.Join(backups, filepath.Base(repo)+".git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("init bare: %v %s", err, out)
+	}
+
+	if err := ensureMirrorRemote(ctx, repo, "backup", ""); err == nil || !strings.Contains(err.Error(), "[mirror] backup") {
+		t.Errorf("missing URL: %v", err)
+	}
+	if err := ensureMirrorRemote(ctx, repo, "origin", filepath.Join(backups, "{{repo}}.git")); err == nil {
+		t.Error("default remote accepted as backup")
+	}
+	if err := ensureMirrorRemote(ctx, repo, "backup", filepath.Join(backups, "{{repo}}.git")); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGit(ctx, repo, "push", "--mirror", "backup"); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := gitOutput(ctx, repo, "rev-parse", "HEAD")
+	branch, _ := currentBranch(ctx, repo)
+	if got, err := gitOutput(ctx, bare, "rev-parse", "refs/heads/"+branch); err != nil || got != want {
+		t.Errorf("backup has %q, %v; want %q", got, err, want)
+	}
+	// an existing remote is kept as is
+	if err := ensureMirrorRemote(ctx, repo, "backup", ""); err != nil {
+		t.Errorf("existing remote: %v", err)
+	}
+}
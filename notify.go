@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// NotifyConfig sets a webhook that receives a summary of every batch run:
+//
+//	[notify]
+//	webhook = "https://hooks.slack.com/services/..."
+type NotifyConfig struct {
+	Webhook string `toml:"webhook"`
+}
+
+// runSummary collects the outcome of the current invocation for --notify.
+var runSummary = struct {
+	sync.Mutex
+	start     time.Time
+	repos     []string
+	failed    map[string]bool
+	skipped   map[string]string        // repository -> reason, never in repos
+	forced    map[string][]string      // repository -> force-pushed remote branches
+	aborted   bool                     // the user declined a confirmation
+	durations map[string]time.Duration // time git took per repository
+}{start: time.Now(), failed: map[string]bool{}, skipped: map[string]string{}, forced: map[string][]string{}, durations: map[string]time.Duration{}}
+
+// recordRepos remembers the repositories a command is about to work on.
+func recordRepos(repos []string) {
+	runSummary.Lock()
+	defer runSummary.Unlock()
+	runSummary.repos = append(runSummary.repos, repos...)
+}
+
+// repoFailed reports err for repo on stderr and counts the repository as
+// failed in the run summary.
+func repoFailed(repo string, err error) {
+	releaseHeader(repo)
+	fmt.Fprintf(os.Stderr, "%s %s: %v%s\n", paint(os.Stderr, ansiRed, "error in"), repo, err, logHint(repo))
+	markRepoFailed(repo)
+}
+
+// markRepoFailed counts repo as failed in the run summary without printing.
+func markRepoFailed(repo string) {
+	runSummary.Lock()
+	defer runSummary.Unlock()
+	runSummary.failed[repo] = true
+}
+
+// markRepoSkipped lists repo with the reason it was left out in the run
+// summary.
+func markRepoSkipped(repo, reason string) {
+	runSummary.Lock()
+	defer runSummary.Unlock()
+	runSummary.skipped[repo] = reason
+}
+
+// markRepoForced lists repo with its force-pushed remote branches in the
+// run summary.
+func markRepoForced(repo string, branches []string) {
+	runSummary.Lock()
+	defer runSummary.Unlock()
+	runSummary.forced[repo] = branches
+}
+
+// notification is the JSON body posted to the webhook. Slack and Teams
+// incoming webhooks display text and ignore the other fields, which are
+// there for generic receivers.
+type notification struct {
+	Text      string   `json:"text"`
+	Command   string   `json:"command"`
+	Repos     int      `json:"repositories"`
+	Succeeded int      `json:"succeeded"`
+	Failed    []string `json:"failed"`
+	Duration  float64  `json:"duration_seconds"`
+	Error     string   `json:"error,omitempty"`
+	// RepoDurations is the time git took in each repository.
+	RepoDurations map[string]float64 `json:"repo_duration_seconds,omitempty"`
+}
+
+func buildNotification(command string, repos []string, failed map[string]bool, durations map[string]time.Duration, elapsed time.Duration, runErr error) notification {
+	n := notification{Command: command, Repos: len(repos), Failed: []string{}, Duration: elapsed.Seconds()}
+	for r, d := range durations {
+		if n.RepoDurations == nil {
+			n.RepoDurations = map[string]float64{}
+		}
+		n.RepoDurations[r] = d.Seconds()
+	}
+	for r := range failed {
+		n.Failed = append(n.Failed, r)
+	}
+	sort.Strings(n.Failed)
+	n.Succeeded = n.Repos - len(n.Failed)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "gitbatch %s: %d of %d repositories succeeded in %s", command, n.Succeeded, n.Repos, elapsed.Round(time.Second))
+	if len(n.Failed) > 0 {
+		fmt.Fprintf(&b, "\nfailed: %s", strings.Join(n.Failed, ", "))
+	}
+	if runErr != nil {
+		n.Error = runErr.Error()
+		fmt.Fprintf(&b, "\nerror: %s", n.Error)
+	}
+	n.Text = b.String()
+	return n
+}
+
+func postNotification(ctx context.Context, webhook string, n notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+var notifyWebhook string
+
+// notifyRun posts the run summary to the webhook from --notify or the
+// config file. Commands that never looked up repositories, and runs that
+// only wrote an --emit-script, are not reported.
+func notifyRun(cmd *cobra.Command, runErr error) {
+	webhook := notifyWebhook
+	if webhook == "" {
+		cfg, err := loadConfig()
+		if err != nil {
+			return // already reported by the command
+		}
+		webhook = cfg.Notify.Webhook
+	}
+	runSummary.Lock()
+	repos, failed, durations := runSummary.repos, runSummary.failed, repoDurations()
+	runSummary.Unlock()
+	if webhook == "" || len(repos) == 0 || cmd == nil || emitScript != "" {
+		return
+	}
+
+	command := strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" ")
+	n := buildNotification(command, repos, failed, durations, time.Since(runSummary.start), runErr)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := postNotification(ctx, webhook, n); err != nil {
+		fmt.Fprintf(os.Stderr, "notify: %v\n", err)
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&notifyWebhook, "notify", "", "post a run summary to this Slack, Teams or generic webhook URL")
+}
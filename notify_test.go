@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildNotification(t *testing.T) {
+	n := buildNotification("pull", []string{"/r/a", "/r/b", "/r/c"}, map[string]bool{"/r/c": true, "/r/b": true}, map[string]time.Duration{"/r/a": 1500 * time.Millisecond}, 90*time.Second, errors.New("boom"))
+	if n.Succeeded != 1 || n.Repos != 3 || strings.Join(n.Failed, ",") != "/r/b,/r/c" {
+		t.Errorf("unexpected counts: %+v", n)
+	}
+	want := "gitbatch pull: 1 of 3 repositories succeeded in 1m30s\nfailed: /r/b, /r/c\nerror: boom"
+	if n.Text != want {
+		t.Errorf("text = %q, want %q", n.Text, want)
+	}
+	if n.RepoDurations["/r/a"] != 1.5 {
+		t.Errorf("repository durations %v", n.RepoDurations)
+	}
+}
+
+func TestPostNotification(t *testing.T) {
+	var got notification
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	n := buildNotification("status", []string{"/r/a"}, nil, nil, time.Second, nil)
+	if err := postNotification(context.Background(), srv.URL, n); err != nil {
+		t.Fatal(err)
+	}
+	if got.Command != "status" || got.Succeeded != 1 || got.Text == "" {
+		t.Errorf("webhook received %+v", got)
+	}
+
+	fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer fail.Close()
+	if err := postNotification(context.Background(), fail.URL, n); err == nil {
+		t.Error("expected an error for a 403 response")
+	}
+}
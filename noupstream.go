@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// --no-upstream modes for repositories whose branch has no upstream
+const (
+	noUpstreamSkip = "skip"
+	noUpstreamFail = "fail"
+	noUpstreamSet  = "set-upstream" // push only
+)
+
+var noUpstreamMode string
+
+// pushSetUpstream is push --set-upstream, short for --no-upstream=set-upstream.
+var pushSetUpstream bool
+
+// lacksUpstream returns the current branch of repo when it has no upstream
+// branch configured, and "" otherwise. Detached repositories have no branch
+// to check and are left to git.
+func lacksUpstream(ctx context.Context, repo string) string {
+	branch, err := currentBranch(ctx, repo)
+	if err != nil || branch == "" {
+		return ""
+	}
+	if _, err := gitOutput(ctx, repo, "rev-parse", "--abbrev-ref", "@{upstream}"); err == nil {
+		return ""
+	}
+	return branch
+}
+
+// checkUpstreams handles the repositories in repos whose branch has no
+// upstream according to --no-upstream, before git would fail in them with
+// its own error. It returns the repositories to run in and, in set-upstream
+// mode, the remote to create the upstream on for those that need one. By
+// default they are skipped and listed in the run summary; in fail mode they
+// count as failed. No repository left is an error.
+func checkUpstreams(repos []string, mode string) (ok []string, setUpstream map[string]string, err error) {
+	switch mode {
+	case noUpstreamSkip, noUpstreamFail, noUpstreamSet:
+	default:
+		return nil, nil, usageErrorf("invalid --no-upstream %q (expected %s, %s or %s)", mode, noUpstreamSkip, noUpstreamFail, noUpstreamSet)
+	}
+	ctx, cancel := runContext()
+	defer cancel()
+	setUpstream = map[string]string{}
+	for _, r := range repos {
+		branch := lacksUpstream(ctx, r)
+		switch {
+		case branch == "":
+			ok = append(ok, r)
+		case mode == noUpstreamSet:
+			setUpstream[r] = defaultRemote(ctx, gitExec, r)
+			ok = append(ok, r)
+		case mode == noUpstreamFail:
+			repoFailed(r, fmt.Errorf("branch %s has no upstream", branch))
+		default:
+			markRepoSkipped(r, "no upstream for "+branch)
+			fmt.Fprintf(os.Stderr, "%s %s: branch %s has no upstream\n", paint(os.Stderr, ansiYellow, "skipping"), r, branch)
+		}
+	}
+	if len(ok) == 0 {
+		return nil, nil, noReposErrorf("no repository is on a branch with an upstream")
+	}
+	return ok, setUpstream, nil
+}
+
+// upstreamGroups groups the repositories of setUpstream by remote, so each
+// group can run with the same arguments.
+func upstreamGroups(setUpstream map[string]string) (remotes []string, groups map[string][]string) {
+	groups = map[string][]string{}
+	for r, remote := range setUpstream {
+		groups[remote] = append(groups[remote], r)
+	}
+	for remote := range groups {
+		sort.Strings(groups[remote])
+		remotes = append(remotes, remote)
+	}
+	sort.Strings(remotes)
+	return remotes, groups
+}
+
+// setUpstreamArgs extends pushArgs to push the current branch to remote
+// and make it the branch's upstream.
+func setUpstreamArgs(pushArgs []string, remote string) []string {
+	return append(slices.Clip(pushArgs), "--set-upstream", remote, "HEAD")
+}
+
+// addNoUpstreamFlag registers --no-upstream on pull or push.
+func addNoUpstreamFlag(cmd *cobra.Command, modes string) {
+	cmd.Flags().StringVar(&noUpstreamMode, "no-upstream", noUpstreamSkip, "repositories whose branch has no upstream: "+modes)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestCheckUpstreams(t *testing.T) {
+	ctx := context.Background()
+	tracked, untracked := initTestRepo(t), initTestRepo(t)
+	for _, r := range []string{tracked, untracked} {
+		commitTestFile(t, r, "a.txt", "a", "first")
+	}
+	branch, err := currentBranch(ctx, tracked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the branch tracks itself through the "." remote
+	for _, args := range [][]string{{"config", "branch." + branch + ".remote", "."}, {"config", "branch." + branch + ".merge", "refs/heads/" + branch}} {
+		c := exec.Command("git", args...)
+		c.Dir = tracked
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+	}
+	repos := []string{tracked, untracked}
+	t.Cleanup(func() {
+		delete(runSummary.skipped, untracked)
+		delete(runSummary.failed, untracked)
+	})
+
+	var ok []string
+	stderr := captureStderr(t, func() { ok, _, err = checkUpstreams(repos, noUpstreamSkip) })
+	if err != nil || !reflect.DeepEqual(ok, []string{tracked}) || runSummary.skipped[untracked] != "no upstream for "+branch {
+		t.Errorf("skip: %v, %v, summary %v, stderr %q", ok, err, runSummary.skipped, stderr)
+	}
+	captureStderr(t, func() { ok, _, err = checkUpstreams(repos, noUpstreamFail) })
+	if err != nil || !reflect.DeepEqual(ok, []string{tracked}) || !runSummary.failed[untracked] {
+		t.Errorf("fail: %v, %v, failed %v", ok, err, runSummary.failed)
+	}
+	ok, set, err := checkUpstreams(repos, noUpstreamSet)
+	if err != nil || !reflect.DeepEqual(ok, repos) || !reflect.DeepEqual(set, map[string]string{untracked: "origin"}) {
+		t.Errorf("set-upstream: %v, %v, %v", ok, set, err)
+	}
+	captureStderr(t, func() { _, _, err = checkUpstreams([]string{untracked}, noUpstreamSkip) })
+	if err == nil {
+		t.Error("no repository left but no error")
+	}
+	if _, _, err := checkUpstreams(repos, "ignore"); err == nil {
+		t.Error("invalid mode accepted")
+	}
+}
+
+func TestUpstreamGroups(t *testing.T) {
+	remotes, groups := upstreamGroups(map[string]string{"/src/b": "origin", "/src/a": "origin", "/src/c": "fork"})
+	if !reflect.DeepEqual(remotes, []string{"fork", "origin"}) || !reflect.DeepEqual(groups["origin"], []string{"/src/a", "/src/b"}) {
+		t.Errorf("upstreamGroups = %v, %v", remotes, groups)
+	}
+	if got := setUpstreamArgs([]string{"push", "--force"}, "fork"); !reflect.DeepEqual(got, []string{"push", "--force", "--set-upstream", "fork", "HEAD"}) {
+		t.Errorf("setUpstreamArgs = %q", got)
+	}
+}
+
+func TestNoUpstreamFlagConflicts(t *testing.T) {
+	oldMode, oldSet := noUpstreamMode, pushSetUpstream
+	f := pushCmd.Flags().Lookup("no-upstream")
+	t.Cleanup(func() { noUpstreamMode, pushSetUpstream, f.Changed = oldMode, oldSet, false })
+
+	noUpstreamMode = noUpstreamSet
+	if err := pullCmd.RunE(pullCmd, []string{t.TempDir()}); exitCode(err) != exitUsage {
+		t.Errorf("pull --no-upstream=%s: exit %d (%v), want %d", noUpstreamSet, exitCode(err), err, exitUsage)
+	}
+	if err := pushCmd.Flags().Set("no-upstream", noUpstreamFail); err != nil {
+		t.Fatal(err)
+	}
+	pushSetUpstream = true
+	if err := pushCmd.RunE(pushCmd, []string{t.TempDir()}); exitCode(err) != exitUsage {
+		t.Errorf("push --set-upstream --no-upstream=%s: exit %d (%v), want %d", noUpstreamFail, exitCode(err), err, exitUsage)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+// upstreamRange describes one direction between a branch and its upstream
+// for outgoing and incoming.
+type upstreamRange struct {
+	from, to string // git log from..to lists the commits
+	verb     string // "push to" or "pull from", before the upstream name
+}
+
+// pushRange is what push would send: the commits the upstream lacks.
+var pushRange = upstreamRange{from: "@{u}", to: "HEAD", verb: "push to"}
+
+// showUpstreamRange prints, for each of repos, the commits in r and the
+// diffstat of the changes they make, the log cut at limit commits.
+// Repositories without an upstream are skipped and those without commits
+// in r are left out. It returns the number of commits and of repositories
+// that have any.
+func showUpstreamRange(ctx context.Context, repos []string, r upstreamRange, limit int) (total, with int) {
+	for _, repo := range repos {
+		upstream, err := gitOutput(ctx, repo, "rev-parse", "--abbrev-ref", "@{u}")
+		if err != nil {
+			fmt.Printf("%s: no upstream, skipped\n", repo)
+			markRepoSkipped(repo, "no upstream")
+			continue
+		}
+		c, err := compareRefs(ctx, repo, r.from, r.to, limit)
+		if err != nil {
+			repoFailed(repo, err)
+			continue
+		}
+		if c.count == 0 {
+			continue
+		}
+		// three dots: the changes since the merge base, as the other side
+		// would receive them
+		var stat []string
+		err = gitExec.Lines(ctx, runner.Git(repo, "diff", "--stat", r.from+"..."+r.to), func(line string) {
+			stat = append(stat, line)
+		})
+		if err != nil {
+			repoFailed(repo, err)
+			continue
+		}
+		total += c.count
+		with++
+		repoHeader(repo)
+		fmt.Printf("%d commits to %s %s\n", c.count, r.verb, upstream)
+		for _, l := range c.log {
+			fmt.Printf("  %s\n", l)
+		}
+		if more := c.count - len(c.log); more > 0 {
+			fmt.Printf("  ... and %d more\n", more)
+		}
+		if len(stat) > 0 {
+			fmt.Printf("\n%s\n", strings.Join(stat, "\n"))
+		}
+	}
+	return total, with
+}
+
+// outgoing command
+var outgoingLimit int
+var outgoingCmd = &cobra.Command{
+	Use:   "outgoing [-n 10] <pattern>...",
+	Short: "Show the commits and diffstat that push would send from matching repositories",
+	Long: `outgoing prints, for every matching repository, the commits on the
+current branch that its upstream lacks (git log @{u}..HEAD), newest -n
+first, and the diffstat of the changes they make. It is the review step
+before push --yes. Repositories with nothing to push are left out, and those
+without an upstream are skipped. The upstream is compared as last fetched,
+so fetch first if others may have pushed since.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if outgoingLimit < 0 {
+			return usageErrorf("-n must not be negative")
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+		total, with := showUpstreamRange(ctx, repos, pushRange, outgoingLimit)
+		fmt.Printf("\n%d commits to push in %d of %d repositories\n", total, with, len(repos))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(outgoingCmd)
+
+	outgoingCmd.Flags().IntVarP(&outgoingLimit, "max-count", "n", 10, "commits to list per repository (0 = all)")
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cloneTestRepo clones upstream into a new directory.
+func cloneTestRepo(t *testing.T, upstream string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "clone")
+	if out, err := exec.Command("git", "clone", "-q", upstream, dir).CombinedOutput(); err != nil {
+		t.Fatalf("git clone: %v %s", err, out)
+	}
+	initTestRepoAt(t, dir) // sets the committer identity
+	return dir
+}
+
+func TestOutgoing(t *testing.T) {
+	ctx := context.Background()
+	upstream := initTestRepo(t)
+	commitTestFile(t, upstream, "a.txt", "a", "first")
+	ahead, even := cloneTestRepo(t, upstream), cloneTestRepo(t, upstream)
+	commitTestFile(t, ahead, "b.txt", "b\n", "add b")
+	commitTestFile(t, ahead, "a.txt", "changed\n", "change a")
+	noUpstream := initTestRepo(t)
+	commitTestFile(t, noUpstream, "c.txt", "c", "first")
+
+	var total, with int
+	out := captureStdout(t, func() {
+		total, with = showUpstreamRange(ctx, []string{ahead, even, noUpstream}, pushRange, 1)
+	})
+	if total != 2 || with != 1 {
+		t.Errorf("total = %d, with = %d", total, with)
+	}
+	for _, want := range []string{"2 commits to push to origin/", " change a\n", "... and 1 more", "a.txt", "b.txt", "2 files changed", noUpstream + ": no upstream, skipped"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, even) {
+		t.Errorf("repository with nothing to push listed:\n%s", out)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// owner is an author with the number of commits (or, by lines, blamed lines)
+// they contributed to a file.
+type owner struct {
+	name  string
+	count int
+}
+
+// fileCommitters returns the authors of commits touching path on HEAD, most
+// active first.
+func fileCommitters(ctx context.Context, repo, path string) ([]owner, error) {
+	// shortlog reads revisions from stdin unless one is given
+	out, err := gitOutput(ctx, repo, "shortlog", "-sne", "HEAD", "--", path)
+	if err != nil {
+		return nil, err
+	}
+	var owners []owner
+	for _, line := range strings.Split(out, "\n") {
+		count, name, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok {
+			continue
+		}
+		n, _ := strconv.Atoi(count)
+		owners = append(owners, owner{name: name, count: n})
+	}
+	return owners, nil
+}
+
+// fileLineOwners counts the lines of path at HEAD by the author who last
+// changed them, most lines first.
+func fileLineOwners(ctx context.Context, repo, path string) ([]owner, error) {
+	out, err := gitOutput(ctx, repo, "blame", "--line-porcelain", "HEAD", "--", path)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	var name string
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "author "):
+			name = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-mail "):
+			counts[name+" "+strings.TrimPrefix(line, "author-mail ")]++
+		}
+	}
+	owners := make([]owner, 0, len(counts))
+	for n, c := range counts {
+		owners = append(owners, owner{name: n, count: c})
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		if owners[i].count != owners[j].count {
+			return owners[i].count > owners[j].count
+		}
+		return owners[i].name < owners[j].name
+	})
+	return owners, nil
+}
+
+// lastChange describes the last commit on HEAD touching path.
+func lastChange(ctx context.Context, repo, path string) (string, error) {
+	return gitOutput(ctx, repo, "log", "-1", "--format=%ad %an (%h)", "--date=short", "HEAD", "--", path)
+}
+
+func formatOwners(owners []owner, top int) string {
+	if len(owners) > top {
+		owners = owners[:top]
+	}
+	parts := make([]string, len(owners))
+	for i, o := range owners {
+		parts[i] = fmt.Sprintf("%s (%d)", o.name, o.count)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// owners command
+var ownersFile string
+var ownersTop int
+var ownersByLines bool
+var ownersCmd = &cobra.Command{
+	Use:   "owners --file <path> <pattern>...",
+	Short: "Show who maintains a file in each matching repository",
+	Long: `owners reports, for every repository that has --file at HEAD, its most
+active committers and its last change. With --by-lines the authors are
+ranked by the lines of the current file they last changed (git blame)
+instead of by commit count.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if ownersFile == "" {
+			return usageErrorf("--file is required")
+		}
+		if ownersTop < 1 {
+			return usageErrorf("--top must be at least 1")
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		found := 0
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPO\tLAST CHANGE\tOWNERS")
+		for _, r := range repos {
+			if _, err := gitOutput(ctx, r, "cat-file", "-e", "HEAD:"+ownersFile); err != nil {
+				continue // file not present
+			}
+			var owners []owner
+			if ownersByLines {
+				owners, err = fileLineOwners(ctx, r, ownersFile)
+			} else {
+				owners, err = fileCommitters(ctx, r, ownersFile)
+			}
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			last, err := lastChange(ctx, r, ownersFile)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			found++
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r, last, formatOwners(owners, ownersTop))
+		}
+		w.Flush()
+		fmt.Printf("\n%s found in %d of %d repositories\n", ownersFile, found, len(repos))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ownersCmd)
+
+	ownersCmd.Flags().StringVar(&ownersFile, "file", "", "file path relative to the repository root")
+	ownersCmd.Flags().IntVar(&ownersTop, "top", 3, "number of owners to show per repository")
+	ownersCmd.Flags().BoolVar(&ownersByLines, "by-lines", false, "rank authors by blamed lines instead of commits")
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFileOwners(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	alice := []string{"GIT_AUTHOR_NAME=Alice", "GIT_AUTHOR_EMAIL=alice@example.com"}
+	bob := []string{"GIT_AUTHOR_NAME=Bob", "GIT_AUTHOR_EMAIL=bob@example.com"}
+	commitTestFile(t, repo, "Makefile", "a\nb\nc\n", "one", alice...)
+	commitTestFile(t, repo, "Makefile", "a\nb\nC\n", "two", bob...)
+	commitTestFile(t, repo, "Makefile", "a\nB\nC\n", "three", bob...)
+	commitTestFile(t, repo, "other.txt", "x\n", "unrelated", alice...)
+
+	committers, err := fileCommitters(ctx, repo, "Makefile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := formatOwners(committers, 3); got != "Bob <bob@example.com> (2), Alice <alice@example.com> (1)" {
+		t.Errorf("unexpected committers: %s", got)
+	}
+
+	lines, err := fileLineOwners(ctx, repo, "Makefile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := formatOwners(lines, 1); got != "Bob <bob@example.com> (2)" {
+		t.Errorf("unexpected line owners: %s", got)
+	}
+
+	last, err := lastChange(ctx, repo, "Makefile")
+	if err != nil || !strings.Contains(last, "Bob") {
+		t.Errorf("unexpected last change: %q, %v", last, err)
+	}
+}
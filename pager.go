@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// usePager is --pager: show the output of the whole run in $PAGER.
+var usePager bool
+
+// pager is the running pager, nil without --pager.
+var pager struct {
+	cmd    *exec.Cmd
+	w      *os.File // the pager's stdin, standing in for stdout and stderr
+	stdout *os.File
+	stderr *os.File
+}
+
+// pagerCommand is the pager to run: $GITBATCH_PAGER, $PAGER or less.
+func pagerCommand() string {
+	for _, env := range []string{"GITBATCH_PAGER", "PAGER"} {
+		if p := strings.TrimSpace(os.Getenv(env)); p != "" {
+			return p
+		}
+	}
+	return "less"
+}
+
+// setupPager starts the pager for --pager and sends stdout and stderr
+// through it. Only read-only commands are paged, since prompts would end
+// up inside the pager. Without a terminal, or with the pager set to cat,
+// output is left alone.
+func setupPager(cmd *cobra.Command) error {
+	if !usePager {
+		return nil
+	}
+	if !allowedReadOnly(cmd) {
+		return usageErrorf("--pager only works with commands that do not change repositories")
+	}
+	name := pagerCommand()
+	if name == "cat" || !isTerminal(os.Stdout) {
+		return nil
+	}
+	if colorMode == "auto" && colorEnabled(os.Stdout) {
+		colorMode = "always" // keep colors when writing to the pager
+	}
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		f := strings.Fields(name)
+		c = exec.Command(f[0], f[1:]...)
+	} else {
+		c = exec.Command("sh", "-c", name)
+	}
+	c.Stdout, c.Stderr = os.Stdout, os.Stderr
+	c.Env = os.Environ()
+	if os.Getenv("LESS") == "" {
+		// as git does: quit when the output fits, keep colors, leave the
+		// output on the screen
+		c.Env = append(c.Env, "LESS=FRX")
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	c.Stdin = r
+	if err := c.Start(); err != nil {
+		r.Close()
+		w.Close()
+		return fmt.Errorf("starting pager %q: %v", name, err)
+	}
+	r.Close()
+	pager.cmd, pager.w, pager.stdout, pager.stderr = c, w, os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	return nil
+}
+
+// closePager ends the output of the run and waits until the pager is quit.
+func closePager() {
+	if pager.cmd == nil {
+		return
+	}
+	os.Stdout, os.Stderr = pager.stdout, pager.stderr
+	pager.w.Close()
+	pager.cmd.Wait()
+	pager.cmd = nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&usePager, "pager", false, "show the output of the whole run in $PAGER (read-only commands only)")
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestPagerCommand(t *testing.T) {
+	t.Setenv("GITBATCH_PAGER", "")
+	t.Setenv("PAGER", "")
+	if got := pagerCommand(); got != "less" {
+		t.Errorf("default pager = %q", got)
+	}
+	t.Setenv("PAGER", "most")
+	if got := pagerCommand(); got != "most" {
+		t.Errorf("$PAGER = %q", got)
+	}
+	t.Setenv("GITBATCH_PAGER", "less -S")
+	if got := pagerCommand(); got != "less -S" {
+		t.Errorf("$GITBATCH_PAGER = %q", got)
+	}
+}
+
+func TestSetupPager(t *testing.T) {
+	t.Cleanup(func() { usePager = false })
+	run := func(*cobra.Command, []string) {}
+	root := &cobra.Command{Use: "gitbatch"}
+	status := &cobra.Command{Use: "status", Run: run}
+	pull := &cobra.Command{Use: "pull", Run: run}
+	root.AddCommand(status, pull)
+
+	usePager = true
+	if err := setupPager(pull); exitCode(err) != exitUsage {
+		t.Errorf("--pager with pull: %v", err)
+	}
+	// go test's stdout is not a terminal, so nothing is started
+	if err := setupPager(status); err != nil || pager.cmd != nil {
+		t.Errorf("--pager without a terminal: %v, %v", err, pager.cmd)
+	}
+	closePager()
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"strings"
+)
+
+var statusPathSpecs []string
+var diffPathSpecs []string
+
+// withPathSpecs appends pathspecs to git args after a "--" separator.
+func withPathSpecs(args, pathspecs []string) []string {
+	if len(pathspecs) == 0 {
+		return args
+	}
+	return append(append(append([]string{}, args...), "--"), pathspecs...)
+}
+
+// changedUnder keeps the repositories in which git args, limited to
+// pathspecs, lists at least one file; the others are recorded as skipped in
+// the run summary. Without pathspecs every repository is kept.
+func changedUnder(ctx context.Context, repos, pathspecs []string, args ...string) []string {
+	if len(pathspecs) == 0 {
+		return repos
+	}
+	var changed []string
+	for _, r := range repos {
+		out, err := gitOutput(ctx, r, withPathSpecs(args, pathspecs)...)
+		if err != nil {
+			repoFailed(r, err)
+			continue
+		}
+		if out == "" {
+			markRepoSkipped(r, "no changes under "+strings.Join(pathspecs, " "))
+			continue
+		}
+		changed = append(changed, r)
+	}
+	return changed
+}
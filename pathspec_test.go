@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWithPathSpecs(t *testing.T) {
+	args := []string{"status"}
+	if got := withPathSpecs(args, nil); !reflect.DeepEqual(got, args) {
+		t.Errorf("without pathspecs = %q", got)
+	}
+	got := withPathSpecs(args, []string{"src/api", "docs"})
+	if want := []string{"status", "--", "src/api", "docs"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("withPathSpecs = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(args, []string{"status"}) {
+		t.Errorf("args modified: %q", args)
+	}
+}
+
+func TestChangedUnder(t *testing.T) {
+	ctx := context.Background()
+	api := initTestRepo(t)
+	commitTestFile(t, api, "src/api/h.go", "a", "first")
+	docs := initTestRepo(t)
+	commitTestFile(t, docs, "docs/a.md", "a", "first")
+	commitTestFile(t, docs, "src/api/h.go", "a", "first")
+	for _, f := range []string{filepath.Join(api, "src/api/h.go"), filepath.Join(docs, "docs/a.md")} {
+		if err := os.WriteFile(f, []byte("changed"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repos := []string{api, docs}
+
+	if got := changedUnder(ctx, repos, nil, "status", "--porcelain"); !reflect.DeepEqual(got, repos) {
+		t.Errorf("without pathspecs = %q", got)
+	}
+	if got := changedUnder(ctx, repos, []string{"src/api"}, "status", "--porcelain"); !reflect.DeepEqual(got, []string{api}) {
+		t.Errorf("under src/api = %q", got)
+	}
+	if got := changedUnder(ctx, repos, []string{"src/api", "docs"}, "diff", "--name-only"); !reflect.DeepEqual(got, repos) {
+		t.Errorf("under src/api and docs = %q", got)
+	}
+
+	s, err := readRepoStateUnder(ctx, docs, []string{"src/api"})
+	if err != nil || !s.clean() {
+		t.Errorf("state of docs under src/api = %+v, %v", s, err)
+	}
+}
@@ -0,0 +1,247 @@
+// Package discover finds git repositories from shell-style glob patterns,
+// including recursive ** patterns.
+package discover
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// ErrNoRepos is returned by Find when no pattern matched a repository.
+var ErrNoRepos = errors.New("no git repositories found for given pattern(s)")
+
+// ErrInvalidPattern is returned by Find for a pattern that is not a valid
+// glob, or regular expression with Options.Regex.
+var ErrInvalidPattern = errors.New("invalid pattern")
+
+// Repo is a git repository found by Find.
+type Repo struct {
+	Path string // absolute path of the matched directory
+	Name string // base name of Path
+}
+
+// Find returns the repositories matching patterns, in pattern order and
+// without duplicates. Patterns are resolved against the current directory;
+// a matched file stands for its parent directory.
+func Find(patterns []string) ([]Repo, error) {
+	return FindFunc(patterns, IsGitRepo)
+}
+
+// FindFunc is Find with a custom test for whether a directory is a
+// repository.
+func FindFunc(patterns []string, isRepo func(dir string) bool) ([]Repo, error) {
+	return FindWith(patterns, Options{IsRepo: isRepo})
+}
+
+// Options tune how FindWith matches patterns.
+type Options struct {
+	// IsRepo tests whether a directory is a repository; IsGitRepo when nil.
+	IsRepo func(dir string) bool
+	// FollowSymlinks lets ** descend into symlinked directories. Symlinks
+	// that lead back to a directory being walked are not followed again.
+	// Without it, a symlink is only matched itself, never walked into.
+	FollowSymlinks bool
+	// CaseInsensitive matches glob patterns regardless of case.
+	CaseInsensitive bool
+	// Regex treats patterns as regular expressions that must match a whole
+	// directory path, relative to the current directory with / separators.
+	Regex bool
+}
+
+// FindWith is Find with options. A repository reached through several
+// paths, such as a symlink and its target, is returned once, under the
+// first path that matched.
+func FindWith(patterns []string, opts Options) ([]Repo, error) {
+	isRepo := opts.IsRepo
+	if isRepo == nil {
+		isRepo = IsGitRepo
+	}
+	var globOpts []doublestar.GlobOption
+	if !opts.FollowSymlinks {
+		globOpts = append(globOpts, doublestar.WithNoFollow())
+	}
+	if opts.CaseInsensitive {
+		globOpts = append(globOpts, doublestar.WithCaseInsensitive())
+	}
+	var dirs []string // every directory, listed once for regex patterns
+	seen := map[string]struct{}{}
+	var repos []Repo
+	for _, pat := range patterns {
+		var matches []string
+		var err error
+		if opts.Regex {
+			if dirs == nil {
+				if dirs, err = listDirs(dirFS(".", opts), globOpts); err != nil {
+					return nil, err
+				}
+			}
+			if matches, err = matchRegex(dirs, pat); err != nil {
+				return nil, err
+			}
+		} else if matches, err = glob(pat, opts, globOpts); err != nil {
+			// try fallback to filepath.Glob (handles simple globs and cases where shell already expanded)
+			matches2, err2 := filepath.Glob(pat)
+			if err2 != nil {
+				return nil, fmt.Errorf("%w %q: %v", ErrInvalidPattern, pat, err)
+			}
+			matches = matches2
+		}
+
+		// doublestar.Glob returns paths relative to FS root; convert to OS paths
+		for _, m := range matches {
+			// doublestar returns paths with unix separators when using DirFS; ensure correct OS path
+			mp := filepath.FromSlash(m)
+			abs, err := filepath.Abs(mp)
+			if err != nil {
+				abs = mp
+			}
+			fi, err := os.Stat(abs)
+			if err != nil {
+				continue
+			}
+			if !fi.IsDir() {
+				// if it's a file, consider its parent
+				abs = filepath.Dir(abs)
+			}
+			key := abs
+			if real, err := filepath.EvalSymlinks(abs); err == nil {
+				key = real
+			}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			if isRepo(abs) {
+				seen[key] = struct{}{}
+				repos = append(repos, Repo{Path: abs, Name: filepath.Base(abs)})
+			}
+		}
+	}
+	if len(repos) == 0 {
+		return nil, ErrNoRepos
+	}
+	return repos, nil
+}
+
+// dirFS is the file system rooted at dir that patterns are matched in.
+func dirFS(dir string, opts Options) fs.FS {
+	if opts.FollowSymlinks {
+		return newLoopFS(dir)
+	}
+	return os.DirFS(dir)
+}
+
+// glob expands a glob pattern with doublestar. Absolute patterns are
+// matched below their leading directory, the part without meta characters,
+// and the matches keep that directory in front.
+func glob(pat string, opts Options, globOpts []doublestar.GlobOption) ([]string, error) {
+	pat = slashPattern(pat, runtime.GOOS)
+	base, rel := ".", pat
+	if isAbsPattern(pat, runtime.GOOS) {
+		base, rel = splitAbsPattern(pat)
+	}
+	matches, err := doublestar.Glob(dirFS(base, opts), globPattern(rel, opts), globOpts...)
+	if err != nil || base == "." {
+		return matches, err
+	}
+	for i, m := range matches {
+		matches[i] = joinBase(base, m)
+	}
+	return matches, nil
+}
+
+// globPattern returns pat for doublestar. For case-insensitive matching
+// every letter becomes a character class, because doublestar compares the
+// part of a pattern before its first meta character as the file system
+// does, which is case-sensitive outside Windows and macOS.
+func globPattern(pat string, opts Options) string {
+	if !opts.CaseInsensitive {
+		return pat
+	}
+	var b strings.Builder
+	inClass := false
+	runes := []rune(pat)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			b.WriteRune(r)
+			b.WriteRune(runes[i])
+		case r == '[':
+			inClass = true
+			b.WriteRune(r)
+		case r == ']':
+			inClass = false
+			b.WriteRune(r)
+		case !inClass && unicode.ToLower(r) != unicode.ToUpper(r):
+			b.WriteString("[" + string(unicode.ToLower(r)) + string(unicode.ToUpper(r)) + "]")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// listDirs lists every directory below the root of fsys, except the
+// insides of .git directories.
+func listDirs(fsys fs.FS, globOpts []doublestar.GlobOption) ([]string, error) {
+	dirs := []string{}
+	err := doublestar.GlobWalk(fsys, "**", func(p string, d fs.DirEntry) error {
+		switch {
+		case d.IsDir() && d.Name() == ".git":
+			return fs.SkipDir
+		case d.IsDir() || d.Type()&fs.ModeSymlink != 0:
+			dirs = append(dirs, p)
+		}
+		return nil
+	}, globOpts...)
+	return dirs, err
+}
+
+// matchRegex returns the dirs that pattern matches as a whole.
+func matchRegex(dirs []string, pattern string) ([]string, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrInvalidPattern, pattern, err)
+	}
+	var matches []string
+	for _, d := range dirs {
+		if re.MatchString(d) {
+			matches = append(matches, d)
+		}
+	}
+	return matches, nil
+}
+
+// Paths returns the paths of repos.
+func Paths(repos []Repo) []string {
+	paths := make([]string, len(repos))
+	for i, r := range repos {
+		paths[i] = r.Path
+	}
+	return paths
+}
+
+// IsGitRepo reports whether dir is inside a git work tree.
+func IsGitRepo(dir string) bool {
+	// Prefer calling git to detect repository (handles git worktrees and submodules)
+	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
+	cmd.Dir = dir
+	cmd.Env = runner.Environ()
+	out, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(out)) == "true"
+}
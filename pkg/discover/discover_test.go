@@ -0,0 +1,101 @@
+package discover
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a", "nested/b"} {
+		dir := filepath.Join(root, name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if out, err := exec.Command("git", "init", dir).CombinedOutput(); err != nil {
+			t.Fatalf("git init failed: %v, out=%s", err, out)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "plain"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(root)
+
+	repos, err := Find([]string{"**", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 2 {
+		t.Fatalf("expected 2 repositories without duplicates, got %+v", repos)
+	}
+	for _, r := range repos {
+		if !filepath.IsAbs(r.Path) || r.Name != filepath.Base(r.Path) {
+			t.Errorf("unexpected repo %+v", r)
+		}
+	}
+	if got := Paths(repos); len(got) != 2 || got[0] != repos[0].Path {
+		t.Errorf("Paths = %v", got)
+	}
+
+	if _, err := Find([]string{"plain"}); !errors.Is(err, ErrNoRepos) {
+		t.Errorf("expected ErrNoRepos, got %v", err)
+	}
+}
+
+func TestFindPatternModes(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"Services/API", "services/web", "tools/cli"} {
+		if err := os.MkdirAll(filepath.Join(root, name, ".git"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(root)
+	isRepo := func(dir string) bool {
+		_, err := os.Stat(filepath.Join(dir, ".git"))
+		return err == nil
+	}
+	names := func(repos []Repo) []string {
+		var n []string
+		for _, r := range repos {
+			rel, _ := filepath.Rel(root, r.Path)
+			n = append(n, filepath.ToSlash(rel))
+		}
+		sort.Strings(n)
+		return n
+	}
+
+	repos, err := FindWith([]string{"services/*"}, Options{IsRepo: isRepo})
+	if err != nil || !reflect.DeepEqual(names(repos), []string{"services/web"}) {
+		t.Errorf("case-sensitive glob = %v, %v", names(repos), err)
+	}
+	repos, err = FindWith([]string{"services/*"}, Options{IsRepo: isRepo, CaseInsensitive: true})
+	if err != nil || !reflect.DeepEqual(names(repos), []string{"Services/API", "services/web"}) {
+		t.Errorf("case-insensitive glob = %v, %v", names(repos), err)
+	}
+
+	repos, err = FindWith([]string{`(?i)services/(api|web)`, `tools/.*`}, Options{IsRepo: isRepo, Regex: true})
+	if err != nil || !reflect.DeepEqual(names(repos), []string{"Services/API", "services/web", "tools/cli"}) {
+		t.Errorf("regex = %v, %v", names(repos), err)
+	}
+	// the whole path must match
+	if _, err := FindWith([]string{`cli`}, Options{IsRepo: isRepo, Regex: true}); !errors.Is(err, ErrNoRepos) {
+		t.Errorf("partial regex match accepted: %v", err)
+	}
+	if _, err := FindWith([]string{`services/(`}, Options{IsRepo: isRepo, Regex: true}); !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("invalid regex: %v", err)
+	}
+}
+
+func TestGlobPattern(t *testing.T) {
+	if got := globPattern("Repos/*", Options{}); got != "Repos/*" {
+		t.Errorf("case-sensitive pattern changed to %q", got)
+	}
+	if got, want := globPattern(`Re\*/[Ab]-1/**`, Options{CaseInsensitive: true}), `[rR][eE]\*/[Ab]-1/**`; got != want {
+		t.Errorf("globPattern = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package discover
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// IgnoreFile is the name of the file, in gitignore syntax, that excludes
+// paths below its directory from discovery.
+const IgnoreFile = ".gitbatchignore"
+
+// Ignore matches paths against the patterns of an ignore file.
+type Ignore struct {
+	Path    string // the ignore file
+	root    []string
+	matcher gitignore.Matcher
+}
+
+// LoadIgnore reads the ignore file in dir or the nearest parent directory
+// that has one. It returns nil, and no error, when there is none.
+func LoadIgnore(dir string) (*Ignore, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		path := filepath.Join(dir, IgnoreFile)
+		f, err := os.Open(path)
+		if err == nil {
+			defer f.Close()
+			return parseIgnore(path, f)
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+func parseIgnore(path string, f *os.File) (*Ignore, error) {
+	var patterns []gitignore.Pattern
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return &Ignore{
+		Path:    path,
+		root:    splitPath(filepath.Dir(path)),
+		matcher: gitignore.NewMatcher(patterns),
+	}, nil
+}
+
+// Ignored reports whether the directory at the absolute path dir, or one of
+// its parents below the ignore file, is excluded. As in git, nothing inside
+// an excluded directory can be included again.
+func (ig *Ignore) Ignored(dir string) bool {
+	if ig == nil {
+		return false
+	}
+	parts := splitPath(dir)
+	if len(parts) <= len(ig.root) {
+		return false
+	}
+	for i, p := range ig.root {
+		if parts[i] != p {
+			return false // outside the ignore file's directory
+		}
+	}
+	rel := parts[len(ig.root):]
+	for i := 1; i <= len(rel); i++ {
+		if ig.matcher.Match(rel[:i], true) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitPath(p string) []string {
+	return strings.FieldsFunc(filepath.ToSlash(filepath.Clean(p)), func(r rune) bool { return r == '/' })
+}
@@ -0,0 +1,50 @@
+package discover
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnore(t *testing.T) {
+	root := t.TempDir()
+	content := "# archived clones\narchive/\n*-old\n/scratch\n\nexperiments/*\n!experiments/keep\n"
+	if err := os.WriteFile(filepath.Join(root, IgnoreFile), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(root, "services")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// found from a subdirectory
+	ig, err := LoadIgnore(sub)
+	if err != nil || ig == nil {
+		t.Fatalf("LoadIgnore = %v, %v", ig, err)
+	}
+	if ig.Path != filepath.Join(root, IgnoreFile) {
+		t.Errorf("Path = %q", ig.Path)
+	}
+	for dir, want := range map[string]bool{
+		"services/api":         false,
+		"archive/api":          true,
+		"services/archive/api": true,
+		"services/api-old":     true,
+		"scratch":              true,
+		"services/scratch":     false,
+		"experiments/a":        true,
+		"experiments/keep":     false,
+		"archive":              true,
+	} {
+		if got := ig.Ignored(filepath.Join(root, dir)); got != want {
+			t.Errorf("Ignored(%s) = %v, want %v", dir, got, want)
+		}
+	}
+	if ig.Ignored(root) || ig.Ignored(filepath.Join(filepath.Dir(root), "archive")) {
+		t.Error("paths outside the ignore file's directory are ignored")
+	}
+
+	if ig, err := LoadIgnore(t.TempDir()); err != nil || ig != nil || ig.Ignored(root) {
+		t.Errorf("without an ignore file: %v, %v", ig, err)
+	}
+}
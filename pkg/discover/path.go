@@ -0,0 +1,59 @@
+package discover
+
+import (
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// slashPattern writes a pattern for goos with / separators. On Windows
+// backslashes separate paths rather than escape meta characters, as in
+// doublestar.FilepathGlob, and the \\?\ prefix of long paths is dropped:
+// the os package adds it back where needed, and its ? would be taken for a
+// meta character.
+func slashPattern(pat, goos string) string {
+	if goos != "windows" {
+		return pat
+	}
+	switch {
+	case strings.HasPrefix(pat, `\\?\UNC\`):
+		pat = `\\` + pat[len(`\\?\UNC\`):]
+	case strings.HasPrefix(pat, `\\?\`):
+		pat = pat[len(`\\?\`):]
+	}
+	return strings.ReplaceAll(pat, `\`, "/")
+}
+
+// isAbsPattern reports whether a pattern from slashPattern is absolute: it
+// starts at the root, or on Windows at a drive letter or a UNC share.
+func isAbsPattern(pat, goos string) bool {
+	if strings.HasPrefix(pat, "/") {
+		return true
+	}
+	return goos == "windows" && len(pat) >= 3 && pat[1] == ':' && pat[2] == '/' &&
+		('a' <= pat[0] && pat[0] <= 'z' || 'A' <= pat[0] && pat[0] <= 'Z')
+}
+
+// splitAbsPattern splits an absolute pattern into its leading directory and
+// the pattern below it. A drive letter keeps its slash, since C: alone is
+// the current directory of drive C.
+func splitAbsPattern(pat string) (base, rel string) {
+	base, rel = doublestar.SplitPattern(pat)
+	if strings.HasSuffix(base, ":") {
+		base += "/"
+	}
+	return base, rel
+}
+
+// joinBase puts the leading directory of an absolute pattern back in front
+// of a match below it. Unlike path.Join it keeps the double slash of UNC
+// paths.
+func joinBase(base, match string) string {
+	switch {
+	case match == ".":
+		return base
+	case strings.HasSuffix(base, "/"):
+		return base + match
+	}
+	return base + "/" + match
+}
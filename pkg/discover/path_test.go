@@ -0,0 +1,70 @@
+package discover
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestWindowsPatterns(t *testing.T) {
+	for _, tt := range []struct {
+		pat, slashed string
+		abs          bool
+		base, rel    string
+	}{
+		{`C:\work\**`, "C:/work/**", true, "C:/work", "**"},
+		{`c:\*`, "c:/*", true, "c:/", "*"},
+		{`\\server\share\repos\*`, "//server/share/repos/*", true, "//server/share/repos", "*"},
+		{`\\?\C:\very\long\path\*`, "C:/very/long/path/*", true, "C:/very/long/path", "*"},
+		{`\\?\UNC\server\share\*`, "//server/share/*", true, "//server/share", "*"},
+		{`repos\*`, "repos/*", false, "", ""},
+	} {
+		slashed := slashPattern(tt.pat, "windows")
+		if slashed != tt.slashed {
+			t.Errorf("slashPattern(%s) = %q, want %q", tt.pat, slashed, tt.slashed)
+			continue
+		}
+		if abs := isAbsPattern(slashed, "windows"); abs != tt.abs {
+			t.Errorf("isAbsPattern(%s) = %v", slashed, abs)
+		}
+		if !tt.abs {
+			continue
+		}
+		if base, rel := splitAbsPattern(slashed); base != tt.base || rel != tt.rel {
+			t.Errorf("splitAbsPattern(%s) = %q, %q, want %q, %q", slashed, base, rel, tt.base, tt.rel)
+		}
+	}
+
+	// elsewhere backslashes escape and drive letters mean nothing
+	if got := slashPattern(`a\*`, "linux"); got != `a\*` {
+		t.Errorf("slashPattern on linux = %q", got)
+	}
+	if isAbsPattern("C:/work", "linux") {
+		t.Error("drive letter absolute on linux")
+	}
+
+	for base, want := range map[string]string{"/": "/a", "//srv/share": "//srv/share/a", "C:/": "C:/a"} {
+		if got := joinBase(base, "a"); got != want {
+			t.Errorf("joinBase(%s, a) = %q, want %q", base, got, want)
+		}
+	}
+	if got := joinBase("/work", "."); got != "/work" {
+		t.Errorf("joinBase(/work, .) = %q", got)
+	}
+}
+
+func TestFindAbsolutePattern(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repos", "api")
+	if out, err := exec.Command("git", "init", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v, out=%s", err, out)
+	}
+	t.Chdir(t.TempDir()) // somewhere else entirely
+
+	for _, pat := range []string{filepath.Join(root, "repos", "*"), filepath.Join(root, "**"), repo} {
+		repos, err := Find([]string{pat})
+		if err != nil || len(repos) != 1 || repos[0].Path != repo {
+			t.Errorf("Find(%s) = %+v, %v", pat, repos, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package discover
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// loopFS is os.DirFS that lists a directory as empty when it resolves, through
+// symlinks, to one of the directories above it, so that a ** walk following
+// symlinks ends instead of going round a loop.
+type loopFS struct {
+	fs.FS
+	root string
+	real map[string]string // resolved path per name, "" when it cannot be resolved
+}
+
+func newLoopFS(root string) *loopFS {
+	// resolve against an absolute root so that relative and absolute link
+	// targets compare equal
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return &loopFS{FS: os.DirFS(root), root: root, real: map[string]string{}}
+}
+
+func (l *loopFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if l.loops(name) {
+		return nil, nil
+	}
+	return fs.ReadDir(l.FS, name)
+}
+
+func (l *loopFS) realPath(name string) string {
+	if p, ok := l.real[name]; ok {
+		return p
+	}
+	p, err := filepath.EvalSymlinks(filepath.Join(l.root, filepath.FromSlash(name)))
+	if err != nil {
+		p = ""
+	}
+	l.real[name] = p
+	return p
+}
+
+// loops reports whether name resolves to the same directory as one of its
+// parents.
+func (l *loopFS) loops(name string) bool {
+	real := l.realPath(name)
+	if real == "" {
+		return false
+	}
+	for p := name; p != "." && p != "/"; {
+		p = path.Dir(p)
+		if l.realPath(p) == real {
+			return true
+		}
+	}
+	return false
+}
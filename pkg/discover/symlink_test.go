@@ -0,0 +1,76 @@
+package discover
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindSymlinks(t *testing.T) {
+	root := t.TempDir()
+	real := filepath.Join(root, "real", "api")
+	if err := os.MkdirAll(real, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "init", real).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v, out=%s", err, out)
+	}
+	ws := filepath.Join(root, "ws")
+	if err := os.MkdirAll(filepath.Join(ws, "group"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{
+		"ws/group/projects": filepath.Join(root, "real"), // symlinked project directory
+		"ws/group/loop":     "..",                        // back to ws
+		"ws/api":            real,                        // the same repository again
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+	t.Chdir(ws)
+	isRepo := func(dir string) bool {
+		_, err := os.Stat(filepath.Join(dir, ".git"))
+		return err == nil
+	}
+
+	repos, err := FindWith([]string{"**"}, Options{IsRepo: isRepo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 1 || repos[0].Path != filepath.Join(ws, "api") {
+		t.Errorf("without following symlinks: %+v", repos)
+	}
+
+	repos, err = FindWith([]string{"group/**"}, Options{IsRepo: isRepo})
+	if err != ErrNoRepos {
+		t.Errorf("symlinked directories walked without FollowSymlinks: %+v, %v", repos, err)
+	}
+
+	repos, err = FindWith([]string{"group/**"}, Options{IsRepo: isRepo, FollowSymlinks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 1 || repos[0].Path != filepath.Join(ws, "group", "projects", "api") {
+		t.Errorf("following symlinks: %+v", repos)
+	}
+
+	// the loop leads back to ws, where api and group/projects/api are the
+	// same repository
+	repos, err = FindWith([]string{"**"}, Options{IsRepo: isRepo, FollowSymlinks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 1 {
+		t.Errorf("repository counted more than once: %+v", repos)
+	}
+
+	l := newLoopFS(".")
+	if entries, err := l.ReadDir("group/loop"); err != nil || len(entries) != 0 {
+		t.Errorf("loop listed: %v, %v", entries, err)
+	}
+	if entries, err := l.ReadDir("group/projects"); err != nil || len(entries) != 1 {
+		t.Errorf("symlinked directory listed as %v, %v", entries, err)
+	}
+}
@@ -0,0 +1,78 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut *junitText    `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",cdata"`
+}
+
+type junitText struct {
+	Text string `xml:",cdata"`
+}
+
+// WriteJUnit writes results as a JUnit XML report with one test suite named
+// suite and one test case per repository, so CI systems can show which
+// repositories failed. A failed case carries the error as its message and
+// the git output as its text; passing cases keep the output in system-out.
+func WriteJUnit(w io.Writer, suite string, results []runner.Result) error {
+	s := junitSuite{Name: suite, Tests: len(results)}
+	var total time.Duration
+	for _, r := range results {
+		total += r.Duration
+		c := junitCase{Name: r.Repo, Classname: suite, Time: seconds(r.Duration)}
+		if r.OK() {
+			if r.Output != "" {
+				c.SystemOut = &junitText{Text: r.Output}
+			}
+		} else {
+			s.Failures++
+			c.Failure = &junitFailure{Message: r.Err.Error(), Text: r.Output}
+		}
+		s.Cases = append(s.Cases, c)
+	}
+	s.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Suites: []junitSuite{s}}); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
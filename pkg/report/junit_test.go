@@ -0,0 +1,40 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	results := []runner.Result{
+		{Repo: "/r/a", Output: "Already up to date.\n", Duration: 1500 * time.Millisecond},
+		{Repo: "/r/b", Output: "fatal: unable to access remote", Err: errors.New("exit status 128"), Duration: time.Second},
+	}
+	var b bytes.Buffer
+	if err := WriteJUnit(&b, "gitbatch pull", results); err != nil {
+		t.Fatal(err)
+	}
+	var got junitSuites
+	if err := xml.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, b.String())
+	}
+	if len(got.Suites) != 1 {
+		t.Fatalf("expected one suite, got %+v", got)
+	}
+	s := got.Suites[0]
+	if s.Name != "gitbatch pull" || s.Tests != 2 || s.Failures != 1 || s.Time != "2.500" {
+		t.Errorf("unexpected suite %+v", s)
+	}
+	if s.Cases[0].Name != "/r/a" || s.Cases[0].Failure != nil || s.Cases[0].SystemOut == nil || s.Cases[0].SystemOut.Text != "Already up to date.\n" {
+		t.Errorf("unexpected passing case %+v", s.Cases[0])
+	}
+	f := s.Cases[1].Failure
+	if f == nil || f.Message != "exit status 128" || f.Text != "fatal: unable to access remote" {
+		t.Errorf("unexpected failure %+v", f)
+	}
+}
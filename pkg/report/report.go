@@ -0,0 +1,82 @@
+// Package report turns runner results into text, JSON and summaries.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// Summary counts the outcome of a run.
+type Summary struct {
+	Total     int
+	Succeeded int
+	Failed    []string // repositories, in run order
+}
+
+// Summarize counts results.
+func Summarize(results []runner.Result) Summary {
+	s := Summary{Total: len(results)}
+	for _, r := range results {
+		if r.OK() {
+			s.Succeeded++
+		} else {
+			s.Failed = append(s.Failed, r.Repo)
+		}
+	}
+	return s
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%d of %d repositories succeeded", s.Succeeded, s.Total)
+}
+
+// WriteText writes each result under the "---- repo ----" header used by
+// the gitbatch commands, with failures after the output.
+func WriteText(w io.Writer, results []runner.Result) error {
+	for _, r := range results {
+		if _, err := fmt.Fprintf(w, "\n---- %s ----\n%s", r.Repo, r.Output); err != nil {
+			return err
+		}
+		if r.Output != "" && !strings.HasSuffix(r.Output, "\n") {
+			fmt.Fprintln(w)
+		}
+		if !r.OK() {
+			fmt.Fprintf(w, "error: %v\n", r.Err)
+		}
+	}
+	return nil
+}
+
+// JSONResult is the JSON form of a runner.Result.
+type JSONResult struct {
+	Repo   string `json:"repo"`
+	OK     bool   `json:"ok"`
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+	// Duration is how long the command took, in seconds.
+	Duration float64 `json:"duration_seconds"`
+}
+
+// ToJSON converts results to their JSON form.
+func ToJSON(results []runner.Result) []JSONResult {
+	out := make([]JSONResult, 0, len(results))
+	for _, r := range results {
+		j := JSONResult{Repo: r.Repo, OK: r.OK(), Output: r.Output, Duration: r.Duration.Seconds()}
+		if r.Err != nil {
+			j.Error = r.Err.Error()
+		}
+		out = append(out, j)
+	}
+	return out
+}
+
+// WriteJSON writes results as an indented JSON array.
+func WriteJSON(w io.Writer, results []runner.Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ToJSON(results))
+}
@@ -0,0 +1,50 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+var results = []runner.Result{
+	{Repo: "/r/a", Output: "ok\n"},
+	{Repo: "/r/b", Output: "fatal: boom", Err: errors.New("exit status 128")},
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize(results)
+	if s.Total != 2 || s.Succeeded != 1 || len(s.Failed) != 1 || s.Failed[0] != "/r/b" {
+		t.Errorf("unexpected summary %+v", s)
+	}
+	if s.String() != "1 of 2 repositories succeeded" {
+		t.Errorf("String() = %q", s.String())
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteText(&b, results); err != nil {
+		t.Fatal(err)
+	}
+	want := "\n---- /r/a ----\nok\n\n---- /r/b ----\nfatal: boom\nerror: exit status 128\n"
+	if b.String() != want {
+		t.Errorf("WriteText = %q, want %q", b.String(), want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteJSON(&b, results); err != nil {
+		t.Fatal(err)
+	}
+	var got []JSONResult
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !got[0].OK || got[1].OK || got[1].Error != "exit status 128" {
+		t.Errorf("unexpected JSON %+v", got)
+	}
+}
@@ -0,0 +1,73 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// Table is row data with a header, written as CSV or Markdown so it can be
+// pasted into spreadsheets and wiki pages.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// WriteCSV writes the header and rows as RFC 4180 CSV.
+func (t Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(t.Rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// WriteMarkdown writes a GitHub-flavored Markdown table. Pipes are escaped
+// and line breaks become <br> so multi-line cells stay in one row.
+func (t Table) WriteMarkdown(w io.Writer) error {
+	sep := make([]string, len(t.Header))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	lines := [][]string{t.Header, sep}
+	for _, row := range t.Rows {
+		cells := make([]string, len(row))
+		for i, c := range row {
+			cells[i] = markdownCell(c)
+		}
+		lines = append(lines, cells)
+	}
+	for _, cells := range lines {
+		if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+
+func markdownCell(s string) string {
+	return markdownEscaper.Replace(strings.TrimRight(s, "\n"))
+}
+
+// ResultsTable lays out results with one row per repository: its path,
+// "ok" or "failed", the duration and the error, if any.
+func ResultsTable(results []runner.Result) Table {
+	t := Table{Header: []string{"repo", "status", "duration", "error"}}
+	for _, r := range results {
+		status, msg := "ok", ""
+		if !r.OK() {
+			status, msg = "failed", r.Err.Error()
+		}
+		t.Rows = append(t.Rows, []string{r.Repo, status, r.Duration.Round(time.Millisecond).String(), msg})
+	}
+	return t
+}
@@ -0,0 +1,36 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTableCSV(t *testing.T) {
+	var b bytes.Buffer
+	tbl := Table{Header: []string{"repo", "branch"}, Rows: [][]string{{"/r/a", "main"}, {"/r/b, c", "dev"}}}
+	if err := tbl.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := "repo,branch\n/r/a,main\n\"/r/b, c\",dev\n"; b.String() != want {
+		t.Errorf("WriteCSV = %q, want %q", b.String(), want)
+	}
+}
+
+func TestTableMarkdown(t *testing.T) {
+	var b bytes.Buffer
+	tbl := Table{Header: []string{"repo", "error"}, Rows: [][]string{{"/r/a", "a|b\nc\n"}}}
+	if err := tbl.WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "| repo | error |\n| --- | --- |\n| /r/a | a\\|b<br>c |\n"
+	if b.String() != want {
+		t.Errorf("WriteMarkdown = %q, want %q", b.String(), want)
+	}
+}
+
+func TestResultsTable(t *testing.T) {
+	tbl := ResultsTable(results)
+	if len(tbl.Rows) != 2 || tbl.Rows[0][1] != "ok" || tbl.Rows[1][1] != "failed" || tbl.Rows[1][3] != "exit status 128" {
+		t.Errorf("unexpected table %+v", tbl)
+	}
+}
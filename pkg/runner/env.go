@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// repoEnvVars are the variables that tie git to one repository, as listed
+// by git rev-parse --local-env-vars. A hook exports GIT_DIR, for example,
+// which would point every git command of a batch at the hook's repository.
+var repoEnvVars = map[string]bool{
+	"GIT_ALTERNATE_OBJECT_DIRECTORIES": true,
+	"GIT_CONFIG":                       true,
+	"GIT_CONFIG_PARAMETERS":            true,
+	"GIT_CONFIG_COUNT":                 true,
+	"GIT_OBJECT_DIRECTORY":             true,
+	"GIT_DIR":                          true,
+	"GIT_WORK_TREE":                    true,
+	"GIT_IMPLICIT_WORK_TREE":           true,
+	"GIT_GRAFT_FILE":                   true,
+	"GIT_INDEX_FILE":                   true,
+	"GIT_NO_REPLACE_OBJECTS":           true,
+	"GIT_REPLACE_REF_BASE":             true,
+	"GIT_PREFIX":                       true,
+	"GIT_INTERNAL_SUPER_PREFIX":        true,
+	"GIT_SHALLOW_FILE":                 true,
+	"GIT_COMMON_DIR":                   true,
+}
+
+// RepoEnvVars lists, sorted, the variables that ScrubEnv removes.
+func RepoEnvVars() []string {
+	names := make([]string, 0, len(repoEnvVars))
+	for name := range repoEnvVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ScrubEnv returns env without the variables that tie git to one
+// repository.
+func ScrubEnv(env []string) []string {
+	var out []string
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if !repoEnvVars[name] {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
+
+// Environ is the environment git runs in: the process environment
+// scrubbed with ScrubEnv, then extra, which may set any variable again on
+// purpose.
+func Environ(extra ...string) []string {
+	return append(ScrubEnv(os.Environ()), extra...)
+}
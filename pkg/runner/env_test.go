@@ -0,0 +1,27 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScrubEnv(t *testing.T) {
+	env := []string{"HOME=/home/me", "GIT_DIR=/hook/repo/.git", "GIT_SSH_COMMAND=ssh -v", "GIT_WORK_TREE=/hook/repo", "GIT_DIRECTORY=kept"}
+	want := []string{"HOME=/home/me", "GIT_SSH_COMMAND=ssh -v", "GIT_DIRECTORY=kept"}
+	if got := ScrubEnv(env); !reflect.DeepEqual(got, want) {
+		t.Errorf("ScrubEnv = %q, want %q", got, want)
+	}
+
+	t.Setenv("GIT_DIR", "/hook/repo/.git")
+	got := Environ("GIT_DIR=/wanted/.git")
+	var dirs []string
+	for _, kv := range got {
+		if strings.HasPrefix(kv, "GIT_DIR=") {
+			dirs = append(dirs, kv)
+		}
+	}
+	if !reflect.DeepEqual(dirs, []string{"GIT_DIR=/wanted/.git"}) {
+		t.Errorf("GIT_DIR in Environ = %q", dirs)
+	}
+}
@@ -0,0 +1,146 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Cmd is a git invocation: git Args... run in Dir, with Env added to the
+// environment. Stdout and Stderr redirect Stream; nil means the terminal.
+type Cmd struct {
+	Dir    string
+	Args   []string
+	Env    []string
+	Stdout io.Writer
+	Stderr io.Writer
+	// Stdin is the input of the command; Stream reads os.Stdin without
+	// one, the other methods nothing.
+	Stdin io.Reader
+}
+
+// Git returns the Cmd running git args in dir.
+func Git(dir string, args ...string) Cmd {
+	return Cmd{Dir: dir, Args: args}
+}
+
+func (c Cmd) String() string {
+	return "git " + strings.Join(c.Args, " ")
+}
+
+// GitExecutor runs git commands. Exec is the real implementation; Mock
+// records invocations and returns canned output for tests.
+type GitExecutor interface {
+	// Stream runs c attached to the terminal.
+	Stream(ctx context.Context, c Cmd) error
+	// Capture returns the combined stdout and stderr of c.
+	Capture(ctx context.Context, c Cmd) (string, error)
+	// Output returns the trimmed stdout of c; the error carries the first
+	// line of stderr.
+	Output(ctx context.Context, c Cmd) (string, error)
+	// Lines calls fn for every line c writes to stdout, without holding
+	// the whole output in memory.
+	Lines(ctx context.Context, c Cmd, fn func(line string)) error
+}
+
+// Exec runs git as a child process, always with --no-pager and in the
+// environment from Environ. Path selects the git binary (git from PATH when
+// empty) and Env is added to the environment of every command.
+type Exec struct {
+	Path string
+	Env  []string
+}
+
+// waitDelay bounds how long a cancelled command may keep its output pipes
+// open through processes it started.
+var waitDelay = 5 * time.Second
+
+// command builds the process for c. Cancelling ctx ends git and, where the
+// OS needs help with that, the processes it started.
+func (e Exec) command(ctx context.Context, c Cmd) *exec.Cmd {
+	name := e.Path
+	if name == "" {
+		name = "git"
+	}
+	args := c.Args
+	if len(args) == 0 || args[0] != "--no-pager" {
+		// a pager configured with core.pager or pager.<cmd> would wait for
+		// a keypress in the middle of a batch
+		args = append([]string{"--no-pager"}, args...)
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = waitDelay
+	setCancel(cmd)
+	cmd.Dir = c.Dir
+	cmd.Env = Environ(append(append([]string{}, e.Env...), c.Env...)...)
+	cmd.Stdin = c.Stdin
+	return cmd
+}
+
+func (e Exec) Stream(ctx context.Context, c Cmd) error {
+	cmd := e.command(ctx, c)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if c.Stdout != nil {
+		cmd.Stdout = c.Stdout
+	}
+	if c.Stderr != nil {
+		cmd.Stderr = c.Stderr
+	}
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+	return cmd.Run()
+}
+
+func (e Exec) Capture(ctx context.Context, c Cmd) (string, error) {
+	b, err := e.command(ctx, c).CombinedOutput()
+	return string(b), err
+}
+
+func (e Exec) Output(ctx context.Context, c Cmd) (string, error) {
+	cmd := e.command(ctx, c)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, firstLine(msg))
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (e Exec) Lines(ctx context.Context, c Cmd, fn func(line string)) error {
+	cmd := e.command(ctx, c)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	s := bufio.NewScanner(stdout)
+	s.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for s.Scan() {
+		fn(s.Text())
+	}
+	if err := s.Err(); err != nil {
+		_ = cmd.Wait()
+		return err
+	}
+	return cmd.Wait()
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
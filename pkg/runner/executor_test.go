@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCancelDoesNotWaitForLeftoverProcesses(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	// stands in for git; the background sleep keeps the output pipe open
+	// after the script is killed
+	script := filepath.Join(t.TempDir(), "git")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nsleep 5 &\nsleep 5\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	old := waitDelay
+	waitDelay = 100 * time.Millisecond
+	t.Cleanup(func() { waitDelay = old })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := Exec{Path: script}.Capture(ctx, Cmd{Args: []string{"status"}})
+	if ctx.Err() == nil || err == nil {
+		t.Fatalf("command ended before the timeout: %v", err)
+	}
+	if d := time.Since(start); d > 3*time.Second {
+		t.Errorf("Capture returned after %s", d)
+	}
+}
+
+func TestExecDisablesPager(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	script := filepath.Join(t.TempDir(), "git")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"log", "-1"}, {"--no-pager", "diff"}} {
+		out, err := Exec{Path: script}.Output(context.Background(), Git(t.TempDir(), args...))
+		if err != nil || !strings.HasPrefix(out, "--no-pager ") || strings.Count(out, "--no-pager") != 1 {
+			t.Errorf("git %v ran as %q, %v", args, out, err)
+		}
+	}
+}
+
+func TestExecStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	script := filepath.Join(t.TempDir(), "git")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Exec{Path: script}.Output(context.Background(), Cmd{Args: []string{"credential", "fill"}, Stdin: strings.NewReader("url=https://example.com\n")})
+	if err != nil || out != "url=https://example.com" {
+		t.Errorf("Output = %q, %v", out, err)
+	}
+}
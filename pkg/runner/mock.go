@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+)
+
+// MockResponse is the canned result of a mocked git command.
+type MockResponse struct {
+	Output string
+	Err    error
+}
+
+// Mock is a GitExecutor for tests. It records every command and answers
+// from Responses, keyed by the space-joined arguments ("rev-parse HEAD").
+// Commands without a response succeed with no output.
+type Mock struct {
+	mu        sync.Mutex
+	Responses map[string]MockResponse
+	Calls     []Cmd
+}
+
+// On sets the response to the command with the given arguments.
+func (m *Mock) On(args string, output string, err error) *Mock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Responses == nil {
+		m.Responses = map[string]MockResponse{}
+	}
+	m.Responses[args] = MockResponse{Output: output, Err: err}
+	return m
+}
+
+// Commands returns the recorded commands as "git <args>" strings.
+func (m *Mock) Commands() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cmds := make([]string, len(m.Calls))
+	for i, c := range m.Calls {
+		cmds[i] = c.String()
+	}
+	return cmds
+}
+
+func (m *Mock) respond(c Cmd) MockResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls = append(m.Calls, c)
+	return m.Responses[strings.Join(c.Args, " ")]
+}
+
+// Stream writes the canned output to c.Stdout when it is set.
+func (m *Mock) Stream(ctx context.Context, c Cmd) error {
+	r := m.respond(c)
+	if c.Stdout != nil {
+		io.WriteString(c.Stdout, r.Output)
+	}
+	return r.Err
+}
+
+func (m *Mock) Capture(ctx context.Context, c Cmd) (string, error) {
+	r := m.respond(c)
+	return r.Output, r.Err
+}
+
+func (m *Mock) Output(ctx context.Context, c Cmd) (string, error) {
+	r := m.respond(c)
+	if r.Err != nil {
+		return "", r.Err
+	}
+	return strings.TrimSpace(r.Output), nil
+}
+
+func (m *Mock) Lines(ctx context.Context, c Cmd, fn func(line string)) error {
+	r := m.respond(c)
+	if out := strings.TrimSuffix(r.Output, "\n"); out != "" {
+		for _, line := range strings.Split(out, "\n") {
+			fn(line)
+		}
+	}
+	return r.Err
+}
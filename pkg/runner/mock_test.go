@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMock(t *testing.T) {
+	ctx := context.Background()
+	m := (&Mock{}).
+		On("rev-parse HEAD", "abc123\n", nil).
+		On("log --format=%s", "one\ntwo\n", nil).
+		On("push", "", errors.New("rejected"))
+
+	if out, err := m.Output(ctx, Git("/r", "rev-parse", "HEAD")); out != "abc123" || err != nil {
+		t.Errorf("Output = %q, %v", out, err)
+	}
+	var lines []string
+	if err := m.Lines(ctx, Git("/r", "log", "--format=%s"), func(l string) { lines = append(lines, l) }); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(lines, []string{"one", "two"}) {
+		t.Errorf("Lines = %q", lines)
+	}
+	if err := m.Stream(ctx, Git("/r", "push")); err == nil {
+		t.Error("expected the canned push error")
+	}
+	if out, err := m.Capture(ctx, Git("/r", "status")); out != "" || err != nil {
+		t.Errorf("unscripted command = %q, %v", out, err)
+	}
+
+	want := []string{"git rev-parse HEAD", "git log --format=%s", "git push", "git status"}
+	if got := m.Commands(); !slices.Equal(got, want) {
+		t.Errorf("Commands = %q, want %q", got, want)
+	}
+
+	results := (&Runner{Executor: m}).Run(ctx, []string{"/a", "/b"}, "push")
+	if len(results) != 2 || results[0].OK() || results[1].Repo != "/b" {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
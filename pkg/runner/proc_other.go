@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package runner
+
+import "os/exec"
+
+// setCancel leaves cmd to the default cancellation, which kills git only.
+func setCancel(cmd *exec.Cmd) {}
@@ -0,0 +1,12 @@
+//go:build unix
+
+package runner
+
+import "os/exec"
+
+// setCancel leaves cmd in gitbatch's process group. Ctrl+C in the terminal
+// then reaches git and everything it started, such as ssh and credential
+// helpers; a process group of its own would keep them running after
+// gitbatch exits. On cancellation git alone is killed, and WaitDelay stops
+// waiting for the processes it left behind.
+func setCancel(cmd *exec.Cmd) {}
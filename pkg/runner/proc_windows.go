@@ -0,0 +1,21 @@
+//go:build windows
+
+package runner
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// setCancel kills the whole process tree of cmd on cancellation, so ssh,
+// credential helpers and hooks started by git end with it. Windows does not
+// kill child processes with their parent.
+func setCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+		if err := kill.Run(); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+}
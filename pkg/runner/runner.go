@@ -0,0 +1,149 @@
+// Package runner runs git commands in one or many repositories.
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of a git command in one repository.
+type Result struct {
+	Repo     string
+	Output   string // combined stdout and stderr
+	Err      error
+	Duration time.Duration
+}
+
+// OK reports whether the command succeeded.
+func (r Result) OK() bool {
+	return r.Err == nil
+}
+
+// Runner runs the same git command in many repositories.
+type Runner struct {
+	// Timeout bounds each repository's command; zero means no limit
+	// beyond the context passed to Run.
+	Timeout time.Duration
+	// Executor runs the commands; nil means Exec.
+	Executor GitExecutor
+	// Env is added to the environment of every command.
+	Env []string
+	// Jobs is the number of repositories processed at once; values below
+	// 2 run them one after the other.
+	Jobs int
+	// After maps a repository to the repositories that must finish before
+	// it starts. Repositories that are not part of the run are ignored,
+	// and the graph must not have cycles.
+	After map[string][]string
+	// OnStart and OnDone, when set, are called as each repository starts
+	// and finishes. With Jobs > 1 they are called from several goroutines.
+	OnStart func(repo string)
+	OnDone  func(Result)
+	// Output, when set, streams each repository's command instead of
+	// capturing it: stdout and stderr are written to the writer Output
+	// returns for the repository as git produces them. Result.Output
+	// still holds the whole output.
+	Output func(repo string) io.Writer
+}
+
+// Run runs git with args in each repository and returns one Result per
+// repository, in the order of repos. Failures do not stop the run.
+func (r *Runner) Run(ctx context.Context, repos []string, args ...string) []Result {
+	results := make([]Result, len(repos))
+	jobs := max(r.Jobs, 1)
+	sem := make(chan struct{}, jobs)
+	done := make(map[string]chan struct{}, len(repos))
+	for _, repo := range repos {
+		done[repo] = make(chan struct{})
+	}
+	var wg sync.WaitGroup
+	for i, repo := range repos {
+		if len(r.After) == 0 {
+			// keep at most jobs goroutines around for large runs
+			sem <- struct{}{}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[repo])
+			if len(r.After) > 0 {
+				// wait before taking a slot, so repositories waiting for
+				// their dependencies do not block the ones they wait for
+				for _, dep := range r.After[repo] {
+					if ch, ok := done[dep]; ok && dep != repo {
+						<-ch
+					}
+				}
+				sem <- struct{}{}
+			}
+			defer func() { <-sem }()
+			if r.OnStart != nil {
+				r.OnStart(repo)
+			}
+			results[i] = r.run(ctx, repo, args)
+			if r.OnDone != nil {
+				r.OnDone(results[i])
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func (r *Runner) run(ctx context.Context, repo string, args []string) Result {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	exe := r.Executor
+	if exe == nil {
+		exe = Exec{}
+	}
+	start := time.Now()
+	c := Cmd{Dir: repo, Args: args, Env: r.Env}
+	if r.Output == nil {
+		out, err := exe.Capture(ctx, c)
+		return Result{Repo: repo, Output: out, Err: err, Duration: time.Since(start)}
+	}
+	w := &teeWriter{w: r.Output(repo)}
+	c.Stdout, c.Stderr = w, w
+	err := exe.Stream(ctx, c)
+	return Result{Repo: repo, Output: w.buf.String(), Err: err, Duration: time.Since(start)}
+}
+
+// teeWriter copies what is written to w into buf. Executors may wrap stdout
+// and stderr separately, so writes are serialized.
+type teeWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf.Write(p)
+	return t.w.Write(p)
+}
+
+// Stream runs git in dir attached to the terminal: output goes straight to
+// stdout and stderr, and stdin is passed through for prompts.
+func Stream(ctx context.Context, dir string, args ...string) error {
+	return Exec{}.Stream(ctx, Git(dir, args...))
+}
+
+// Capture runs git in dir and returns its combined output.
+func Capture(ctx context.Context, dir string, args ...string) (string, error) {
+	return Exec{}.Capture(ctx, Git(dir, args...))
+}
+
+// Output runs git in dir and returns its trimmed stdout. Unlike Capture,
+// stderr is kept out of the result so it can be parsed; on failure the
+// error carries the first line of git's stderr.
+func Output(ctx context.Context, dir string, args ...string) (string, error) {
+	return Exec{}.Output(ctx, Git(dir, args...))
+}
@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v, out=%s", err, out)
+	}
+	missing := repo + "/missing"
+
+	results := (&Runner{}).Run(context.Background(), []string{repo, missing}, "status", "--short", "--branch")
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if !results[0].OK() || !strings.HasPrefix(results[0].Output, "## ") || results[0].Repo != repo {
+		t.Errorf("unexpected first result: %+v", results[0])
+	}
+	if results[1].OK() {
+		t.Errorf("expected failure in a missing directory: %+v", results[1])
+	}
+}
+
+func TestOutput(t *testing.T) {
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v, out=%s", err, out)
+	}
+	out, err := Output(context.Background(), repo, "rev-parse", "--is-inside-work-tree")
+	if err != nil || out != "true" {
+		t.Errorf("Output = %q, %v", out, err)
+	}
+	if _, err := Output(context.Background(), repo, "rev-parse", "--verify", "nope"); err == nil || !strings.Contains(err.Error(), "fatal") {
+		t.Errorf("expected git's stderr in the error, got %v", err)
+	}
+}
+
+func TestRunStreamsToOutput(t *testing.T) {
+	var mu sync.Mutex
+	streamed := map[string]*strings.Builder{}
+	r := &Runner{
+		Executor: (&Mock{}).On("pull", "Already up to date.\n", nil),
+		Jobs:     2,
+		Output: func(repo string) io.Writer {
+			mu.Lock()
+			defer mu.Unlock()
+			streamed[repo] = &strings.Builder{}
+			return streamed[repo]
+		},
+	}
+	results := r.Run(context.Background(), []string{"/a", "/b"}, "pull")
+	for _, res := range results {
+		if res.Output != "Already up to date.\n" || streamed[res.Repo].String() != res.Output {
+			t.Errorf("%s: result %q, streamed %q", res.Repo, res.Output, streamed[res.Repo])
+		}
+	}
+}
+
+func TestRunParallelKeepsOrder(t *testing.T) {
+	repos := []string{"/a", "/b", "/c", "/d", "/e"}
+	var mu sync.Mutex
+	started, done := 0, 0
+	r := &Runner{
+		Executor: &Mock{},
+		Jobs:     3,
+		OnStart:  func(string) { mu.Lock(); started++; mu.Unlock() },
+		OnDone:   func(Result) { mu.Lock(); done++; mu.Unlock() },
+	}
+	results := r.Run(context.Background(), repos, "fetch")
+	for i, res := range results {
+		if res.Repo != repos[i] || !res.OK() {
+			t.Errorf("result %d = %+v", i, res)
+		}
+	}
+	if started != len(repos) || done != len(repos) {
+		t.Errorf("hooks called %d/%d times, want %d", started, done, len(repos))
+	}
+}
+
+func TestRunAfter(t *testing.T) {
+	for _, jobs := range []int{1, 3} {
+		var mu sync.Mutex
+		var events []string
+		r := &Runner{
+			Executor: &Mock{},
+			Jobs:     jobs,
+			After:    map[string][]string{"/api": {"/common"}, "/web": {"/api", "/elsewhere"}},
+			OnStart:  func(repo string) { mu.Lock(); events = append(events, "start "+repo); mu.Unlock() },
+			OnDone:   func(res Result) { mu.Lock(); events = append(events, "done "+res.Repo); mu.Unlock() },
+		}
+		results := r.Run(context.Background(), []string{"/web", "/api", "/common", "/tools"}, "pull")
+		if len(results) != 4 || results[0].Repo != "/web" {
+			t.Fatalf("jobs %d: results out of order: %+v", jobs, results)
+		}
+		at := map[string]int{}
+		for i, e := range events {
+			at[e] = i
+		}
+		if at["done /common"] > at["start /api"] || at["done /api"] > at["start /web"] {
+			t.Errorf("jobs %d: dependencies not respected: %v", jobs, events)
+		}
+	}
+}
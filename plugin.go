@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// pluginPrefix names external subcommands: gitbatch foo runs the
+// gitbatch-foo executable from PATH, the way git and kubectl do.
+const pluginPrefix = "gitbatch-"
+
+// findPlugin returns the executable for args when the first argument is
+// not a gitbatch command but a plugin of that name is on PATH.
+func findPlugin(args []string) (string, bool) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", false
+	}
+	rootCmd.InitDefaultHelpCmd()
+	rootCmd.InitDefaultCompletionCmd()
+	if cmd, _, err := rootCmd.Find(args); err != nil || cmd != rootCmd {
+		return "", false
+	}
+	path, err := exec.LookPath(pluginPrefix + args[0])
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// parsePluginFlags sets the global flags found in the arguments of a
+// plugin, and then the GITBATCH_* variables, so they apply to the plugin
+// as to any command. The plugin still gets every argument, and flags
+// gitbatch does not know are its own.
+func parsePluginFlags(args []string) error {
+	rootCmd.FParseErrWhitelist.UnknownFlags = true
+	defer func() { rootCmd.FParseErrWhitelist.UnknownFlags = false }()
+	if err := rootCmd.ParseFlags(args); err != nil {
+		return usageErrorf("%v", err)
+	}
+	return applyEnv(rootCmd)
+}
+
+// pluginRepos resolves the arguments that are repository patterns: every
+// argument that is not a flag and matches at least one repository. Other
+// arguments are left for the plugin to interpret. The patterns go through
+// collectRepos, so .gitbatchignore, the selectors and the policy path
+// rules apply as for built-in commands.
+func pluginRepos(args []string) ([]string, error) {
+	var patterns []string
+	for _, a := range args {
+		if a == "--" {
+			break
+		}
+		if strings.HasPrefix(a, "-") {
+			continue
+		}
+		if _, err := discoverRepos([]string{a}); err == nil {
+			patterns = append(patterns, a)
+		}
+	}
+	if len(patterns) == 0 && !selecting() {
+		return nil, nil
+	}
+	return collectRepos(patterns)
+}
+
+// pluginEnv is the environment of a plugin: the matching repositories, one
+// per line, and the gitbatch executable so the plugin can call back into it.
+func pluginEnv(repos []string) []string {
+	self, err := os.Executable()
+	if err != nil {
+		self = os.Args[0]
+	}
+	return append(os.Environ(),
+		"GITBATCH="+self,
+		"GITBATCH_REPOS="+strings.Join(repos, "\n"),
+	)
+}
+
+// runPlugin runs the plugin at path with args and returns its exit code.
+// Plugins are refused in read-only mode: gitbatch cannot tell whether
+// they change repositories.
+func runPlugin(path string, args []string) int {
+	name := args[0]
+	if err := preparePlugin(args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCode(err)
+	}
+	repos, err := pluginRepos(args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCode(err)
+	}
+	cmd := exec.Command(path, args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = pluginEnv(repos)
+	if err := cmd.Run(); err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && exit.ExitCode() > 0 {
+			return exit.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "plugin %s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// preparePlugin is prepareRun for a plugin: it applies the global flags
+// and the environment, refuses the plugin in read-only mode or when a
+// policy denies it, and sets up git for finding the repositories.
+func preparePlugin(args []string) error {
+	if err := parsePluginFlags(args[1:]); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if readOnly || cfg.ReadOnly {
+		return exitWith(exitPolicy, fmt.Errorf("read-only mode: plugin %q is not allowed", args[0]))
+	}
+	if err := checkPluginPolicy(args); err != nil {
+		return err
+	}
+	return setupGitBinary()
+}
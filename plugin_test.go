@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlugin(t *testing.T) {
+	bin := t.TempDir()
+	out := filepath.Join(t.TempDir(), "out")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$*\" \"$GITBATCH_REPOS\" > " + shellQuote(out) + "\nexit 3\n"
+	if err := os.WriteFile(filepath.Join(bin, "gitbatch-hello"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	useTestConfig(t, "")
+
+	if _, ok := findPlugin([]string{"status", "x"}); ok {
+		t.Error("built-in status dispatched to a plugin")
+	}
+	if _, ok := findPlugin([]string{"missing"}); ok {
+		t.Error("found a plugin that is not on PATH")
+	}
+	path, ok := findPlugin([]string{"hello", "x"})
+	if !ok {
+		t.Fatal("gitbatch-hello not found")
+	}
+
+	ws := t.TempDir()
+	repo := filepath.Join(ws, "api")
+	initTestRepoAt(t, repo)
+	initTestRepoAt(t, filepath.Join(ws, "web"))
+	if err := os.WriteFile(filepath.Join(ws, ".gitbatchignore"), []byte("web\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(ws)
+	prevExec := gitExec
+	t.Cleanup(func() {
+		gitExec, readOnly, pathPolicies = prevExec, false, nil
+		rootCmd.Flags().Lookup("read-only").Changed = false
+	})
+	if code := runPlugin(path, []string{"hello", "--since", "7d", "*"}); code != 3 {
+		t.Errorf("exit code %d, want the plugin's 3", code)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--since 7d *\n" + repo + "\n"
+	if string(b) != want {
+		t.Errorf("plugin saw %q, want %q", b, want)
+	}
+
+	useTestConfig(t, "[[policy]]\ncommand = \"hello\"\npaths = [\"elsewhere\"]\n")
+	if code := runPlugin(path, []string{"hello", "api"}); code != exitPolicy {
+		t.Errorf("plugin ran outside its policy paths (exit %d)", code)
+	}
+
+	useTestConfig(t, "")
+	if code := runPlugin(path, []string{"hello", "api", "--read-only"}); code != exitPolicy {
+		t.Errorf("plugin ran with --read-only (exit %d)", code)
+	}
+	readOnly = false
+	rootCmd.Flags().Lookup("read-only").Changed = false
+	t.Setenv("GITBATCH_READ_ONLY", "true")
+	if code := runPlugin(path, []string{"hello"}); code != exitPolicy {
+		t.Errorf("plugin ran with GITBATCH_READ_ONLY (exit %d)", code)
+	}
+	readOnly = false
+	rootCmd.Flags().Lookup("read-only").Changed = false
+	t.Setenv("GITBATCH_READ_ONLY", "")
+
+	useTestConfig(t, "read_only = true\n")
+	if code := runPlugin(path, []string{"hello"}); code != exitPolicy {
+		t.Errorf("plugin ran in read-only mode (exit %d)", code)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// PolicyRule is a [[policy]] entry of the config file. It forbids an
+// operation outright or only allows it in repositories under some paths:
+//
+//	[[policy]]
+//	command = "push --force"
+//	deny = true
+//	reason = "force pushes need a review"
+//
+//	[[policy]]
+//	command = "commit --amend"
+//	paths = ["~/src/sandbox"]
+//
+// command is a gitbatch command ("push", "remote set-url") optionally
+// followed by flags that must be set for the rule to apply; "--flag=value"
+// also compares the value.
+type PolicyRule struct {
+	Command string   `toml:"command"`
+	Deny    bool     `toml:"deny"`
+	Paths   []string `toml:"paths"`
+	Reason  string   `toml:"reason"`
+}
+
+// parts splits the rule into the command path and its flags.
+func (p PolicyRule) parts() (command string, flags []string) {
+	var words []string
+	for _, f := range strings.Fields(p.Command) {
+		if strings.HasPrefix(f, "-") {
+			flags = append(flags, f)
+		} else {
+			words = append(words, f)
+		}
+	}
+	return strings.Join(words, " "), flags
+}
+
+func (p PolicyRule) validate() error {
+	command, _ := p.parts()
+	if command == "" {
+		return errors.New("[[policy]] needs a command")
+	}
+	if !p.Deny && len(p.Paths) == 0 {
+		return fmt.Errorf("[[policy]] %q needs deny = true or paths", p.Command)
+	}
+	return nil
+}
+
+// matches reports whether the rule covers running cmd with its flags.
+func (p PolicyRule) matches(cmd *cobra.Command) bool {
+	command, flags := p.parts()
+	if commandName(cmd) != command {
+		return false
+	}
+	for _, f := range flags {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(f, "-"), "=")
+		var flag *pflag.Flag
+		if strings.HasPrefix(f, "--") {
+			flag = cmd.Flags().Lookup(name)
+		} else {
+			flag = cmd.Flags().ShorthandLookup(name)
+		}
+		if flag == nil || !flag.Changed {
+			return false
+		}
+		if hasValue && flag.Value.String() != value {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesStep reports whether the rule covers the git command line args of
+// an alias step: the rule's command words start args and its flags are
+// among the rest. Where gitbatch has a command of the same name, its
+// shorthands count too, so a "push --force" rule also covers "push -f".
+func (p PolicyRule) matchesStep(args []string) bool {
+	command, flags := p.parts()
+	words := strings.Fields(command)
+	if len(args) < len(words) || !slices.Equal(args[:len(words)], words) {
+		return false
+	}
+	rest := args[len(words):]
+	var set []defaultFlag
+	cmd, _, err := rootCmd.Find(words)
+	if err != nil || commandName(cmd) != command {
+		cmd = nil
+	}
+	if cmd != nil {
+		probe := pflag.NewFlagSet(command, pflag.ContinueOnError)
+		probe.ParseErrorsWhitelist.UnknownFlags = true
+		probe.SetOutput(io.Discard)
+		cmd.Flags().VisitAll(func(f *pflag.Flag) {
+			probe.AddFlag(&pflag.Flag{Name: f.Name, Shorthand: f.Shorthand, NoOptDefVal: f.NoOptDefVal, Value: recordValue{f.Name, f.Value.Type(), &set}})
+		})
+		probe.Parse(rest)
+	}
+	for _, f := range flags {
+		spelled, value, hasValue := strings.Cut(f, "=")
+		name := strings.TrimLeft(spelled, "-")
+		if cmd != nil && !strings.HasPrefix(spelled, "--") {
+			if sf := cmd.Flags().ShorthandLookup(name); sf != nil {
+				name = sf.Name
+			}
+		}
+		found := false
+		for _, s := range set {
+			found = found || s.name == name && (!hasValue || s.value == value)
+		}
+		// flags gitbatch does not know are compared as written
+		for i, a := range rest {
+			if a == "--" {
+				break
+			}
+			flag, v, inline := strings.Cut(a, "=")
+			switch {
+			case flag != spelled:
+			case !hasValue:
+				found = true
+			case inline:
+				found = found || v == value
+			default:
+				found = found || i+1 < len(rest) && rest[i+1] == value
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// checkStepPolicies fails, before any repository is touched, when a deny
+// rule covers one of the git steps of an alias, or a path rule covering
+// one does not allow one of repos. Aliases run git directly, so the rules
+// are matched against each step as well as against the alias itself.
+func checkStepPolicies(steps [][]string, repos []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	for _, p := range cfg.Policy {
+		if err := p.validate(); err != nil {
+			return err
+		}
+		for _, step := range steps {
+			if !p.matchesStep(step) {
+				continue
+			}
+			if p.Deny {
+				return p.violation(fmt.Sprintf("is not allowed, in alias step %q", "git "+shellJoin(step)))
+			}
+			if err := checkPathRules([]PolicyRule{p}, repos); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkPluginPolicy fails when a deny rule covers the plugin command line
+// args, and remembers the path rules covering it like checkPolicy. The
+// rules are matched as for alias steps, since gitbatch does not know the
+// flags of a plugin.
+func checkPluginPolicy(args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	pathPolicies = nil
+	for _, p := range cfg.Policy {
+		if err := p.validate(); err != nil {
+			return err
+		}
+		if !p.matchesStep(args) {
+			continue
+		}
+		if p.Deny {
+			return p.violation("is not allowed")
+		}
+		pathPolicies = append(pathPolicies, p)
+	}
+	return nil
+}
+
+func (p PolicyRule) violation(detail string) error {
+	msg := fmt.Sprintf("policy: %q %s", p.Command, detail)
+	if p.Reason != "" {
+		msg += ": " + p.Reason
+	}
+	return exitWith(exitPolicy, errors.New(msg))
+}
+
+// commandName is the command path without the program name, e.g.
+// "remote add".
+func commandName(cmd *cobra.Command) string {
+	_, name, _ := strings.Cut(cmd.CommandPath(), " ")
+	return name
+}
+
+// pathPolicies are the rules with allowed paths that apply to the running
+// command; collectRepos checks them once the repositories are known.
+var pathPolicies []PolicyRule
+
+// checkPolicy fails when a deny rule covers cmd, and remembers the path
+// rules that apply to it.
+func checkPolicy(cmd *cobra.Command) error {
+	rules, err := commandPolicies(cmd)
+	pathPolicies = rules
+	return err
+}
+
+// commandPolicies fails when a deny rule covers cmd, and returns the path
+// rules that apply to it otherwise.
+func commandPolicies(cmd *cobra.Command) ([]PolicyRule, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	var rules []PolicyRule
+	for _, p := range cfg.Policy {
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+		if !p.matches(cmd) {
+			continue
+		}
+		if p.Deny {
+			return nil, p.violation("is not allowed")
+		}
+		rules = append(rules, p)
+	}
+	return rules, nil
+}
+
+// checkPolicyPaths fails, before any repository is touched, when a path
+// rule of the running command does not allow one of repos.
+func checkPolicyPaths(repos []string) error {
+	return checkPathRules(pathPolicies, repos)
+}
+
+// checkPathRules fails when one of the path rules does not allow one of
+// repos.
+func checkPathRules(rules []PolicyRule, repos []string) error {
+	for _, p := range rules {
+		var outside []string
+		for _, r := range repos {
+			if !underAny(r, p.Paths) {
+				outside = append(outside, r)
+			}
+		}
+		if len(outside) > 0 {
+			return p.violation(fmt.Sprintf("is only allowed under %s, not in %s", strings.Join(p.Paths, ", "), strings.Join(outside, ", ")))
+		}
+	}
+	return nil
+}
+
+// underAny reports whether repo is one of prefixes or inside one of them.
+func underAny(repo string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		abs, err := filepath.Abs(expandHome(prefix))
+		if err != nil {
+			continue
+		}
+		if repo == abs || strings.HasPrefix(repo, abs+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
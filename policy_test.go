@@ -0,0 +1,138 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// policyTestCommands returns "gitbatch push" and "gitbatch remote add"
+// commands with the flags the rules below look at.
+func policyTestCommands() (push, remoteAdd *cobra.Command) {
+	root := &cobra.Command{Use: "gitbatch"}
+	push = &cobra.Command{Use: "push"}
+	push.Flags().BoolP("force", "f", false, "")
+	push.Flags().String("preflight", "", "")
+	remote := &cobra.Command{Use: "remote"}
+	remoteAdd = &cobra.Command{Use: "add"}
+	root.AddCommand(push, remote)
+	remote.AddCommand(remoteAdd)
+	return push, remoteAdd
+}
+
+func TestCheckPolicy(t *testing.T) {
+	sandbox := t.TempDir()
+	useTestConfig(t, `
+[[policy]]
+command = "push -f"
+deny = true
+reason = "force pushes need a review"
+
+[[policy]]
+command = "push --preflight=skip"
+deny = true
+
+[[policy]]
+command = "remote add"
+paths = ["`+sandbox+`"]
+`)
+	t.Cleanup(func() { pathPolicies = nil })
+
+	push, remoteAdd := policyTestCommands()
+	if err := checkPolicy(push); err != nil {
+		t.Errorf("plain push was refused: %v", err)
+	}
+	push.Flags().Set("preflight", "abort")
+	if err := checkPolicy(push); err != nil {
+		t.Errorf("push --preflight=abort was refused: %v", err)
+	}
+	push.Flags().Set("force", "true")
+	err := checkPolicy(push)
+	if err == nil || err.Error() != `policy: "push -f" is not allowed: force pushes need a review` {
+		t.Errorf("unexpected error for push --force: %v", err)
+	}
+
+	if err := checkPolicy(remoteAdd); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPolicyPaths([]string{filepath.Join(sandbox, "a"), sandbox}); err != nil {
+		t.Errorf("repositories in the sandbox were refused: %v", err)
+	}
+	err = checkPolicyPaths([]string{filepath.Join(sandbox, "a"), sandbox + "-other"})
+	if err == nil || !strings.Contains(err.Error(), "not in "+sandbox+"-other") {
+		t.Errorf("unexpected error for a repository outside the sandbox: %v", err)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	for _, p := range []PolicyRule{{Command: "--force", Deny: true}, {Command: "push"}} {
+		if err := p.validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", p)
+		}
+	}
+}
+
+func TestPolicyMatchesStep(t *testing.T) {
+	tests := []struct {
+		rule string
+		step string
+		want bool
+	}{
+		{"push --force", "push --force origin main", true},
+		{"push --force", "push -f", true}, // gitbatch push knows -f as --force
+		{"push --force", "push origin main", false},
+		{"push --force", "pull --force", false},
+		{"push --force", "push -- --force", false},
+		{"commit --amend", "commit --amend --no-edit", true},
+		{"remote set-url", "remote set-url origin git@example.com:x.git", true},
+		{"remote set-url", "remote add origin x", false},
+		{"push --push-option=ci.skip", "push --push-option ci.skip", true},
+		{"push --push-option=ci.skip", "push --push-option=ci.skip", true},
+		{"push --push-option=ci.skip", "push --push-option=other", false},
+	}
+	for _, tt := range tests {
+		step, err := splitWords(tt.step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := (PolicyRule{Command: tt.rule, Deny: true}).matchesStep(step); got != tt.want {
+			t.Errorf("%q matchesStep(%q) = %v, want %v", tt.rule, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestCheckStepPolicies(t *testing.T) {
+	sandbox := t.TempDir()
+	useTestConfig(t, `
+[[policy]]
+command = "push --force"
+deny = true
+
+[[policy]]
+command = "commit --amend"
+paths = ["`+sandbox+`"]
+`)
+	steps := func(lines ...string) [][]string {
+		parsed, err := parseSteps("release", lines)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	repos := []string{filepath.Join(sandbox, "api")}
+	if err := checkStepPolicies(steps("fetch", "push origin main"), repos); err != nil {
+		t.Errorf("allowed steps refused: %v", err)
+	}
+	err := checkStepPolicies(steps("fetch", "push --force"), repos)
+	if exitCode(err) != exitPolicy || !strings.Contains(err.Error(), `alias step "git push --force"`) {
+		t.Errorf("denied step: %v", err)
+	}
+	if err := checkStepPolicies(steps("commit --amend --no-edit"), repos); err != nil {
+		t.Errorf("step in the allowed paths refused: %v", err)
+	}
+	if err := checkStepPolicies(steps("commit --amend --no-edit"), []string{sandbox + "-other"}); exitCode(err) != exitPolicy {
+		t.Errorf("step outside the allowed paths: %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// ghOutput runs the GitHub CLI in dir and returns its trimmed stdout, with
+// the first line of stderr in the error like gitOutput.
+func ghOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	ctx, cancel := commandContext(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, firstLine(msg))
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func requireGH() error {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return errors.New("the GitHub CLI (gh) is not installed: see https://cli.github.com")
+	}
+	return nil
+}
+
+// prBranch is the branch of a repository a pull request would be opened
+// for, or the reason there is none.
+type prBranch struct {
+	branch, base string
+	skip         string
+}
+
+// prBranchFor checks that repo is on a branch other than base (the default
+// branch when empty) that has been pushed completely and has commits base
+// does not.
+func prBranchFor(ctx context.Context, repo, base string) (prBranch, error) {
+	branch, err := currentBranch(ctx, repo)
+	if err != nil {
+		return prBranch{}, err
+	}
+	if branch == "" {
+		return prBranch{skip: "detached HEAD"}, nil
+	}
+	if base == "" {
+		if base, err = defaultBranch(ctx, repo); err != nil {
+			return prBranch{}, err
+		}
+	}
+	p := prBranch{branch: branch, base: base}
+	if branch == base {
+		p.skip = "on " + base
+		return p, nil
+	}
+	upstream, err := gitOutput(ctx, repo, "rev-parse", "--abbrev-ref", "@{upstream}")
+	if err != nil {
+		p.skip = "branch not pushed"
+		return p, nil
+	}
+	if ahead, _, err := aheadBehind(ctx, repo, "HEAD", upstream); err != nil {
+		return prBranch{}, err
+	} else if ahead > 0 {
+		p.skip = fmt.Sprintf("%d unpushed commit(s)", ahead)
+		return p, nil
+	}
+	baseRef := base
+	if remote, _, ok := strings.Cut(upstream, "/"); ok && refExists(ctx, repo, "refs/remotes/"+remote+"/"+base) {
+		baseRef = remote + "/" + base
+	}
+	if ahead, _, err := aheadBehind(ctx, repo, upstream, baseRef); err != nil {
+		return prBranch{}, err
+	} else if ahead == 0 {
+		p.skip = "no commits ahead of " + base
+	}
+	return p, nil
+}
+
+// pr command
+var prCmd = &cobra.Command{
+	Use:   "pr",
+	Short: "Open and inspect pull requests for matching repositories",
+}
+
+// readPRBody reads the pull request description of --body-file once, so
+// every repository gets the same one: a relative path is the user's, not
+// one inside each repository, and stdin can only be read once.
+func readPRBody(path string) (string, error) {
+	var data []byte
+	var err error
+	switch path {
+	case "":
+		return "", nil
+	case "-":
+		data, err = io.ReadAll(os.Stdin)
+	default:
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("--body-file: %v", err)
+	}
+	return string(data), nil
+}
+
+var prTitle string
+var prBodyFile string
+var prBase string
+var prDraft bool
+var prDryRun bool
+var prCreateCmd = &cobra.Command{
+	Use:   "create --title <title> [--body-file <file>] [--dry-run] <pattern>...",
+	Short: "Open a GitHub pull request for the current branch of each repository",
+	Long: `create opens a pull request with the GitHub CLI (gh) for every repository
+whose current branch is pushed and has commits ahead of the base branch
+(--base, or the repository's default branch). Repositories on the base
+branch, with unpushed commits or with nothing to merge are skipped.
+--dry-run lists the pull requests that would be opened without opening
+them.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if strings.TrimSpace(prTitle) == "" {
+			return usageErrorf("--title is required")
+		}
+		if !prDryRun {
+			if err := requireGH(); err != nil {
+				return err
+			}
+		}
+		body, err := readPRBody(prBodyFile)
+		if err != nil {
+			return err
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		ghArgs := []string{"pr", "create", "--title", prTitle, "--body", body}
+		if prDraft {
+			ghArgs = append(ghArgs, "--draft")
+		}
+
+		// failures are reported after the table, which is only written
+		// out once every row is known
+		created := 0
+		failures := map[string]error{}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPO\tBRANCH\tPULL REQUEST")
+		for _, r := range repos {
+			p, err := prBranchFor(ctx, r, prBase)
+			if err != nil {
+				failures[r] = err
+				fmt.Fprintf(w, "%s\t\tfailed\n", r)
+				continue
+			}
+			if p.skip != "" {
+				fmt.Fprintf(w, "%s\t%s\tskipped: %s\n", r, p.branch, p.skip)
+				continue
+			}
+			if prDryRun {
+				created++
+				fmt.Fprintf(w, "%s\t%s\twould open into %s\n", r, p.branch, p.base)
+				continue
+			}
+			out, err := ghOutput(ctx, r, append(ghArgs, "--base", p.base, "--head", p.branch)...)
+			if err != nil {
+				failures[r] = err
+				fmt.Fprintf(w, "%s\t%s\tfailed\n", r, p.branch)
+				continue
+			}
+			created++
+			lines := strings.Split(out, "\n")
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r, p.branch, lines[len(lines)-1])
+		}
+		w.Flush()
+		for _, r := range repos {
+			if err, ok := failures[r]; ok {
+				repoFailed(r, err)
+			}
+		}
+		if prDryRun {
+			fmt.Printf("\n%d of %d repositories would get a pull request\n", created, len(repos))
+			return nil
+		}
+		fmt.Printf("\n%d of %d repositories got a pull request\n", created, len(repos))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(prCmd)
+	prCmd.AddCommand(prCreateCmd)
+
+	prCreateCmd.Flags().StringVar(&prTitle, "title", "", "pull request title")
+	prCreateCmd.Flags().StringVar(&prBodyFile, "body-file", "", "file with the pull request description (- for stdin)")
+	prCreateCmd.Flags().StringVar(&prBase, "base", "", "branch to merge into (default: each repository's default branch)")
+	prCreateCmd.Flags().BoolVar(&prDraft, "draft", false, "open the pull requests as drafts")
+	prCreateCmd.Flags().BoolVarP(&prDryRun, "dry-run", "n", false, "list the pull requests that would be opened without opening them")
+}
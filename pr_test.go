@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPRBranchFor(t *testing.T) {
+	ctx := context.Background()
+	bare := filepath.Join(t.TempDir(), "origin.git")
+	if out, err := exec.Command("git", "init", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare failed: %v, out=%s", err, out)
+	}
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+	git := func(args ...string) {
+		t.Helper()
+		if out, err := runGitCapture(ctx, repo, args...); err != nil {
+			t.Fatalf("git %v failed: %v, out=%s", args, err, out)
+		}
+	}
+	git("branch", "-M", "main")
+	git("remote", "add", "origin", bare)
+	git("push", "-u", "origin", "main")
+
+	check := func(want string) {
+		t.Helper()
+		p, err := prBranchFor(ctx, repo, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.skip != want {
+			t.Errorf("skip = %q, want %q", p.skip, want)
+		}
+	}
+	check("on main")
+
+	git("checkout", "-b", "feature")
+	check("branch not pushed")
+	git("push", "-u", "origin", "feature")
+	check("no commits ahead of main")
+	commitTestFile(t, repo, "b.txt", "b", "feature work")
+	check("1 unpushed commit(s)")
+	git("push")
+	check("")
+}
+
+func TestReadPRBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "body.md")
+	if err := os.WriteFile(path, []byte("Bumps the SDK.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// relative to the working directory, not to each repository
+	t.Chdir(filepath.Dir(path))
+	if body, err := readPRBody("body.md"); err != nil || body != "Bumps the SDK.\n" {
+		t.Errorf("readPRBody = %q, %v", body, err)
+	}
+	if body, err := readPRBody(""); err != nil || body != "" {
+		t.Errorf("readPRBody without a file = %q, %v", body, err)
+	}
+	if _, err := readPRBody("missing.md"); err == nil {
+		t.Error("readPRBody of a missing file succeeded")
+	}
+}
+
+func TestPRCreateDryRun(t *testing.T) {
+	ctx := context.Background()
+	bare := filepath.Join(t.TempDir(), "origin.git")
+	if out, err := exec.Command("git", "init", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare failed: %v, out=%s", err, out)
+	}
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+	git := func(args ...string) {
+		t.Helper()
+		if out, err := runGitCapture(ctx, repo, args...); err != nil {
+			t.Fatalf("git %v failed: %v, out=%s", args, err, out)
+		}
+	}
+	git("branch", "-M", "main")
+	git("remote", "add", "origin", bare)
+	git("push", "-u", "origin", "main")
+	git("checkout", "-b", "feature")
+	commitTestFile(t, repo, "b.txt", "b", "feature work")
+	git("push", "-u", "origin", "feature")
+
+	// a gh that leaves a mark if it is run at all
+	bin, mark := t.TempDir(), filepath.Join(t.TempDir(), "gh-ran")
+	if err := os.WriteFile(filepath.Join(bin, "gh"), []byte("#!/bin/sh\ntouch "+mark+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	prTitle, prDryRun = "Bump deps", true
+	t.Cleanup(func() { prTitle, prDryRun = "", false })
+
+	out := captureStdout(t, func() {
+		if err := prCreateCmd.RunE(prCreateCmd, []string{repo}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(out, "would open into main") || !strings.Contains(out, "1 of 1 repositories would get a pull request") {
+		t.Errorf("dry run output:\n%s", out)
+	}
+	if _, err := os.Stat(mark); err == nil {
+		t.Error("gh ran during --dry-run")
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// prefixOutput (--prefix) streams the output of parallel runs as it
+// arrives, each line labeled with its repository, like docker compose.
+var prefixOutput bool
+
+// prefixColors tell the labels of neighbouring repositories apart. Red is
+// left out, it marks failures.
+var prefixColors = []string{"\033[36m", "\033[33m", "\033[32m", "\033[35m", "\033[34m", "\033[96m", "\033[93m", "\033[92m", "\033[95m", "\033[94m"}
+
+// prefixLabels gives each repository a label: its directory name, with a
+// numeric suffix when names repeat, padded so the output lines up.
+func prefixLabels(repos []string) map[string]string {
+	names := make([]string, len(repos))
+	taken := map[string]bool{}
+	width := 0
+	for i, r := range repos {
+		base := shortRepoName(r)
+		name := base
+		for n := 2; taken[name]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		taken[name] = true
+		names[i] = name
+		width = max(width, len(name))
+	}
+	labels := make(map[string]string, len(repos))
+	for i, r := range repos {
+		labels[r] = paint(os.Stdout, prefixColors[i%len(prefixColors)], fmt.Sprintf("%-*s |", width, names[i]))
+	}
+	return labels
+}
+
+// prefixWriter writes whole lines to out, each starting with label. A line
+// is held until its newline arrives, so lines of different repositories do
+// not run into each other.
+type prefixWriter struct {
+	mu      *sync.Mutex // shared by the writers of one run
+	out     io.Writer
+	label   string
+	partial []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	data := append(p.partial, b...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		p.line(data[:i])
+		data = data[i+1:]
+	}
+	p.partial = append([]byte(nil), data...)
+	return len(b), nil
+}
+
+func (p *prefixWriter) line(l []byte) {
+	if len(l) == 0 {
+		fmt.Fprintln(p.out, p.label)
+		return
+	}
+	fmt.Fprintf(p.out, "%s %s\n", p.label, l)
+}
+
+// flush writes what is left of a last line without a newline.
+func (p *prefixWriter) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.partial) > 0 {
+		p.line(p.partial)
+		p.partial = nil
+	}
+}
+
+// runPrefixed runs git args in repos with --jobs workers and prints each
+// line of output as it arrives, prefixed with the repository's label. A
+// failed repository ends with its error.
+func runPrefixed(repos []string, args ...string) []runner.Result {
+	var mu sync.Mutex
+	writers := make(map[string]*prefixWriter, len(repos))
+	for r, label := range prefixLabels(repos) {
+		writers[r] = &prefixWriter{mu: &mu, out: os.Stdout, label: label}
+	}
+	r := gitRunner()
+	r.Output = func(repo string) io.Writer { return writers[repo] }
+	r.OnDone = func(res runner.Result) {
+		w := writers[res.Repo]
+		w.flush()
+		if !res.OK() {
+			fmt.Fprintf(w, "%s%s\n", paint(os.Stdout, ansiRed, fmt.Sprintf("error: %v", res.Err)), logHint(res.Repo))
+		}
+	}
+	results := r.Run(context.Background(), repos, args...)
+	for _, res := range results {
+		if !res.OK() {
+			markRepoFailed(res.Repo)
+		}
+	}
+	return results
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&prefixOutput, "prefix", false, "with --jobs, stream each line of git output as it arrives, prefixed with its repository")
+}
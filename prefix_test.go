@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+func TestPrefixLabels(t *testing.T) {
+	labels := prefixLabels([]string{"/src/api", "/src/web", "/vendor/api"})
+	want := map[string]string{"/src/api": "api   |", "/src/web": "web   |", "/vendor/api": "api-2 |"}
+	for r, l := range want {
+		if labels[r] != l {
+			t.Errorf("label of %s = %q, want %q", r, labels[r], l)
+		}
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var mu sync.Mutex
+	var out strings.Builder
+	w := &prefixWriter{mu: &mu, out: &out, label: "api |"}
+	w.Write([]byte("Updating 3f1c2aa..9b0de41\nFast-"))
+	w.Write([]byte("forward\n\n main.go | 2 +-"))
+	w.flush()
+	want := "api | Updating 3f1c2aa..9b0de41\napi | Fast-forward\napi |\napi |  main.go | 2 +-\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestRunPrefixed(t *testing.T) {
+	prev, prevJobs := gitExec, jobs
+	t.Cleanup(func() {
+		gitExec, jobs = prev, prevJobs
+		runSummary.Lock()
+		delete(runSummary.failed, "/src/web")
+		runSummary.Unlock()
+	})
+	gitExec = (&runner.Mock{}).On("pull", "Already up to date.\n", nil)
+	jobs = 2
+	var results []runner.Result
+	out := captureStdout(t, func() { results = runPrefixed([]string{"/src/api", "/src/web"}, "pull") })
+	for _, l := range []string{"api | Already up to date.\n", "web | Already up to date.\n"} {
+		if !strings.Contains(out, l) {
+			t.Errorf("missing %q in %q", l, out)
+		}
+	}
+	if len(results) != 2 || results[0].Output != "Already up to date.\n" {
+		t.Errorf("results %+v", results)
+	}
+
+	gitExec = (&runner.Mock{}).On("push", "rejected\n", errors.New("exit status 1"))
+	out = captureStdout(t, func() { runPrefixed([]string{"/src/web"}, "push") })
+	if out != "web | rejected\nweb | error: exit status 1\n" {
+		t.Errorf("failed repository printed %q", out)
+	}
+	if !runSummary.failed["/src/web"] {
+		t.Error("failure not recorded for the summary")
+	}
+}
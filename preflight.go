@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// --preflight modes
+const (
+	preflightSkip  = "skip"
+	preflightAbort = "abort"
+)
+
+var preflightMode string
+var preflightTimeout time.Duration
+
+// preflightParallel is the number of remotes probed at once.
+const preflightParallel = 8
+
+// probeRemotes checks with git ls-remote that every repository can reach
+// its default remote and returns the failures by repository.
+func probeRemotes(ctx context.Context, repos []string, timeout time.Duration) map[string]error {
+	var mu sync.Mutex
+	failed := map[string]error{}
+	sem := make(chan struct{}, preflightParallel)
+	var wg sync.WaitGroup
+	for _, r := range repos {
+		wg.Add(1)
+		go func(r string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			remote := defaultRemote(ctx, gitExec, r)
+			err := lsRemote(ctx, r, remote, timeout)
+			if err != nil {
+				mu.Lock()
+				failed[r] = fmt.Errorf("%s unreachable: %v", remote, err)
+				mu.Unlock()
+			}
+		}(r)
+	}
+	wg.Wait()
+	return failed
+}
+
+// preflight checks the credentials for the remotes of repos and, when
+// --preflight is set, probes the remotes. In skip mode it warns about and
+// drops repositories whose remote is unreachable; in abort mode any
+// unreachable remote stops the run before it starts.
+func preflight(repos []string) ([]string, error) {
+	checkCredentials(context.Background(), repos, isTerminal(os.Stdin))
+	if preflightMode == "" {
+		return repos, nil
+	}
+	if preflightMode != preflightSkip && preflightMode != preflightAbort {
+		return nil, usageErrorf("invalid --preflight %q (expected skip or abort)", preflightMode)
+	}
+	failed := probeRemotes(context.Background(), repos, preflightTimeout)
+	if len(failed) == 0 {
+		return repos, nil
+	}
+
+	var ok []string
+	for _, r := range repos {
+		err, bad := failed[r]
+		if !bad {
+			ok = append(ok, r)
+			continue
+		}
+		markRepoFailed(r)
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", paint(os.Stderr, ansiYellow, "preflight:"), r, err)
+	}
+	if preflightMode == preflightAbort {
+		return nil, fmt.Errorf("preflight failed: %d of %d repositories cannot reach their remote", len(failed), len(repos))
+	}
+	if len(ok) == 0 {
+		return nil, errors.New("preflight failed: no repository can reach its remote")
+	}
+	fmt.Fprintf(os.Stderr, "skipping %d of %d repositories with unreachable remotes\n", len(failed), len(repos))
+	return ok, nil
+}
+
+// addPreflightFlags registers --preflight, --preflight-timeout and
+// --no-credential-check on a
+// network command.
+func addPreflightFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&preflightMode, "preflight", "", "probe remotes with git ls-remote first; skip unreachable repositories (skip) or stop the run (abort)")
+	cmd.Flags().Lookup("preflight").NoOptDefVal = preflightSkip
+	cmd.Flags().DurationVar(&preflightTimeout, "preflight-timeout", 5*time.Second, "timeout for probing each remote")
+	cmd.Flags().BoolVar(&noCredentialCheck, "no-credential-check", false, "do not check credentials for every remote host before the run")
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPreflight(t *testing.T) {
+	ctx := context.Background()
+	bare := filepath.Join(t.TempDir(), "origin.git")
+	if out, err := exec.Command("git", "init", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare failed: %v, out=%s", err, out)
+	}
+	up, down := initTestRepo(t), initTestRepo(t)
+	if _, err := runGitCapture(ctx, up, "remote", "add", "origin", bare); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGitCapture(ctx, down, "remote", "add", "origin", filepath.Join(t.TempDir(), "missing.git")); err != nil {
+		t.Fatal(err)
+	}
+	preflightTimeout = 5 * time.Second
+	defer func() { preflightMode = "" }()
+
+	failed := probeRemotes(ctx, []string{up, down}, preflightTimeout)
+	if len(failed) != 1 || failed[down] == nil {
+		t.Fatalf("expected only %s to fail, got %v", down, failed)
+	}
+
+	preflightMode = preflightSkip
+	repos, err := preflight([]string{up, down})
+	if err != nil || len(repos) != 1 || repos[0] != up {
+		t.Errorf("skip: got %v, %v", repos, err)
+	}
+	if _, err := preflight([]string{down}); err == nil {
+		t.Error("skip: expected an error when no remote is reachable")
+	}
+
+	preflightMode = preflightAbort
+	if _, err := preflight([]string{up, down}); err == nil {
+		t.Error("abort: expected an error")
+	}
+}
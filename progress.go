@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/report"
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+var jobs int
+
+// isTerminal reports whether f is a character device, i.e. an interactive
+// terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// progress tracks a buffered run. On a terminal it redraws a single status
+// line with a bar, the repositories in flight and an ETA; otherwise it logs
+// one line per finished repository.
+type progress struct {
+	mu      sync.Mutex
+	w       io.Writer
+	tty     bool
+	total   int
+	done    int
+	start   time.Time
+	running map[string]bool
+}
+
+func newProgress(w io.Writer, tty bool, total int) *progress {
+	return &progress{w: w, tty: tty, total: total, start: time.Now(), running: map[string]bool{}}
+}
+
+func (p *progress) started(repo string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running[repo] = true
+	if p.tty {
+		p.draw()
+	}
+}
+
+func (p *progress) finished(res runner.Result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.running, res.Repo)
+	p.done++
+	if p.tty {
+		p.draw()
+		return
+	}
+	status := paint(p.out(), ansiGreen, "ok")
+	if !res.OK() {
+		status = paint(p.out(), ansiRed, "failed")
+	}
+	fmt.Fprintf(p.w, "[%d/%d] %s %s (%s)\n", p.done, p.total, res.Repo, status, res.Duration.Round(time.Millisecond))
+}
+
+// out returns the file progress is written to, for color decisions.
+func (p *progress) out() *os.File {
+	if f, ok := p.w.(*os.File); ok {
+		return f
+	}
+	return nil
+}
+
+// eta extrapolates the remaining time from the average so far.
+func (p *progress) eta() time.Duration {
+	if p.done == 0 {
+		return 0
+	}
+	per := time.Since(p.start) / time.Duration(p.done)
+	return (per * time.Duration(p.total-p.done)).Round(time.Second)
+}
+
+// line renders the status line, at most width characters wide.
+func (p *progress) line(width int) string {
+	const barWidth = 20
+	filled := barWidth * p.done / max(p.total, 1)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+	eta := "--"
+	if p.done > 0 {
+		eta = p.eta().String()
+	}
+	names := make([]string, 0, len(p.running))
+	for r := range p.running {
+		names = append(names, shortRepoName(r))
+	}
+	sort.Strings(names)
+	l := fmt.Sprintf("[%s] %d/%d eta %s  %s", bar, p.done, p.total, eta, strings.Join(names, ", "))
+	if len(l) > width {
+		l = l[:width-3] + "..."
+	}
+	return l
+}
+
+func (p *progress) draw() {
+	// clear the line first: it may be shorter than the previous one
+	fmt.Fprintf(p.w, "\r\033[K%s", p.line(100))
+}
+
+// clear removes the status line before the results are printed.
+func (p *progress) clear() {
+	if p.tty {
+		fmt.Fprint(p.w, "\r\033[K")
+	}
+}
+
+// shortRepoName is the last path element of repo.
+func shortRepoName(repo string) string {
+	return repo[strings.LastIndexAny(repo, `/\`)+1:]
+}
+
+// runBuffered runs git args in repos with --jobs workers, showing progress
+// on stderr, and prints every repository's output once all have finished,
+// or only that of the failed ones with --only-failures. With --prefix the
+// output is streamed instead.
+func runBuffered(repos []string, args ...string) []runner.Result {
+	if prefixOutput {
+		return runPrefixed(repos, args...)
+	}
+	results := runCollect(repos, args...)
+	if logLevel == levelQuiet {
+		for _, res := range results {
+			if res.OK() {
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "%s %s: %v%s\n", paint(os.Stderr, ansiRed, "error in"), res.Repo, res.Err, logHint(res.Repo))
+			if runLogs == nil {
+				fmt.Fprint(os.Stderr, res.Output)
+			}
+		}
+		return results
+	}
+	if onlyFailures {
+		var failed []runner.Result
+		for _, res := range results {
+			if !res.OK() {
+				failed = append(failed, res)
+			}
+		}
+		report.WriteText(os.Stdout, failed)
+		return results
+	}
+	report.WriteText(os.Stdout, results)
+	return results
+}
+
+// gitRunner returns the runner for running git in repositories in
+// parallel.
+func gitRunner() *runner.Runner {
+	// output is captured, so git must fail instead of prompting for
+	// credentials in the middle of other repositories' output
+	return &runner.Runner{Timeout: defaultTimeout, Executor: gitExec, Jobs: jobs, Env: []string{"GIT_TERMINAL_PROMPT=0"}, After: repoDeps}
+}
+
+// runCollect runs git args in repos with --jobs workers, showing progress on
+// stderr, and returns the results with failures recorded for the summary.
+func runCollect(repos []string, args ...string) []runner.Result {
+	var p *progress
+	if logLevel > levelQuiet {
+		p = newProgress(os.Stderr, isTerminal(os.Stderr), len(repos))
+	}
+	r := gitRunner()
+	if p != nil {
+		r.OnStart, r.OnDone = p.started, p.finished
+	}
+	results := r.Run(context.Background(), repos, args...)
+	if p != nil {
+		p.clear()
+	}
+
+	for _, res := range results {
+		if !res.OK() {
+			markRepoFailed(res.Repo)
+		}
+	}
+	return results
+}
+
+func init() {
+	rootCmd.PersistentFlags().IntVarP(&jobs, "jobs", "j", 1, "repositories to process in parallel for status, diff, pull and push (output is buffered)")
+	rootCmd.PersistentFlags().DurationVar(&defaultTimeout, "timeout", defaultTimeout, "time limit for each git command, in every repository (0 = no limit)")
+}
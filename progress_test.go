@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+func TestProgressLine(t *testing.T) {
+	p := newProgress(&bytes.Buffer{}, true, 4)
+	p.started("/src/api")
+	p.started("/src/web")
+	if got := p.line(100); got != "[                    ] 0/4 eta --  api, web" {
+		t.Errorf("line = %q", got)
+	}
+	p.start = time.Now().Add(-10 * time.Second)
+	p.finished(runner.Result{Repo: "/src/api"})
+	got := p.line(100)
+	if !strings.HasPrefix(got, "[=====               ] 1/4 eta 30s  web") {
+		t.Errorf("line = %q", got)
+	}
+	if long := p.line(20); len(long) != 20 || !strings.HasSuffix(long, "...") {
+		t.Errorf("line not truncated to width: %q", long)
+	}
+}
+
+func TestProgressPlainLog(t *testing.T) {
+	var b bytes.Buffer
+	p := newProgress(&b, false, 2)
+	p.started("/src/api")
+	p.finished(runner.Result{Repo: "/src/api", Duration: 1500 * time.Millisecond})
+	p.finished(runner.Result{Repo: "/src/web", Err: errors.New("exit status 1")})
+	want := "[1/2] /src/api ok (1.5s)\n[2/2] /src/web failed (0s)\n"
+	if b.String() != want {
+		t.Errorf("log = %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// pullRequest is an open pull (or merge) request with a summary of its CI.
+type pullRequest struct {
+	number int
+	title  string
+	url    string
+	checks string // "passing", "failing", "pending" or "no checks"
+}
+
+// forgeFor guesses the hosting service of repo from the origin URL:
+// "gitlab" when the host name mentions GitLab, "github" otherwise.
+func forgeFor(ctx context.Context, repo string) (string, error) {
+	u, err := gitOutput(ctx, repo, "remote", "get-url", "origin")
+	if err != nil {
+		return "", errors.New("no origin remote")
+	}
+	if strings.Contains(strings.ToLower(remoteHost(u)), "gitlab") {
+		return "gitlab", nil
+	}
+	return "github", nil
+}
+
+// remoteHost returns the host of a remote URL in URL or scp-like form.
+func remoteHost(remote string) string {
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	host, _, _ := strings.Cut(remote, ":")
+	if _, h, ok := strings.Cut(host, "@"); ok {
+		return h
+	}
+	return host
+}
+
+// githubCheck is an entry of gh's statusCheckRollup: a check run (status and
+// conclusion) or a commit status (state).
+type githubCheck struct {
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	State      string `json:"state"`
+}
+
+// summarizeChecks reduces a check rollup to a single word; one failing
+// check fails the whole pull request.
+func summarizeChecks(checks []githubCheck) string {
+	if len(checks) == 0 {
+		return "no checks"
+	}
+	pending := false
+	for _, c := range checks {
+		switch {
+		case c.Conclusion == "FAILURE" || c.Conclusion == "TIMED_OUT" || c.Conclusion == "CANCELLED" ||
+			c.Conclusion == "ACTION_REQUIRED" || c.State == "FAILURE" || c.State == "ERROR":
+			return "failing"
+		case c.State == "PENDING" || c.State == "EXPECTED" || (c.Status != "" && c.Status != "COMPLETED"):
+			pending = true
+		}
+	}
+	if pending {
+		return "pending"
+	}
+	return "passing"
+}
+
+// parseGitHubPRs parses the output of gh pr list --json
+// number,title,url,statusCheckRollup.
+func parseGitHubPRs(data []byte) ([]pullRequest, error) {
+	var raw []struct {
+		Number int           `json:"number"`
+		Title  string        `json:"title"`
+		URL    string        `json:"url"`
+		Checks []githubCheck `json:"statusCheckRollup"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing gh output: %v", err)
+	}
+	prs := make([]pullRequest, 0, len(raw))
+	for _, p := range raw {
+		prs = append(prs, pullRequest{number: p.Number, title: p.Title, url: p.URL, checks: summarizeChecks(p.Checks)})
+	}
+	return prs, nil
+}
+
+func githubPRs(ctx context.Context, repo, branch string) ([]pullRequest, error) {
+	out, err := ghOutput(ctx, repo, "pr", "list", "--state", "open", "--head", branch, "--json", "number,title,url,statusCheckRollup")
+	if err != nil {
+		return nil, err
+	}
+	return parseGitHubPRs([]byte(out))
+}
+
+// gitlabPipelineChecks maps a GitLab pipeline status onto the words used
+// for GitHub checks.
+func gitlabPipelineChecks(status string) string {
+	switch status {
+	case "":
+		return "no checks"
+	case "success":
+		return "passing"
+	case "failed", "canceled":
+		return "failing"
+	}
+	return "pending"
+}
+
+func gitlabMRs(ctx context.Context, repo, branch string) ([]pullRequest, error) {
+	if _, err := exec.LookPath("glab"); err != nil {
+		return nil, errors.New("the GitLab CLI (glab) is not installed")
+	}
+	glab := func(endpoint string, v any) error {
+		ctx, cancel := commandContext(ctx)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "glab", "api", endpoint)
+		cmd.Dir = repo
+		out, err := cmd.Output()
+		if err != nil {
+			return fmt.Errorf("glab api %s: %v", endpoint, err)
+		}
+		return json.Unmarshal(out, v)
+	}
+	var list []struct {
+		IID int `json:"iid"`
+	}
+	if err := glab("projects/:id/merge_requests?state=opened&source_branch="+url.QueryEscape(branch), &list); err != nil {
+		return nil, err
+	}
+	var mrs []pullRequest
+	for _, m := range list {
+		// the list endpoint leaves out the pipeline, the single MR has it
+		var mr struct {
+			IID      int    `json:"iid"`
+			Title    string `json:"title"`
+			WebURL   string `json:"web_url"`
+			Pipeline *struct {
+				Status string `json:"status"`
+			} `json:"head_pipeline"`
+		}
+		if err := glab(fmt.Sprintf("projects/:id/merge_requests/%d", m.IID), &mr); err != nil {
+			return nil, err
+		}
+		status := ""
+		if mr.Pipeline != nil {
+			status = mr.Pipeline.Status
+		}
+		mrs = append(mrs, pullRequest{number: mr.IID, title: mr.Title, url: mr.WebURL, checks: gitlabPipelineChecks(status)})
+	}
+	return mrs, nil
+}
+
+var prStatusCmd = &cobra.Command{
+	Use:   "status <pattern>...",
+	Short: "Show open pull requests and CI status for the current branch of each repository",
+	Long: `status lists the open pull requests (GitHub, through gh) or merge requests
+(GitLab, through glab) for the current branch of every repository, with the
+combined state of their checks. The service is picked from the origin URL.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		failing := 0
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPO\tBRANCH\tPR\tCHECKS\tTITLE")
+		for _, r := range repos {
+			branch, err := currentBranch(ctx, r)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			if branch == "" {
+				fmt.Fprintf(w, "%s\t(detached)\t-\t\t\n", r)
+				continue
+			}
+			forge, err := forgeFor(ctx, r)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			var prs []pullRequest
+			if forge == "gitlab" {
+				prs, err = gitlabMRs(ctx, r, branch)
+			} else if err = requireGH(); err == nil {
+				prs, err = githubPRs(ctx, r, branch)
+			}
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			if len(prs) == 0 {
+				fmt.Fprintf(w, "%s\t%s\t-\t\t\n", r, branch)
+				continue
+			}
+			for _, p := range prs {
+				if p.checks == "failing" {
+					failing++
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r, branch, p.url, p.checks, p.title)
+			}
+		}
+		w.Flush()
+		if failing > 0 {
+			fmt.Printf("\n%d pull requests have failing checks\n", failing)
+		}
+		return nil
+	},
+}
+
+func init() {
+	prCmd.AddCommand(prStatusCmd)
+}
@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestRemoteHost(t *testing.T) {
+	cases := map[string]string{
+		"https://github.com/org/repo.git":       "github.com",
+		"ssh://git@gitlab.example.com:2222/a/b": "gitlab.example.com",
+		"git@gitlab.com:group/project.git":      "gitlab.com",
+		"github.com:org/repo":                   "github.com",
+	}
+	for in, want := range cases {
+		if got := remoteHost(in); got != want {
+			t.Errorf("remoteHost(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseGitHubPRs(t *testing.T) {
+	data := []byte(`[
+		{"number": 7, "title": "Bump deps", "url": "https://github.com/o/r/pull/7",
+		 "statusCheckRollup": [
+			{"__typename": "CheckRun", "status": "COMPLETED", "conclusion": "SUCCESS"},
+			{"__typename": "StatusContext", "state": "PENDING"}]},
+		{"number": 8, "title": "Fix", "url": "u8",
+		 "statusCheckRollup": [
+			{"__typename": "CheckRun", "status": "IN_PROGRESS", "conclusion": ""},
+			{"__typename": "CheckRun", "status": "COMPLETED", "conclusion": "FAILURE"}]},
+		{"number": 9, "title": "Docs", "url": "u9", "statusCheckRollup": []}
+	]`)
+	prs, err := parseGitHubPRs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"pending", "failing", "no checks"}
+	if len(prs) != len(want) {
+		t.Fatalf("got %d pull requests, want %d", len(prs), len(want))
+	}
+	for i, w := range want {
+		if prs[i].checks != w {
+			t.Errorf("PR %d checks = %q, want %q", prs[i].number, prs[i].checks, w)
+		}
+	}
+	if prs[0].title != "Bump deps" || prs[0].url != "https://github.com/o/r/pull/7" {
+		t.Errorf("unexpected first PR: %+v", prs[0])
+	}
+}
+
+func TestGitlabPipelineChecks(t *testing.T) {
+	for status, want := range map[string]string{"": "no checks", "success": "passing", "failed": "failing", "running": "pending"} {
+		if got := gitlabPipelineChecks(status); got != want {
+			t.Errorf("gitlabPipelineChecks(%q) = %q, want %q", status, got, want)
+		}
+	}
+}
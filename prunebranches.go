@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// mergedBranch is a local branch fully merged into the default branch.
+type mergedBranch struct {
+	name         string
+	remote       string // remote of the upstream, empty if none
+	remoteBranch string // branch name on that remote
+	remoteSHA    string // last fetched tip of the upstream, empty if it is gone
+	remoteAhead  bool   // the upstream has commits not merged into the default branch
+}
+
+type prunePlan struct {
+	repo     string
+	base     string
+	branches []mergedBranch
+}
+
+// planPruneBranches lists the local branches of dir that are merged into the
+// default branch, leaving out the default branch, the checked out branch and
+// any branch matching one of the keep patterns.
+func planPruneBranches(ctx context.Context, dir string, keep []string) (prunePlan, error) {
+	plan := prunePlan{repo: dir}
+	base, err := defaultBranch(ctx, dir)
+	if err != nil {
+		return plan, err
+	}
+	plan.base = base
+	current, err := currentBranch(ctx, dir)
+	if err != nil {
+		return plan, err
+	}
+	out, err := gitOutput(ctx, dir, "for-each-ref", "--merged=refs/heads/"+base,
+		"--format=%(refname:short)%00%(upstream:remotename)%00%(upstream:remoteref)%00%(upstream)", "refs/heads")
+	if err != nil {
+		return plan, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 4 || fields[0] == base || fields[0] == current || matchesAny(fields[0], keep) {
+			continue
+		}
+		b := mergedBranch{name: fields[0]}
+		if fields[1] != "" && fields[2] != "" {
+			b.remote = fields[1]
+			b.remoteBranch = strings.TrimPrefix(fields[2], "refs/heads/")
+			b.remoteSHA, _ = gitOutput(ctx, dir, "rev-parse", "--verify", "--quiet", fields[3])
+			b.remoteAhead = b.remoteSHA != "" && !isAncestor(ctx, dir, b.remoteSHA, "refs/heads/"+base)
+		}
+		plan.branches = append(plan.branches, b)
+	}
+	return plan, nil
+}
+
+// deletesRemote reports whether pruning b with --remote deletes its
+// upstream branch: only when the upstream was fetched and everything on it
+// is merged into the default branch.
+func (b mergedBranch) deletesRemote(base string) bool {
+	return b.remote != "" && b.remoteSHA != "" && !b.remoteAhead && b.remoteBranch != base
+}
+
+// pruneBranches deletes the branches of plan and, with remote, their
+// upstream branches. A branch is deleted locally first, so when git branch
+// -d refuses, for a branch not merged into HEAD or its upstream, the remote
+// branch is left alone. The remote branch is deleted with a lease on its
+// last fetched tip, so commits pushed to it since then are not lost.
+func pruneBranches(ctx context.Context, plan prunePlan, remote bool) {
+	for _, b := range plan.branches {
+		if err := runGit(ctx, plan.repo, "branch", "-d", b.name); err != nil {
+			repoFailed(plan.repo, fmt.Errorf("deleting %s: %v", b.name, err))
+			continue
+		}
+		if remote && b.deletesRemote(plan.base) {
+			lease := "--force-with-lease=refs/heads/" + b.remoteBranch + ":" + b.remoteSHA
+			if err := runGit(ctx, plan.repo, "push", lease, b.remote, "--delete", b.remoteBranch); err != nil {
+				repoFailed(plan.repo, fmt.Errorf("deleting %s/%s: %v", b.remote, b.remoteBranch, err))
+			}
+		}
+	}
+}
+
+// matchesAny reports whether name matches one of the shell patterns.
+func matchesAny(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// prune-branches command
+var pruneRemote bool
+var pruneYes bool
+var pruneKeep []string
+var pruneBranchesCmd = &cobra.Command{
+	Use:   "prune-branches [--remote] <pattern>...",
+	Short: "Delete local branches already merged into the default branch",
+	Long: `prune-branches finds local branches that are fully merged into each
+repository's default branch, prints the complete list and asks for
+confirmation before deleting them with git branch -d. With --remote the
+upstream branches are deleted on their remotes as well, unless they have
+commits not merged into the default branch. They are deleted with
+--force-with-lease on their last fetched tip, so anything pushed to them
+since then is kept. The default branch, the checked out branch and branches
+matching --keep are never deleted.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		var plans []prunePlan
+		total := 0
+		for _, r := range repos {
+			plan, err := planPruneBranches(ctx, r, pruneKeep)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			if len(plan.branches) == 0 {
+				continue
+			}
+			plans = append(plans, plan)
+			total += len(plan.branches)
+			repoHeader(r)
+			fmt.Printf("merged into %s:\n", plan.base)
+			for _, b := range plan.branches {
+				switch {
+				case pruneRemote && b.deletesRemote(plan.base):
+					fmt.Printf("  %s  (and %s/%s)\n", b.name, b.remote, b.remoteBranch)
+				case pruneRemote && b.remoteAhead:
+					fmt.Printf("  %s  (keeping %s/%s, it has unmerged commits)\n", b.name, b.remote, b.remoteBranch)
+				default:
+					fmt.Printf("  %s\n", b.name)
+				}
+			}
+		}
+		if total == 0 {
+			fmt.Println("no merged branches to delete")
+			return nil
+		}
+
+		if !pruneYes {
+			what := "local branches"
+			if pruneRemote {
+				what = "local branches and their remote counterparts"
+			}
+			fmt.Printf("\nAbout to delete %d %s in %d repositories. Continue? (y/N): ", total, what, len(plans))
+			if !userConfirm() {
+				fmt.Println("aborted")
+				markAborted()
+				return nil
+			}
+		}
+
+		for _, plan := range plans {
+			repoHeader(plan.repo)
+			pruneBranches(ctx, plan, pruneRemote)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pruneBranchesCmd)
+
+	pruneBranchesCmd.Flags().BoolVar(&pruneRemote, "remote", false, "also delete the upstream branches on their remotes")
+	pruneBranchesCmd.Flags().BoolVarP(&pruneYes, "yes", "y", false, "skip confirmation")
+	pruneBranchesCmd.Flags().StringSliceVar(&pruneKeep, "keep", []string{"main", "master", "develop", "release/*"}, "branch name patterns that are never deleted")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPlanPruneBranches(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+	for _, args := range [][]string{
+		{"branch", "-M", "main"},
+		{"branch", "merged-feature"},
+		{"branch", "release/1.0"},
+		{"switch", "-c", "open-feature"},
+	} {
+		if out, err := runGitCapture(ctx, repo, args...); err != nil {
+			t.Fatalf("git %v failed: %v, out=%s", args, err, out)
+		}
+	}
+	commitTestFile(t, repo, "b.txt", "b", "unmerged work")
+	if out, err := runGitCapture(ctx, repo, "switch", "-c", "checked-out", "main"); err != nil {
+		t.Fatalf("git switch failed: %v, out=%s", err, out)
+	}
+
+	plan, err := planPruneBranches(ctx, repo, []string{"release/*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.base != "main" {
+		t.Errorf("expected base main, got %q", plan.base)
+	}
+	if len(plan.branches) != 1 || plan.branches[0].name != "merged-feature" {
+		t.Errorf("expected only merged-feature to be pruned, got %+v", plan.branches)
+	}
+}
+
+func TestPruneBranchesKeepsRemoteWhenLocalRefused(t *testing.T) {
+	ctx := context.Background()
+	upstream := initTestRepo(t)
+	commitTestFile(t, upstream, "a.txt", "a", "first")
+	if out, err := runGitCapture(ctx, upstream, "branch", "feature"); err != nil {
+		t.Fatalf("git branch: %v %s", err, out)
+	}
+	clone := cloneTestRepo(t, upstream)
+	base, err := currentBranch(ctx, clone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// feature gets a commit that is neither pushed nor in HEAD, so git
+	// branch -d refuses it
+	for _, args := range [][]string{{"switch", "-q", "feature"}, {"commit", "-q", "--allow-empty", "-m", "unpushed"}, {"switch", "-q", base}} {
+		if out, err := runGitCapture(ctx, clone, args...); err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+	}
+
+	plan := prunePlan{repo: clone, base: base, branches: []mergedBranch{{name: "feature", remote: "origin", remoteBranch: "feature"}}}
+	captureStderr(t, func() {
+		captureStdout(t, func() { pruneBranches(ctx, plan, true) })
+	})
+	if !runSummary.failed[clone] {
+		t.Error("refused deletion not reported")
+	}
+	if _, err := gitOutput(ctx, upstream, "rev-parse", "--verify", "refs/heads/feature"); err != nil {
+		t.Error("remote branch deleted although the local one was kept")
+	}
+}
+
+func TestPruneBranchesRemoteSafety(t *testing.T) {
+	ctx := context.Background()
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		out, err := gitOutput(ctx, dir, args...)
+		if err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+		return out
+	}
+	// newCommit adds a commit on top of branch in repo without checking it out
+	newCommit := func(repo, branch string) {
+		sha := git(repo, "commit-tree", "-p", "refs/heads/"+branch, "-m", "pushed by a colleague", "HEAD^{tree}")
+		git(repo, "update-ref", "refs/heads/"+branch, sha)
+	}
+	upstream := initTestRepo(t)
+	commitTestFile(t, upstream, "a.txt", "a", "first")
+	for _, b := range []string{"done", "moved", "ahead"} {
+		git(upstream, "branch", b)
+	}
+	newCommit(upstream, "ahead")
+	clone := cloneTestRepo(t, upstream)
+	base, err := currentBranch(ctx, clone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []string{"done", "moved", "ahead"} {
+		git(clone, "branch", "--no-track", b, base)
+		git(clone, "branch", "--set-upstream-to=origin/"+b, b)
+	}
+	// moved gets a commit on the remote after the clone last fetched
+	newCommit(upstream, "moved")
+
+	plan, err := planPruneBranches(ctx, clone, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ahead := map[string]bool{}
+	for _, b := range plan.branches {
+		ahead[b.name] = b.remoteAhead
+	}
+	if len(ahead) != 3 || !ahead["ahead"] || ahead["done"] || ahead["moved"] {
+		t.Fatalf("plan = %+v", plan.branches)
+	}
+	captureStderr(t, func() {
+		captureStdout(t, func() { pruneBranches(ctx, plan, true) })
+	})
+	if _, err := gitOutput(ctx, upstream, "rev-parse", "--verify", "refs/heads/done"); err == nil {
+		t.Error("merged remote branch not deleted")
+	}
+	for _, b := range []string{"moved", "ahead"} {
+		if _, err := gitOutput(ctx, upstream, "rev-parse", "--verify", "refs/heads/"+b); err != nil {
+			t.Errorf("remote branch %s with unmerged commits deleted", b)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// Pull modes. ff-only, the default, never creates merge commits; git
+// leaves the choice to each repository's pull.rebase and pull.ff.
+const (
+	pullFFOnly = "ff-only"
+	pullMerge  = "merge"
+	pullRebase = "rebase"
+	pullGit    = "git"
+)
+
+// PullConfig is the [pull] section of the config file; --ff-only, --merge
+// and --rebase take precedence:
+//
+//	[pull]
+//	mode = "rebase"
+type PullConfig struct {
+	Mode string `toml:"mode"`
+}
+
+var pullFFOnlyFlag bool
+var pullMergeFlag bool
+var pullRebaseFlag bool
+
+// pullMode resolves the pull strategy from the flags, the config file and
+// the ff-only default.
+func pullMode() (string, error) {
+	var mode string
+	for _, f := range []struct {
+		set  bool
+		mode string
+	}{{pullFFOnlyFlag, pullFFOnly}, {pullMergeFlag, pullMerge}, {pullRebaseFlag, pullRebase}} {
+		if !f.set {
+			continue
+		}
+		if mode != "" {
+			return "", usageErrorf("--%s and --%s cannot be combined", mode, f.mode)
+		}
+		mode = f.mode
+	}
+	if mode != "" {
+		return mode, nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	switch cfg.Pull.Mode {
+	case "":
+		return pullFFOnly, nil
+	case pullFFOnly, pullMerge, pullRebase, pullGit:
+		return cfg.Pull.Mode, nil
+	}
+	return "", fmt.Errorf("[pull] mode: invalid %q (expected %s, %s, %s or %s)", cfg.Pull.Mode, pullFFOnly, pullMerge, pullRebase, pullGit)
+}
+
+// pullModeArgs are the git pull options for mode.
+func pullModeArgs(mode string) []string {
+	switch mode {
+	case pullFFOnly:
+		return []string{"--ff-only"}
+	case pullMerge:
+		return []string{"--no-rebase"}
+	case pullRebase:
+		return []string{"--rebase"}
+	}
+	return nil
+}
+
+// explainPullFailure adds the reason to a --ff-only pull that failed
+// because repo's branch has diverged from its upstream.
+func explainPullFailure(ctx context.Context, mode, repo string, err error) error {
+	if mode != pullFFOnly {
+		return err
+	}
+	ahead, behind, abErr := aheadBehind(ctx, repo, "HEAD", "@{upstream}")
+	if abErr != nil || ahead == 0 || behind == 0 {
+		return err
+	}
+	return fmt.Errorf("%v: cannot fast-forward, %d local and %d upstream commits have diverged (pull with --merge or --rebase)", err, ahead, behind)
+}
+
+// explainPullResults applies explainPullFailure to the failed results.
+func explainPullResults(mode string, results []runner.Result) {
+	ctx, cancel := runContext()
+	defer cancel()
+	for i, res := range results {
+		if !res.OK() {
+			results[i].Err = explainPullFailure(ctx, mode, res.Repo, res.Err)
+		}
+	}
+}
+
+// reportDiverged lists the repositories of a buffered pull that could not
+// fast-forward, after git's output.
+func reportDiverged(mode string, results []runner.Result) {
+	ctx, cancel := runContext()
+	defer cancel()
+	for _, res := range results {
+		if res.OK() {
+			continue
+		}
+		if err := explainPullFailure(ctx, mode, res.Repo, res.Err); err != res.Err {
+			fmt.Fprintf(os.Stderr, "%s %s: %v\n", paint(os.Stderr, ansiYellow, "not pulled"), res.Repo, err)
+		}
+	}
+}
+
+func init() {
+	pullCmd.Flags().BoolVar(&pullFFOnlyFlag, "ff-only", false, "only fast-forward; fail in repositories that have diverged (the default)")
+	pullCmd.Flags().BoolVar(&pullMergeFlag, "merge", false, "merge the upstream into diverged branches, creating merge commits")
+	pullCmd.Flags().BoolVar(&pullRebaseFlag, "rebase", false, "rebase diverged branches onto their upstream")
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPullMode(t *testing.T) {
+	t.Cleanup(func() { pullFFOnlyFlag, pullMergeFlag, pullRebaseFlag = false, false, false })
+
+	useTestConfig(t, "")
+	if mode, err := pullMode(); err != nil || mode != pullFFOnly {
+		t.Errorf("default mode = %q, %v", mode, err)
+	}
+	useTestConfig(t, "[pull]\nmode = \"rebase\"\n")
+	if mode, err := pullMode(); err != nil || mode != pullRebase {
+		t.Errorf("config mode = %q, %v", mode, err)
+	}
+	pullFFOnlyFlag = true
+	if mode, err := pullMode(); err != nil || mode != pullFFOnly {
+		t.Errorf("--ff-only over the config = %q, %v", mode, err)
+	}
+	pullMergeFlag = true
+	if _, err := pullMode(); exitCode(err) != exitUsage {
+		t.Errorf("--ff-only with --merge accepted: %v", err)
+	}
+	pullFFOnlyFlag, pullMergeFlag = false, false
+	useTestConfig(t, "[pull]\nmode = \"octopus\"\n")
+	if _, err := pullMode(); err == nil {
+		t.Error("invalid [pull] mode accepted")
+	}
+
+	for mode, want := range map[string][]string{pullFFOnly: {"--ff-only"}, pullMerge: {"--no-rebase"}, pullRebase: {"--rebase"}, pullGit: nil} {
+		if got := pullModeArgs(mode); !reflect.DeepEqual(got, want) {
+			t.Errorf("pullModeArgs(%s) = %q, want %q", mode, got, want)
+		}
+	}
+}
+
+func TestExplainPullFailure(t *testing.T) {
+	ctx := context.Background()
+	upstream := initTestRepo(t)
+	commitTestFile(t, upstream, "a.txt", "a", "first")
+	repo := filepath.Join(t.TempDir(), "clone")
+	if out, err := exec.Command("git", "clone", "-q", upstream, repo).CombinedOutput(); err != nil {
+		t.Fatalf("git clone: %v %s", err, out)
+	}
+	initTestRepoAt(t, repo) // sets the committer identity
+	pullErr := errors.New("exit status 128")
+
+	commitTestFile(t, upstream, "b.txt", "b", "upstream")
+	if out, err := exec.Command("git", "-C", repo, "fetch", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git fetch: %v %s", err, out)
+	}
+	if err := explainPullFailure(ctx, pullFFOnly, repo, pullErr); err != pullErr {
+		t.Errorf("behind only: %v", err)
+	}
+
+	commitTestFile(t, repo, "c.txt", "c", "local")
+	err := explainPullFailure(ctx, pullFFOnly, repo, pullErr)
+	if err == nil || !strings.Contains(err.Error(), "cannot fast-forward, 1 local and 1 upstream commits have diverged") {
+		t.Errorf("diverged: %v", err)
+	}
+	if err := explainPullFailure(ctx, pullMerge, repo, pullErr); err != pullErr {
+		t.Errorf("merge mode: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// readOnly is --read-only (or read_only in the config file): only commands
+// that leave repositories and remotes untouched may run.
+var readOnly bool
+
+// readOnlyCommands are the commands that never write to a repository or a
+// remote. Everything else, including commands added later, counts as
+// mutating until listed here.
+var readOnlyCommands = map[string]bool{
+	"status":         true,
+	"describe":       true,
+	"diff":           true,
+	"difftool":       true,
+	"divergence":     true,
+	"compare":        true,
+	"doctor":         true,
+	"dupes":          true,
+	"find-commit":    true,
+	"outgoing":       true,
+	"owners":         true,
+	"show":           true,
+	"size":           true,
+	"stale":          true,
+	"stats":          true,
+	"unpushed":       true,
+	"verify":         true,
+	"archive":        true, // writes files outside the repositories
+	"bundle create":  true,
+	"config get":     true,
+	"hooks status":   true,
+	"identity list":  true,
+	"identity audit": true,
+	"lfs status":     true,
+	"pr status":      true,
+	"remote list":    true,
+	"sparse list":    true,
+	"tag verify":     true,
+	"serve":          true, // POST /run is limited to status instead
+	"history":        true,
+	"import":         true, // prints config entries
+	"workspace":      true, // writes editor files outside the repositories
+	"docs":           true,
+	"version":        true,
+	"help":           true,
+}
+
+// readOnlyUnless lists read-only commands that mutate when the named flag
+// is set.
+var readOnlyUnless = map[string]string{
+	"default-branch": "rename",
+}
+
+// readOnlyWith lists commands that are read-only only when the named flag
+// is set.
+var readOnlyWith = map[string]string{
+	"check-merge": "no-fetch",
+	"incoming":    "no-fetch",
+}
+
+// allowedReadOnly reports whether cmd may run in read-only mode. Commands
+// run with --dry-run are allowed too.
+func allowedReadOnly(cmd *cobra.Command) bool {
+	name := commandName(cmd)
+	if !cmd.Runnable() || name == "" || readOnlyCommands[name] {
+		return true
+	}
+	// cobra's completion commands only print scripts and candidates
+	if strings.HasPrefix(name, "completion ") || strings.HasPrefix(name, "__complete") {
+		return true
+	}
+	if flag, ok := readOnlyUnless[name]; ok {
+		return !cmd.Flags().Changed(flag)
+	}
+	if flag, ok := readOnlyWith[name]; ok {
+		f := cmd.Flags().Lookup(flag)
+		return f.Changed && f.Value.String() == "true"
+	}
+	f := cmd.Flags().Lookup("dry-run")
+	return f != nil && f.Changed && f.Value.String() == "true"
+}
+
+// checkReadOnly refuses mutating commands under --read-only or read_only.
+func checkReadOnly(cmd *cobra.Command) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if !readOnly && !cfg.ReadOnly {
+		return nil
+	}
+	readOnly = true
+	if !allowedReadOnly(cmd) {
+		return exitWith(exitPolicy, fmt.Errorf("read-only mode: %q changes repositories and is not allowed", commandName(cmd)))
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&readOnly, "read-only", false, "refuse to run commands that change repositories or remotes")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCheckReadOnly(t *testing.T) {
+	useTestConfig(t, "read_only = true\n")
+	t.Cleanup(func() { readOnly = false })
+
+	run := func(*cobra.Command, []string) {}
+	root := &cobra.Command{Use: "gitbatch"}
+	status := &cobra.Command{Use: "status", Run: run}
+	push := &cobra.Command{Use: "push", Run: run}
+	rm := &cobra.Command{Use: "rm", Run: run}
+	rm.Flags().BoolP("dry-run", "n", false, "")
+	defaultBranch := &cobra.Command{Use: "default-branch", Run: run}
+	defaultBranch.Flags().StringArray("rename", nil, "")
+	checkMerge := &cobra.Command{Use: "check-merge", Run: run}
+	checkMerge.Flags().Bool("no-fetch", false, "")
+	remote := &cobra.Command{Use: "remote"}
+	remoteList := &cobra.Command{Use: "list", Run: run}
+	remoteAdd := &cobra.Command{Use: "add", Run: run}
+	remote.AddCommand(remoteList, remoteAdd)
+	root.AddCommand(status, push, rm, defaultBranch, checkMerge, remote)
+
+	for _, cmd := range []*cobra.Command{status, remote, remoteList, defaultBranch} {
+		if err := checkReadOnly(cmd); err != nil {
+			t.Errorf("%s refused: %v", cmd.CommandPath(), err)
+		}
+	}
+	for _, cmd := range []*cobra.Command{push, rm, remoteAdd, checkMerge} {
+		if err := checkReadOnly(cmd); err == nil {
+			t.Errorf("%s allowed in read-only mode", cmd.CommandPath())
+		}
+	}
+	rm.Flags().Set("dry-run", "true")
+	defaultBranch.Flags().Set("rename", "master")
+	if err := checkReadOnly(rm); err != nil {
+		t.Errorf("rm --dry-run refused: %v", err)
+	}
+	if err := checkReadOnly(defaultBranch); err == nil {
+		t.Error("default-branch --rename allowed in read-only mode")
+	}
+	checkMerge.Flags().Set("no-fetch", "true")
+	if err := checkReadOnly(checkMerge); err != nil {
+		t.Errorf("check-merge --no-fetch refused: %v", err)
+	}
+}
+
+func TestReadOnlyEmitScript(t *testing.T) {
+	useTestConfig(t, "")
+	repo := initTestRepo(t)
+	readOnly, emitScript = true, filepath.Join(t.TempDir(), "out.sh")
+	t.Cleanup(func() { readOnly, emitScript = false, "" })
+
+	err := checkReadOnly(configSetCmd)
+	if exitCode(err) != exitPolicy {
+		t.Errorf("config set --read-only --emit-script: exit %d (%v), want %d", exitCode(err), err, exitPolicy)
+	}
+	if err == nil {
+		captureStdout(t, func() { configSetCmd.RunE(configSetCmd, []string{"user.email", "new@example.com", repo}) })
+	}
+	if got, _ := gitOutput(context.Background(), repo, "config", "--local", "--get", "user.email"); got == "new@example.com" {
+		t.Error("config changed in read-only mode")
+	}
+}
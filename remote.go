@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// urlRewrite replaces the prefix from with to in remote URLs.
+type urlRewrite struct {
+	from, to string
+}
+
+// parseURLRewrites parses --url-rewrite values of the form "from=to".
+func parseURLRewrites(values []string) ([]urlRewrite, error) {
+	var rules []urlRewrite
+	for _, v := range values {
+		from, to, ok := strings.Cut(v, "=")
+		if !ok || from == "" {
+			return nil, usageErrorf("invalid --url-rewrite %q: expected from=to", v)
+		}
+		rules = append(rules, urlRewrite{from: from, to: to})
+	}
+	return rules, nil
+}
+
+// rewriteURL applies the first rule whose prefix matches url. ok is false
+// when no rule matched.
+func rewriteURL(url string, rules []urlRewrite) (string, bool) {
+	for _, r := range rules {
+		if strings.HasPrefix(url, r.from) {
+			return r.to + strings.TrimPrefix(url, r.from), true
+		}
+	}
+	return url, false
+}
+
+// expandRepoURL substitutes {{repo}} in tmpl with the base name of the
+// repository directory, so one URL template can serve many repositories.
+func expandRepoURL(tmpl, dir string) string {
+	return strings.ReplaceAll(tmpl, "{{repo}}", filepath.Base(dir))
+}
+
+var remoteURLRewrites []string
+var remoteSource string
+
+// remote command
+var remoteCmd = &cobra.Command{
+	Use:   "remote",
+	Short: "List, add, remove or rewrite remotes in matching repositories",
+	Long: `remote manages git remotes across repositories. URLs given on the command
+line may contain {{repo}}, which is replaced by the repository's directory
+name. --url-rewrite from=to derives URLs from existing ones instead, e.g.
+
+  gitbatch remote set-url origin --url-rewrite 'https://github.com/=git@github.com:' repos/*
+  gitbatch remote add upstream --url-rewrite 'git@github.com:me/=git@github.com:org/' forks/*`,
+}
+
+var remoteListCmd = &cobra.Command{
+	Use:   "list <pattern>...",
+	Short: "List remotes and their URLs",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPO\tREMOTE\tURL")
+		for _, r := range repos {
+			names, err := remoteNames(ctx, r)
+			if err != nil {
+				fmt.Fprintf(w, "%s\t-\terror: %v\n", r, err)
+				continue
+			}
+			if len(names) == 0 {
+				fmt.Fprintf(w, "%s\t-\t(no remotes)\n", r)
+			}
+			for _, name := range names {
+				url, _ := gitOutput(ctx, r, "remote", "get-url", name)
+				fmt.Fprintf(w, "%s\t%s\t%s\n", r, name, url)
+			}
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var remoteAddCmd = &cobra.Command{
+	Use:   "add <name> [<url>] <pattern>...",
+	Short: "Add a remote; the URL may use {{repo}} or be derived with --url-rewrite",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rules, err := parseURLRewrites(remoteURLRewrites)
+		if err != nil {
+			return err
+		}
+		name, urlTmpl, patterns, err := remoteTargetArgs(args, rules)
+		if err != nil {
+			return err
+		}
+		repos, err := collectRepos(patterns)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		for _, r := range repos {
+			repoHeader(r)
+			if hasRemote(ctx, r, name) {
+				fmt.Printf("remote %s already exists, skipped\n", name)
+				continue
+			}
+			url, err := remoteURLFor(ctx, r, urlTmpl, remoteSource, rules)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			if err := runGit(ctx, r, "remote", "add", name, url); err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			fmt.Printf("added %s %s\n", name, url)
+		}
+		return nil
+	},
+}
+
+var remoteRemoveCmd = &cobra.Command{
+	Use:   "remove <name> <pattern>...",
+	Short: "Remove a remote",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		repos, err := collectRepos(args[1:])
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		for _, r := range repos {
+			repoHeader(r)
+			if !hasRemote(ctx, r, name) {
+				fmt.Printf("no remote %s, skipped\n", name)
+				continue
+			}
+			if err := runGit(ctx, r, "remote", "remove", name); err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			fmt.Printf("removed %s\n", name)
+		}
+		return nil
+	},
+}
+
+var remoteSetURLCmd = &cobra.Command{
+	Use:   "set-url <name> [<url>] <pattern>...",
+	Short: "Change a remote's URL; the URL may use {{repo}} or be rewritten with --url-rewrite",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rules, err := parseURLRewrites(remoteURLRewrites)
+		if err != nil {
+			return err
+		}
+		name, urlTmpl, patterns, err := remoteTargetArgs(args, rules)
+		if err != nil {
+			return err
+		}
+		repos, err := collectRepos(patterns)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		for _, r := range repos {
+			repoHeader(r)
+			if !hasRemote(ctx, r, name) {
+				fmt.Printf("no remote %s, skipped\n", name)
+				continue
+			}
+			old, _ := gitOutput(ctx, r, "remote", "get-url", name)
+			url, err := remoteURLFor(ctx, r, urlTmpl, name, rules)
+			if err != nil {
+				fmt.Printf("%s: %v, unchanged\n", name, err)
+				continue
+			}
+			if url == old {
+				fmt.Printf("%s already %s\n", name, url)
+				continue
+			}
+			if err := runGit(ctx, r, "remote", "set-url", name, url); err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			fmt.Printf("%s: %s -> %s\n", name, old, url)
+		}
+		return nil
+	},
+}
+
+// remoteTargetArgs splits "<name> [<url>] <pattern>..." arguments. The URL is
+// required unless rewrite rules are given, in which case it must be omitted.
+func remoteTargetArgs(args []string, rules []urlRewrite) (name, urlTmpl string, patterns []string, err error) {
+	if len(rules) > 0 {
+		return args[0], "", args[1:], nil
+	}
+	if len(args) < 3 {
+		return "", "", nil, usageErrorf("need <name> <url> <pattern>... (or --url-rewrite instead of <url>)")
+	}
+	return args[0], args[1], args[2:], nil
+}
+
+// remoteURLFor computes the URL for a repository: the expanded template when
+// one is given, otherwise the URL of remote source rewritten by rules.
+func remoteURLFor(ctx context.Context, dir, urlTmpl, source string, rules []urlRewrite) (string, error) {
+	if urlTmpl != "" {
+		return expandRepoURL(urlTmpl, dir), nil
+	}
+	base, err := gitOutput(ctx, dir, "remote", "get-url", source)
+	if err != nil {
+		return "", fmt.Errorf("cannot read %s url: %v", source, err)
+	}
+	url, ok := rewriteURL(base, rules)
+	if !ok {
+		return "", fmt.Errorf("no --url-rewrite rule matches %s", base)
+	}
+	return url, nil
+}
+
+func init() {
+	rootCmd.AddCommand(remoteCmd)
+	remoteCmd.AddCommand(remoteListCmd)
+	remoteCmd.AddCommand(remoteAddCmd)
+	remoteCmd.AddCommand(remoteRemoveCmd)
+	remoteCmd.AddCommand(remoteSetURLCmd)
+
+	remoteCmd.PersistentFlags().StringArrayVar(&remoteURLRewrites, "url-rewrite", nil, "derive URLs by replacing a prefix, as from=to (repeatable)")
+	remoteAddCmd.Flags().StringVar(&remoteSource, "from", "origin", "remote whose URL --url-rewrite is applied to")
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRewriteURL(t *testing.T) {
+	rules, err := parseURLRewrites([]string{"https://github.com/=git@github.com:", "git@gitlab.com:me/=git@gitlab.com:org/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"https://github.com/acme/api.git", "git@github.com:acme/api.git", true},
+		{"git@gitlab.com:me/tool.git", "git@gitlab.com:org/tool.git", true},
+		{"ssh://example.com/x.git", "ssh://example.com/x.git", false},
+	}
+	for _, c := range cases {
+		got, ok := rewriteURL(c.in, rules)
+		if got != c.want || ok != c.ok {
+			t.Errorf("rewriteURL(%q) = %q, %v; want %q, %v", c.in, got, ok, c.want, c.ok)
+		}
+	}
+
+	if _, err := parseURLRewrites([]string{"no-separator"}); err == nil {
+		t.Errorf("expected error for rule without '='")
+	}
+}
+
+func TestExpandRepoURL(t *testing.T) {
+	if got := expandRepoURL("ssh://backup/{{repo}}.git", "/work/clients/site-a"); got != "ssh://backup/site-a.git" {
+		t.Errorf("expandRepoURL = %q", got)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// RepoOverride is a [[repo]] entry of the config file: environment
+// variables and git config values injected into every git command run in
+// the matching repositories, and tags to select them with --tag.
+//
+//	[[repo]]
+//	path = "~/src/corp/**"          # or remote = "*.corp.example.com"
+//	env = { HTTPS_PROXY = "http://proxy.corp:3128" }
+//	git_config = { "http.proxy" = "http://proxy.corp:3128" }
+//	tags = ["team:payments", "lang:go"]
+//
+// path is a glob over the repository path. An absolute or ~/ pattern must
+// match the whole path; any other pattern matches its trailing elements, so
+// "corp/*" matches /home/me/src/corp/api. remote is a glob over the host of
+// the repository's default remote. An entry with both applies only when
+// both match.
+type RepoOverride struct {
+	Path      string            `toml:"path"`
+	Remote    string            `toml:"remote"`
+	Env       map[string]string `toml:"env"`
+	GitConfig map[string]string `toml:"git_config"`
+	Tags      []string          `toml:"tags"`
+}
+
+// validate checks the patterns of o.
+func (o RepoOverride) validate() error {
+	if o.Path == "" && o.Remote == "" {
+		return fmt.Errorf("[[repo]] needs a path or remote pattern")
+	}
+	for _, p := range []string{o.Path, o.Remote} {
+		if p != "" && !doublestar.ValidatePattern(p) {
+			return fmt.Errorf("[[repo]]: invalid pattern %q", p)
+		}
+	}
+	return nil
+}
+
+// matchesPath reports whether the path pattern of o matches repo.
+func (o RepoOverride) matchesPath(repo string) bool {
+	pattern := expandHome(o.Path)
+	if !filepath.IsAbs(pattern) {
+		pattern = "**/" + pattern
+	}
+	ok, _ := doublestar.Match(filepath.ToSlash(pattern), filepath.ToSlash(repo))
+	return ok
+}
+
+// repoOverrides resolves, once per repository, the overrides that apply.
+type repoOverrides struct {
+	rules []RepoOverride
+	exe   runner.GitExecutor // for looking up remotes, not overridden
+
+	mu    sync.Mutex
+	cache map[string]overrideSet
+}
+
+// overrideSet is what gets added to a repository's git commands, and the
+// tags of the repository.
+type overrideSet struct {
+	configArgs []string // -c key=value ...
+	env        []string
+	tags       []string
+}
+
+// remoteHostOf returns the host of the default remote of repo, or "".
+func (r *repoOverrides) remoteHostOf(ctx context.Context, repo string) string {
+	u, err := r.exe.Output(ctx, runner.Git(repo, "remote", "get-url", defaultRemote(ctx, r.exe, repo)))
+	if err != nil {
+		return ""
+	}
+	return networkHost(u)
+}
+
+// lookup returns the merged overrides for repo; later entries win over
+// earlier ones for the same key.
+func (r *repoOverrides) lookup(ctx context.Context, repo string) overrideSet {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if set, ok := r.cache[repo]; ok {
+		return set
+	}
+	env, config, tags := map[string]string{}, map[string]string{}, map[string]string{}
+	host, hostKnown := "", false
+	for _, o := range r.rules {
+		if o.Path != "" && !o.matchesPath(repo) {
+			continue
+		}
+		if o.Remote != "" {
+			if !hostKnown {
+				host, hostKnown = r.remoteHostOf(ctx, repo), true
+			}
+			if ok, _ := doublestar.Match(strings.ToLower(o.Remote), host); host == "" || !ok {
+				continue
+			}
+		}
+		for k, v := range o.Env {
+			env[k] = v
+		}
+		for k, v := range o.GitConfig {
+			config[k] = v
+		}
+		for _, t := range o.Tags {
+			tags[t] = t
+		}
+	}
+	var set overrideSet
+	for _, k := range sortedKeys(config) {
+		set.configArgs = append(set.configArgs, "-c", k+"="+config[k])
+	}
+	for _, k := range sortedKeys(env) {
+		set.env = append(set.env, k+"="+env[k])
+	}
+	set.tags = sortedKeys(tags)
+	r.cache[repo] = set
+	return set
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// overrideExecutor wraps the git executor to add the configured
+// environment and -c options to commands run in matching repositories.
+type overrideExecutor struct {
+	next      runner.GitExecutor
+	overrides *repoOverrides
+}
+
+func (e overrideExecutor) apply(ctx context.Context, c runner.Cmd) runner.Cmd {
+	if c.Dir == "" {
+		return c
+	}
+	set := e.overrides.lookup(ctx, c.Dir)
+	if len(set.configArgs) > 0 {
+		// after a leading --no-pager: runner.Exec only recognizes it as the
+		// first argument and would add a second one
+		i := 0
+		if len(c.Args) > 0 && c.Args[0] == "--no-pager" {
+			i = 1
+		}
+		c.Args = append(append(append([]string{}, c.Args[:i]...), set.configArgs...), c.Args[i:]...)
+	}
+	if len(set.env) > 0 {
+		// command-specific variables are appended last and still win
+		c.Env = append(append([]string{}, set.env...), c.Env...)
+	}
+	return c
+}
+
+func (e overrideExecutor) Stream(ctx context.Context, c runner.Cmd) error {
+	return e.next.Stream(ctx, e.apply(ctx, c))
+}
+
+func (e overrideExecutor) Capture(ctx context.Context, c runner.Cmd) (string, error) {
+	return e.next.Capture(ctx, e.apply(ctx, c))
+}
+
+func (e overrideExecutor) Output(ctx context.Context, c runner.Cmd) (string, error) {
+	return e.next.Output(ctx, e.apply(ctx, c))
+}
+
+func (e overrideExecutor) Lines(ctx context.Context, c runner.Cmd, fn func(string)) error {
+	return e.next.Lines(ctx, e.apply(ctx, c), fn)
+}
+
+// repoSettings resolves the [[repo]] entries of the config file; nil
+// without entries.
+var repoSettings *repoOverrides
+
+// setupRepoOverrides routes git through overrideExecutor when the config
+// file has [[repo]] entries.
+func setupRepoOverrides() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if len(cfg.Repos) == 0 {
+		return nil
+	}
+	for _, o := range cfg.Repos {
+		if err := o.validate(); err != nil {
+			return err
+		}
+	}
+	repoSettings = &repoOverrides{rules: cfg.Repos, exe: gitExec, cache: map[string]overrideSet{}}
+	gitExec = overrideExecutor{next: gitExec, overrides: repoSettings}
+	return nil
+}
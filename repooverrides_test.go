@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+func TestOverrideExecutor(t *testing.T) {
+	ctx := context.Background()
+	m := (&runner.Mock{}).On("remote get-url origin", "git@git.corp.example.com:team/api.git", nil)
+	rules := []RepoOverride{
+		{Path: "corp/*", Env: map[string]string{"HTTPS_PROXY": "http://proxy:3128"}},
+		{Remote: "*.corp.example.com", GitConfig: map[string]string{"http.proxy": "http://proxy:3128", "core.autocrlf": "false"}},
+		{Path: "/other/**", Env: map[string]string{"NEVER": "1"}},
+	}
+	for _, o := range rules {
+		if err := o.validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exe := overrideExecutor{next: m, overrides: &repoOverrides{rules: rules, exe: m, cache: map[string]overrideSet{}}}
+
+	exe.Capture(ctx, runner.Cmd{Dir: "/home/me/src/corp/api", Args: []string{"pull"}, Env: []string{"GIT_TERMINAL_PROMPT=0"}})
+	last := m.Calls[len(m.Calls)-1]
+	if want := []string{"-c", "core.autocrlf=false", "-c", "http.proxy=http://proxy:3128", "pull"}; !reflect.DeepEqual(last.Args, want) {
+		t.Errorf("args = %q, want %q", last.Args, want)
+	}
+	if want := []string{"HTTPS_PROXY=http://proxy:3128", "GIT_TERMINAL_PROMPT=0"}; !reflect.DeepEqual(last.Env, want) {
+		t.Errorf("env = %q, want %q", last.Env, want)
+	}
+
+	exe.Stream(ctx, runner.Git("/home/me/src/corp/api", "--no-pager", "diff"))
+	last = m.Calls[len(m.Calls)-1]
+	if want := []string{"--no-pager", "-c", "core.autocrlf=false", "-c", "http.proxy=http://proxy:3128", "diff"}; !reflect.DeepEqual(last.Args, want) {
+		t.Errorf("args = %q, want %q", last.Args, want)
+	}
+
+	// the remote is looked up once per repository
+	calls := len(m.Calls)
+	exe.Output(ctx, runner.Git("/home/me/src/corp/api", "status"))
+	if len(m.Calls) != calls+1 {
+		t.Errorf("expected a cached lookup, saw %v", m.Commands()[calls:])
+	}
+
+	m.On("remote get-url origin", "https://github.com/me/tool.git", nil)
+	exe.Output(ctx, runner.Git("/home/me/src/tool", "status"))
+	if last := m.Calls[len(m.Calls)-1]; len(last.Args) != 1 || last.Env != nil {
+		t.Errorf("unexpected overrides for a non-matching repository: %+v", last)
+	}
+
+	if err := (RepoOverride{Env: map[string]string{"A": "1"}}).validate(); err == nil {
+		t.Error("expected an error for an entry without path or remote")
+	}
+}
+
+func TestSetupRepoOverrides(t *testing.T) {
+	useTestConfig(t, `
+[[repo]]
+path = "corp/**"
+git_config = { "http.proxy" = "http://proxy:3128" }
+`)
+	m := useMockGit(t)
+	if err := setupRepoOverrides(); err != nil {
+		t.Fatal(err)
+	}
+	gitOutput(context.Background(), "/src/corp/api", "fetch")
+	if got := m.Commands(); got[len(got)-1] != "git -c http.proxy=http://proxy:3128 fetch" {
+		t.Errorf("unexpected commands %q", got)
+	}
+
+	useTestConfig(t, `
+[[repo]]
+env = { A = "1" }
+`)
+	if err := setupRepoOverrides(); err == nil {
+		t.Error("expected an error for [[repo]] without path or remote")
+	}
+}
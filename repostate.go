@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// includeDetached is --include-detached: mutating commands also run in
+// repositories that are detached or in the middle of an operation.
+var includeDetached bool
+
+// skipBusy is set for mutating commands unless --include-detached is set.
+var skipBusy bool
+
+// inProgress maps files in the git directory to the operation they mark.
+// The rebase entries come first: a rebase stopped at a conflict can leave
+// CHERRY_PICK_HEAD behind as well.
+var inProgress = []struct{ file, state string }{
+	{"rebase-merge", "rebase in progress"},
+	{"rebase-apply", "rebase in progress"},
+	{"MERGE_HEAD", "merge in progress"},
+	{"CHERRY_PICK_HEAD", "cherry-pick in progress"},
+}
+
+// busyState describes why repo is not on a branch in a settled state:
+// an operation in progress or a detached HEAD. It returns "" otherwise.
+func busyState(ctx context.Context, repo string) (string, error) {
+	dir, err := gitDir(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range inProgress {
+		if _, err := os.Stat(filepath.Join(dir, p.file)); err == nil {
+			return p.state, nil
+		}
+	}
+	branch, err := currentBranch(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+	if branch == "" {
+		return "detached HEAD", nil
+	}
+	return "", nil
+}
+
+// setupRepoState decides whether busy repositories are skipped.
+func setupRepoState(cmd *cobra.Command) error {
+	skipBusy = !includeDetached && mutating(cmd)
+	return nil
+}
+
+// skipBusyRepos drops repositories that are detached or in the middle of a
+// rebase, merge or cherry-pick before a mutating command runs. They are
+// reported now and listed in the run summary.
+func skipBusyRepos(repos []string) ([]string, error) {
+	if !skipBusy {
+		return repos, nil
+	}
+	return dropBusyRepos(repos)
+}
+
+// dropBusyRepos returns repos without those that are detached or in the
+// middle of an operation, reporting each one it drops.
+func dropBusyRepos(repos []string) ([]string, error) {
+	ctx, cancel := runContext()
+	defer cancel()
+	var ok []string
+	for _, r := range repos {
+		state, err := busyState(ctx, r)
+		if err != nil || state == "" {
+			// errors are left for the command itself to report
+			ok = append(ok, r)
+			continue
+		}
+		markRepoSkipped(r, state)
+		fmt.Fprintf(os.Stderr, "%s %s: %s\n", paint(os.Stderr, ansiYellow, "skipping"), r, state)
+	}
+	if len(ok) == 0 {
+		return nil, noReposErrorf("every repository is detached or in the middle of an operation (use --include-detached to run anyway)")
+	}
+	return ok, nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&includeDetached, "include-detached", false, "also run mutating commands in repositories with a detached HEAD or a rebase, merge or cherry-pick in progress")
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSkipBusyRepos(t *testing.T) {
+	ctx := context.Background()
+	clean, detached, merging := initTestRepo(t), initTestRepo(t), initTestRepo(t)
+	for _, r := range []string{clean, detached, merging} {
+		commitTestFile(t, r, "a.txt", "a", "first")
+	}
+	c := exec.Command("git", "checkout", "--quiet", "--detach")
+	c.Dir = detached
+	if out, err := c.CombinedOutput(); err != nil {
+		t.Fatalf("detach: %v %s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(merging, ".git", "MERGE_HEAD"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for repo, want := range map[string]string{clean: "", detached: "detached HEAD", merging: "merge in progress"} {
+		if got, err := busyState(ctx, repo); err != nil || got != want {
+			t.Errorf("busyState(%s) = %q, %v; want %q", repo, got, err, want)
+		}
+	}
+
+	old := skipBusy
+	t.Cleanup(func() {
+		skipBusy = old
+		delete(runSummary.skipped, detached)
+		delete(runSummary.skipped, merging)
+	})
+	skipBusy = true
+	var got []string
+	var err error
+	stderr := captureStderr(t, func() { got, err = skipBusyRepos([]string{clean, detached, merging}) })
+	if err != nil || !reflect.DeepEqual(got, []string{clean}) {
+		t.Errorf("skipBusyRepos = %v, %v", got, err)
+	}
+	if runSummary.skipped[merging] != "merge in progress" || stderr == "" {
+		t.Errorf("skip not reported: summary %v, stderr %q", runSummary.skipped, stderr)
+	}
+	if _, err := skipBusyRepos([]string{detached}); err == nil {
+		t.Error("no repository left but no error")
+	}
+
+	skipBusy = false // --include-detached or a read-only command
+	if got, _ := skipBusyRepos([]string{detached, merging}); len(got) != 2 {
+		t.Errorf("skipped without skipBusy: %v", got)
+	}
+}
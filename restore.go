@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// restoreCandidates returns the "git diff --name-status" lines of the files
+// under pathspecs that restore would touch: staged changes with staged,
+// unstaged changes otherwise.
+func restoreCandidates(ctx context.Context, repo string, staged bool, pathspecs []string) ([]string, error) {
+	args := []string{"diff", "--name-status"}
+	if staged {
+		if _, err := gitOutput(ctx, repo, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+			return nil, errors.New("no commits yet, nothing to unstage against")
+		}
+		args = append(args, "--cached")
+	}
+	out, err := gitOutput(ctx, repo, append(append(args, "--"), pathspecs...)...)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// restore command
+var restorePathSpecs []string
+var restoreStaged bool
+var restoreYes bool
+var restoreCmd = &cobra.Command{
+	Use:   "restore [--staged] --pathspec <path>... <pattern>...",
+	Short: "Discard or unstage changes to paths in matching repositories",
+	Long: `restore lists the changed files under the pathspecs in every repository,
+asks for confirmation and then runs git restore. Without --staged the
+working tree changes are discarded, which cannot be undone; with --staged
+the changes are only removed from the index.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(restorePathSpecs) == 0 {
+			return usageErrorf("at least one --pathspec is required")
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+
+		var pending []string
+		scanCtx, scanCancel := runContext()
+		for _, r := range repos {
+			files, err := restoreCandidates(scanCtx, r, restoreStaged, restorePathSpecs)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			if len(files) == 0 {
+				continue
+			}
+			repoHeader(r)
+			for _, f := range files {
+				fmt.Println(f)
+			}
+			pending = append(pending, r)
+		}
+		scanCancel()
+		if len(pending) == 0 {
+			fmt.Println("nothing to restore")
+			return nil
+		}
+
+		if !restoreYes {
+			what := "Discard working tree changes"
+			if restoreStaged {
+				what = "Unstage changes"
+			}
+			fmt.Printf("\n%s in %d repositories? (y/N): ", what, len(pending))
+			if !userConfirm() {
+				fmt.Println("aborted")
+				markAborted()
+				return nil
+			}
+		}
+
+		ctx, cancel := runContext()
+		defer cancel()
+		gitArgs := []string{"restore"}
+		if restoreStaged {
+			gitArgs = append(gitArgs, "--staged")
+		}
+		gitArgs = append(append(gitArgs, "--"), restorePathSpecs...)
+		for _, r := range pending {
+			if err := runGit(ctx, r, gitArgs...); err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			fmt.Printf("%s: restored\n", r)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().StringArrayVarP(&restorePathSpecs, "pathspec", "p", nil, "pathspec to restore (repeatable)")
+	restoreCmd.Flags().BoolVar(&restoreStaged, "staged", false, "unstage instead of discarding working tree changes")
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "skip confirmation")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRestoreCandidates(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "one\n", "init")
+	commitTestFile(t, repo, "b.txt", "one\n", "second")
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte("two\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, err := runGitCapture(ctx, repo, "add", "b.txt"); err != nil {
+		t.Fatalf("git add failed: %v, out=%s", err, out)
+	}
+
+	unstaged, err := restoreCandidates(ctx, repo, false, []string{"."})
+	if err != nil || len(unstaged) != 1 || unstaged[0] != "M\ta.txt" {
+		t.Errorf("unstaged candidates = %q, %v", unstaged, err)
+	}
+	staged, err := restoreCandidates(ctx, repo, true, []string{"."})
+	if err != nil || len(staged) != 1 || staged[0] != "M\tb.txt" {
+		t.Errorf("staged candidates = %q, %v", staged, err)
+	}
+	none, err := restoreCandidates(ctx, repo, true, []string{"a.txt"})
+	if err != nil || len(none) != 0 {
+		t.Errorf("expected no staged changes under a.txt, got %q, %v", none, err)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+var retries int
+var retryDelay time.Duration
+
+// retriedCommands are the git commands that talk to a remote and are safe
+// to run again after a transient failure.
+var retriedCommands = map[string]bool{"pull": true, "push": true, "fetch": true, "clone": true}
+
+// transientErrors are fragments of git and curl messages for failures that
+// are likely to go away on their own: timeouts, dropped connections, DNS
+// hiccups and 5xx responses from smart HTTP servers.
+var transientErrors = []string{
+	"timed out",
+	"timeout",
+	"connection reset",
+	"connection closed",
+	"connection was reset",
+	"broken pipe",
+	"early eof",
+	"the remote end hung up unexpectedly",
+	"temporary failure in name resolution",
+	"could not resolve host",
+	"rpc failed",
+	"the requested url returned error: 5",
+	"http 5",
+	"502 bad gateway",
+	"503 service unavailable",
+	"504 gateway",
+	"ssh_exchange_identification",
+	"kex_exchange_identification",
+}
+
+// isTransient reports whether output of a failed git command looks like a
+// transient network error.
+func isTransient(output string) bool {
+	output = strings.ToLower(output)
+	for _, s := range transientErrors {
+		if strings.Contains(output, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// retriedError is the final failure of a command that was retried after
+// transient errors.
+type retriedError struct {
+	attempts int
+	err      error
+}
+
+func (e *retriedError) Error() string {
+	return fmt.Sprintf("failed after %d attempts: %v", e.attempts, e.err)
+}
+
+func (e *retriedError) Unwrap() error { return e.err }
+
+// retryExecutor wraps the git executor to run network commands again, with
+// exponential backoff, when they fail with a transient error.
+type retryExecutor struct {
+	next     runner.GitExecutor
+	attempts int           // retries + 1
+	delay    time.Duration // before the first retry, doubled after each
+}
+
+// do runs attempt until it succeeds, fails permanently or the attempts run
+// out. attempt returns the command's output for classifying errors.
+func (e retryExecutor) do(ctx context.Context, c runner.Cmd, attempt func() (string, error)) error {
+	if len(c.Args) == 0 || !retriedCommands[c.Args[0]] {
+		_, err := attempt()
+		return err
+	}
+	delay := e.delay
+	for n := 1; ; n++ {
+		out, err := attempt()
+		if err == nil {
+			return nil
+		}
+		if !isTransient(out + "\n" + err.Error()) {
+			if n > 1 {
+				return &retriedError{attempts: n, err: err}
+			}
+			return err
+		}
+		if n == e.attempts {
+			return &retriedError{attempts: n, err: err}
+		}
+		logf(levelNormal, "[%s] %s failed with a transient error, retrying in %s (attempt %d of %d)", c.Dir, c, delay, n+1, e.attempts)
+		select {
+		case <-ctx.Done():
+			return &retriedError{attempts: n, err: err}
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (e retryExecutor) Stream(ctx context.Context, c runner.Cmd) error {
+	return e.do(ctx, c, func() (string, error) {
+		// keep a copy of stderr to tell transient errors apart
+		var stderr bytes.Buffer
+		attempt := c
+		var w io.Writer = os.Stderr
+		if c.Stderr != nil {
+			w = c.Stderr
+		}
+		attempt.Stderr = io.MultiWriter(w, &stderr)
+		err := e.next.Stream(ctx, attempt)
+		return stderr.String(), err
+	})
+}
+
+func (e retryExecutor) Capture(ctx context.Context, c runner.Cmd) (string, error) {
+	var out string
+	err := e.do(ctx, c, func() (string, error) {
+		var err error
+		out, err = e.next.Capture(ctx, c)
+		return out, err
+	})
+	return out, err
+}
+
+func (e retryExecutor) Output(ctx context.Context, c runner.Cmd) (string, error) {
+	var out string
+	err := e.do(ctx, c, func() (string, error) {
+		var err error
+		out, err = e.next.Output(ctx, c)
+		return out, err
+	})
+	return out, err
+}
+
+func (e retryExecutor) Lines(ctx context.Context, c runner.Cmd, fn func(string)) error {
+	// lines already passed to fn cannot be taken back, so no retries here
+	return e.next.Lines(ctx, c, fn)
+}
+
+// setupRetries routes git through retryExecutor when --retries is set.
+func setupRetries() error {
+	if retries < 0 {
+		return usageErrorf("--retries must not be negative")
+	}
+	if retries > 0 {
+		gitExec = retryExecutor{next: gitExec, attempts: retries + 1, delay: retryDelay}
+	}
+	return nil
+}
+
+// addRetryFlags registers --retries and --retry-delay on a network command.
+func addRetryFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&retries, "retries", 0, "retry the git command up to N times on transient network errors")
+	cmd.Flags().DurationVar(&retryDelay, "retry-delay", 2*time.Second, "delay before the first retry, doubled after each attempt")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// flakyExecutor fails the first failures calls with output, then succeeds.
+type flakyExecutor struct {
+	runner.Mock
+	failures int
+	output   string
+	calls    int
+}
+
+func (f *flakyExecutor) Capture(ctx context.Context, c runner.Cmd) (string, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.output, errors.New("exit status 128")
+	}
+	return "ok\n", nil
+}
+
+func TestIsTransient(t *testing.T) {
+	for _, s := range []string{
+		"fatal: unable to access 'https://example.com/r.git/': Failed to connect to example.com port 443: Connection timed out",
+		"error: RPC failed; curl 56 Recv failure: Connection reset by peer",
+		"fatal: unable to access 'https://example.com/r.git/': The requested URL returned error: 503",
+		"fatal: the remote end hung up unexpectedly",
+	} {
+		if !isTransient(s) {
+			t.Errorf("expected %q to be transient", s)
+		}
+	}
+	for _, s := range []string{
+		"fatal: Authentication failed for 'https://example.com/r.git/'",
+		"fatal: repository 'https://example.com/r.git/' not found",
+		"CONFLICT (content): Merge conflict in a.txt",
+	} {
+		if isTransient(s) {
+			t.Errorf("expected %q not to be transient", s)
+		}
+	}
+}
+
+func TestRetryExecutor(t *testing.T) {
+	ctx := context.Background()
+	flaky := &flakyExecutor{failures: 2, output: "fatal: the remote end hung up unexpectedly\n"}
+	exe := retryExecutor{next: flaky, attempts: 3, delay: 0}
+	if out, err := exe.Capture(ctx, runner.Git("/r", "fetch")); err != nil || out != "ok\n" {
+		t.Errorf("expected success on the third attempt, got %q, %v", out, err)
+	}
+
+	flaky = &flakyExecutor{failures: 5, output: "fatal: the remote end hung up unexpectedly\n"}
+	exe.next = flaky
+	_, err := exe.Capture(ctx, runner.Git("/r", "pull"))
+	var retried *retriedError
+	if !errors.As(err, &retried) || retried.attempts != 3 || flaky.calls != 3 {
+		t.Errorf("expected a retried failure after 3 attempts, got %v (%d calls)", err, flaky.calls)
+	}
+	if !strings.HasPrefix(err.Error(), "failed after 3 attempts") {
+		t.Errorf("unexpected error text %q", err)
+	}
+
+	// permanent errors and local commands are not retried
+	flaky = &flakyExecutor{failures: 5, output: "fatal: Authentication failed\n"}
+	exe.next = flaky
+	if _, err := exe.Capture(ctx, runner.Git("/r", "push")); errors.As(err, &retried) || flaky.calls != 1 {
+		t.Errorf("permanent error was retried: %v (%d calls)", err, flaky.calls)
+	}
+	flaky = &flakyExecutor{failures: 5, output: "fatal: the remote end hung up unexpectedly\n"}
+	exe.next = flaky
+	if _, err := exe.Capture(ctx, runner.Git("/r", "status")); err == nil || flaky.calls != 1 {
+		t.Errorf("status was retried: %v (%d calls)", err, flaky.calls)
+	}
+}
+
+func TestSetupRetriesNegative(t *testing.T) {
+	old := retries
+	t.Cleanup(func() { retries = old })
+	retries = -1
+	if err := setupRetries(); exitCode(err) != exitUsage {
+		t.Errorf("--retries -1: exit %d (%v), want %d", exitCode(err), err, exitUsage)
+	}
+}
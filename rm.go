@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// rm command
+var rmPathSpecs []string
+var rmCached bool
+var rmDryRun bool
+var rmRecursive bool
+var rmCmd = &cobra.Command{
+	Use:   "rm --pathspec <path>... <pattern>...",
+	Short: "Remove and stage files matching a pathspec in matching repositories",
+	Long: `rm runs git rm for the given pathspecs in every matching repository, so a
+file such as an old CI config can be deleted everywhere at once. Repositories
+that do not track any matching file are skipped. With --cached the files are
+only removed from the index and left on disk.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(rmPathSpecs) == 0 {
+			return usageErrorf("at least one --pathspec is required")
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		for _, r := range repos {
+			tracked, err := gitOutput(ctx, r, append([]string{"ls-files", "--"}, rmPathSpecs...)...)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			if tracked == "" {
+				fmt.Printf("%s: no tracked files match, skipped\n", r)
+				continue
+			}
+			repoHeader(r)
+			if err := runGit(ctx, r, rmArgs()...); err != nil {
+				repoFailed(r, err)
+			}
+		}
+		return nil
+	},
+}
+
+func rmArgs() []string {
+	args := []string{"rm"}
+	if rmCached {
+		args = append(args, "--cached")
+	}
+	if rmDryRun {
+		args = append(args, "--dry-run")
+	}
+	if rmRecursive {
+		args = append(args, "-r")
+	}
+	return append(append(args, "--"), rmPathSpecs...)
+}
+
+func init() {
+	rootCmd.AddCommand(rmCmd)
+
+	rmCmd.Flags().StringArrayVarP(&rmPathSpecs, "pathspec", "p", nil, "pathspec to remove (repeatable)")
+	rmCmd.Flags().BoolVar(&rmCached, "cached", false, "only remove from the index, keep the files on disk")
+	rmCmd.Flags().BoolVarP(&rmDryRun, "dry-run", "n", false, "show what would be removed without removing anything")
+	rmCmd.Flags().BoolVarP(&rmRecursive, "recursive", "r", false, "allow removing whole directories")
+}
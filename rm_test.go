@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRmArgs(t *testing.T) {
+	defer func() { rmPathSpecs, rmCached, rmDryRun, rmRecursive = nil, false, false, false }()
+	rmPathSpecs = []string{".travis.yml", "ci/"}
+	rmCached, rmRecursive = true, true
+
+	want := []string{"rm", "--cached", "-r", "--", ".travis.yml", "ci/"}
+	if got := rmArgs(); !slices.Equal(got, want) {
+		t.Fatalf("rmArgs() = %v, want %v", got, want)
+	}
+
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, ".travis.yml", "language: go\n", "add ci")
+	commitTestFile(t, repo, "ci/build.sh", "make\n", "add script")
+	if out, err := runGitCapture(context.Background(), repo, rmArgs()...); err != nil {
+		t.Fatalf("git rm failed: %v, out=%s", err, out)
+	}
+	staged, err := gitOutput(context.Background(), repo, "diff", "--cached", "--name-status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if staged != "D\t.travis.yml\nD\tci/build.sh" {
+		t.Errorf("unexpected staged changes: %q", staged)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".travis.yml")); err != nil {
+		t.Errorf("--cached should keep the file on disk: %v", err)
+	}
+}
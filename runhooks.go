@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// RunHook is a [[hook]] entry of the config file: shell commands run
+// before and after gitbatch commands (not to be confused with the git hooks
+// managed by gitbatch hooks):
+//
+//	[[hook]]
+//	commands = ["push", "pull"]
+//	pre = "ssh-add -l >/dev/null || ssh-add ~/.ssh/work"
+//
+//	[[hook]]
+//	post = "test -z \"$GITBATCH_FAILED\" || ./file-ticket.sh"
+//
+// commands limits the hook to some commands; a command also covers its
+// subcommands, so "remote" applies to "remote add". Without commands the
+// hook runs for every command that works on repositories.
+type RunHook struct {
+	Commands []string `toml:"commands"`
+	Pre      string   `toml:"pre"`
+	Post     string   `toml:"post"`
+}
+
+func (h RunHook) validate() error {
+	if h.Pre == "" && h.Post == "" {
+		return errors.New("[[hook]] needs pre or post")
+	}
+	return nil
+}
+
+// matches reports whether the hook applies to the command called name.
+func (h RunHook) matches(name string) bool {
+	if len(h.Commands) == 0 {
+		return true
+	}
+	for _, c := range h.Commands {
+		c = strings.Join(strings.Fields(c), " ")
+		if name == c || strings.HasPrefix(name, c+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// runHooks holds the hooks of the running command.
+var runHooks = struct {
+	sync.Mutex
+	command string
+	hooks   []RunHook
+	preRan  bool
+}{}
+
+// hookEnvVar is set for hooks, so a gitbatch run started by a hook does
+// not run the hooks again.
+const hookEnvVar = "GITBATCH_HOOK"
+
+// setupRunHooks selects the [[hook]] entries that apply to cmd. Nothing
+// runs for --emit-script, which only writes the git commands down, or
+// inside a hook.
+func setupRunHooks(cmd *cobra.Command) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	runHooks.Lock()
+	defer runHooks.Unlock()
+	runHooks.command, runHooks.hooks, runHooks.preRan = commandName(cmd), nil, false
+	for _, h := range cfg.Hooks {
+		if err := h.validate(); err != nil {
+			return err
+		}
+		if emitScript == "" && os.Getenv(hookEnvVar) == "" && h.matches(runHooks.command) {
+			runHooks.hooks = append(runHooks.hooks, h)
+		}
+	}
+	return nil
+}
+
+// hookEnv is the environment of a hook: the command and its repositories,
+// one per line, and after the run the repositories that failed and the
+// error of the run.
+func hookEnv(command string, repos []string, failed map[string]bool, runErr error) []string {
+	env := append(os.Environ(),
+		hookEnvVar+"=1",
+		"GITBATCH_COMMAND="+command,
+		"GITBATCH_REPOS="+strings.Join(repos, "\n"),
+	)
+	if failed == nil {
+		return env
+	}
+	var names []string
+	for r := range failed {
+		names = append(names, r)
+	}
+	sort.Strings(names)
+	env = append(env, "GITBATCH_FAILED="+strings.Join(names, "\n"))
+	if runErr != nil {
+		env = append(env, "GITBATCH_ERROR="+runErr.Error())
+	}
+	return env
+}
+
+// runShell runs script with sh in the current directory.
+func runShell(script string, env []string, stdin io.Reader) error {
+	cmd := exec.Command("sh", "-c", script)
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// runPreHooks runs the pre hooks once the repositories of the run are
+// known, before the workspace is locked. A failing pre hook stops the
+// command before it touches any repository.
+func runPreHooks(repos []string) error {
+	runHooks.Lock()
+	defer runHooks.Unlock()
+	if runHooks.preRan {
+		return nil
+	}
+	runHooks.preRan = true
+	for _, h := range runHooks.hooks {
+		if h.Pre == "" {
+			continue
+		}
+		logf(levelVerbose, "pre hook: %s", h.Pre)
+		if err := runShell(h.Pre, hookEnv(runHooks.command, repos, nil, nil), os.Stdin); err != nil {
+			return fmt.Errorf("pre hook %q: %v", h.Pre, err)
+		}
+	}
+	return nil
+}
+
+// runPostHooks runs the post hooks after a command that worked on
+// repositories, whether it succeeded or not. The run summary is passed as
+// JSON on stdin, in the same shape as --notify posts it. A failing post
+// hook is reported but does not change the exit status.
+func runPostHooks(runErr error) {
+	runHooks.Lock()
+	command, hooks := runHooks.command, runHooks.hooks
+	runHooks.Unlock()
+	runSummary.Lock()
+	repos, failed, durations := runSummary.repos, runSummary.failed, repoDurations()
+	runSummary.Unlock()
+	if len(repos) == 0 {
+		return
+	}
+	var summary []byte
+	for _, h := range hooks {
+		if h.Post == "" {
+			continue
+		}
+		if summary == nil {
+			n := buildNotification(command, repos, failed, durations, time.Since(runSummary.start), runErr)
+			summary, _ = json.Marshal(n)
+		}
+		logf(levelVerbose, "post hook: %s", h.Post)
+		if err := runShell(h.Post, hookEnv(command, repos, failed, runErr), bytes.NewReader(summary)); err != nil {
+			fmt.Fprintf(os.Stderr, "post hook %q: %v\n", h.Post, err)
+		}
+	}
+}
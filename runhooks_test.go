@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRunHookMatches(t *testing.T) {
+	h := RunHook{Commands: []string{"push", "remote"}}
+	for name, want := range map[string]bool{
+		"push":        true,
+		"remote add":  true,
+		"remote":      true,
+		"pull":        false,
+		"push-mirror": false,
+	} {
+		if got := h.matches(name); got != want {
+			t.Errorf("matches(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if !(RunHook{}).matches("anything") {
+		t.Error("a hook without commands should apply to every command")
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	q := shellQuote(out)
+	useTestConfig(t, `
+[[hook]]
+commands = ["push"]
+pre = 'echo "pre $GITBATCH_COMMAND $GITBATCH_REPOS" >> `+q+`'
+post = 'echo "post [$GITBATCH_FAILED] $GITBATCH_ERROR" >> `+q+`; cat >> `+q+`'
+
+[[hook]]
+commands = ["pull"]
+pre = "exit 1"
+`)
+	oldSummary := runSummary.repos
+	t.Cleanup(func() {
+		runSummary.Lock()
+		runSummary.repos, runSummary.failed = oldSummary, map[string]bool{}
+		runSummary.Unlock()
+		runHooks.command, runHooks.hooks, runHooks.preRan = "", nil, false
+	})
+
+	run := func(*cobra.Command, []string) {}
+	root := &cobra.Command{Use: "gitbatch"}
+	push := &cobra.Command{Use: "push", Run: run}
+	pull := &cobra.Command{Use: "pull", Run: run}
+	root.AddCommand(push, pull)
+
+	if err := setupRunHooks(push); err != nil {
+		t.Fatal(err)
+	}
+	if err := runPreHooks([]string{"/src/a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runPreHooks([]string{"/src/a"}); err != nil {
+		t.Fatal(err)
+	}
+	runSummary.Lock()
+	runSummary.repos, runSummary.failed = []string{"/src/a", "/src/b"}, map[string]bool{"/src/b": true}
+	runSummary.Unlock()
+	runPostHooks(errors.New("boom"))
+
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitN(string(b), "\n", 3)
+	if len(lines) < 3 || lines[0] != "pre push /src/a" || lines[1] != "post [/src/b] boom" {
+		t.Fatalf("hooks wrote:\n%s", b)
+	}
+	var n notification
+	if err := json.Unmarshal([]byte(lines[2]), &n); err != nil || n.Command != "push" || n.Succeeded != 1 || n.Error != "boom" {
+		t.Errorf("post hook stdin = %s (%v)", lines[2], err)
+	}
+
+	if err := setupRunHooks(pull); err != nil {
+		t.Fatal(err)
+	}
+	if err := runPreHooks([]string{"/src/a"}); err == nil {
+		t.Error("a failing pre hook did not stop the command")
+	}
+
+	t.Setenv(hookEnvVar, "1")
+	if err := setupRunHooks(pull); err != nil {
+		t.Fatal(err)
+	}
+	if err := runPreHooks([]string{"/src/a"}); err != nil {
+		t.Errorf("hooks ran inside a hook: %v", err)
+	}
+}
+
+func TestHookEnv(t *testing.T) {
+	env := hookEnv("pull", []string{"/a", "/b"}, nil, nil)
+	if !slices.Contains(env, "GITBATCH_REPOS=/a\n/b") || !slices.Contains(env, hookEnvVar+"=1") {
+		t.Errorf("pre hook env lacks the repositories: %v", env[len(env)-3:])
+	}
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "GITBATCH_FAILED=") {
+			t.Error("pre hook env has GITBATCH_FAILED")
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// SecretsConfig configures the scan run before push, declared as
+//
+//	[secrets]
+//	disable_defaults = false
+//	[[secrets.rules]]
+//	name = "internal token"
+//	pattern = "corp_[a-z0-9]{32}"
+type SecretsConfig struct {
+	DisableDefaults bool         `toml:"disable_defaults"`
+	Rules           []SecretRule `toml:"rules"`
+}
+
+// SecretRule is a named regular expression matched against added lines.
+type SecretRule struct {
+	Name    string `toml:"name"`
+	Pattern string `toml:"pattern"`
+}
+
+var defaultSecretRules = []SecretRule{
+	{"AWS access key", `\b(AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{"GitHub token", `\bgh[pousr]_[A-Za-z0-9]{36,}\b`},
+	{"GitLab token", `\bglpat-[A-Za-z0-9_\-]{20,}\b`},
+	{"Slack token", `\bxox[abprs]-[A-Za-z0-9-]{10,}\b`},
+	{"Google API key", `\bAIza[0-9A-Za-z_\-]{35}\b`},
+	{"Stripe live key", `\b[sr]k_live_[0-9A-Za-z]{24,}\b`},
+	{"private key", `-----BEGIN ([A-Z]+ )?PRIVATE KEY-----`},
+	{"hardcoded credential", `(?i)\b(api[_-]?key|secret|passw(or)?d|token)\b\s*[:=]\s*["'][^"'\s]{12,}["']`},
+}
+
+type secretRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// secretRules compiles the default rules (unless disabled) plus the rules
+// from the config file.
+func secretRules(cfg SecretsConfig) ([]secretRule, error) {
+	var specs []SecretRule
+	if !cfg.DisableDefaults {
+		specs = append(specs, defaultSecretRules...)
+	}
+	specs = append(specs, cfg.Rules...)
+	rules := make([]secretRule, 0, len(specs))
+	for _, s := range specs {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("secret rule %q: %v", s.Name, err)
+		}
+		rules = append(rules, secretRule{name: s.Name, re: re})
+	}
+	return rules, nil
+}
+
+// filterSecretFindings scans the outgoing commits of every repository and
+// returns the repositories that are safe to push, reporting the others.
+func filterSecretFindings(repos []string) ([]string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	rules, err := secretRules(cfg.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := runContext()
+	defer cancel()
+	var clean []string
+	for _, r := range repos {
+		findings, err := scanOutgoing(ctx, r, rules)
+		if err != nil {
+			repoFailed(r, fmt.Errorf("secret scan: %v (not pushing)", err))
+			continue
+		}
+		if len(findings) == 0 {
+			clean = append(clean, r)
+			continue
+		}
+		markRepoFailed(r)
+		fmt.Fprintf(os.Stderr, "\n%s %s: possible secrets in outgoing commits\n", paint(os.Stderr, ansiRed, "push blocked in"), r)
+		for _, f := range findings {
+			fmt.Fprintf(os.Stderr, "  %s %s: %s (%s)\n", f.commit, f.path, f.rule, f.match)
+		}
+	}
+	return clean, nil
+}
+
+// secretFinding is a rule match in a line added by an outgoing commit.
+type secretFinding struct {
+	commit string
+	path   string
+	rule   string
+	match  string // redacted
+}
+
+// outgoingRange returns the rev-list arguments selecting the commits a push
+// of the current branch would send: those not on the upstream, or not on any
+// remote when the branch has no upstream.
+func outgoingRange(ctx context.Context, repo string) []string {
+	if _, err := gitOutput(ctx, repo, "rev-parse", "--abbrev-ref", "@{upstream}"); err == nil {
+		return []string{"@{upstream}..HEAD"}
+	}
+	return []string{"HEAD", "--not", "--remotes"}
+}
+
+// scanOutgoing matches rules against every line added by the commits that
+// would be pushed from repo.
+func scanOutgoing(ctx context.Context, repo string, rules []secretRule) ([]secretFinding, error) {
+	if _, err := gitOutput(ctx, repo, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return nil, nil // no commits yet
+	}
+	args := append([]string{"log", "-p", "--no-color", "--no-ext-diff", "--format=%x00%h"}, outgoingRange(ctx, repo)...)
+	var findings []secretFinding
+	var commit, path string
+	err := gitExec.Lines(ctx, runner.Git(repo, args...), func(line string) {
+		switch {
+		case strings.HasPrefix(line, "\x00"):
+			commit = line[1:]
+		case strings.HasPrefix(line, "+++ "):
+			path = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+		case strings.HasPrefix(line, "+"):
+			for _, r := range rules {
+				if m := r.re.FindString(line[1:]); m != "" {
+					findings = append(findings, secretFinding{commit: commit, path: path, rule: r.name, match: redact(m)})
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
+
+// redact keeps only the first few characters of a secret.
+func redact(s string) string {
+	const keep = 6
+	if len(s) <= keep {
+		return strings.Repeat("*", len(s))
+	}
+	return s[:keep] + strings.Repeat("*", min(len(s)-keep, 12))
+}
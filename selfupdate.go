@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Release files. Every release carries one binary per platform and a
+// checksums.txt in sha256sum format covering them. When release builds are
+// stamped with a public key, checksums.txt must also come with an ed25519
+// signature of the release tag, a newline and checksums.txt, so the files
+// of one release cannot be passed off as another:
+//
+//	go build -ldflags "-X main.releaseKey=<base64 ed25519 public key>"
+const (
+	checksumsAsset = "checksums.txt"
+	signatureAsset = "checksums.txt.sig"
+)
+
+// releaseKey is the base64 ed25519 public key release checksums are signed
+// with. Without one, self-update refuses to run unless --allow-unsigned is
+// given, since checksums.txt alone only guards against corrupt downloads.
+var releaseKey = ""
+
+// selfExecutable is the binary self-update replaces.
+var selfExecutable = os.Executable
+
+// maxChecksumsSize caps checksums.txt and its signature.
+const maxChecksumsSize = 1 << 20
+
+// binaryAsset is the name of the release binary for this platform.
+func binaryAsset() string {
+	name := "gitbatch_" + runtime.GOOS + "_" + runtime.GOARCH
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// download fetches url. The caller closes the body.
+func download(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// downloadSmall fetches a release file that fits in memory.
+func downloadSmall(ctx context.Context, url string) ([]byte, error) {
+	body, err := download(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, maxChecksumsSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxChecksumsSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", url, maxChecksumsSize)
+	}
+	return data, nil
+}
+
+// signedPayload is what the signature of a release covers: its tag and
+// checksums.txt.
+func signedPayload(tag string, checksums []byte) []byte {
+	return append([]byte(tag+"\n"), checksums...)
+}
+
+// verifySignature checks sig, a base64 or raw ed25519 signature, of the
+// checksums of release tag against releaseKey.
+func verifySignature(tag string, checksums, sig []byte) error {
+	key, err := base64.StdEncoding.DecodeString(releaseKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.New("this binary has an invalid release key")
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err == nil {
+		sig = decoded
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), signedPayload(tag, checksums), sig) {
+		return fmt.Errorf("%s of %s does not match its signature", checksumsAsset, tag)
+	}
+	return nil
+}
+
+// findChecksum returns the SHA-256 of name in a sha256sum listing.
+func findChecksum(checksums []byte, name string) ([]byte, error) {
+	sc := bufio.NewScanner(bytes.NewReader(checksums))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		sum, err := hex.DecodeString(fields[0])
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("%s: invalid checksum for %s", checksumsAsset, name)
+		}
+		return sum, nil
+	}
+	return nil, fmt.Errorf("%s lists no checksum for %s", checksumsAsset, name)
+}
+
+// releaseChecksum downloads the checksums of release, checks their
+// signature when the binary has a release key, and returns the checksum of
+// asset. Without a key, the caller has already required --allow-unsigned.
+func releaseChecksum(ctx context.Context, release githubRelease, asset string) ([]byte, error) {
+	url, ok := release.assetURL(checksumsAsset)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s", release.TagName, checksumsAsset)
+	}
+	checksums, err := downloadSmall(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if releaseKey != "" {
+		url, ok := release.assetURL(signatureAsset)
+		if !ok {
+			return nil, fmt.Errorf("release %s has no %s", release.TagName, signatureAsset)
+		}
+		sig, err := downloadSmall(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifySignature(release.TagName, checksums, sig); err != nil {
+			return nil, err
+		}
+	}
+	return findChecksum(checksums, asset)
+}
+
+// replaceExecutable downloads url next to exe, checks it against sum and
+// renames it over exe, so the binary is never left half written. Windows
+// cannot overwrite a running binary, so there the old one is moved aside to
+// exe.old first.
+func replaceExecutable(ctx context.Context, exe, url string, sum []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+	body, err := download(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".gitbatch-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, sum) {
+		return fmt.Errorf("checksum mismatch: downloaded %x, expected %x", got, sum)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0o111); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), exe)
+}
+
+// self-update command
+var selfUpdateChannel string
+var selfUpdateForce bool
+var selfUpdateUnsigned bool
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update [--channel stable|prerelease] [--force] [--allow-unsigned]",
+	Short: "Replace the gitbatch binary with the latest release",
+	Long: `self-update downloads the latest gitbatch release for this platform from
+GitHub and replaces the running binary with it. The download is checked
+against the release's checksums.txt, and checksums.txt against its
+signature with the release key built into the binary. The new binary is
+written next to the old one and renamed over it, so an interrupted update
+leaves the old binary in place.
+
+Binaries built without a release key cannot check signatures and refuse to
+update. --allow-unsigned updates them anyway, checking only checksums.txt,
+which was downloaded from the same place as the binary.
+
+--channel prerelease also considers pre-releases. Nothing is downloaded when
+the running version is already the latest; --force installs the latest
+release anyway, also over development builds without a version. A release
+older than the running version is never installed, not even with --force.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if selfUpdateChannel != "stable" && selfUpdateChannel != "prerelease" {
+			return usageErrorf("invalid --channel %q (expected stable or prerelease)", selfUpdateChannel)
+		}
+		if releaseKey == "" {
+			if !selfUpdateUnsigned {
+				return errors.New("this binary has no release key to check release signatures with; use --allow-unsigned to update without them")
+			}
+			fmt.Fprintln(os.Stderr, "warning: this binary has no release key; the update is checked against checksums.txt only")
+		}
+		exe, err := selfExecutable()
+		if err != nil {
+			return err
+		}
+		if exe, err = filepath.EvalSymlinks(exe); err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		release, err := latestRelease(ctx, selfUpdateChannel == "prerelease")
+		if err != nil {
+			return err
+		}
+		current := currentBuild().Version
+		// a signed but older release could bring back fixed bugs
+		if older, err := newerRelease(current, release.TagName); err == nil && older {
+			return fmt.Errorf("release %s is older than gitbatch %s, not downgrading", release.TagName, current)
+		}
+		if !selfUpdateForce {
+			newer, err := newerRelease(release.TagName, current)
+			if err != nil {
+				return fmt.Errorf("%v; use --force to install %s", err, release.TagName)
+			}
+			if !newer {
+				fmt.Printf("gitbatch %s is up to date\n", current)
+				return nil
+			}
+		}
+		asset := binaryAsset()
+		url, ok := release.assetURL(asset)
+		if !ok {
+			return fmt.Errorf("release %s has no binary for %s/%s (%s)", release.TagName, runtime.GOOS, runtime.GOARCH, asset)
+		}
+		sum, err := releaseChecksum(ctx, release, asset)
+		if err != nil {
+			return err
+		}
+		if err := replaceExecutable(ctx, exe, url, sum); err != nil {
+			return fmt.Errorf("updating %s: %v", exe, err)
+		}
+		fmt.Printf("updated %s from %s to %s\n", exe, current, release.TagName)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(selfUpdateCmd)
+
+	selfUpdateCmd.Flags().StringVar(&selfUpdateChannel, "channel", "stable", "release channel: stable or prerelease")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateForce, "force", false, "install the latest release even if it is not newer")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateUnsigned, "allow-unsigned", false, "update even though this binary has no release key to check signatures with")
+}
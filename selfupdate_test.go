@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// releaseServer serves a releases listing with one release, tag, whose
+// files are in files.
+func releaseServer(t *testing.T, tag string, files map[string][]byte) {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/releases" {
+			var assets []string
+			for name := range files {
+				assets = append(assets, fmt.Sprintf(`{"name": %q, "browser_download_url": %q}`, name, srv.URL+"/download/"+name))
+			}
+			fmt.Fprintf(w, `[{"tag_name": %q, "assets": [%s]}]`, tag, strings.Join(assets, ","))
+			return
+		}
+		data, ok := files[strings.TrimPrefix(r.URL.Path, "/download/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	oldURL := releasesURL
+	releasesURL = srv.URL + "/releases"
+	t.Cleanup(func() { releasesURL = oldURL })
+}
+
+func TestSelfUpdate(t *testing.T) {
+	exe := filepath.Join(t.TempDir(), "gitbatch")
+	if err := os.WriteFile(exe, []byte("old binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	oldExe, oldVersion, oldKey, oldForce, oldChannel, oldUnsigned := selfExecutable, version, releaseKey, selfUpdateForce, selfUpdateChannel, selfUpdateUnsigned
+	t.Cleanup(func() {
+		selfExecutable, version, releaseKey, selfUpdateForce, selfUpdateChannel, selfUpdateUnsigned = oldExe, oldVersion, oldKey, oldForce, oldChannel, oldUnsigned
+	})
+	selfExecutable = func() (string, error) { return exe, nil }
+	selfUpdateChannel, selfUpdateForce = "stable", false
+
+	binary := []byte("new binary")
+	sum := sha256.Sum256(binary)
+	checksums := []byte(fmt.Sprintf("%x  %s\n%x  gitbatch_other_os\n", sum, binaryAsset(), sha256.Sum256(nil)))
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		binaryAsset():  binary,
+		checksumsAsset: checksums,
+		signatureAsset: []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, signedPayload("v1.4.0", checksums)))),
+	}
+	releaseServer(t, "v1.4.0", files)
+	releaseKey = base64.StdEncoding.EncodeToString(pub)
+
+	version = "v1.4.0"
+	if err := selfUpdateCmd.RunE(selfUpdateCmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old binary" {
+		t.Fatal("binary replaced although up to date")
+	}
+
+	// a tampered checksum list fails the signature check
+	files[checksumsAsset] = []byte(fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte("evil")), binaryAsset()))
+	version = "v1.3.0"
+	if err := selfUpdateCmd.RunE(selfUpdateCmd, nil); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("tampered checksums: err = %v", err)
+	}
+	// without a release key, updating needs --allow-unsigned
+	releaseKey = ""
+	if err := selfUpdateCmd.RunE(selfUpdateCmd, nil); err == nil || !strings.Contains(err.Error(), "--allow-unsigned") {
+		t.Errorf("no release key: err = %v", err)
+	}
+	// and then only the checksum is checked, and it fails
+	selfUpdateUnsigned = true
+	if err := selfUpdateCmd.RunE(selfUpdateCmd, nil); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("wrong checksum: err = %v", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old binary" {
+		t.Fatal("binary replaced despite failed verification")
+	}
+
+	releaseKey, selfUpdateUnsigned = base64.StdEncoding.EncodeToString(pub), false
+	files[checksumsAsset] = checksums
+	if err := selfUpdateCmd.RunE(selfUpdateCmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(exe)
+	if string(data) != "new binary" {
+		t.Errorf("binary = %q after update", data)
+	}
+	if info, _ := os.Stat(exe); info.Mode().Perm()&0o111 == 0 {
+		t.Errorf("updated binary is not executable: %v", info.Mode())
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(exe), ".gitbatch-update-*")); len(leftovers) > 0 {
+		t.Errorf("temporary files left: %v", leftovers)
+	}
+
+	version = ""
+	if err := selfUpdateCmd.RunE(selfUpdateCmd, nil); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("dev build: err = %v", err)
+	}
+
+	// an older release is refused, even with --force
+	if err := os.WriteFile(exe, []byte("old binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	version, selfUpdateForce = "v1.5.0", true
+	if err := selfUpdateCmd.RunE(selfUpdateCmd, nil); err == nil || !strings.Contains(err.Error(), "not downgrading") {
+		t.Errorf("downgrade: err = %v", err)
+	}
+	// and so are its files served as a newer release
+	releaseServer(t, "v1.6.0", files)
+	if err := selfUpdateCmd.RunE(selfUpdateCmd, nil); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("replayed release: err = %v", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old binary" {
+		t.Fatal("binary replaced by an older release")
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/report"
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+// serveRuns are the git commands POST /run/{command} may start, with their
+// arguments.
+var serveRuns = map[string][]string{
+	"fetch":  {"fetch", "--all", "--prune"},
+	"pull":   {"pull", "--ff-only"},
+	"status": {"status", "--short", "--branch"},
+}
+
+// apiServer serves the JSON API of gitbatch serve. Runs are serialized so
+// two clients cannot pull the same repository at once.
+type apiServer struct {
+	defaultPatterns []string
+	token           string // the bearer token every request must carry
+	listenHost      string // the host of --listen, accepted in Host headers
+	runMu           sync.Mutex
+	results         *opResults
+}
+
+func newAPIServer(patterns []string, token, listenHost string) *apiServer {
+	return &apiServer{defaultPatterns: patterns, token: token, listenHost: listenHost, results: newOpResults()}
+}
+
+// newServeToken returns a random bearer token for one run of serve.
+func newServeToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// patterns returns the repeated ?pattern= query values, or the patterns
+// serve was started with. A query may only narrow the patterns down, so a
+// client cannot reach repositories elsewhere on disk.
+func (s *apiServer) patterns(r *http.Request) ([]string, error) {
+	p := r.URL.Query()["pattern"]
+	if len(p) == 0 {
+		return s.defaultPatterns, nil
+	}
+	for _, pattern := range p {
+		if !slices.Contains(s.defaultPatterns, pattern) {
+			return nil, fmt.Errorf("pattern %q was not given to serve", pattern)
+		}
+	}
+	return p, nil
+}
+
+// allowedHost reports whether the Host header names the server: a loopback
+// address, localhost or the host of --listen. Anything else is a DNS
+// rebinding attempt, a page whose own name resolves to this server.
+func (s *apiServer) allowedHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if host == "localhost" || host == s.listenHost {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// guard rejects requests from browsers, which send an Origin header on
+// cross-site POSTs, requests for another Host and requests without the
+// bearer token, before they reach next.
+func (s *apiServer) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Origin") != "":
+			writeError(w, http.StatusForbidden, errors.New("requests from web pages are not allowed"))
+		case !s.allowedHost(r.Host):
+			writeError(w, http.StatusForbidden, fmt.Errorf("unexpected host %q", r.Host))
+		case subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.token)) != 1:
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("missing or wrong bearer token"))
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos", s.handleRepos)
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("POST /run/{command}", s.handleRun)
+	mux.Handle("GET /metrics", metricsHandler(func(r *http.Request) ([]string, error) {
+		patterns, err := s.patterns(r)
+		if err != nil {
+			return nil, err
+		}
+		return discoverRepos(patterns)
+	}, s.results))
+	return s.guard(mux)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func (s *apiServer) repos(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	patterns, err := s.patterns(r)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return nil, false
+	}
+	repos, err := resolveRepos(patterns)
+	if err != nil {
+		code := http.StatusNotFound
+		if exitCode(err) == exitPolicy {
+			code = http.StatusForbidden
+		}
+		writeError(w, code, err)
+		return nil, false
+	}
+	return repos, true
+}
+
+func (s *apiServer) handleRepos(w http.ResponseWriter, r *http.Request) {
+	if repos, ok := s.repos(w, r); ok {
+		writeJSON(w, http.StatusOK, repos)
+	}
+}
+
+func (s *apiServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	repos, ok := s.repos(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	type status struct {
+		repoState
+		Error string `json:"error,omitempty"`
+	}
+	states := make([]status, 0, len(repos))
+	for _, repo := range repos {
+		st, err := readRepoState(ctx, repo)
+		st.Repo = repo
+		if err != nil {
+			states = append(states, status{repoState: st, Error: err.Error()})
+			continue
+		}
+		states = append(states, status{repoState: st})
+	}
+	writeJSON(w, http.StatusOK, states)
+}
+
+func (s *apiServer) handleRun(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("command")
+	gitArgs, ok := serveRuns[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown command %q", name))
+		return
+	}
+	cmd, _, err := rootCmd.Find([]string{name})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	changes := !allowedReadOnly(cmd)
+	if readOnly && changes {
+		writeError(w, http.StatusForbidden, fmt.Errorf("read-only mode: %q is not allowed", name))
+		return
+	}
+	rules, err := commandPolicies(cmd)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	repos, ok := s.repos(w, r)
+	if !ok {
+		return
+	}
+	if err := checkPathRules(rules, repos); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	if changes && !includeDetached {
+		if repos, err = dropBusyRepos(repos); err != nil {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+	}
+	results, err := s.run(r, name, gitArgs, repos, changes)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	for _, res := range results {
+		s.results.record(res.Repo, res.OK())
+	}
+	writeJSON(w, http.StatusOK, report.ToJSON(results))
+}
+
+// run runs gitArgs in repos the way the command line would: through the
+// git executor, and for commands that change repositories, with their
+// workspace locked and the run appended to the audit log.
+func (s *apiServer) run(r *http.Request, name string, gitArgs, repos []string, changes bool) ([]runner.Result, error) {
+	if !changes {
+		return gitRunner().Run(r.Context(), repos, gitArgs...), nil
+	}
+	if !noLock {
+		dir, err := lockDir()
+		if err != nil {
+			return nil, fmt.Errorf("lock: %v", err)
+		}
+		lock, err := acquireLock(dir, workspaceRoot(repos), name, lockWait)
+		if err != nil {
+			return nil, err
+		}
+		defer lock.release()
+	}
+	before, start := headSHAs(repos), time.Now()
+	results := gitRunner().Run(r.Context(), repos, gitArgs...)
+	failed := map[string]bool{}
+	for _, res := range results {
+		if !res.OK() {
+			failed[res.Repo] = true
+		}
+	}
+	patterns, _ := s.patterns(r)
+	auditServeRun(name, append([]string{"serve", name}, patterns...), repos, failed, before, time.Since(start))
+	return results, nil
+}
+
+// serve command
+var serveListen string
+var serveCmd = &cobra.Command{
+	Use:   "serve [--listen 127.0.0.1:7070] <pattern>...",
+	Short: "Serve a local JSON API to list repositories, query status and run commands",
+	Long: `serve exposes gitbatch over HTTP for editors and dashboards:
+
+  GET  /repos                  matching repositories
+  GET  /status                 branch, ahead/behind and change counts per repository
+  POST /run/{fetch|pull|status} run the command and return each repository's output
+  GET  /metrics                Prometheus gauges: dirty files, ahead/behind, age of
+                               unpushed commits and of the last fetch, last result
+
+Repositories are those matching the patterns given on the command line,
+resolved against the directory serve was started in. Endpoints take one or
+more ?pattern= query parameters to narrow them down to some of those
+patterns; others are refused. Runs get the filters, policies, workspace
+lock and audit log of the same command on the command line, but not its
+hooks.
+
+Every request must carry the token printed at startup, as
+"Authorization: Bearer <token>". Requests with an Origin header, as web
+pages send, and with a Host other than loopback or the --listen address are
+refused, so a web page open in a browser cannot reach the API.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host, _, err := net.SplitHostPort(serveListen)
+		if err != nil {
+			return usageErrorf("invalid --listen address: %v", err)
+		} else if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			fmt.Fprintf(os.Stderr, "warning: %s is reachable from other machines, and the token travels over plain HTTP\n", serveListen)
+		}
+		token, err := newServeToken()
+		if err != nil {
+			return err
+		}
+		srv := &http.Server{Addr: serveListen, Handler: newAPIServer(args, token, host).handler()}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			srv.Shutdown(shutdownCtx)
+		}()
+
+		fmt.Printf("listening on http://%s\ntoken: %s\n", serveListen, token)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:7070", "address to listen on")
+}
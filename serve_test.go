@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/patrickkdev/gitbatch/pkg/report"
+)
+
+func TestServeAPI(t *testing.T) {
+	root := t.TempDir()
+	initTestRepoAt(t, filepath.Join(root, "one"))
+	initTestRepoAt(t, filepath.Join(root, "two"))
+	commitTestFile(t, filepath.Join(root, "one"), "a.txt", "a", "first")
+	t.Chdir(root)
+
+	srv := httptest.NewServer(newAPIServer([]string{"one", "two"}, "tok", "127.0.0.1").handler())
+	defer srv.Close()
+
+	send := func(method, path string, header map[string]string, v any) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, nil)
+		for k, v := range header {
+			if k == "Host" {
+				req.Host = v
+				continue
+			}
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if v != nil {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode
+	}
+	get := func(method, path string, v any) int {
+		t.Helper()
+		return send(method, path, map[string]string{"Authorization": "Bearer tok"}, v)
+	}
+
+	var repos []string
+	if code := get("GET", "/repos", &repos); code != http.StatusOK || len(repos) != 2 {
+		t.Errorf("GET /repos = %d, %v", code, repos)
+	}
+	if code := get("GET", "/repos?pattern=one", &repos); code != http.StatusOK || len(repos) != 1 {
+		t.Errorf("GET /repos?pattern=one = %d, %v", code, repos)
+	}
+
+	var states []repoState
+	if code := get("GET", "/status?pattern=one", &states); code != http.StatusOK || len(states) != 1 || states[0].Branch == "" {
+		t.Errorf("GET /status = %d, %+v", code, states)
+	}
+
+	var results []report.JSONResult
+	if code := get("POST", "/run/status", &results); code != http.StatusOK || len(results) != 2 || !results[0].OK {
+		t.Errorf("POST /run/status = %d, %+v", code, results)
+	}
+	if code := get("POST", "/run/push", nil); code != http.StatusNotFound {
+		t.Errorf("POST /run/push = %d, want 404", code)
+	}
+	if code := get("GET", "/run/status", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /run/status = %d, want 405", code)
+	}
+
+	if code := get("GET", "/repos?pattern=/", nil); code != http.StatusForbidden {
+		t.Errorf("GET /repos?pattern=/ = %d, want 403", code)
+	}
+	for name, header := range map[string]map[string]string{
+		"no token":    nil,
+		"wrong token": {"Authorization": "Bearer nope"},
+		"web page":    {"Authorization": "Bearer tok", "Origin": "https://evil.example"},
+		"rebinding":   {"Authorization": "Bearer tok", "Host": "evil.example:7070"},
+	} {
+		if code := send("POST", "/run/status", header, nil); code != http.StatusUnauthorized && code != http.StatusForbidden {
+			t.Errorf("%s: POST /run/status = %d", name, code)
+		}
+	}
+
+	readOnly = true
+	defer func() { readOnly = false }()
+	if code := get("POST", "/run/fetch", nil); code != http.StatusForbidden {
+		t.Errorf("read-only POST /run/fetch = %d, want 403", code)
+	}
+}
+
+func TestServeRunLikeCLI(t *testing.T) {
+	root := t.TempDir()
+	initTestRepoAt(t, filepath.Join(root, "one"))
+	initTestRepoAt(t, filepath.Join(root, "two"))
+	t.Chdir(root)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	useTestConfig(t, "[audit]\npath = \""+auditPath+"\"\n\n[[policy]]\ncommand = \"pull\"\ndeny = true\n")
+
+	srv := httptest.NewServer(newAPIServer([]string{"one", "two"}, "tok", "127.0.0.1").handler())
+	defer srv.Close()
+	post := func(path string) int {
+		t.Helper()
+		req, _ := http.NewRequest("POST", srv.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer tok")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("/run/pull"); code != http.StatusForbidden {
+		t.Errorf("POST /run/pull denied by policy = %d, want 403", code)
+	}
+	if code := post("/run/fetch"); code != http.StatusOK {
+		t.Fatalf("POST /run/fetch = %d", code)
+	}
+	records, err := readAudit(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Command != "fetch" || len(records[0].Repos) != 2 {
+		t.Errorf("audit log = %+v, want one fetch of two repositories", records)
+	}
+}
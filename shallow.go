@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// cloneShape describes how much of its history a clone holds.
+type cloneShape struct {
+	shallow bool   // cloned or fetched with --depth
+	filter  string // partial clone filter, e.g. blob:none; "" for a full clone
+}
+
+func (s cloneShape) String() string {
+	var parts []string
+	if s.shallow {
+		parts = append(parts, "shallow")
+	}
+	if s.filter != "" {
+		parts = append(parts, "partial ("+s.filter+")")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// inspectClone reports whether dir is a shallow or partial clone. A partial
+// clone records its filter on the remote it was cloned from.
+func inspectClone(ctx context.Context, dir string) (cloneShape, error) {
+	var s cloneShape
+	out, err := gitOutput(ctx, dir, "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return s, err
+	}
+	s.shallow = out == "true"
+	// config --get-regexp exits 1 when nothing matches
+	filters, _ := gitOutput(ctx, dir, "config", "--get-regexp", `^remote\..*\.partialclonefilter$`)
+	if line, _, _ := strings.Cut(filters, "\n"); line != "" {
+		if _, f, ok := strings.Cut(line, " "); ok {
+			s.filter = f
+		}
+	}
+	return s, nil
+}
+
+// unshallow command
+var unshallowCmd = &cobra.Command{
+	Use:   "unshallow <pattern>...",
+	Short: "Fetch the full history of shallow clones",
+	Long: `unshallow runs git fetch --unshallow in every matching repository that
+is a shallow clone, and skips the others. Partial clones keep their filter:
+missing objects are still fetched on demand.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		var shallow []string
+		for _, r := range repos {
+			s, err := inspectClone(ctx, r)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			if s.shallow {
+				shallow = append(shallow, r)
+			}
+		}
+		if len(shallow) == 0 {
+			fmt.Printf("none of %d repositories is shallow\n", len(repos))
+			return nil
+		}
+		if jobs > 1 {
+			runBuffered(shallow, "fetch", "--unshallow")
+			return nil
+		}
+		for _, r := range shallow {
+			repoHeader(r)
+			if err := runGit(ctx, r, "fetch", "--unshallow"); err != nil {
+				repoFailed(r, err)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(unshallowCmd)
+	addRetryFlags(unshallowCmd)
+}
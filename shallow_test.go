@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestInspectCloneAndUnshallow(t *testing.T) {
+	origin := initTestRepo(t)
+	commitTestFile(t, origin, "a.txt", "one\n", "first")
+	commitTestFile(t, origin, "a.txt", "two\n", "second")
+	if out, err := exec.Command("git", "-C", origin, "config", "uploadpack.allowFilter", "true").CombinedOutput(); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	ws := t.TempDir()
+	for _, c := range [][]string{
+		{"clone", "-q", "--depth", "1", "file://" + origin, filepath.Join(ws, "shallow")},
+		{"clone", "-q", "--filter=blob:none", "file://" + origin, filepath.Join(ws, "partial")},
+	} {
+		if out, err := exec.Command("git", c...).CombinedOutput(); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+	}
+	ctx := context.Background()
+
+	s, err := inspectClone(ctx, filepath.Join(ws, "shallow"))
+	if err != nil || !s.shallow || s.filter != "" || s.String() != "shallow" {
+		t.Errorf("shallow clone = %+v, %v", s, err)
+	}
+	s, err = inspectClone(ctx, filepath.Join(ws, "partial"))
+	if err != nil || s.shallow || s.String() != "partial (blob:none)" {
+		t.Errorf("partial clone = %+v, %v", s, err)
+	}
+	if s, err := inspectClone(ctx, origin); err != nil || s.String() != "" {
+		t.Errorf("full clone = %+v, %v", s, err)
+	}
+
+	t.Chdir(ws)
+	if err := unshallowCmd.RunE(unshallowCmd, []string{"*"}); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := inspectClone(ctx, filepath.Join(ws, "shallow")); s.shallow {
+		t.Error("still shallow after unshallow")
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+// refCommit is the commit a ref points to in one repository. Fields are
+// exported for --format templates.
+type refCommit struct {
+	Repo    string    `json:"repo"`
+	SHA     string    `json:"sha"`
+	Short   string    `json:"short_sha"`
+	Subject string    `json:"subject"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+}
+
+// showCommit looks up the commit ref points to in dir.
+func showCommit(ctx context.Context, dir, ref string) (refCommit, error) {
+	out, err := gitOutput(ctx, dir, "log", "-1", "--format=%H%x00%h%x00%an%x00%aI%x00%s", ref, "--")
+	if err != nil {
+		return refCommit{}, err
+	}
+	f := strings.SplitN(out, "\x00", 5)
+	if len(f) != 5 {
+		return refCommit{}, fmt.Errorf("unexpected git log output %q", out)
+	}
+	date, err := time.Parse(time.RFC3339, f[3])
+	if err != nil {
+		return refCommit{}, fmt.Errorf("unexpected commit date %q", f[3])
+	}
+	return refCommit{Repo: dir, SHA: f[0], Short: f[1], Author: f[2], Date: date, Subject: f[4]}, nil
+}
+
+// showTable lays out commits for csv and markdown output.
+func showTable(commits []refCommit) report.Table {
+	t := report.Table{Header: []string{"repo", "sha", "date", "author", "subject"}}
+	for _, c := range commits {
+		t.Rows = append(t.Rows, []string{c.Repo, c.SHA, c.Date.Format(time.RFC3339), c.Author, c.Subject})
+	}
+	return t
+}
+
+// show command
+var showRef string
+var showFormat string
+var showOutput string
+var showCmd = &cobra.Command{
+	Use:   "show [--ref <ref>] <pattern>...",
+	Short: "Show the commit HEAD or a ref points to in each repository",
+	Long: `show prints one line per repository with the commit HEAD, or the ref
+given with --ref, points to: abbreviated SHA, author date, author and
+subject. Use it to check that a batch checkout or tag landed everywhere.
+Repositories where the ref does not exist are reported as errors. --format
+takes a Go template with the fields .Repo, .SHA, .Short, .Subject, .Author
+and .Date (a time.Time). --output prints the list as json, csv or markdown.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkFormatOutput(showFormat, showOutput); err != nil {
+			return err
+		}
+		var tmpl *template.Template
+		if showFormat != "" {
+			var err error
+			if tmpl, err = parseFormat(showFormat); err != nil {
+				return err
+			}
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		var commits []refCommit
+		for _, r := range repos {
+			c, err := showCommit(ctx, r, showRef)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			commits = append(commits, c)
+		}
+		if tmpl != nil {
+			return writeFormatted(os.Stdout, tmpl, commits)
+		}
+		if showOutput != "" {
+			return writeOutput(os.Stdout, showOutput, commits, showTable(commits))
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SHA\tDATE\tAUTHOR\tREPO\tSUBJECT")
+		for _, c := range commits {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Short, c.Date.Format("2006-01-02 15:04"), c.Author, c.Repo, c.Subject)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+
+	showCmd.Flags().StringVar(&showRef, "ref", "HEAD", "branch, tag or commit to show instead of HEAD")
+	addFormatFlag(showCmd, &showFormat, `{{.Short}}\t{{.Repo}}`)
+	addOutputFlag(showCmd, &showOutput)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestShowCommit(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first", "GIT_AUTHOR_DATE=2024-03-01T10:00:00Z")
+	commitTestFile(t, repo, "a.txt", "b", "second: with a colon", "GIT_AUTHOR_DATE=2024-03-02T10:00:00Z")
+
+	c, err := showCommit(ctx, repo, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Subject != "second: with a colon" || c.Author != "tester" || len(c.SHA) != 40 || c.Short == "" {
+		t.Errorf("HEAD = %+v", c)
+	}
+	if !c.Date.Equal(time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("date = %v", c.Date)
+	}
+	if c, err := showCommit(ctx, repo, "HEAD~1"); err != nil || c.Subject != "first" {
+		t.Errorf("HEAD~1 = %+v, %v", c, err)
+	}
+	if _, err := showCommit(ctx, repo, "v9.9.9"); err == nil {
+		t.Error("missing ref accepted")
+	}
+}
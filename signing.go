@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// signWithDefaultKey is the value of a bare --gpg-sign: sign with the key
+// git would pick on its own (user.signingkey or the committer identity).
+const signWithDefaultKey = "default"
+
+var signKey string
+var signFormat string
+var signIdentity string
+
+// addSigningFlags registers the signing flags shared by commit and tag.
+func addSigningFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&signKey, "gpg-sign", "S", "", "sign, optionally with the given key id (or SSH key path with --signing-format ssh)")
+	cmd.Flags().Lookup("gpg-sign").NoOptDefVal = signWithDefaultKey
+	cmd.Flags().StringVar(&signFormat, "signing-format", "", "signature format: openpgp, x509 or ssh (sets gpg.format)")
+	cmd.Flags().StringVar(&signIdentity, "sign-identity", "", "sign with the signing key of this identity profile from the config file")
+}
+
+// signing is the resolved signing request of a commit or tag.
+type signing struct {
+	configArgs []string // "-c gpg.format=..." placed before the git subcommand
+	key        string   // empty to let git choose the key
+}
+
+// resolveSigning combines --gpg-sign, --signing-format and --sign-identity.
+// It returns nil when no signature was requested.
+func resolveSigning() (*signing, error) {
+	key, format := signKey, signFormat
+	if signIdentity != "" {
+		id, err := lookupIdentity(signIdentity)
+		if err != nil {
+			return nil, err
+		}
+		if id.SigningKey == "" {
+			return nil, fmt.Errorf("identity %q has no signing_key", signIdentity)
+		}
+		if key == "" || key == signWithDefaultKey {
+			key = id.SigningKey
+		}
+		if format == "" {
+			format = id.SigningFormat
+		}
+	}
+	if key == "" {
+		if format != "" {
+			return nil, usageErrorf("--signing-format requires --gpg-sign or --sign-identity")
+		}
+		return nil, nil
+	}
+
+	s := &signing{}
+	if key != signWithDefaultKey {
+		s.key = key
+	}
+	switch format {
+	case "":
+	case "ssh":
+		s.key = expandHome(s.key)
+		fallthrough
+	case "openpgp", "x509":
+		s.configArgs = []string{"-c", "gpg.format=" + format}
+	default:
+		return nil, usageErrorf("unknown signing format %q (expected openpgp, x509 or ssh)", format)
+	}
+	return s, nil
+}
+
+// commitArgs returns the git commit option requesting a signature.
+func (s *signing) commitArgs() []string {
+	if s.key == "" {
+		return []string{"--gpg-sign"}
+	}
+	return []string{"--gpg-sign=" + s.key}
+}
+
+// tagArgs returns the git tag options requesting a signature.
+func (s *signing) tagArgs() []string {
+	if s.key == "" {
+		return []string{"--sign"}
+	}
+	return []string{"--local-user=" + s.key}
+}
+
+// tag command
+var tagMsg string
+var tagCmd = &cobra.Command{
+	Use:   "tag <name> [-m <message>] [--gpg-sign[=<key>]] <pattern>...",
+	Short: "Create a tag at HEAD in matching repositories",
+	Long: `tag creates <name> at HEAD in every matching repository: an annotated tag
+when -m is given, a signed tag with --gpg-sign or --sign-identity, and a
+lightweight tag otherwise. Repositories that already have the tag are
+skipped.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		sign, err := resolveSigning()
+		if err != nil {
+			return err
+		}
+		if sign != nil && strings.TrimSpace(tagMsg) == "" {
+			return usageErrorf("signed tags need a message: use -m \"message\"")
+		}
+		repos, err := collectRepos(args[1:])
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		for _, r := range repos {
+			repoHeader(r)
+			if refExists(ctx, r, "refs/tags/"+name) {
+				fmt.Printf("tag %s already exists, skipped\n", name)
+				continue
+			}
+			var gitArgs []string
+			if sign != nil {
+				gitArgs = append(gitArgs, sign.configArgs...)
+			}
+			gitArgs = append(gitArgs, "tag")
+			if sign != nil {
+				gitArgs = append(gitArgs, sign.tagArgs()...)
+			}
+			if tagMsg != "" {
+				gitArgs = append(gitArgs, "-m", tagMsg)
+			}
+			gitArgs = append(gitArgs, name)
+			if err := runGit(ctx, r, gitArgs...); err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			fmt.Printf("tagged %s\n", name)
+		}
+		return nil
+	},
+}
+
+// signatureStatus describes git's %G? signature check codes.
+var signatureStatus = map[string]string{
+	"G": "good",
+	"U": "good, unknown validity",
+	"X": "good, expired signature",
+	"Y": "good, expired key",
+	"R": "revoked key",
+	"B": "BAD signature",
+	"E": "cannot be checked",
+	"N": "unsigned",
+}
+
+// signedCommit is one commit line of the verify output.
+type signedCommit struct {
+	hash, status, signer, subject string
+}
+
+func (c signedCommit) trusted() bool {
+	return c.status == "G" || c.status == "U"
+}
+
+// recentSignatures checks the signatures of the last n commits on HEAD.
+func recentSignatures(ctx context.Context, repo string, n int) ([]signedCommit, error) {
+	out, err := gitOutput(ctx, repo, "log", fmt.Sprintf("-n%d", n), "--format=%h%x00%G?%x00%GS%x00%s")
+	if err != nil {
+		return nil, err
+	}
+	var commits []signedCommit
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.SplitN(line, "\x00", 4)
+		if len(f) != 4 {
+			continue
+		}
+		commits = append(commits, signedCommit{hash: f[0], status: f[1], signer: f[2], subject: f[3]})
+	}
+	return commits, nil
+}
+
+// verify command
+var verifyCount int
+var verifyCmd = &cobra.Command{
+	Use:   "verify [-n 10] <pattern>...",
+	Short: "Check signatures on recent commits in matching repositories",
+	Long: `verify checks the signatures of the last -n commits on HEAD of every
+repository and lists the commits that are unsigned or whose signature is
+not good. SSH signatures are only checked when gpg.ssh.allowedSignersFile
+is configured.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		failing := 0
+		for _, r := range repos {
+			commits, err := recentSignatures(ctx, r, verifyCount)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			var bad []signedCommit
+			for _, c := range commits {
+				if !c.trusted() {
+					bad = append(bad, c)
+				}
+			}
+			if len(bad) == 0 {
+				fmt.Printf("%s: %d commits, all signed\n", r, len(commits))
+				continue
+			}
+			failing++
+			fmt.Printf("\n---- %s: %d of %d commits not verified ----\n", r, len(bad), len(commits))
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, c := range bad {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.hash, signatureStatus[c.status], c.signer, c.subject)
+			}
+			w.Flush()
+		}
+		if failing > 0 {
+			fmt.Printf("\n%d of %d repositories have unverified commits\n", failing, len(repos))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	rootCmd.AddCommand(verifyCmd)
+
+	tagCmd.Flags().StringVarP(&tagMsg, "message", "m", "", "tag message (creates an annotated tag)")
+	addSigningFlags(tagCmd)
+
+	verifyCmd.Flags().IntVarP(&verifyCount, "count", "n", 10, "number of recent commits to check per repository")
+}
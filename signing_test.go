@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestResolveSigning(t *testing.T) {
+	useTestConfig(t, `
+[identity.work]
+email = "jane@corp.example"
+signing_key = "~/.ssh/id_sign.pub"
+signing_format = "ssh"
+`)
+	reset := func(key, format, identity string) {
+		signKey, signFormat, signIdentity = key, format, identity
+	}
+	t.Cleanup(func() { reset("", "", "") })
+
+	reset("", "", "")
+	if s, err := resolveSigning(); err != nil || s != nil {
+		t.Errorf("expected no signing by default, got %+v, %v", s, err)
+	}
+
+	reset(signWithDefaultKey, "", "")
+	s, err := resolveSigning()
+	if err != nil || s == nil || !reflect.DeepEqual(s.commitArgs(), []string{"--gpg-sign"}) || len(s.configArgs) != 0 {
+		t.Errorf("unexpected signing for bare --gpg-sign: %+v, %v", s, err)
+	}
+
+	reset("ABCD1234", "openpgp", "")
+	s, err = resolveSigning()
+	if err != nil || !reflect.DeepEqual(s.tagArgs(), []string{"--local-user=ABCD1234"}) || !reflect.DeepEqual(s.configArgs, []string{"-c", "gpg.format=openpgp"}) {
+		t.Errorf("unexpected signing for explicit key: %+v, %v", s, err)
+	}
+
+	reset("", "", "work")
+	s, err = resolveSigning()
+	if err != nil || s == nil || s.key == "~/.ssh/id_sign.pub" || !reflect.DeepEqual(s.configArgs, []string{"-c", "gpg.format=ssh"}) {
+		t.Errorf("expected identity ssh key with expanded path, got %+v, %v", s, err)
+	}
+
+	reset("", "ssh", "")
+	if _, err := resolveSigning(); err == nil {
+		t.Errorf("expected --signing-format without a key to fail")
+	}
+}
+
+func TestRecentSignatures(t *testing.T) {
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "unsigned work")
+	commits, err := recentSignatures(context.Background(), repo, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || commits[0].status != "N" || commits[0].trusted() || commits[0].subject != "unsigned work" {
+		t.Errorf("unexpected signature info %+v", commits)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+// workTreeSize returns the size of the files in repo outside .git.
+func workTreeSize(repo string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(repo, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Name() == ".git" && path != repo {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil // .git file of a worktree or submodule
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// objectStoreSize returns the bytes used by loose and packed objects as
+// reported by git count-objects -v.
+func objectStoreSize(ctx context.Context, repo string) (int64, error) {
+	out, err := gitOutput(ctx, repo, "count-objects", "-v")
+	if err != nil {
+		return 0, err
+	}
+	var kib int64
+	for _, line := range strings.Split(out, "\n") {
+		key, val, ok := strings.Cut(line, ": ")
+		if !ok || (key != "size" && key != "size-pack") {
+			continue
+		}
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected count-objects line %q", line)
+		}
+		kib += n
+	}
+	return kib * 1024, nil
+}
+
+// gitObject is an object in the repository's object database.
+type gitObject struct {
+	name string
+	kind string
+	size int64  // bytes on disk
+	path string // path the object was found at, for blobs and trees
+}
+
+// largestObjects returns the n objects taking the most disk space, with the
+// path they appear under in history where one is known.
+func largestObjects(ctx context.Context, repo string, n int) ([]gitObject, error) {
+	out, err := gitOutput(ctx, repo, "cat-file", "--batch-all-objects", "--batch-check=%(objectsize:disk) %(objecttype) %(objectname)")
+	if err != nil {
+		return nil, err
+	}
+	var objects []gitObject
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		objects = append(objects, gitObject{size: size, kind: fields[1], name: fields[2]})
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].size > objects[j].size })
+	if len(objects) > n {
+		objects = objects[:n]
+	}
+	if len(objects) == 0 {
+		return objects, nil
+	}
+
+	want := map[string]*gitObject{}
+	for i := range objects {
+		want[objects[i].name] = &objects[i]
+	}
+	// rev-list prints "<sha> <path>" for every reachable tree and blob;
+	// stream it since it can be large on big repositories.
+	_ = gitExec.Lines(ctx, runner.Git(repo, "rev-list", "--objects", "--all"), func(line string) {
+		name, path, ok := strings.Cut(line, " ")
+		if o := want[name]; ok && o != nil && o.path == "" {
+			o.path = path
+		}
+	})
+	return objects, nil
+}
+
+type repoSize struct {
+	repo            string
+	gitDir, objects int64
+	workTree        int64
+	largest         []gitObject
+}
+
+// size command
+var sizeTop int
+var sizeCmd = &cobra.Command{
+	Use:   "size [--objects 5] <pattern>...",
+	Short: "Report .git and working tree sizes and the largest objects",
+	Long: `size reports, per repository, the size of the .git directory, the object
+database (git count-objects -v) and the working tree, sorted by total size,
+followed by each repository's largest objects.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		var sizes []repoSize
+		for _, r := range repos {
+			s := repoSize{repo: r}
+			dir, err := gitDir(ctx, r)
+			if err == nil {
+				s.gitDir, err = dirSize(dir)
+			}
+			if err == nil {
+				s.objects, err = objectStoreSize(ctx, r)
+			}
+			if err == nil {
+				s.workTree, err = workTreeSize(r)
+			}
+			if err == nil && sizeTop > 0 {
+				s.largest, err = largestObjects(ctx, r, sizeTop)
+			}
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			sizes = append(sizes, s)
+		}
+		sort.SliceStable(sizes, func(i, j int) bool {
+			return sizes[i].gitDir+sizes[i].workTree > sizes[j].gitDir+sizes[j].workTree
+		})
+
+		var totalGit, totalTree int64
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, ".GIT\tOBJECTS\tWORKTREE\t\tREPO")
+		for _, s := range sizes {
+			totalGit += s.gitDir
+			totalTree += s.workTree
+			fmt.Fprintf(w, "%s\t%s\t%s\t\t%s\n", formatBytes(s.gitDir), formatBytes(s.objects), formatBytes(s.workTree), s.repo)
+		}
+		w.Flush()
+		fmt.Printf("\ntotal: %s in .git, %s in working trees\n", formatBytes(totalGit), formatBytes(totalTree))
+
+		for _, s := range sizes {
+			if len(s.largest) == 0 {
+				continue
+			}
+			repoHeader(s.repo)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, o := range s.largest {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", formatBytes(o.size), o.kind, o.name[:12], o.path)
+			}
+			w.Flush()
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sizeCmd)
+
+	sizeCmd.Flags().IntVar(&sizeTop, "objects", 5, "number of largest objects to list per repository (0 disables)")
+}
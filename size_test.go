@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLargestObjectsAndWorkTreeSize(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	// random-looking content so zlib cannot shrink it below the small file
+	var big strings.Builder
+	for i := 0; i < 4000; i++ {
+		big.WriteString(strings.Repeat(string(rune('a'+i*7%26)), i%13+1))
+	}
+	commitTestFile(t, repo, "assets/big.bin", big.String(), "add big file")
+	commitTestFile(t, repo, "small.txt", "x", "add small file")
+
+	objects, err := largestObjects(ctx, repo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].kind != "blob" || objects[0].path != "assets/big.bin" {
+		t.Errorf("expected big.bin to be the largest object, got %+v", objects)
+	}
+
+	size, err := workTreeSize(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(big.Len() + 1); size != want {
+		t.Errorf("workTreeSize = %d, want %d", size, want)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// --skip-current modes
+const (
+	skipCurrentFetch = "fetch" // fetch, then compare with the upstream
+	skipCurrentLocal = "local" // compare with the upstream as last fetched
+)
+
+var pullSkipCurrent string
+
+// currentCheckParallel is the number of repositories checked at once.
+const currentCheckParallel = 8
+
+// behindUpstream returns how many commits the upstream of repo's branch has
+// that HEAD does not. With fetch set it fetches first, with fetchArgs.
+func behindUpstream(ctx context.Context, repo string, fetch bool, fetchArgs []string) (int, error) {
+	if fetch {
+		if _, err := runGitCapture(ctx, repo, append([]string{"fetch"}, fetchArgs...)...); err != nil {
+			return 0, err
+		}
+	}
+	out, err := gitOutput(ctx, repo, "rev-list", "--count", "HEAD..@{upstream}")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(out)
+}
+
+// checkSkipCurrent validates --skip-current.
+func checkSkipCurrent() error {
+	switch pullSkipCurrent {
+	case "", skipCurrentFetch, skipCurrentLocal:
+		return nil
+	}
+	return usageErrorf("invalid --skip-current %q (expected %s or %s)", pullSkipCurrent, skipCurrentFetch, skipCurrentLocal)
+}
+
+// skipCurrent splits repos into those to pull and those already current
+// with their upstream when --skip-current is set. Repositories that cannot
+// be checked are pulled, so git reports what is wrong with them.
+func skipCurrent(repos []string, fetchArgs []string) (pull, current []string) {
+	if pullSkipCurrent == "" || emitScript != "" {
+		return repos, nil // a script fetches nothing to compare with
+	}
+	ctx, cancel := runContext()
+	defer cancel()
+	var mu sync.Mutex
+	isCurrent := map[string]bool{}
+	sem := make(chan struct{}, currentCheckParallel)
+	var wg sync.WaitGroup
+	for _, r := range repos {
+		wg.Add(1)
+		go func(r string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if n, err := behindUpstream(ctx, r, pullSkipCurrent == skipCurrentFetch, fetchArgs); err == nil && n == 0 {
+				mu.Lock()
+				isCurrent[r] = true
+				mu.Unlock()
+			}
+		}(r)
+	}
+	wg.Wait()
+
+	for _, r := range repos {
+		if !isCurrent[r] {
+			pull = append(pull, r)
+			continue
+		}
+		current = append(current, r)
+		if logLevel > levelQuiet && !onlyFailures && pullOutput == "" {
+			fmt.Printf("%s: %s\n", r, paint(os.Stdout, ansiGreen, "current"))
+		}
+	}
+	if len(current) > 0 {
+		logf(levelVerbose, "skipped pulling %d of %d repositories that are current", len(current), len(repos))
+	}
+	return pull, current
+}
+
+// withCurrent adds a successful result for each current repository to the
+// results of a pull, keeping the order of repos.
+func withCurrent(repos []string, results []runner.Result, current []string) []runner.Result {
+	byRepo := map[string]runner.Result{}
+	for _, res := range results {
+		byRepo[res.Repo] = res
+	}
+	for _, r := range current {
+		byRepo[r] = runner.Result{Repo: r, Output: "current\n"}
+	}
+	all := make([]runner.Result, 0, len(byRepo))
+	for _, r := range repos {
+		if res, ok := byRepo[r]; ok {
+			all = append(all, res)
+		}
+	}
+	return all
+}
+
+func init() {
+	pullCmd.Flags().StringVar(&pullSkipCurrent, "skip-current", "", "skip repositories already current with their upstream: fetch and compare (fetch), or compare with the last fetch (local)")
+	pullCmd.Flags().Lookup("skip-current").NoOptDefVal = skipCurrentFetch
+}
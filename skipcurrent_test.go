@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+func TestSkipCurrent(t *testing.T) {
+	upstream := initTestRepo(t)
+	commitTestFile(t, upstream, "a.txt", "a", "first")
+	clone := func(name string) string {
+		dir := filepath.Join(t.TempDir(), name)
+		if out, err := exec.Command("git", "clone", "-q", upstream, dir).CombinedOutput(); err != nil {
+			t.Fatalf("git clone: %v %s", err, out)
+		}
+		return dir
+	}
+	stale := clone("stale")
+	commitTestFile(t, upstream, "b.txt", "b", "second")
+	fresh := clone("fresh")
+	repos := []string{stale, fresh}
+	t.Cleanup(func() { pullSkipCurrent = "" })
+
+	pullSkipCurrent = ""
+	if pull, current := skipCurrent(repos, nil); !reflect.DeepEqual(pull, repos) || current != nil {
+		t.Errorf("without --skip-current: pull %q, current %q", pull, current)
+	}
+
+	// the stale clone has not fetched the second commit yet
+	pullSkipCurrent = skipCurrentLocal
+	captureStdout(t, func() {
+		if pull, current := skipCurrent(repos, nil); len(pull) != 0 || !reflect.DeepEqual(current, repos) {
+			t.Errorf("local: pull %q, current %q", pull, current)
+		}
+	})
+
+	pullSkipCurrent = skipCurrentFetch
+	out := captureStdout(t, func() {
+		if pull, current := skipCurrent(repos, nil); !reflect.DeepEqual(pull, []string{stale}) || !reflect.DeepEqual(current, []string{fresh}) {
+			t.Errorf("fetch: pull %q, current %q", pull, current)
+		}
+	})
+	if out != fresh+": current\n" {
+		t.Errorf("reported %q", out)
+	}
+
+	pullSkipCurrent = "sometimes"
+	if err := checkSkipCurrent(); exitCode(err) != exitUsage {
+		t.Errorf("invalid mode accepted: %v", err)
+	}
+}
+
+func TestWithCurrent(t *testing.T) {
+	results := []runner.Result{{Repo: "/r/c", Output: "Fast-forward\n"}}
+	got := withCurrent([]string{"/r/a", "/r/b", "/r/c"}, results, []string{"/r/a"})
+	if len(got) != 2 || got[0].Repo != "/r/a" || got[0].Output != "current\n" || got[1].Repo != "/r/c" {
+		t.Errorf("withCurrent = %+v", got)
+	}
+}
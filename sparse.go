@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// sparseEnabled reports whether dir's working tree is a sparse checkout.
+func sparseEnabled(ctx context.Context, dir string) bool {
+	out, _ := gitOutput(ctx, dir, "config", "--bool", "core.sparseCheckout")
+	return out == "true"
+}
+
+// sparseModeArg is --cone or --no-cone. git keeps the previous mode when
+// neither is given, so gitbatch always passes one to get the same result in
+// every repository.
+func sparseModeArg() string {
+	if sparseNoCone {
+		return "--no-cone"
+	}
+	return "--cone"
+}
+
+// sparse command
+var sparsePaths []string
+var sparseNoCone bool
+var sparseCmd = &cobra.Command{
+	Use:   "sparse",
+	Short: "Manage git sparse-checkout in the matching repositories",
+	Long: `sparse applies the same git sparse-checkout setup to every matching
+repository, so large monorepo clones only check out the directories you work
+on:
+
+  gitbatch sparse set --path services/api --path libs "monorepo-*"
+  gitbatch sparse list "monorepo-*"
+  gitbatch sparse disable "monorepo-*"
+
+Paths are directories in cone mode (the default) and gitignore-style
+patterns with --no-cone.`,
+}
+
+var sparseInitCmd = &cobra.Command{
+	Use:   "init [--no-cone] <pattern>...",
+	Short: "Turn on sparse checkout, keeping only top-level files",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSparse(args, "init", sparseModeArg())
+	},
+}
+
+var sparseSetCmd = &cobra.Command{
+	Use:   "set --path <path>... [--no-cone] <pattern>...",
+	Short: "Check out only the given paths, turning on sparse checkout if needed",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(sparsePaths) == 0 {
+			return usageErrorf("at least one --path is required")
+		}
+		return runSparse(args, append([]string{"set", sparseModeArg(), "--"}, sparsePaths...)...)
+	},
+}
+
+var sparseListCmd = &cobra.Command{
+	Use:   "list <pattern>...",
+	Short: "Show the sparse-checkout paths of each repository",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		for _, r := range repos {
+			repoHeader(r)
+			if !sparseEnabled(ctx, r) {
+				fmt.Println("not sparse")
+				continue
+			}
+			if err := runGit(ctx, r, "sparse-checkout", "list"); err != nil {
+				repoFailed(r, err)
+			}
+		}
+		return nil
+	},
+}
+
+var sparseDisableCmd = &cobra.Command{
+	Use:   "disable <pattern>...",
+	Short: "Turn off sparse checkout and restore the full working tree",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		skipped := 0
+		for _, r := range repos {
+			if !sparseEnabled(ctx, r) {
+				skipped++
+				continue
+			}
+			repoHeader(r)
+			if err := runGit(ctx, r, "sparse-checkout", "disable"); err != nil {
+				repoFailed(r, err)
+			}
+		}
+		if skipped > 0 {
+			fmt.Printf("\nskipped %d repositories without sparse checkout\n", skipped)
+		}
+		return nil
+	},
+}
+
+// runSparse runs git sparse-checkout with gitArgs in every repository
+// matching patterns.
+func runSparse(patterns []string, gitArgs ...string) error {
+	repos, err := collectRepos(patterns)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := runContext()
+	defer cancel()
+
+	for _, r := range repos {
+		repoHeader(r)
+		if err := runGit(ctx, r, append([]string{"sparse-checkout"}, gitArgs...)...); err != nil {
+			repoFailed(r, err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(sparseCmd)
+	sparseCmd.AddCommand(sparseInitCmd)
+	sparseCmd.AddCommand(sparseSetCmd)
+	sparseCmd.AddCommand(sparseListCmd)
+	sparseCmd.AddCommand(sparseDisableCmd)
+
+	sparseSetCmd.Flags().StringArrayVarP(&sparsePaths, "path", "p", nil, "directory (or pattern with --no-cone) to check out (repeatable)")
+	for _, c := range []*cobra.Command{sparseInitCmd, sparseSetCmd} {
+		c.Flags().BoolVar(&sparseNoCone, "no-cone", false, "use gitignore-style patterns instead of directories")
+	}
+}
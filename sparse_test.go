@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSparseSetAndDisable(t *testing.T) {
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "api/main.go", "package main\n", "api")
+	commitTestFile(t, repo, "web/index.html", "<html>\n", "web")
+	t.Chdir(filepath.Dir(repo))
+	oldPaths, oldNoCone := sparsePaths, sparseNoCone
+	t.Cleanup(func() { sparsePaths, sparseNoCone = oldPaths, oldNoCone })
+	ctx := context.Background()
+
+	sparsePaths = nil
+	if err := sparseSetCmd.RunE(sparseSetCmd, []string{filepath.Base(repo)}); err == nil {
+		t.Error("set without --path accepted")
+	}
+
+	sparsePaths = []string{"api"}
+	if err := sparseSetCmd.RunE(sparseSetCmd, []string{filepath.Base(repo)}); err != nil {
+		t.Fatal(err)
+	}
+	if !sparseEnabled(ctx, repo) {
+		t.Fatal("sparse checkout not enabled")
+	}
+	if cone, _ := gitOutput(ctx, repo, "config", "--bool", "core.sparseCheckoutCone"); cone != "true" {
+		t.Errorf("core.sparseCheckoutCone = %q, want true", cone)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "web", "index.html")); !os.IsNotExist(err) {
+		t.Errorf("web/index.html still checked out: %v", err)
+	}
+	if list, _ := gitOutput(ctx, repo, "sparse-checkout", "list"); list != "api" {
+		t.Errorf("sparse-checkout list = %q", list)
+	}
+
+	if err := sparseDisableCmd.RunE(sparseDisableCmd, []string{filepath.Base(repo)}); err != nil {
+		t.Fatal(err)
+	}
+	if sparseEnabled(ctx, repo) {
+		t.Error("sparse checkout still enabled")
+	}
+	if _, err := os.Stat(filepath.Join(repo, "web", "index.html")); err != nil {
+		t.Errorf("web/index.html not restored: %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+// ageValue is a flag value for human-friendly durations. On top of Go
+// durations ("36h") it accepts day, week and year suffixes ("90d", "6w", "1y").
+type ageValue time.Duration
+
+func (a *ageValue) String() string {
+	if *a == 0 {
+		return "" // unset, not shown as a default
+	}
+	return formatAge(time.Duration(*a))
+}
+
+func (a *ageValue) Type() string { return "age" }
+
+func (a *ageValue) Set(s string) error {
+	d, err := parseAge(s)
+	if err != nil {
+		return err
+	}
+	*a = ageValue(d)
+	return nil
+}
+
+var ageUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+	"y": 365 * 24 * time.Hour,
+}
+
+// parseAge parses durations like "90d", "2w", "1y" or anything accepted by
+// time.ParseDuration.
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	if unit, ok := ageUnits[s[len(s)-1:]]; ok {
+		n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n * float64(unit)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: use e.g. 90d, 6w, 1y or 36h", s)
+	}
+	return d, nil
+}
+
+// formatAge renders d in the largest whole unit that fits: days when at
+// least one day, hours otherwise.
+func formatAge(d time.Duration) string {
+	if d >= 24*time.Hour {
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	}
+	return fmt.Sprintf("%dh", int(d/time.Hour))
+}
+
+// lastCommitTime returns the committer date of the most recent commit on any
+// local branch. ok is false for repositories without commits.
+func lastCommitTime(ctx context.Context, dir string) (t time.Time, ok bool, err error) {
+	if useGoGit() {
+		return gogitLastCommitTime(dir)
+	}
+	out, err := gitOutput(ctx, dir, "for-each-ref", "--sort=-committerdate", "--count=1", "--format=%(committerdate:unix)", "refs/heads")
+	if err != nil || out == "" {
+		return time.Time{}, false, err
+	}
+	sec, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("unexpected commit date %q", out)
+	}
+	return time.Unix(sec, 0), true, nil
+}
+
+// lastFetchTime returns the modification time of FETCH_HEAD, which git
+// rewrites on every fetch. ok is false if the repository was never fetched.
+func lastFetchTime(ctx context.Context, dir string) (time.Time, bool) {
+	p, err := gitOutput(ctx, dir, "rev-parse", "--git-path", "FETCH_HEAD")
+	if err != nil {
+		return time.Time{}, false
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(dir, p)
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return fi.ModTime(), true
+}
+
+// staleRepo is one row of the stale report. Fields are exported for
+// --format templates.
+type staleRepo struct {
+	Repo     string    `json:"repo"`
+	Activity time.Time `json:"last_activity,omitzero"` // zero for repositories without any commit
+	Age      string    `json:"age,omitempty"`          // formatted age of Activity, "" without commits
+}
+
+// staleTable lays out stale repositories for csv and markdown output.
+func staleTable(stale []staleRepo) report.Table {
+	t := report.Table{Header: []string{"repo", "age", "last_activity"}}
+	for _, s := range stale {
+		last := ""
+		if !s.Activity.IsZero() {
+			last = s.Activity.Format("2006-01-02")
+		}
+		t.Rows = append(t.Rows, []string{s.Repo, s.Age, last})
+	}
+	return t
+}
+
+// stale command
+var staleOlderThan = ageValue(90 * 24 * time.Hour)
+var staleIncludeFetch bool
+var staleFormat string
+var staleOutput string
+var staleCmd = &cobra.Command{
+	Use:   "stale [--older-than 90d] <pattern>...",
+	Short: "List repositories without recent commits",
+	Long: `stale lists repositories whose most recent commit on any local branch is
+older than --older-than. With --include-fetch a recent fetch also counts as
+activity. Repositories without commits are always listed. --format takes a
+Go template with the fields .Repo, .Age and .Activity (a time.Time, zero for
+repositories without commits). --output prints the list as json, csv or
+markdown.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkFormatOutput(staleFormat, staleOutput); err != nil {
+			return err
+		}
+		var tmpl *template.Template
+		if staleFormat != "" {
+			var err error
+			if tmpl, err = parseFormat(staleFormat); err != nil {
+				return err
+			}
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		now := time.Now()
+		threshold := time.Duration(staleOlderThan)
+		var stale []staleRepo
+		for _, r := range repos {
+			activity, ok, err := lastCommitTime(ctx, r)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			if staleIncludeFetch {
+				if fetched, fok := lastFetchTime(ctx, r); fok && fetched.After(activity) {
+					activity, ok = fetched, true
+				}
+			}
+			if !ok {
+				stale = append(stale, staleRepo{Repo: r})
+				continue
+			}
+			if now.Sub(activity) > threshold {
+				stale = append(stale, staleRepo{Repo: r, Activity: activity, Age: formatAge(now.Sub(activity))})
+			}
+		}
+
+		// oldest first; repositories without commits sort before everything
+		sort.SliceStable(stale, func(i, j int) bool { return stale[i].Activity.Before(stale[j].Activity) })
+		if tmpl != nil {
+			return writeFormatted(os.Stdout, tmpl, stale)
+		}
+		if staleOutput != "" {
+			return writeOutput(os.Stdout, staleOutput, stale, staleTable(stale))
+		}
+		if len(stale) == 0 {
+			fmt.Printf("no repositories inactive for more than %s\n", staleOlderThan.String())
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "AGE\tLAST ACTIVITY\tREPO")
+		for _, s := range stale {
+			if s.Activity.IsZero() {
+				fmt.Fprintf(w, "-\tno commits\t%s\n", s.Repo)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Age, s.Activity.Format("2006-01-02"), s.Repo)
+		}
+		w.Flush()
+		fmt.Printf("\n%d of %d repositories inactive for more than %s\n", len(stale), len(repos), staleOlderThan.String())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(staleCmd)
+
+	staleCmd.Flags().Var(&staleOlderThan, "older-than", "inactivity threshold (e.g. 90d, 6w, 1y, 36h)")
+	staleCmd.Flags().BoolVar(&staleIncludeFetch, "include-fetch", false, "count the last fetch as activity")
+	addFormatFlag(staleCmd, &staleFormat, `{{.Age}}\t{{.Repo}}`)
+	addOutputFlag(staleCmd, &staleOutput)
+}
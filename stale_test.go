@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	cases := map[string]time.Duration{
+		"90d": 90 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"1y":  365 * 24 * time.Hour,
+		"36h": 36 * time.Hour,
+	}
+	for in, want := range cases {
+		got, err := parseAge(in)
+		if err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "d", "ninety days", "-3d"} {
+		if _, err := parseAge(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLastCommitTime(t *testing.T) {
+	repo := initTestRepo(t)
+	ctx := context.Background()
+
+	if _, ok, err := lastCommitTime(ctx, repo); ok || err != nil {
+		t.Fatalf("expected no commit time for empty repo, got ok=%v err=%v", ok, err)
+	}
+
+	commitTestFile(t, repo, "a.txt", "a", "old commit", "GIT_COMMITTER_DATE=2020-01-02T03:04:05Z")
+	got, ok, err := lastCommitTime(ctx, repo)
+	if err != nil || !ok {
+		t.Fatalf("lastCommitTime failed: ok=%v err=%v", ok, err)
+	}
+	if want := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("lastCommitTime = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// stashInfo counts the stash entries of a repository and dates the oldest.
+type stashInfo struct {
+	count  int
+	oldest time.Time // zero without stashes
+}
+
+// readStashes lists the stash entries of repo with their dates.
+func readStashes(ctx context.Context, repo string) (stashInfo, error) {
+	var s stashInfo
+	out, err := gitOutput(ctx, repo, "stash", "list", "--format=%ct")
+	if err != nil || out == "" {
+		return s, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		sec, err := strconv.ParseInt(line, 10, 64)
+		if err != nil {
+			return s, fmt.Errorf("unexpected stash list output %q", line)
+		}
+		t := time.Unix(sec, 0)
+		if s.count == 0 || t.Before(s.oldest) {
+			s.oldest = t
+		}
+		s.count++
+	}
+	return s, nil
+}
+
+// age is how old the oldest stash is at now, 0 without stashes.
+func (s stashInfo) age(now time.Time) time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	return now.Sub(s.oldest)
+}
+
+// stashWarnAge is --warn-stash-older-than; 0 turns the warning off.
+var stashWarnAge ageValue
+
+// warnOldStash warns on stderr when the oldest stash of repo is older than
+// --warn-stash-older-than.
+func warnOldStash(repo string, s stashInfo, now time.Time) {
+	if stashWarnAge == 0 || s.count == 0 || s.age(now) < time.Duration(stashWarnAge) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s %s: %d stashes, the oldest %s old\n", paint(os.Stderr, ansiYellow, "old stash in"), repo, s.count, formatAge(s.age(now)))
+}
+
+// warnOldStashes reads the stashes of repos and warns about old ones, when
+// --warn-stash-older-than is set.
+func warnOldStashes(ctx context.Context, repos []string) {
+	if stashWarnAge == 0 {
+		return
+	}
+	now := time.Now()
+	for _, r := range repos {
+		if s, err := readStashes(ctx, r); err == nil {
+			warnOldStash(r, s, now)
+		}
+	}
+}
+
+// addStashWarnFlag registers --warn-stash-older-than on cmd.
+func addStashWarnFlag(cmd *cobra.Command) {
+	cmd.Flags().Var(&stashWarnAge, "warn-stash-older-than", "warn about repositories with a stash older than this (e.g. 30d, 6w)")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadStashes(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+
+	s, err := readStashes(ctx, repo)
+	if err != nil || s.count != 0 || s.age(time.Now()) != 0 {
+		t.Fatalf("without stashes: %+v, %v", s, err)
+	}
+
+	stash := func(content, date string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repo, "a.txt"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		c := exec.Command("git", "stash")
+		c.Dir = repo
+		c.Env = append(os.Environ(), "GIT_COMMITTER_DATE="+date)
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("git stash: %v %s", err, out)
+		}
+	}
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	stash("old", old.Format(time.RFC3339))
+	stash("new", time.Now().Format(time.RFC3339))
+
+	s, err = readStashes(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.count != 2 || s.oldest.Unix() != old.Unix() {
+		t.Errorf("readStashes = %+v, want 2 stashes, the oldest at %v", s, old)
+	}
+
+	t.Cleanup(func() { stashWarnAge = 0 })
+	now := time.Now()
+	if out := captureStderr(t, func() { warnOldStash(repo, s, now) }); out != "" {
+		t.Errorf("warned without --warn-stash-older-than: %q", out)
+	}
+	if err := stashWarnAge.Set("30d"); err != nil {
+		t.Fatal(err)
+	}
+	out := captureStderr(t, func() { warnOldStash(repo, s, now) })
+	if !strings.Contains(out, repo+": 2 stashes, the oldest 40d old") {
+		t.Errorf("warning = %q", out)
+	}
+	if err := stashWarnAge.Set("6w"); err != nil {
+		t.Fatal(err)
+	}
+	if out := captureStderr(t, func() { warnOldStash(repo, s, now) }); out != "" {
+		t.Errorf("warned below the threshold: %q", out)
+	}
+}
+
+func TestWriteStatusSummary(t *testing.T) {
+	var b bytes.Buffer
+	writeStatusSummary(&b, []repoState{
+		{Repo: "/r/a", Branch: "main", Ahead: 1},
+		{Repo: "/r/b", Unstaged: 2, Stashes: 1, OldestStash: "3d"},
+	})
+	want := `REPO  BRANCH      AHEAD  BEHIND  CHANGES                            STASHES  OLDEST STASH
+/r/a  main        1      0       clean                              0        -
+/r/b  (detached)  0      0       0 staged, 2 unstaged, 0 untracked  1        3d
+`
+	if b.String() != want {
+		t.Errorf("summary:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
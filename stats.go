@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/patrickkdev/gitbatch/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+// authorStats is the activity of one author, summed over repositories.
+type authorStats struct {
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	Commits      int    `json:"commits"`
+	Additions    int    `json:"additions"`
+	Deletions    int    `json:"deletions"`
+	Repositories int    `json:"repositories"`
+}
+
+// repoAuthorStats parses git log --numstat of repo into per-author totals
+// keyed by lower-cased email. Merge commits are left out and binary files
+// count as changed without lines.
+func repoAuthorStats(ctx context.Context, repo, since string) (map[string]*authorStats, error) {
+	stats := map[string]*authorStats{}
+	if _, err := gitOutput(ctx, repo, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return stats, nil // no commits yet
+	}
+	args := []string{"log", "--no-merges", "--numstat", "--format=%x00%aN%x00%aE", "HEAD"}
+	if since != "" {
+		args = append(args, "--since="+since)
+	}
+	out, err := gitOutput(ctx, repo, args...)
+	if err != nil {
+		return nil, err
+	}
+	var cur *authorStats
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "\x00") {
+			f := strings.SplitN(line[1:], "\x00", 2)
+			if len(f) != 2 {
+				continue
+			}
+			key := strings.ToLower(f[1])
+			if cur = stats[key]; cur == nil {
+				cur = &authorStats{Name: f[0], Email: f[1], Repositories: 1}
+				stats[key] = cur
+			}
+			cur.Commits++
+			continue
+		}
+		f := strings.SplitN(line, "\t", 3)
+		if cur == nil || len(f) != 3 {
+			continue
+		}
+		added, _ := strconv.Atoi(f[0]) // "-" for binary files
+		deleted, _ := strconv.Atoi(f[1])
+		cur.Additions += added
+		cur.Deletions += deleted
+	}
+	return stats, nil
+}
+
+// mergeAuthorStats adds the per-repository stats into total.
+func mergeAuthorStats(total, repo map[string]*authorStats) {
+	for key, s := range repo {
+		t := total[key]
+		if t == nil {
+			c := *s
+			total[key] = &c
+			continue
+		}
+		t.Commits += s.Commits
+		t.Additions += s.Additions
+		t.Deletions += s.Deletions
+		t.Repositories += s.Repositories
+	}
+}
+
+// rankAuthors orders authors by commits, then changed lines, then name.
+func rankAuthors(total map[string]*authorStats) []authorStats {
+	ranked := make([]authorStats, 0, len(total))
+	for _, s := range total {
+		ranked = append(ranked, *s)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Commits != b.Commits {
+			return a.Commits > b.Commits
+		}
+		if a.Additions+a.Deletions != b.Additions+b.Deletions {
+			return a.Additions+a.Deletions > b.Additions+b.Deletions
+		}
+		return a.Name < b.Name
+	})
+	return ranked
+}
+
+// authorTable lays out ranked authors for csv and markdown output.
+func authorTable(ranked []authorStats) report.Table {
+	t := report.Table{Header: []string{"name", "email", "commits", "additions", "deletions", "repositories"}}
+	for _, s := range ranked {
+		t.Rows = append(t.Rows, []string{s.Name, s.Email, strconv.Itoa(s.Commits),
+			strconv.Itoa(s.Additions), strconv.Itoa(s.Deletions), strconv.Itoa(s.Repositories)})
+	}
+	return t
+}
+
+// stats command
+var statsSince string
+var statsJSON bool
+var statsFormat string
+var statsOutput string
+var statsCmd = &cobra.Command{
+	Use:   "stats [--since 3.months] [--output json|csv|markdown] <pattern>...",
+	Short: "Rank authors by commits and changed lines across matching repositories",
+	Long: `stats sums the non-merge commits and changed lines on HEAD of every
+repository per author (identified by email, honouring .mailmap) and prints
+one ranked table. --since takes any date git understands, such as
+"3.months", "2 weeks ago" or "2024-01-01". --format takes a Go template with
+the fields .Name, .Email, .Commits, .Additions, .Deletions and
+.Repositories. --output prints the same fields as json, csv or markdown;
+--json is short for --output json.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if statsJSON {
+			if statsOutput != "" && statsOutput != outputJSON {
+				return usageErrorf("--json and --output cannot be used together")
+			}
+			statsOutput = outputJSON
+		}
+		if err := checkFormatOutput(statsFormat, statsOutput); err != nil {
+			return err
+		}
+		var tmpl *template.Template
+		if statsFormat != "" {
+			var err error
+			if tmpl, err = parseFormat(statsFormat); err != nil {
+				return err
+			}
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		total := map[string]*authorStats{}
+		for _, r := range repos {
+			s, err := repoAuthorStats(ctx, r, statsSince)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			mergeAuthorStats(total, s)
+		}
+		ranked := rankAuthors(total)
+
+		if tmpl != nil {
+			return writeFormatted(os.Stdout, tmpl, ranked)
+		}
+		if statsOutput != "" {
+			return writeOutput(os.Stdout, statsOutput, ranked, authorTable(ranked))
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "AUTHOR\tEMAIL\tCOMMITS\tADDED\tDELETED\tREPOS")
+		for _, s := range ranked {
+			fmt.Fprintf(w, "%s\t%s\t%d\t+%d\t-%d\t%d\n", s.Name, s.Email, s.Commits, s.Additions, s.Deletions, s.Repositories)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().StringVar(&statsSince, "since", "", "only count commits newer than this date (e.g. 3.months)")
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "print the ranking as JSON")
+	addFormatFlag(statsCmd, &statsFormat, `{{.Commits}}\t{{.Email}}`)
+	addOutputFlag(statsCmd, &statsOutput)
+}
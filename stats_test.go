@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAuthorStats(t *testing.T) {
+	ctx := context.Background()
+	alice := []string{"GIT_AUTHOR_NAME=Alice", "GIT_AUTHOR_EMAIL=alice@example.com"}
+	bob := []string{"GIT_AUTHOR_NAME=Bob", "GIT_AUTHOR_EMAIL=Bob@Example.com"}
+
+	one := initTestRepo(t)
+	commitTestFile(t, one, "a.txt", "1\n2\n3\n", "add a", alice...)
+	commitTestFile(t, one, "a.txt", "1\n2\n", "trim a", bob...)
+	two := initTestRepo(t)
+	commitTestFile(t, two, "b.txt", "x\n", "add b", alice...)
+	commitTestFile(t, two, "b.txt", "y\n", "edit b", alice...)
+	commitTestFile(t, two, "c.txt", "z\n", "add c", []string{"GIT_AUTHOR_NAME=Bob", "GIT_AUTHOR_EMAIL=bob@example.com"}...)
+
+	total := map[string]*authorStats{}
+	for _, r := range []string{one, two} {
+		s, err := repoAuthorStats(ctx, r, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		mergeAuthorStats(total, s)
+	}
+	ranked := rankAuthors(total)
+	if len(ranked) != 2 {
+		t.Fatalf("expected 2 authors (emails are case-insensitive), got %+v", ranked)
+	}
+	want := []authorStats{
+		{Name: "Alice", Email: "alice@example.com", Commits: 3, Additions: 5, Deletions: 1, Repositories: 2},
+		{Name: "Bob", Email: "Bob@Example.com", Commits: 2, Additions: 1, Deletions: 1, Repositories: 2},
+	}
+	for i := range want {
+		if ranked[i] != want[i] {
+			t.Errorf("rank %d = %+v, want %+v", i, ranked[i], want[i])
+		}
+	}
+
+	old := initTestRepo(t)
+	commitTestFile(t, old, "a.txt", "1\n", "ancient", append(alice, "GIT_COMMITTER_DATE=2005-04-07T22:13:13")...)
+	commitTestFile(t, old, "a.txt", "2\n", "recent", bob...)
+	recent, err := repoAuthorStats(ctx, old, "2010-01-01")
+	if err != nil || len(recent) != 1 || recent["bob@example.com"] == nil {
+		t.Errorf("expected --since to keep only the recent commit, got %+v, %v", recent, err)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+// statusUntracked is --untracked: how status shows untracked files, as in
+// git status --untracked-files; git's default when empty.
+var statusUntracked string
+
+// statusIgnored is --ignored: status also shows ignored files.
+var statusIgnored bool
+
+// statusFileArgs returns the git status options for --untracked and
+// --ignored.
+func statusFileArgs() ([]string, error) {
+	var args []string
+	switch statusUntracked {
+	case "":
+	case "no", "normal", "all":
+		args = append(args, "--untracked-files="+statusUntracked)
+	default:
+		return nil, usageErrorf("invalid --untracked %q (expected no, normal or all)", statusUntracked)
+	}
+	if statusIgnored {
+		if statusUntracked == "no" {
+			// git only finds ignored files while looking for untracked ones
+			return nil, usageErrorf("--ignored cannot be combined with --untracked=no")
+		}
+		args = append(args, "--ignored")
+	}
+	return args, nil
+}
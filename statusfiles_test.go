@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStatusFileArgs(t *testing.T) {
+	defer func() { statusUntracked, statusIgnored = "", false }()
+	for untracked, want := range map[string][]string{
+		"":    nil,
+		"no":  {"--untracked-files=no"},
+		"all": {"--untracked-files=all"},
+	} {
+		statusUntracked = untracked
+		if got, err := statusFileArgs(); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("--untracked=%s: %q, %v", untracked, got, err)
+		}
+	}
+	statusUntracked, statusIgnored = "normal", true
+	if got, _ := statusFileArgs(); !reflect.DeepEqual(got, []string{"--untracked-files=normal", "--ignored"}) {
+		t.Errorf("--untracked=normal --ignored: %q", got)
+	}
+	statusUntracked = "no"
+	if _, err := statusFileArgs(); exitCode(err) != exitUsage {
+		t.Errorf("--ignored with --untracked=no accepted: %v", err)
+	}
+	statusUntracked, statusIgnored = "some", false
+	if _, err := statusFileArgs(); exitCode(err) != exitUsage {
+		t.Errorf("invalid --untracked accepted: %v", err)
+	}
+}
+
+func TestStatusUntrackedAndIgnored(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, ".gitignore", "*.log\n", "ignore logs")
+	for _, f := range []string{"build/a.o", "build/b.o", "notes.txt", "run.log"} {
+		path := filepath.Join(repo, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		opts               []string
+		untracked, ignored int
+	}{
+		{nil, 2, 0}, // build/ and notes.txt
+		{[]string{"--untracked-files=all"}, 3, 0},
+		{[]string{"--untracked-files=no"}, 0, 0},
+		{[]string{"--ignored"}, 2, 1},
+	} {
+		s, err := readRepoStateUnder(ctx, repo, nil, tc.opts...)
+		if err != nil || s.Untracked != tc.untracked || s.Ignored != tc.ignored {
+			t.Errorf("%q: untracked=%d ignored=%d, %v", tc.opts, s.Untracked, s.Ignored, err)
+		}
+	}
+
+	var b strings.Builder
+	writeStatusSummary(&b, []repoState{{Repo: "/r/a", Branch: "main", Ignored: 4}})
+	if !strings.Contains(b.String(), "clean, 4 ignored") {
+		t.Errorf("summary:\n%s", b.String())
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+// runContext returns the context of a run over repositories. It has no
+// deadline of its own: --timeout bounds each git command instead, through
+// timeoutExecutor, so the last repositories of a long run get as much time
+// as the first.
+func runContext() (context.Context, context.CancelFunc) {
+	return context.WithCancel(context.Background())
+}
+
+// commandContext bounds one command by --timeout; zero means no limit.
+// Commands other than git, such as gh or ssh-add, use it directly.
+func commandContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if defaultTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, defaultTimeout)
+}
+
+// timeoutExecutor gives every git command its own --timeout.
+type timeoutExecutor struct {
+	next runner.GitExecutor
+}
+
+func (e timeoutExecutor) Stream(ctx context.Context, c runner.Cmd) error {
+	ctx, cancel := commandContext(ctx)
+	defer cancel()
+	return e.next.Stream(ctx, c)
+}
+
+func (e timeoutExecutor) Capture(ctx context.Context, c runner.Cmd) (string, error) {
+	ctx, cancel := commandContext(ctx)
+	defer cancel()
+	return e.next.Capture(ctx, c)
+}
+
+func (e timeoutExecutor) Output(ctx context.Context, c runner.Cmd) (string, error) {
+	ctx, cancel := commandContext(ctx)
+	defer cancel()
+	return e.next.Output(ctx, c)
+}
+
+func (e timeoutExecutor) Lines(ctx context.Context, c runner.Cmd, fn func(string)) error {
+	ctx, cancel := commandContext(ctx)
+	defer cancel()
+	return e.next.Lines(ctx, c, fn)
+}
+
+// setupTimeout checks --timeout and routes git through timeoutExecutor,
+// right above the git binary, so neither waiting for a host slot nor the
+// delay between retries counts against it.
+func setupTimeout() error {
+	if defaultTimeout < 0 {
+		return usageErrorf("invalid --timeout %s (expected a positive duration, or 0 for no limit)", defaultTimeout)
+	}
+	gitExec = timeoutExecutor{next: gitExec}
+	return nil
+}
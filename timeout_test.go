@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+func TestTimeoutExecutor(t *testing.T) {
+	prev := defaultTimeout
+	defer func() { defaultTimeout = prev }()
+	defaultTimeout = 500 * time.Millisecond
+
+	exe := timeoutExecutor{next: runner.Exec{}}
+	ctx, cancel := runContext()
+	defer cancel()
+	sleep := func(d string) error {
+		_, err := exe.Output(ctx, runner.Git(t.TempDir(), "-c", "alias.slow=!sleep "+d, "slow"))
+		return err
+	}
+	// each command gets its own limit, however long the run has taken
+	for i := 0; i < 3; i++ {
+		if err := sleep("0.3"); err != nil {
+			t.Fatalf("command %d: %v", i, err)
+		}
+	}
+	if err := sleep("1.5"); err == nil {
+		t.Error("command past --timeout was not killed")
+	}
+
+	defaultTimeout = 0
+	if err := sleep("0.6"); err != nil {
+		t.Errorf("--timeout 0: %v", err)
+	}
+}
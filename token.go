@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// authToken is --auth-token: a token that git sends as the password for
+// HTTPS remotes. GITBATCH_TOKEN is used when it is not set.
+var authToken string
+
+// authHosts is --auth-host: the hosts that get the token; every HTTPS host
+// when empty.
+var authHosts []string
+
+// tokenEnv holds the token for gitbatch running as git's credential helper.
+const tokenEnv = "GITBATCH_TOKEN"
+
+// credentialHelperArg is the first argument of gitbatch running as git's
+// credential helper.
+const credentialHelperArg = "credential-helper"
+
+// tokenHelperEnv configures helper as the only credential helper, for
+// hosts or for every host, through git's GIT_CONFIG_COUNT variables. An
+// empty credential.helper drops the helpers configured before it, so a
+// stale password from another helper cannot win over the token. The
+// entries are numbered from first, after the ones already set in env.
+func tokenHelperEnv(helper string, hosts []string, first int) []string {
+	keys := []string{"credential.helper"}
+	if len(hosts) > 0 {
+		keys = nil
+		for _, h := range hosts {
+			keys = append(keys, "credential.https://"+h+".helper")
+		}
+	}
+	env := []string{fmt.Sprintf("GIT_CONFIG_COUNT=%d", first+2*len(keys))}
+	for i, k := range keys {
+		n := first + 2*i
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", n, k), fmt.Sprintf("GIT_CONFIG_VALUE_%d=", n),
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", n+1, k), fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", n+1, helper))
+	}
+	return env
+}
+
+// configCount returns the last GIT_CONFIG_COUNT in env, or 0 without one.
+func configCount(env []string) (int, error) {
+	count := 0
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, "GIT_CONFIG_COUNT="); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return 0, usageErrorf("invalid --env GIT_CONFIG_COUNT=%s", v)
+			}
+			count = n
+		}
+	}
+	return count, nil
+}
+
+// authTokenEnv returns the environment that makes git use the token of
+// --auth-token or GITBATCH_TOKEN, or nil without one. The token itself is
+// only handed down in GITBATCH_TOKEN, through secretEnv to git and from
+// there to gitbatch answering as credential helper, so it never ends up in
+// a remote URL, a config file, a script written by --emit-script or the
+// environment of hooks, plugins and the pager. The helper is configured
+// after the entries of a GIT_CONFIG_COUNT in --env, which it keeps.
+func authTokenEnv() ([]string, error) {
+	token := authToken
+	if token == "" {
+		token = os.Getenv(tokenEnv)
+	}
+	if token == "" {
+		if len(authHosts) > 0 {
+			return nil, usageErrorf("--auth-host needs --auth-token or %s", tokenEnv)
+		}
+		return nil, nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("--auth-token: %v", err)
+	}
+	first, err := configCount(extraEnv)
+	if err != nil {
+		return nil, err
+	}
+	secretEnv = append(secretEnv, tokenEnv+"="+token)
+	return tokenHelperEnv("!"+shellQuote(exe)+" "+credentialHelperArg, authHosts, first), nil
+}
+
+// redactArgs returns args with the value of --auth-token replaced, for
+// command lines that are recorded or echoed.
+func redactArgs(args []string) []string {
+	out := append([]string{}, args...)
+	for i := 0; i < len(out); i++ {
+		switch {
+		case out[i] == "--":
+			return out
+		case out[i] == "--auth-token" && i+1 < len(out):
+			i++
+			out[i] = redacted
+		case strings.HasPrefix(out[i], "--auth-token="):
+			out[i] = "--auth-token=" + redacted
+		}
+	}
+	return out
+}
+
+// redacted stands in for a secret in recorded command lines.
+const redacted = "REDACTED"
+
+// credentialHelper answers git's credential helper operation op, reading
+// the request from in. Only get is answered, and only for HTTPS, so the
+// token is never sent in clear text; store and erase are ignored, since
+// there is nothing to keep.
+func credentialHelper(op, token string, in io.Reader, out io.Writer) {
+	if op != "get" {
+		return
+	}
+	attrs := map[string]string{}
+	s := bufio.NewScanner(in)
+	for s.Scan() && s.Text() != "" {
+		if k, v, ok := strings.Cut(s.Text(), "="); ok {
+			attrs[k] = v
+		}
+	}
+	if attrs["protocol"] != "https" {
+		return
+	}
+	user := attrs["username"]
+	if user == "" {
+		// GitHub takes any username with a token; this one names the token
+		// in its logs
+		user = "x-access-token"
+	}
+	fmt.Fprintf(out, "username=%s\npassword=%s\n", user, token)
+}
+
+// runCredentialHelper answers git when gitbatch runs as its credential
+// helper, and reports whether it did.
+func runCredentialHelper(args []string) (code int, ok bool) {
+	token := os.Getenv(tokenEnv)
+	if len(args) != 2 || args[0] != credentialHelperArg || token == "" {
+		return 0, false
+	}
+	credentialHelper(args[1], token, os.Stdin, os.Stdout)
+	return 0, true
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&authToken, "auth-token", "", "token for HTTPS remotes, given to git through a temporary credential helper (default $"+tokenEnv+")")
+	rootCmd.PersistentFlags().StringSliceVar(&authHosts, "auth-host", nil, "only send the token to these hosts (default: every HTTPS host)")
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/patrickkdev/gitbatch/pkg/runner"
+)
+
+func TestCredentialHelper(t *testing.T) {
+	for in, want := range map[string]string{
+		"protocol=https\nhost=github.com\n\n":              "username=x-access-token\npassword=tok\n",
+		"protocol=https\nhost=github.com\nusername=ci\n\n": "username=ci\npassword=tok\n",
+		"protocol=http\nhost=github.com\n\n":               "",
+	} {
+		var out strings.Builder
+		credentialHelper("get", "tok", strings.NewReader(in), &out)
+		if out.String() != want {
+			t.Errorf("get %q = %q, want %q", in, out.String(), want)
+		}
+	}
+	var out strings.Builder
+	credentialHelper("store", "tok", strings.NewReader("protocol=https\nhost=github.com\n\n"), &out)
+	if out.Len() != 0 {
+		t.Errorf("store answered %q", out.String())
+	}
+}
+
+func TestTokenHelperEnv(t *testing.T) {
+	repo := initTestRepo(t)
+	if _, err := runGitCapture(context.Background(), repo, "config", "credential.helper", "!f() { echo username=me; echo password=stale; }; f"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(tokenEnv, "tok")
+	helper := `!f() { test "$1" = get && echo username=ci && echo password=$GITBATCH_TOKEN; }; f`
+	fill := func(env []string, url string) string {
+		cmd := exec.Command("git", "credential", "fill")
+		cmd.Dir = repo
+		cmd.Env = runner.Environ(env...)
+		cmd.Stdin = strings.NewReader("url=" + url + "\n\n")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git credential fill %s: %v", url, err)
+		}
+		return string(out)
+	}
+
+	if out := fill(tokenHelperEnv(helper, nil, 0), "https://git.example.com/a.git"); !strings.Contains(out, "password=tok\n") {
+		t.Errorf("token not used: %q", out)
+	}
+	env := tokenHelperEnv(helper, []string{"git.example.com"}, 0)
+	if out := fill(env, "https://git.example.com/a.git"); !strings.Contains(out, "password=tok\n") {
+		t.Errorf("token not used for --auth-host: %q", out)
+	}
+	if out := fill(env, "https://other.example.com/a.git"); !strings.Contains(out, "password=stale\n") {
+		t.Errorf("token sent to another host: %q", out)
+	}
+}
+
+func TestSetupAuthToken(t *testing.T) {
+	prevExec, prevBinary, prevConfig := gitExec, gitBinary, loadedConfig
+	defer func() {
+		gitExec, gitBinary, loadedConfig, authToken, authHosts, secretEnv, extraEnv = prevExec, prevBinary, prevConfig, "", nil, nil, nil
+	}()
+	loadedConfig = &Config{}
+	t.Setenv(tokenEnv, "")
+
+	authHosts = []string{"github.com"}
+	if err := setupGitBinary(); exitCode(err) != exitUsage {
+		t.Errorf("--auth-host without a token accepted: %v", err)
+	}
+
+	authToken = "tok"
+	if err := setupGitBinary(); err != nil {
+		t.Fatal(err)
+	}
+	// only git gets the token, not hooks, plugins or the pager
+	if os.Getenv(tokenEnv) != "" {
+		t.Errorf("%s set in the process environment", tokenEnv)
+	}
+	env, err := gitExec.Output(context.Background(), runner.Git(t.TempDir(), "-c", "alias.token=!printenv "+tokenEnv, "token"))
+	if err != nil || env != "tok" {
+		t.Errorf("%s in git = %q, %v", tokenEnv, env, err)
+	}
+	env = strings.Join(gitBinary.Env, "\n")
+	if strings.Contains(env, "tok\n") || !strings.Contains(env, "GIT_CONFIG_KEY_1=credential.https://github.com.helper") || !strings.Contains(env, " "+credentialHelperArg) {
+		t.Errorf("git environment:\n%s", env)
+	}
+
+	// the helper is numbered after the user's own GIT_CONFIG entries
+	extraEnv = []string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=gitbatch.test", "GIT_CONFIG_VALUE_0=kept"}
+	gitBinary = prevBinary
+	if err := setupGitBinary(); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if got, err := gitExec.Output(context.Background(), runner.Git(dir, "config", "gitbatch.test")); err != nil || got != "kept" {
+		t.Errorf("--env GIT_CONFIG entry = %q, %v", got, err)
+	}
+	if got, err := gitExec.Output(context.Background(), runner.Git(dir, "config", "--get-all", "credential.https://github.com.helper")); err != nil || !strings.HasSuffix(got, " "+credentialHelperArg) {
+		t.Errorf("token helper = %q, %v", got, err)
+	}
+	extraEnv = []string{"GIT_CONFIG_COUNT=x"}
+	if err := setupGitBinary(); exitCode(err) != exitUsage {
+		t.Errorf("invalid GIT_CONFIG_COUNT accepted: %v", err)
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	got := redactArgs([]string{"pull", "--auth-token", "s3cret", "--auth-token=s3cret", "api", "--", "--auth-token", "x"})
+	want := []string{"pull", "--auth-token", redacted, "--auth-token=" + redacted, "api", "--", "--auth-token", "x"}
+	if !slices.Equal(got, want) {
+		t.Errorf("redactArgs = %q", got)
+	}
+}
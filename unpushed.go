@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// branchWork describes a local branch holding commits that are not on any
+// remote-tracking ref.
+type branchWork struct {
+	name      string
+	commits   int
+	localOnly bool // no upstream configured
+}
+
+// repoWork summarizes everything in a repository that exists only locally.
+type repoWork struct {
+	repo     string
+	commits  int // distinct commits on local branches missing from every remote
+	stashes  stashInfo
+	branches []branchWork
+}
+
+func (w repoWork) empty() bool {
+	return w.commits == 0 && w.stashes.count == 0
+}
+
+// unpushedWork inspects dir for commits not reachable from any remote, per
+// branch and in total, and reads its stash entries.
+func unpushedWork(ctx context.Context, dir string) (repoWork, error) {
+	w := repoWork{repo: dir}
+
+	total, err := gitOutput(ctx, dir, "rev-list", "--count", "--branches", "--not", "--remotes")
+	if err != nil {
+		return w, err
+	}
+	if w.commits, err = strconv.Atoi(total); err != nil {
+		return w, fmt.Errorf("unexpected rev-list output %q", total)
+	}
+
+	refs, err := gitOutput(ctx, dir, "for-each-ref", "--format=%(refname:short)%00%(upstream:short)", "refs/heads")
+	if err != nil {
+		return w, err
+	}
+	for _, line := range strings.Split(refs, "\n") {
+		if line == "" {
+			continue
+		}
+		name, upstream, _ := strings.Cut(line, "\x00")
+		out, err := gitOutput(ctx, dir, "rev-list", "--count", "refs/heads/"+name, "--not", "--remotes")
+		if err != nil {
+			return w, err
+		}
+		n, _ := strconv.Atoi(out)
+		if n > 0 {
+			w.branches = append(w.branches, branchWork{name: name, commits: n, localOnly: upstream == ""})
+		}
+	}
+
+	w.stashes, err = readStashes(ctx, dir)
+	return w, err
+}
+
+// unpushed command
+var unpushedCmd = &cobra.Command{
+	Use:   "unpushed <pattern>...",
+	Short: "List repositories with commits or stashes that exist only locally",
+	Long: `unpushed reports, per repository, commits on local branches that are not
+on any remote-tracking ref, the branches holding them (marked "local" when
+the branch has no upstream), the number of stash entries and the age of
+the oldest one. Repositories with the most unpushed commits are listed
+first. --warn-stash-older-than also warns on stderr about repositories whose
+oldest stash is older than the threshold.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := runContext()
+		defer cancel()
+
+		var found []repoWork
+		now := time.Now()
+		for _, r := range repos {
+			w, err := unpushedWork(ctx, r)
+			if err != nil {
+				repoFailed(r, err)
+				continue
+			}
+			warnOldStash(r, w.stashes, now)
+			if !w.empty() {
+				found = append(found, w)
+			}
+		}
+		if len(found) == 0 {
+			fmt.Printf("nothing unpushed in %d repositories\n", len(repos))
+			return nil
+		}
+		sort.SliceStable(found, func(i, j int) bool {
+			if found[i].commits != found[j].commits {
+				return found[i].commits > found[j].commits
+			}
+			return found[i].stashes.count > found[j].stashes.count
+		})
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "COMMITS\tSTASHES\tOLDEST STASH\tREPO\tBRANCHES")
+		for _, f := range found {
+			var branches []string
+			for _, b := range f.branches {
+				s := fmt.Sprintf("%s(+%d", b.name, b.commits)
+				if b.localOnly {
+					s += ", local"
+				}
+				branches = append(branches, s+")")
+			}
+			oldest := "-"
+			if f.stashes.count > 0 {
+				oldest = formatAge(f.stashes.age(now))
+			}
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", f.commits, f.stashes.count, oldest, f.repo, strings.Join(branches, " "))
+		}
+		w.Flush()
+		fmt.Printf("\n%d of %d repositories have unpushed work\n", len(found), len(repos))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(unpushedCmd)
+
+	addStashWarnFlag(unpushedCmd)
+}
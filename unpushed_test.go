@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnpushedWork(t *testing.T) {
+	ctx := context.Background()
+	bare := filepath.Join(t.TempDir(), "origin.git")
+	if out, err := exec.Command("git", "init", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare failed: %v, out=%s", err, out)
+	}
+
+	repo := initTestRepo(t)
+	commitTestFile(t, repo, "a.txt", "a", "first")
+	for _, args := range [][]string{
+		{"branch", "-M", "main"},
+		{"remote", "add", "origin", bare},
+		{"push", "-u", "origin", "main"},
+	} {
+		if out, err := runGitCapture(ctx, repo, args...); err != nil {
+			t.Fatalf("git %v failed: %v, out=%s", args, err, out)
+		}
+	}
+
+	w, err := unpushedWork(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.empty() {
+		t.Fatalf("expected nothing unpushed after push, got %+v", w)
+	}
+
+	commitTestFile(t, repo, "b.txt", "b", "second")
+	if out, err := runGitCapture(ctx, repo, "switch", "-c", "wip"); err != nil {
+		t.Fatalf("git switch failed: %v, out=%s", err, out)
+	}
+	commitTestFile(t, repo, "c.txt", "c", "third")
+	if err := os.WriteFile(filepath.Join(repo, "a.txt"), []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := runGitCapture(ctx, repo, "stash"); err != nil {
+		t.Fatalf("git stash failed: %v, out=%s", err, out)
+	}
+
+	w, err = unpushedWork(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.commits != 2 || w.stashes.count != 1 {
+		t.Errorf("expected 2 commits and 1 stash, got %+v", w)
+	}
+	if len(w.branches) != 2 {
+		t.Fatalf("expected main and wip to be listed, got %+v", w.branches)
+	}
+	for _, b := range w.branches {
+		if b.name == "wip" && (!b.localOnly || b.commits != 2) {
+			t.Errorf("expected wip to be local-only with 2 commits, got %+v", b)
+		}
+		if b.name == "main" && (b.localOnly || b.commits != 1) {
+			t.Errorf("expected main to track origin with 1 commit, got %+v", b)
+		}
+	}
+}
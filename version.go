@@ -0,0 +1,265 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Build metadata, set by release builds with
+//
+//	go build -ldflags "-X main.version=v1.4.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%FT%TZ)"
+//
+// Builds without them fall back to what the Go toolchain records: the module
+// version for go install, and the commit and its time for go build in a
+// checkout.
+var (
+	version   = ""
+	commit    = ""
+	buildDate = ""
+)
+
+// buildMetadata is the version information of the running binary.
+type buildMetadata struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// currentBuild merges the ldflags values with the module version of
+// go install and the VCS stamp of go build in a checkout.
+func currentBuild() buildMetadata {
+	b := buildMetadata{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if b.Version == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			b.Version = info.Main.Version
+		}
+		settings := map[string]string{}
+		for _, s := range info.Settings {
+			settings[s.Key] = s.Value
+		}
+		if b.Commit == "" && settings["vcs.revision"] != "" {
+			b.Commit = settings["vcs.revision"]
+			if settings["vcs.modified"] == "true" {
+				b.Commit += "-dirty"
+			}
+		}
+		if b.BuildDate == "" {
+			b.BuildDate = settings["vcs.time"]
+		}
+	}
+	if b.Version == "" {
+		b.Version = "dev"
+	}
+	return b
+}
+
+// parseRelease splits a release tag such as v1.4.0 or v1.5.0-rc.1 into its
+// numbers and pre-release suffix.
+func parseRelease(tag string) (v [3]int, pre string, ok bool) {
+	s, pre, _ := strings.Cut(strings.TrimPrefix(tag, "v"), "-")
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return v, "", false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return v, "", false
+		}
+		v[i] = n
+	}
+	return v, pre, true
+}
+
+// newerRelease reports whether release tag latest is newer than current.
+// A release is newer than its own pre-releases; pre-releases of the same
+// version are compared as semver does it, so rc.10 is newer than rc.9.
+func newerRelease(latest, current string) (bool, error) {
+	l, lpre, ok := parseRelease(latest)
+	if !ok {
+		return false, fmt.Errorf("cannot parse release %q", latest)
+	}
+	c, cpre, ok := parseRelease(current)
+	if !ok {
+		return false, fmt.Errorf("cannot compare with version %q", current)
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i], nil
+		}
+	}
+	switch {
+	case lpre == cpre:
+		return false, nil
+	case lpre == "":
+		return true, nil
+	case cpre == "":
+		return false, nil
+	}
+	return comparePrerelease(lpre, cpre) > 0, nil
+}
+
+// comparePrerelease compares two pre-release suffixes such as rc.9 and
+// rc.10 identifier by identifier: numbers by value and below words, words
+// as strings, and a suffix below the longer ones it starts.
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if c := cmp.Compare(an, bn); c != 0 {
+				return c
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// releasesURL is the GitHub API endpoint listing gitbatch releases, newest
+// first.
+var releasesURL = "https://api.github.com/repos/patrickkdev/gitbatch/releases"
+
+// githubRelease is the part of a GitHub release gitbatch uses.
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	HTMLURL    string `json:"html_url"`
+	Prerelease bool   `json:"prerelease"`
+	Draft      bool   `json:"draft"`
+	Assets     []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// assetURL returns the download URL of the release file named name.
+func (r githubRelease) assetURL(name string) (string, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL, true
+		}
+	}
+	return "", false
+}
+
+// latestRelease returns the newest published release, including
+// pre-releases when prerelease is set.
+func latestRelease(ctx context.Context, prerelease bool) (githubRelease, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releasesURL, nil)
+	if err != nil {
+		return githubRelease{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return githubRelease{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return githubRelease{}, fmt.Errorf("%s returned %s", releasesURL, resp.Status)
+	}
+	var releases []githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return githubRelease{}, fmt.Errorf("%s: %v", releasesURL, err)
+	}
+	for _, r := range releases {
+		if !r.Draft && (prerelease || !r.Prerelease) {
+			return r, nil
+		}
+	}
+	return githubRelease{}, fmt.Errorf("no published release found at %s", releasesURL)
+}
+
+// version command
+var versionCheckUpdate bool
+var versionJSON bool
+var versionCmd = &cobra.Command{
+	Use:   "version [--check-update] [--json]",
+	Short: "Print the gitbatch version, commit, build date and Go version",
+	Long: `version prints what the running binary was built from, for bug reports:
+its version, commit, build date, Go version and platform. With
+--check-update it also asks GitHub for the latest release and says on stderr
+whether it is newer. --json prints the build information as a JSON object.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		b := currentBuild()
+		if versionJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(b); err != nil {
+				return err
+			}
+		} else {
+			fmt.Printf("gitbatch %s\n", b.Version)
+			fmt.Printf("commit:   %s\n", valueOr(b.Commit, "unknown"))
+			fmt.Printf("built:    %s\n", valueOr(b.BuildDate, "unknown"))
+			fmt.Printf("go:       %s\n", b.GoVersion)
+			fmt.Printf("platform: %s\n", b.Platform)
+		}
+		if !versionCheckUpdate {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		latest, err := latestRelease(ctx, false)
+		if err != nil {
+			return fmt.Errorf("checking for updates: %v", err)
+		}
+		// on stderr, so --json output stays parseable
+		newer, err := newerRelease(latest.TagName, b.Version)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "latest release is %s (%s); %v\n", latest.TagName, latest.HTMLURL, err)
+		case newer:
+			fmt.Fprintln(os.Stderr, paint(os.Stderr, ansiYellow, fmt.Sprintf("gitbatch %s is available: %s", latest.TagName, latest.HTMLURL)))
+		default:
+			fmt.Fprintf(os.Stderr, "gitbatch %s is the latest release\n", b.Version)
+		}
+		return nil
+	},
+}
+
+// valueOr returns s, or def when s is empty.
+func valueOr(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+	rootCmd.Version = currentBuild().Version
+
+	versionCmd.Flags().BoolVar(&versionCheckUpdate, "check-update", false, "also check GitHub for a newer release")
+	versionCmd.Flags().BoolVar(&versionJSON, "json", false, "print the build metadata as JSON")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewerRelease(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v1.4.0", "v1.3.9", true},
+		{"v1.4.0", "v1.4.0", false},
+		{"v1.4.0", "v1.10.0", false},
+		{"v2.0.0", "v1.99.99", true},
+		{"v1.4.0", "v1.4.0-rc.1", true},
+		{"v1.4.0-rc.2", "v1.4.0-rc.1", true},
+		{"v1.4.0-rc.1", "v1.4.0", false},
+		{"v1.4.0-rc.10", "v1.4.0-rc.9", true},
+		{"v1.4.0-rc.9", "v1.4.0-rc.10", false},
+		{"v1.4.0-rc.1", "v1.4.0-rc", true},
+		{"v1.4.0-rc.1", "v1.4.0-beta.2", true},
+		{"v1.4.0-alpha", "v1.4.0-1", true},
+		{"v1.4.0", "v0.0.0-20260101000000-abcdef123456", true},
+	}
+	for _, tt := range tests {
+		got, err := newerRelease(tt.latest, tt.current)
+		if err != nil || got != tt.want {
+			t.Errorf("newerRelease(%q, %q) = %v, %v, want %v", tt.latest, tt.current, got, err, tt.want)
+		}
+	}
+	if _, err := newerRelease("v1.4.0", "dev"); err == nil {
+		t.Error("dev build compared")
+	}
+}
+
+func TestVersionCheckUpdate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"tag_name": "v2.0.0-rc.1", "prerelease": true, "html_url": "https://example.com/rc"},
+			{"tag_name": "v1.5.0", "draft": true, "html_url": "https://example.com/draft"},
+			{"tag_name": "v1.4.0", "html_url": "https://example.com/v1.4.0"}
+		]`))
+	}))
+	defer srv.Close()
+	oldURL, oldVersion, oldCheck := releasesURL, version, versionCheckUpdate
+	t.Cleanup(func() { releasesURL, version, versionCheckUpdate = oldURL, oldVersion, oldCheck })
+	releasesURL, versionCheckUpdate = srv.URL, true
+
+	for current, want := range map[string]string{
+		"v1.3.0": "gitbatch v1.4.0 is available: https://example.com/v1.4.0",
+		"v1.4.0": "gitbatch v1.4.0 is the latest release",
+	} {
+		version = current
+		var err error
+		out := captureStderr(t, func() { err = versionCmd.RunE(versionCmd, nil) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, want) {
+			t.Errorf("version %s: stderr = %q, want %q", current, out, want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// remoteRefs returns the remote-tracking branches of repo mapped to the
+// commits they point at.
+func remoteRefs(ctx context.Context, repo string) (map[string]string, error) {
+	out, err := gitOutput(ctx, repo, "for-each-ref", "--format=%(refname:short) %(objectname)", "refs/remotes")
+	if err != nil {
+		return nil, err
+	}
+	refs := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		name, hash, ok := strings.Cut(line, " ")
+		if ok && !strings.HasSuffix(name, "/HEAD") {
+			refs[name] = hash
+		}
+	}
+	return refs, nil
+}
+
+// refChanges describes how the remote-tracking branches moved between two
+// snapshots, one line per branch in name order.
+func refChanges(ctx context.Context, repo string, before, after map[string]string) []string {
+	var changes []string
+	for name, hash := range after {
+		old, ok := before[name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("%s: new branch at %s", name, hash[:7]))
+		case old != hash:
+			line := fmt.Sprintf("%s: %s..%s", name, old[:7], hash[:7])
+			if n, err := gitOutput(ctx, repo, "rev-list", "--count", old+".."+hash); err == nil {
+				line += " (" + n + " new commits)"
+			}
+			if !isAncestor(ctx, repo, old, hash) {
+				line += " forced"
+			}
+			changes = append(changes, line)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			changes = append(changes, name+": deleted")
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// watchRound fetches every repository and returns the branch changes per
+// repository that saw any. The fetch results are recorded in results.
+func watchRound(ctx context.Context, repos []string, results *opResults) map[string][]string {
+	moved := map[string][]string{}
+	for _, r := range repos {
+		before, err := remoteRefs(ctx, r)
+		if err != nil {
+			repoFailed(r, err)
+			continue
+		}
+		err = gitChange(ctx, r, "fetch", "--all", "--prune", "--quiet")
+		results.record(r, err == nil)
+		if err != nil {
+			repoFailed(r, err)
+			continue
+		}
+		after, err := remoteRefs(ctx, r)
+		if err != nil {
+			repoFailed(r, err)
+			continue
+		}
+		if c := refChanges(ctx, r, before, after); len(c) > 0 {
+			moved[r] = c
+		}
+	}
+	return moved
+}
+
+// watch command
+var watchInterval time.Duration
+var watchOnce bool
+var watchMetricsListen string
+var watchCmd = &cobra.Command{
+	Use:   "watch [--interval 15m] <pattern>...",
+	Short: "Periodically fetch matching repositories and report upstream changes",
+	Long: `watch fetches every repository each --interval and prints which
+remote-tracking branches moved, were created or were deleted since the
+previous fetch. With --notify (or notify.webhook in the config file) each
+round with changes is also posted to the webhook. --metrics-listen serves
+Prometheus gauges for the watched repositories on /metrics. Stop it with
+Ctrl-C.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if watchInterval < time.Minute && !watchOnce {
+			return usageErrorf("--interval must be at least 1m, got %s", watchInterval)
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		webhook := notifyWebhook
+		if webhook == "" {
+			webhook = cfg.Notify.Webhook
+		}
+		// watch posts its own messages, not a summary when it exits
+		notifyWebhook, cfg.Notify.Webhook = "", ""
+
+		sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		results := newOpResults()
+		if watchMetricsListen != "" {
+			mux := http.NewServeMux()
+			mux.Handle("GET /metrics", metricsHandler(func(*http.Request) ([]string, error) { return repos, nil }, results))
+			srv := &http.Server{Addr: watchMetricsListen, Handler: mux}
+			go func() {
+				if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					fmt.Fprintf(os.Stderr, "metrics: %v\n", err)
+				}
+			}()
+			defer srv.Close()
+			fmt.Printf("metrics on http://%s/metrics\n", watchMetricsListen)
+		}
+		fmt.Printf("watching %d repositories every %s\n", len(repos), watchInterval)
+		for round := 0; ; round++ {
+			// the workspace is locked only while fetching, so other runs
+			// can go ahead between rounds
+			if round > 0 {
+				if err := lockWorkspace(repos); err != nil {
+					fmt.Fprintf(os.Stderr, "skipping round: %v\n", err)
+					if !sleepCtx(sigCtx, watchInterval) {
+						return nil
+					}
+					continue
+				}
+			}
+			moved := watchRound(sigCtx, repos, results)
+			unlockWorkspace()
+
+			var b strings.Builder
+			names := make([]string, 0, len(moved))
+			for r := range moved {
+				names = append(names, r)
+			}
+			sort.Strings(names)
+			for _, r := range names {
+				fmt.Fprintf(&b, "\n%s\n  %s\n", r, strings.Join(moved[r], "\n  "))
+			}
+			if b.Len() > 0 {
+				report := fmt.Sprintf("%s: upstream changes in %d repositories\n%s", time.Now().Format("15:04:05"), len(moved), b.String())
+				fmt.Print(report)
+				if webhook != "" {
+					n := notification{Text: report, Command: "watch", Repos: len(repos), Failed: []string{}}
+					pctx, pcancel := context.WithTimeout(sigCtx, 10*time.Second)
+					if err := postNotification(pctx, webhook, n); err != nil {
+						fmt.Fprintf(os.Stderr, "notify: %v\n", err)
+					}
+					pcancel()
+				}
+			}
+
+			if watchOnce {
+				return nil
+			}
+			if !sleepCtx(sigCtx, watchInterval) {
+				return nil
+			}
+		}
+	},
+}
+
+// sleepCtx waits for d and reports false if ctx is done first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 15*time.Minute, "time between fetches (e.g. 15m, 1h)")
+	watchCmd.Flags().StringVar(&watchMetricsListen, "metrics-listen", "", "serve Prometheus metrics on this address (e.g. 127.0.0.1:9070)")
+	watchCmd.Flags().BoolVar(&watchOnce, "once", false, "fetch and report a single time, then exit")
+}
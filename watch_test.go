@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWatchRound(t *testing.T) {
+	ctx := context.Background()
+	bare := filepath.Join(t.TempDir(), "origin.git")
+	if out, err := exec.Command("git", "init", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare failed: %v, out=%s", err, out)
+	}
+	upstream := initTestRepo(t)
+	commitTestFile(t, upstream, "a.txt", "a", "first")
+	push := func(args ...string) {
+		t.Helper()
+		if out, err := runGitCapture(ctx, upstream, append([]string{"push", bare}, args...)...); err != nil {
+			t.Fatalf("git push failed: %v, out=%s", err, out)
+		}
+	}
+	push("HEAD:refs/heads/main", "HEAD:refs/heads/old")
+
+	clone := filepath.Join(t.TempDir(), "clone")
+	if out, err := exec.Command("git", "clone", "-q", bare, clone).CombinedOutput(); err != nil {
+		t.Fatalf("git clone failed: %v, out=%s", err, out)
+	}
+	if moved := watchRound(ctx, []string{clone}, newOpResults()); len(moved) != 0 {
+		t.Fatalf("expected no changes right after clone, got %v", moved)
+	}
+
+	commitTestFile(t, upstream, "a.txt", "b", "second")
+	commitTestFile(t, upstream, "a.txt", "c", "third")
+	push("HEAD:refs/heads/main", "HEAD:refs/heads/feature", ":refs/heads/old")
+
+	changes := watchRound(ctx, []string{clone}, newOpResults())[clone]
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %q", changes)
+	}
+	if !strings.HasPrefix(changes[0], "origin/feature: new branch at ") ||
+		!strings.HasSuffix(changes[1], "(2 new commits)") || !strings.HasPrefix(changes[1], "origin/main: ") ||
+		changes[2] != "origin/old: deleted" {
+		t.Errorf("unexpected changes: %q", changes)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// workspaceFolder is one folder of a VS Code multi-root workspace.
+type workspaceFolder struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path"`
+}
+
+// relativeTo returns path relative to dir, so the generated files keep
+// working when the whole workspace is moved. Paths that cannot be made
+// relative, such as those on another Windows drive, stay absolute.
+func relativeTo(dir, path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(dir, abs); err == nil {
+		return filepath.ToSlash(rel), nil
+	}
+	return abs, nil
+}
+
+// vscodeWorkspace builds the .code-workspace file at file for repos. Folders
+// are named after their path relative to the current directory when two of
+// them share a base name.
+func vscodeWorkspace(file string, repos []string) ([]byte, error) {
+	dir, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	bases := map[string]int{}
+	for _, r := range repos {
+		bases[filepath.Base(r)]++
+	}
+	ws := struct {
+		Folders  []workspaceFolder `json:"folders"`
+		Settings struct{}          `json:"settings"`
+	}{Folders: []workspaceFolder{}}
+	for _, r := range repos {
+		path, err := relativeTo(dir, r)
+		if err != nil {
+			return nil, err
+		}
+		f := workspaceFolder{Path: path}
+		if bases[filepath.Base(r)] > 1 {
+			if f.Name, err = relativeTo(cwd, r); err != nil {
+				return nil, err
+			}
+		}
+		ws.Folders = append(ws.Folders, f)
+	}
+	data, err := json.MarshalIndent(ws, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// jetbrainsVCS builds .idea/vcs.xml for a JetBrains project in dir, with
+// every repository registered as a git root.
+func jetbrainsVCS(dir string, repos []string) ([]byte, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	type mapping struct {
+		Directory string `xml:"directory,attr"`
+		VCS       string `xml:"vcs,attr"`
+	}
+	vcs := struct {
+		XMLName   xml.Name `xml:"project"`
+		Version   string   `xml:"version,attr"`
+		Component struct {
+			Name     string    `xml:"name,attr"`
+			Mappings []mapping `xml:"mapping"`
+		} `xml:"component"`
+	}{Version: "4"}
+	vcs.Component.Name = "VcsDirectoryMappings"
+	for _, r := range repos {
+		path, err := relativeTo(dir, r)
+		if err != nil {
+			return nil, err
+		}
+		if path == "." {
+			path = "$PROJECT_DIR$"
+		} else if !filepath.IsAbs(path) {
+			path = "$PROJECT_DIR$/" + path
+		}
+		vcs.Component.Mappings = append(vcs.Component.Mappings, mapping{Directory: path, VCS: "Git"})
+	}
+	data, err := xml.MarshalIndent(vcs, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// workspace command
+var workspaceVSCode string
+var workspaceJetBrains string
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace [--vscode <file>] [--jetbrains <dir>] <pattern>...",
+	Short: "Generate editor workspace files from the matching repositories",
+	Long: `workspace writes editor configuration listing every matching repository.
+--vscode writes a multi-root VS Code workspace (a .code-workspace file) with
+one folder per repository. --jetbrains writes <dir>/.idea/vcs.xml, which
+registers every repository as a git root of the JetBrains project in <dir>.
+Paths are stored relative to the generated file where possible. Existing
+files are overwritten.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if workspaceVSCode == "" && workspaceJetBrains == "" {
+			return usageErrorf("at least one of --vscode and --jetbrains is required")
+		}
+		repos, err := collectRepos(args)
+		if err != nil {
+			return err
+		}
+
+		type output struct {
+			file  string
+			build func() ([]byte, error)
+		}
+		var outputs []output
+		if workspaceVSCode != "" {
+			outputs = append(outputs, output{workspaceVSCode, func() ([]byte, error) {
+				return vscodeWorkspace(workspaceVSCode, repos)
+			}})
+		}
+		if workspaceJetBrains != "" {
+			outputs = append(outputs, output{filepath.Join(workspaceJetBrains, ".idea", "vcs.xml"), func() ([]byte, error) {
+				return jetbrainsVCS(workspaceJetBrains, repos)
+			}})
+		}
+		for _, o := range outputs {
+			data, err := o.build()
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(o.file), 0o755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(o.file, data, 0o644); err != nil {
+				return err
+			}
+			fmt.Printf("wrote %s with %d repositories\n", o.file, len(repos))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(workspaceCmd)
+
+	workspaceCmd.Flags().StringVar(&workspaceVSCode, "vscode", "", "write a VS Code multi-root workspace to this .code-workspace file")
+	workspaceCmd.Flags().StringVar(&workspaceJetBrains, "jetbrains", "", "write .idea/vcs.xml in this JetBrains project directory")
+}
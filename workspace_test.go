@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceCommand(t *testing.T) {
+	dir := t.TempDir()
+	for _, r := range []string{"api", "web", "tools/api"} {
+		if out, err := exec.Command("git", "init", "-q", filepath.Join(dir, r)).CombinedOutput(); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+	}
+	t.Chdir(dir)
+	oldVSCode, oldJetBrains := workspaceVSCode, workspaceJetBrains
+	t.Cleanup(func() { workspaceVSCode, workspaceJetBrains = oldVSCode, oldJetBrains })
+
+	workspaceVSCode, workspaceJetBrains = "", ""
+	if err := workspaceCmd.RunE(workspaceCmd, []string{"**"}); err == nil {
+		t.Error("no output accepted")
+	}
+
+	workspaceVSCode, workspaceJetBrains = "ide/all.code-workspace", "."
+	if err := workspaceCmd.RunE(workspaceCmd, []string{"**"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "ide", "all.code-workspace"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ws struct{ Folders []workspaceFolder }
+	if err := json.Unmarshal(data, &ws); err != nil {
+		t.Fatal(err)
+	}
+	want := []workspaceFolder{
+		{Name: "api", Path: "../api"},
+		{Name: "tools/api", Path: "../tools/api"},
+		{Path: "../web"},
+	}
+	if !reflect.DeepEqual(ws.Folders, want) {
+		t.Errorf("folders = %+v, want %+v", ws.Folders, want)
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, ".idea", "vcs.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []string{`<mapping directory="$PROJECT_DIR$/api" vcs="Git"></mapping>`, `<mapping directory="$PROJECT_DIR$/tools/api" vcs="Git"></mapping>`} {
+		if !strings.Contains(string(data), m) {
+			t.Errorf("vcs.xml lacks %s:\n%s", m, data)
+		}
+	}
+}